@@ -120,6 +120,10 @@ func AllowAnonymousRequest(req *http.Request) bool {
 		return true
 	}
 
+	if strings.HasPrefix(req.URL.Path, "/.api/gitlab-webhooks") {
+		return true
+	}
+
 	if strings.HasPrefix(req.URL.Path, "/.api/bitbucket-server-webhooks") {
 		return true
 	}
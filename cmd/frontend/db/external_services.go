@@ -30,6 +30,7 @@ type ExternalServicesStore struct {
 	GitHubValidators          []func(*schema.GitHubConnection) error
 	GitLabValidators          []func(*schema.GitLabConnection, []schema.AuthProviders) error
 	BitbucketServerValidators []func(*schema.BitbucketServerConnection) error
+	GiteaValidators           []func(*schema.GiteaConnection) error
 }
 
 // ExternalServiceKinds contains a map of all supported kinds of
@@ -38,6 +39,8 @@ var ExternalServiceKinds = map[string]ExternalServiceKind{
 	"AWSCODECOMMIT":   {CodeHost: true, JSONSchema: schema.AWSCodeCommitSchemaJSON},
 	"BITBUCKETCLOUD":  {CodeHost: true, JSONSchema: schema.BitbucketCloudSchemaJSON},
 	"BITBUCKETSERVER": {CodeHost: true, JSONSchema: schema.BitbucketServerSchemaJSON},
+	"GERRIT":          {CodeHost: true, JSONSchema: schema.GerritSchemaJSON},
+	"GITEA":           {CodeHost: true, JSONSchema: schema.GiteaSchemaJSON},
 	"GITHUB":          {CodeHost: true, JSONSchema: schema.GitHubSchemaJSON},
 	"GITLAB":          {CodeHost: true, JSONSchema: schema.GitLabSchemaJSON},
 	"GITOLITE":        {CodeHost: true, JSONSchema: schema.GitoliteSchemaJSON},
@@ -130,6 +133,20 @@ func (e *ExternalServicesStore) ValidateConfig(kind, config string, ps []schema.
 		}
 		err = e.validateBitbucketServerConnection(&c)
 
+	case "GITEA":
+		var c schema.GiteaConnection
+		if err = json.Unmarshal(normalized, &c); err != nil {
+			return err
+		}
+		err = e.validateGiteaConnection(&c)
+
+	case "GERRIT":
+		var c schema.GerritConnection
+		if err = json.Unmarshal(normalized, &c); err != nil {
+			return err
+		}
+		err = validateGerritConnection(&c)
+
 	case "OTHER":
 		var c schema.OtherExternalServiceConnection
 		if err = json.Unmarshal(normalized, &c); err != nil {
@@ -204,6 +221,26 @@ func (e *ExternalServicesStore) validateBitbucketServerConnection(c *schema.Bitb
 	return err.ErrorOrNil()
 }
 
+func (e *ExternalServicesStore) validateGiteaConnection(c *schema.GiteaConnection) error {
+	err := new(multierror.Error)
+	for _, validate := range e.GiteaValidators {
+		err = multierror.Append(err, validate(c))
+	}
+
+	if c.Orgs == nil && c.Users == nil && c.Repos == nil {
+		err = multierror.Append(err, errors.New("at least one of orgs, users or repos must be set"))
+	}
+
+	return err.ErrorOrNil()
+}
+
+func validateGerritConnection(c *schema.GerritConnection) error {
+	if c.Projects == nil && c.ProjectQuery == nil {
+		return errors.New("at least one of projects or projectQuery must be set")
+	}
+	return nil
+}
+
 // Create creates a external service.
 //
 // Since this method is used before the configuration server has started
@@ -414,6 +451,28 @@ func (c *ExternalServicesStore) ListGitHubConnections(ctx context.Context) ([]*s
 	return connections, nil
 }
 
+// ListGiteaConnections returns a list of GiteaConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *ExternalServicesStore) ListGiteaConnections(ctx context.Context) ([]*schema.GiteaConnection, error) {
+	var connections []*schema.GiteaConnection
+	if err := c.listConfigs(ctx, "GITEA", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+// ListGerritConnections returns a list of GerritConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *ExternalServicesStore) ListGerritConnections(ctx context.Context) ([]*schema.GerritConnection, error) {
+	var connections []*schema.GerritConnection
+	if err := c.listConfigs(ctx, "GERRIT", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
 // ListGitLabConnections returns a list of GitLabConnection configs.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is a site admin.
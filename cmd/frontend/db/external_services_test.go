@@ -22,6 +22,31 @@ func TestExternalServicesStore_ValidateConfig(t *testing.T) {
 			config:  `{"url": "https://github.com", "repositoryQuery": ["none"], "token": "", "x": 123}`,
 			wantErr: "2 errors occurred:\n\t* Additional property x is not allowed\n\t* token: String length must be greater than or equal to 1\n\n",
 		},
+		"gitea without repos": {
+			kind:    "GITEA",
+			config:  `{"url": "https://gitea.mycorp.com", "token": "abc"}`,
+			wantErr: "1 error occurred:\n\t* at least one of orgs, users or repos must be set\n\n",
+		},
+		"gitea": {
+			kind:    "GITEA",
+			config:  `{"url": "https://gitea.mycorp.com", "token": "abc", "orgs": ["infra"]}`,
+			wantErr: "",
+		},
+		"gerrit without projects": {
+			kind:    "GERRIT",
+			config:  `{"url": "https://gerrit.mycorp.com"}`,
+			wantErr: "1 error occurred:\n\t* at least one of projects or projectQuery must be set\n\n",
+		},
+		"gerrit username without password": {
+			kind:    "GERRIT",
+			config:  `{"url": "https://gerrit.mycorp.com", "username": "admin", "projectQuery": ["state:active"]}`,
+			wantErr: "1 error occurred:\n\t* Has a dependency on password\n\n",
+		},
+		"gerrit": {
+			kind:    "GERRIT",
+			config:  `{"url": "https://gerrit.mycorp.com", "username": "admin", "password": "secret", "projectQuery": ["state:active"], "changeQuery": "status:open"}`,
+			wantErr: "",
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
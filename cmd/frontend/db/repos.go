@@ -117,6 +117,50 @@ func (s *repos) GetByIDs(ctx context.Context, ids ...api.RepoID) ([]*types.Repo,
 	return s.getReposBySQL(ctx, true, q)
 }
 
+// StarCounts returns the number of stars of the repositories with the given IDs
+// on their code host, as recorded in the code host metadata of the
+// repositories. Repositories whose code host does not report stars (or whose
+// metadata was synced before stars were recorded) are omitted.
+func (s *repos) StarCounts(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int, error) {
+	if Mocks.Repos.StarCounts != nil {
+		return Mocks.Repos.StarCounts(ctx, ids...)
+	}
+
+	counts := make(map[api.RepoID]int)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	items := make([]*sqlf.Query, len(ids))
+	for i := range ids {
+		items[i] = sqlf.Sprintf("%d", ids[i])
+	}
+	// The metadata of GitHub repositories has a StargazerCount field, and the
+	// metadata of GitLab projects has a star_count field.
+	q := sqlf.Sprintf(`
+SELECT id, COALESCE((metadata->>'StargazerCount')::int, (metadata->>'star_count')::int)
+FROM repo
+WHERE id IN (%s) AND deleted_at IS NULL
+AND (metadata ? 'StargazerCount' OR metadata ? 'star_count')`, sqlf.Join(items, ","))
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    api.RepoID
+			count int
+		)
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
 func (s *repos) Count(ctx context.Context, opt ReposListOptions) (int, error) {
 	if Mocks.Repos.Count != nil {
 		return Mocks.Repos.Count(ctx, opt)
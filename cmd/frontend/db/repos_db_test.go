@@ -235,6 +235,38 @@ func TestRepos_GetByIDs(t *testing.T) {
 	}
 }
 
+func TestRepos_StarCounts(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	repos := mustCreate(ctx, t, &types.Repo{Name: "github"}, &types.Repo{Name: "gitlab"}, &types.Repo{Name: "other"})
+	for _, update := range []struct {
+		id       api.RepoID
+		metadata string
+	}{
+		{repos[0].ID, `{"NameWithOwner": "a/b", "StargazerCount": 42}`},
+		{repos[1].ID, `{"path_with_namespace": "a/b", "star_count": 7}`},
+		{repos[2].ID, `{"name": "a/b"}`},
+	} {
+		if _, err := dbconn.Global.ExecContext(ctx, "UPDATE repo SET metadata = $1 WHERE id = $2", update.metadata, update.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts, err := Repos.StarCounts(ctx, repos[0].ID, repos[1].ID, repos[2].ID, 404)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[api.RepoID]int{repos[0].ID: 42, repos[1].ID: 7}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("got %v, want %v", counts, want)
+	}
+}
+
 func TestRepos_List(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
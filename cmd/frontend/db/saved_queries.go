@@ -3,9 +3,11 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
 )
 
@@ -16,6 +18,10 @@ type SavedQueryInfo struct {
 	LastExecuted time.Time
 	LatestResult time.Time
 	ExecDuration time.Duration
+
+	// ResultMatches are the matches found by the last execution of the query
+	// (see api.SavedQueryInfo).
+	ResultMatches []*api.SavedQueryMatch
 }
 
 // Get gets the saved query information for the given query. nil
@@ -24,12 +30,15 @@ func (s *queryRunnerState) Get(ctx context.Context, query string) (*SavedQueryIn
 	info := &SavedQueryInfo{
 		Query: query,
 	}
-	var execDurationNs int64
+	var (
+		execDurationNs int64
+		resultMatches  []byte
+	)
 	err := dbconn.Global.QueryRowContext(
 		ctx,
-		"SELECT last_executed, latest_result, exec_duration_ns FROM query_runner_state WHERE query=$1",
+		"SELECT last_executed, latest_result, exec_duration_ns, result_matches FROM query_runner_state WHERE query=$1",
 		query,
-	).Scan(&info.LastExecuted, &info.LatestResult, &execDurationNs)
+	).Scan(&info.LastExecuted, &info.LatestResult, &execDurationNs, &resultMatches)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -37,6 +46,11 @@ func (s *queryRunnerState) Get(ctx context.Context, query string) (*SavedQueryIn
 		return nil, errors.Wrap(err, "QueryRow")
 	}
 	info.ExecDuration = time.Duration(execDurationNs)
+	if resultMatches != nil {
+		if err := json.Unmarshal(resultMatches, &info.ResultMatches); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+	}
 	return info, nil
 }
 
@@ -45,12 +59,21 @@ func (s *queryRunnerState) Get(ctx context.Context, query string) (*SavedQueryIn
 // It is not safe to call concurrently for the same info.Query, as it uses a
 // poor man's upsert implementation.
 func (s *queryRunnerState) Set(ctx context.Context, info *SavedQueryInfo) error {
+	var resultMatches []byte
+	if info.ResultMatches != nil {
+		var err error
+		resultMatches, err = json.Marshal(info.ResultMatches)
+		if err != nil {
+			return errors.Wrap(err, "Marshal")
+		}
+	}
 	res, err := dbconn.Global.ExecContext(
 		ctx,
-		"UPDATE query_runner_state SET last_executed=$1, latest_result=$2, exec_duration_ns=$3 WHERE query=$4",
+		"UPDATE query_runner_state SET last_executed=$1, latest_result=$2, exec_duration_ns=$3, result_matches=$4 WHERE query=$5",
 		info.LastExecuted,
 		info.LatestResult,
 		int64(info.ExecDuration),
+		resultMatches,
 		info.Query,
 	)
 	if err != nil {
@@ -64,11 +87,12 @@ func (s *queryRunnerState) Set(ctx context.Context, info *SavedQueryInfo) error
 		// Didn't update any row, so insert a new one.
 		_, err := dbconn.Global.ExecContext(
 			ctx,
-			"INSERT INTO query_runner_state(query, last_executed, latest_result, exec_duration_ns) VALUES($1, $2, $3, $4)",
+			"INSERT INTO query_runner_state(query, last_executed, latest_result, exec_duration_ns, result_matches) VALUES($1, $2, $3, $4, $5)",
 			info.Query,
 			info.LastExecuted,
 			info.LatestResult,
 			int64(info.ExecDuration),
+			resultMatches,
 		)
 		if err != nil {
 			return errors.Wrap(err, "INSERT")
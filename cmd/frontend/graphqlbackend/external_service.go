@@ -3,12 +3,15 @@ package graphqlbackend
 import (
 	"context"
 	"fmt"
+	"time"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 )
 
 type externalServiceResolver struct {
@@ -80,3 +83,66 @@ func (r *externalServiceResolver) Warning() *string {
 	}
 	return &r.warning
 }
+
+func (r *externalServiceResolver) LastSync(ctx context.Context) (*externalServiceSyncStateResolver, error) {
+	result, err := repoupdater.DefaultClient.ExternalServiceSyncState(ctx, protocol.ExternalServiceSyncStateArgs{
+		ExternalServiceID: r.externalService.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.State == nil {
+		return nil, nil
+	}
+	return &externalServiceSyncStateResolver{state: result.State}, nil
+}
+
+type externalServiceSyncStateResolver struct {
+	state *protocol.ExternalServiceSyncState
+}
+
+func (r *externalServiceSyncStateResolver) StartedAt() DateTime {
+	return DateTime{Time: r.state.StartedAt}
+}
+
+func (r *externalServiceSyncStateResolver) FinishedAt() DateTime {
+	return DateTime{Time: r.state.FinishedAt}
+}
+
+func (r *externalServiceSyncStateResolver) DurationMilliseconds() int32 {
+	return int32(r.state.FinishedAt.Sub(r.state.StartedAt) / time.Millisecond)
+}
+
+func (r *externalServiceSyncStateResolver) NextSyncAt() *DateTime {
+	if r.state.NextSyncAt.IsZero() {
+		return nil
+	}
+	return &DateTime{Time: r.state.NextSyncAt}
+}
+
+func (r *externalServiceSyncStateResolver) Incremental() bool {
+	return r.state.Incremental
+}
+
+func (r *externalServiceSyncStateResolver) Error() *string {
+	if r.state.Error == "" {
+		return nil
+	}
+	return &r.state.Error
+}
+
+func (r *externalServiceSyncStateResolver) Added() int32 {
+	return int32(r.state.Added)
+}
+
+func (r *externalServiceSyncStateResolver) Modified() int32 {
+	return int32(r.state.Modified)
+}
+
+func (r *externalServiceSyncStateResolver) Deleted() int32 {
+	return int32(r.state.Deleted)
+}
+
+func (r *externalServiceSyncStateResolver) Unmodified() int32 {
+	return int32(r.state.Unmodified)
+}
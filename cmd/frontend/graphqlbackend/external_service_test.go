@@ -0,0 +1,99 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
+)
+
+func TestExternalServiceLastSync(t *testing.T) {
+	resetMocks()
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	db.Mocks.ExternalServices.GetByID = func(id int64) (*types.ExternalService, error) {
+		return &types.ExternalService{ID: id, Kind: "GITLAB"}, nil
+	}
+
+	startedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	repoupdater.MockExternalServiceSyncState = func(args protocol.ExternalServiceSyncStateArgs) (*protocol.ExternalServiceSyncStateResult, error) {
+		if args.ExternalServiceID != 1 {
+			return &protocol.ExternalServiceSyncStateResult{}, nil
+		}
+		return &protocol.ExternalServiceSyncStateResult{
+			State: &protocol.ExternalServiceSyncState{
+				ExternalServiceID: 1,
+				StartedAt:         startedAt,
+				FinishedAt:        startedAt.Add(1500 * time.Millisecond),
+				Incremental:       true,
+				Error:             "boom",
+				Added:             1,
+				Modified:          2,
+				Deleted:           3,
+				Unmodified:        4,
+			},
+		}, nil
+	}
+	defer func() { repoupdater.MockExternalServiceSyncState = nil }()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					synced: node(id: "RXh0ZXJuYWxTZXJ2aWNlOjE=") {
+						... on ExternalService {
+							lastSync {
+								startedAt
+								finishedAt
+								durationMilliseconds
+								nextSyncAt
+								incremental
+								error
+								added
+								modified
+								deleted
+								unmodified
+							}
+						}
+					}
+					notSynced: node(id: "RXh0ZXJuYWxTZXJ2aWNlOjI=") {
+						... on ExternalService {
+							lastSync {
+								startedAt
+							}
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"synced": {
+						"lastSync": {
+							"startedAt": "2020-01-02T03:04:05Z",
+							"finishedAt": "2020-01-02T03:04:06Z",
+							"durationMilliseconds": 1500,
+							"nextSyncAt": null,
+							"incremental": true,
+							"error": "boom",
+							"added": 1,
+							"modified": 2,
+							"deleted": 3,
+							"unmodified": 4
+						}
+					},
+					"notSynced": {
+						"lastSync": null
+					}
+				}
+			`,
+		},
+	})
+}
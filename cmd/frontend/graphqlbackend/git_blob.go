@@ -10,13 +10,23 @@ import (
 
 func (r *GitTreeEntryResolver) Blame(ctx context.Context,
 	args *struct {
-		StartLine int32
-		EndLine   int32
+		StartLine    int32
+		EndLine      int32
+		IgnoreRevs   *[]string
+		DetectMoves  bool
+		DetectCopies bool
 	}) ([]*hunkResolver, error) {
+	var ignoreRevs []string
+	if args.IgnoreRevs != nil {
+		ignoreRevs = *args.IgnoreRevs
+	}
 	hunks, err := git.BlameFile(ctx, gitserver.Repo{Name: r.commit.repo.repo.Name}, r.Path(), &git.BlameOptions{
 		NewestCommit: api.CommitID(r.commit.OID()),
 		StartLine:    int(args.StartLine),
 		EndLine:      int(args.EndLine),
+		IgnoreRevs:   ignoreRevs,
+		DetectMoves:  args.DetectMoves,
+		DetectCopies: args.DetectCopies,
 	})
 	if err != nil {
 		return nil, err
@@ -1,6 +1,13 @@
 package graphqlbackend
 
-import "github.com/sourcegraph/sourcegraph/internal/vcs/git"
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
 
 type gitSubmoduleResolver struct {
 	submodule git.Submodule
@@ -17,3 +24,46 @@ func (r *gitSubmoduleResolver) Commit() string {
 func (r *gitSubmoduleResolver) Path() string {
 	return r.submodule.Path
 }
+
+func (r *gitSubmoduleResolver) Repository(ctx context.Context) (*RepositoryResolver, error) {
+	repo, err := submoduleRepo(ctx, r.submodule.URL)
+	if repo == nil || err != nil {
+		return nil, err
+	}
+	return NewRepositoryResolver(repo), nil
+}
+
+func (r *gitSubmoduleResolver) Tree(ctx context.Context, args *struct{ Path string }) (*GitTreeEntryResolver, error) {
+	repo, err := r.Repository(ctx)
+	if repo == nil || err != nil {
+		return nil, err
+	}
+	commit, err := repo.Commit(ctx, &RepositoryCommitArgs{Rev: string(r.submodule.CommitID)})
+	if commit == nil || err != nil {
+		// The pinned commit may not have been pushed.
+		return nil, err
+	}
+	return commit.Tree(ctx, &struct {
+		Path      string
+		Recursive bool
+	}{Path: args.Path})
+}
+
+// submoduleRepo returns the repository that a submodule with the clone URL
+// url refers to, or nil if it is not a repository on Sourcegraph that the
+// actor can read. Unlike backend.Repos.GetByName, it never adds the
+// repository.
+func submoduleRepo(ctx context.Context, url string) (*types.Repo, error) {
+	if url == "" {
+		return nil, nil
+	}
+	name, err := reposourceCloneURLToRepoName(ctx, url)
+	if name == "" || err != nil {
+		return nil, err
+	}
+	repo, err := db.Repos.GetByName(ctx, name)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return repo, err
+}
@@ -0,0 +1,70 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pathindex"
+)
+
+// maxFuzzyFilesFirst is the maximum number of matches that a fuzzyFiles query
+// can request.
+const maxFuzzyFilesFirst = 1000
+
+type repositoryFuzzyFilesArgs struct {
+	Query string
+	Rev   *string
+	First int32
+}
+
+func (r *RepositoryResolver) FuzzyFiles(ctx context.Context, args *repositoryFuzzyFilesArgs) ([]*fuzzyFileMatchResolver, error) {
+	if args.First < 0 || args.First > maxFuzzyFilesFirst {
+		return nil, errors.New("first must be between 0 and 1000")
+	}
+
+	rev, inputRev := "HEAD", (*string)(nil)
+	if args.Rev != nil && *args.Rev != "" {
+		rev, inputRev = *args.Rev, args.Rev
+	}
+	commitID, err := backend.Repos.ResolveRev(ctx, r.repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	cachedRepo, err := backend.CachedGitRepo(ctx, r.repo)
+	if err != nil {
+		return nil, err
+	}
+	index, err := pathindex.Get(ctx, *cachedRepo, commitID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like FileMatchResolver.File, the commit omits its other fields, which
+	// would be slow to fetch.
+	commit := &GitCommitResolver{repo: r, oid: GitObjectID(commitID), inputRev: inputRev}
+	matches := index.Search(args.Query, int(args.First))
+	resolvers := make([]*fuzzyFileMatchResolver, len(matches))
+	for i, m := range matches {
+		resolvers[i] = &fuzzyFileMatchResolver{
+			file:      &GitTreeEntryResolver{commit: commit, stat: CreateFileInfo(m.Path, false)},
+			positions: m.Offsets,
+		}
+	}
+	return resolvers, nil
+}
+
+type fuzzyFileMatchResolver struct {
+	file      *GitTreeEntryResolver
+	positions []int
+}
+
+func (r *fuzzyFileMatchResolver) File() *GitTreeEntryResolver { return r.file }
+
+func (r *fuzzyFileMatchResolver) Positions() []int32 {
+	positions := make([]int32, len(r.positions))
+	for i, pos := range r.positions {
+		positions[i] = int32(pos)
+	}
+	return positions
+}
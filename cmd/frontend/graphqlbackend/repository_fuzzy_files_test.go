@@ -0,0 +1,80 @@
+package graphqlbackend
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go/gqltesting"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pathindex"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestRepository_FuzzyFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pathindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pathindex.CacheDir = dir
+
+	resetMocks()
+	db.Mocks.Repos.MockGetByName(t, "github.com/gorilla/mux", 2)
+	backend.Mocks.Repos.ResolveRev = func(ctx context.Context, repo *types.Repo, rev string) (api.CommitID, error) {
+		if repo.ID != 2 || rev != "HEAD" {
+			t.Error("wrong arguments to ResolveRev")
+		}
+		return exampleCommitSHA1, nil
+	}
+	git.Mocks.ListFiles = func(commit api.CommitID) ([]string, error) {
+		if commit != exampleCommitSHA1 {
+			t.Errorf("got commit %q, want %q", commit, exampleCommitSHA1)
+		}
+		return []string{"mux.go", "mux_test.go", "regexp.go", "route.go"}, nil
+	}
+	defer func() { git.Mocks.ListFiles = nil }()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					repository(name: "github.com/gorilla/mux") {
+						fuzzyFiles(query: "mx", first: 2) {
+							file {
+								path
+							}
+							positions
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"repository": {
+						"fuzzyFiles": [
+							{
+								"file": {
+									"path": "mux.go"
+								},
+								"positions": [0, 2]
+							},
+							{
+								"file": {
+									"path": "mux_test.go"
+								},
+								"positions": [0, 2]
+							}
+						]
+					}
+				}
+			`,
+		},
+	})
+}
@@ -3,12 +3,14 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
@@ -274,3 +276,88 @@ func (r *schemaResolver) UpdateAllMirrorRepositories(ctx context.Context) (*Empt
 	}
 	return &EmptyResponse{}, nil
 }
+
+func (r *repositoryMirrorInfoResolver) UpdateHistory(ctx context.Context, args *graphqlutil.ConnectionArgs) (*repositoryUpdateAttemptConnectionResolver, error) {
+	// 🚨 SECURITY: The output of git may contain details of the code host that only site admins
+	// should see.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if args.First != nil && *args.First < 0 {
+		return nil, errors.New("first must not be negative")
+	}
+
+	info, err := r.gitserverRepoInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &repositoryUpdateAttemptConnectionResolver{attempts: info.UpdateHistory, first: args.First}, nil
+}
+
+type repositoryUpdateAttemptConnectionResolver struct {
+	attempts []protocol.RepoUpdateAttempt
+	first    *int32
+}
+
+func (r *repositoryUpdateAttemptConnectionResolver) Nodes() []*repositoryUpdateAttemptResolver {
+	attempts := r.attempts
+	if r.first != nil && int(*r.first) < len(attempts) {
+		attempts = attempts[:*r.first]
+	}
+	resolvers := make([]*repositoryUpdateAttemptResolver, len(attempts))
+	for i := range attempts {
+		resolvers[i] = &repositoryUpdateAttemptResolver{attempt: &attempts[i]}
+	}
+	return resolvers
+}
+
+func (r *repositoryUpdateAttemptConnectionResolver) TotalCount() int32 {
+	return int32(len(r.attempts))
+}
+
+func (r *repositoryUpdateAttemptConnectionResolver) PageInfo() *graphqlutil.PageInfo {
+	return graphqlutil.HasNextPage(r.first != nil && int(*r.first) < len(r.attempts))
+}
+
+type repositoryUpdateAttemptResolver struct {
+	attempt *protocol.RepoUpdateAttempt
+}
+
+func (r *repositoryUpdateAttemptResolver) Kind() string {
+	return strings.ToUpper(r.attempt.Kind)
+}
+
+func (r *repositoryUpdateAttemptResolver) StartedAt() DateTime {
+	return DateTime{Time: r.attempt.Start}
+}
+
+func (r *repositoryUpdateAttemptResolver) FinishedAt() DateTime {
+	return DateTime{Time: r.attempt.End}
+}
+
+func (r *repositoryUpdateAttemptResolver) DurationMilliseconds() int32 {
+	return int32(r.attempt.End.Sub(r.attempt.Start).Milliseconds())
+}
+
+func (r *repositoryUpdateAttemptResolver) BytesTransferred() float64 {
+	return float64(r.attempt.Bytes)
+}
+
+func (r *repositoryUpdateAttemptResolver) ExitStatus() *int32 {
+	if r.attempt.ExitStatus < 0 {
+		return nil
+	}
+	exitStatus := int32(r.attempt.ExitStatus)
+	return &exitStatus
+}
+
+func (r *repositoryUpdateAttemptResolver) Stderr() string {
+	return r.attempt.Stderr
+}
+
+func (r *repositoryUpdateAttemptResolver) Error() *string {
+	if r.attempt.Error == "" {
+		return nil
+	}
+	return &r.attempt.Error
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/graph-gophers/graphql-go/gqltesting"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 )
@@ -135,3 +138,66 @@ func TestCheckMirrorRepositoryConnection(t *testing.T) {
 		}
 	})
 }
+
+func TestRepositoryUpdateAttemptConnection(t *testing.T) {
+	start := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	attempts := []gitserverprotocol.RepoUpdateAttempt{
+		{Kind: "fetch", Start: start.Add(time.Hour), End: start.Add(time.Hour + 1500*time.Millisecond), ExitStatus: 128, Stderr: "fatal: could not read from remote repository", Error: "failed to update: exit status 128"},
+		{Kind: "clone", Start: start, End: start.Add(time.Minute), Bytes: 1 << 20},
+	}
+
+	first := int32(1)
+	r := &repositoryUpdateAttemptConnectionResolver{attempts: attempts, first: &first}
+	if got := r.TotalCount(); got != 2 {
+		t.Errorf("got total count %d, want 2", got)
+	}
+	if !r.PageInfo().HasNextPage() {
+		t.Error("want next page")
+	}
+	nodes := r.Nodes()
+	if len(nodes) != 1 {
+		t.Fatalf("got %d nodes, want 1", len(nodes))
+	}
+	fetch := nodes[0]
+	if got := fetch.Kind(); got != "FETCH" {
+		t.Errorf("got kind %q, want FETCH", got)
+	}
+	if got := fetch.DurationMilliseconds(); got != 1500 {
+		t.Errorf("got duration %dms, want 1500ms", got)
+	}
+	if got := fetch.ExitStatus(); got == nil || *got != 128 {
+		t.Errorf("got exit status %v, want 128", got)
+	}
+	if got := fetch.Error(); got == nil || *got != attempts[0].Error {
+		t.Errorf("got error %v, want %q", got, attempts[0].Error)
+	}
+
+	r = &repositoryUpdateAttemptConnectionResolver{attempts: attempts}
+	if r.PageInfo().HasNextPage() {
+		t.Error("want no next page")
+	}
+	clone := r.Nodes()[1]
+	if got := clone.Kind(); got != "CLONE" {
+		t.Errorf("got kind %q, want CLONE", got)
+	}
+	if got := clone.BytesTransferred(); got != 1<<20 {
+		t.Errorf("got %v bytes, want %v", got, 1<<20)
+	}
+	if got := clone.Error(); got != nil {
+		t.Errorf("got error %q, want none", *got)
+	}
+}
+
+func TestRepositoryMirrorInfo_UpdateHistory_negativeFirst(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	defer resetMocks()
+
+	first := int32(-1)
+	r := &repositoryMirrorInfoResolver{repository: &RepositoryResolver{repo: &types.Repo{Name: "my/repo"}}}
+	if _, err := r.UpdateHistory(context.Background(), &graphqlutil.ConnectionArgs{First: &first}); err == nil {
+		t.Error("got no error for negative first")
+	}
+}
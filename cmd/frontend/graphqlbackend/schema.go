@@ -1408,6 +1408,79 @@ type Search {
     # cached and thus quicker to query. Useful for e.g. querying sparkline
     # data.
     stats: SearchResultsStats!
+    # Runs the search and describes how it was run, for debugging slow or
+    # unexpected searches. Null if the query is invalid.
+    explain: SearchExplanation
+}
+
+# A description of how a search was run.
+type SearchExplanation {
+    # The fields of the typechecked query, sorted by field name.
+    fields: [SearchExplanationField!]!
+    # The query after and/or operators were simplified, or null if the query
+    # has no and/or operators.
+    operators: String
+    # The pattern type that the query was interpreted as ("literal", "regexp" or "structural").
+    patternType: String!
+    # The types of results that were searched for (e.g. "file", "repo" or "diff").
+    resultTypes: [String!]!
+    # The maximum number of results.
+    resultLimit: Int!
+    # The timeout of the search, e.g. "10s".
+    timeout: String!
+    # The repositories that the query resolved to, with their revisions and how they were searched.
+    repositories: [SearchExplanationRepository!]!
+    # The search backends that ran, in the order in which they returned.
+    backends: [SearchExplanationBackend!]!
+    # The limits, timeouts and other decisions that changed how the search was run.
+    decisions: [String!]!
+    # Whether a limit was hit, so that not all results were found.
+    limitHit: Boolean!
+    # The number of results that were found.
+    resultCount: Int!
+    # The time that the whole search took.
+    elapsedMilliseconds: Int!
+}
+
+# A field of a search query, as in "repo:foo".
+type SearchExplanationField {
+    # The name of the field, or the empty string for search patterns.
+    field: String!
+    # The value of the field.
+    value: String!
+    # Whether the field is negated, as in "-repo:foo".
+    negated: Boolean!
+}
+
+# A repository that a search query resolved to.
+type SearchExplanationRepository {
+    # The name of the repository.
+    name: String!
+    # The revisions of the repository that were searched. An empty revision means the default branch.
+    revisions: [String!]!
+    # The backend that ran the text search of the repository: "indexed" or
+    # "unindexed". Null if the repository was not text searched.
+    backend: String
+    # Whether the repository was searched: "searched", "cloning", "missing",
+    # "timedout", "missing revision" or "not searched".
+    status: String!
+}
+
+# A search backend that ran as part of a search.
+type SearchExplanationBackend {
+    # The name of the backend, e.g. "symbol", "text.indexed" or "text.unindexed".
+    name: String!
+    # Whether the search waited for the backend. Optional backends are canceled
+    # soon after the required ones return.
+    required: Boolean!
+    # The number of repositories that the backend searched.
+    repositories: Int!
+    # The time that the backend took.
+    durationMilliseconds: Int!
+    # The number of results that the backend found.
+    resultCount: Int!
+    # The error that the backend returned, if any.
+    error: String
 }
 
 # Predefined suggestions for search filters when backfill.
@@ -1522,6 +1595,9 @@ type SearchResults {
     #
     # In paginated search requests, this field is not relevant.
     timedout: [Repository!]!
+    # Repositories whose history was only partially searched by commit and diff searches,
+    # because they are shallow clones. Older commits in these repositories are not searched.
+    truncatedHistory: [Repository!]!
     # True if indexed search is enabled but was not available during this search.
     indexUnavailable: Boolean!
     # An alert message that should be displayed before any results.
@@ -1547,6 +1623,50 @@ type SearchResultsStats {
     #
     # Known issue: The LanguageStatistics.totalBytes field values are incorrect in the result.
     languages: [LanguageStatistics!]!
+    # Match counts of the search results grouped by a property, such as their repository. Unlike
+    # the other statistics, aggregations are computed over the full result set instead of the
+    # results that would be displayed, and they are not cached.
+    aggregate(
+        # The property to group the results by.
+        by: SearchAggregationGroupBy!
+        # For DIRECTORY, the number of leading path components to group by. By default, results are
+        # grouped by their full directory.
+        depth: Int
+        # For CAPTURE_GROUP, the regular expression whose first capture group is extracted from
+        # every matching line, such as version = "(\d+\.\d+)".
+        pattern: String
+    ): SearchAggregation!
+}
+
+# A property to group search results by in an aggregation.
+enum SearchAggregationGroupBy {
+    # The repository of the result.
+    REPOSITORY
+    # The directory of the matching file, or a prefix of it.
+    DIRECTORY
+    # The extension of the matching file, such as ".go".
+    FILE_EXTENSION
+    # The author of the matching commit. Only commit and diff results are counted.
+    AUTHOR
+    # The value of the first capture group of a regular expression in the matching lines.
+    CAPTURE_GROUP
+}
+
+# Match counts of search results grouped by a property.
+type SearchAggregation {
+    # The groups, ordered by descending count.
+    groups: [SearchAggregationGroup!]!
+    # Whether the search stopped before all results were found, in which case the counts are lower
+    # bounds.
+    limitHit: Boolean!
+}
+
+# A group of search results in an aggregation.
+type SearchAggregationGroup {
+    # The value that the results in the group have in common, such as the repository name.
+    value: String!
+    # The number of matches in the group.
+    count: Int!
 }
 
 # A search filter.
@@ -1712,6 +1832,8 @@ enum ExternalServiceKind {
     AWSCODECOMMIT
     BITBUCKETCLOUD
     BITBUCKETSERVER
+    GERRIT
+    GITEA
     GITHUB
     GITLAB
     GITOLITE
@@ -1739,6 +1861,38 @@ type ExternalService implements Node {
     # It is a field on ExternalService instead of a separate thing in order to
     # not break the API and stay backwards compatible.
     warning: String
+    # The state of the last sync of the external service's repositories, or null if they weren't
+    # synced since repo-updater was started. Sync states are only kept in the memory of
+    # repo-updater, so they are lost when it restarts.
+    lastSync: ExternalServiceSyncState
+}
+
+# The state of a sync of the repositories of an external service. Each external service is
+# synced on its own schedule.
+type ExternalServiceSyncState {
+    # When the sync started.
+    startedAt: DateTime!
+    # When the sync finished.
+    finishedAt: DateTime!
+    # The duration of the sync, in milliseconds.
+    durationMilliseconds: Int!
+    # When the next sync is scheduled, or null if none is.
+    nextSyncAt: DateTime
+    # Whether only the repositories which changed since the previous sync were synced, which
+    # is the case for code hosts that support filtering repositories by their last activity.
+    # Incremental syncs never delete repositories.
+    incremental: Boolean!
+    # The error of the sync, or null if it succeeded.
+    error: String
+    # The number of repositories added by the sync.
+    added: Int!
+    # The number of repositories modified by the sync, including the ones which are no longer
+    # mirrored from the external service but still from others.
+    modified: Int!
+    # The number of repositories deleted by the sync.
+    deleted: Int!
+    # The number of repositories the sync left unmodified.
+    unmodified: Int!
 }
 
 # A list of repositories.
@@ -1872,6 +2026,19 @@ type Repository implements Node & GenericSearchResultInterface {
         # Returns the first n contributors from the list.
         first: Int
     ): RepositoryContributorConnection!
+    # Files in the repository whose paths fuzzily match the query, best matches first. A path
+    # matches if it contains the characters of the query in order (case-insensitively). Matches
+    # are ranked by how many characters are consecutive or start a path component or word, and
+    # then by path length.
+    fuzzyFiles(
+        # The characters to match. Whitespace is ignored.
+        query: String!
+        # The Git revision specifier (revspec) of the commit whose files to match. The default is
+        # the default branch.
+        rev: String
+        # Returns the first n matches.
+        first: Int = 50
+    ): [FuzzyFileMatch!]!
     # Link to another Sourcegraph instance location where this repository is located.
     redirectURL: String @deprecated(reason: "use repositoryRedirect query instead")
     # Whether the viewer has admin privileges on this repository.
@@ -1966,6 +2133,52 @@ type MirrorRepositoryInfo {
     updateSchedule: UpdateSchedule
     # The state of this repository in the update queue.
     updateQueue: UpdateQueue
+    # The most recent attempts to clone or fetch the repository, most recent first. Only site
+    # admins may view them.
+    updateHistory(
+        # Returns the first n attempts from the list.
+        first: Int
+    ): RepositoryUpdateAttemptConnection!
+}
+
+# A list of attempts to clone or fetch a repository.
+type RepositoryUpdateAttemptConnection {
+    # A list of attempts to clone or fetch a repository.
+    nodes: [RepositoryUpdateAttempt!]!
+    # The total count of attempts in the connection. This total count may be larger than the number
+    # of nodes in this object when the result is paginated.
+    totalCount: Int!
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# The kind of a repository update attempt.
+enum RepositoryUpdateAttemptKind {
+    # The repository was cloned.
+    CLONE
+    # The repository was fetched.
+    FETCH
+}
+
+# An attempt to clone or fetch a repository.
+type RepositoryUpdateAttempt {
+    # Whether the repository was cloned or fetched.
+    kind: RepositoryUpdateAttemptKind!
+    # When the attempt started.
+    startedAt: DateTime!
+    # When the attempt finished.
+    finishedAt: DateTime!
+    # How long the attempt took.
+    durationMilliseconds: Int!
+    # How many bytes the git objects of the repository grew by, which is about how many bytes were
+    # transferred.
+    bytesTransferred: Float!
+    # The exit status of git, or null if the attempt failed before git exited.
+    exitStatus: Int
+    # The end of the output of git, with credentials redacted.
+    stderr: String!
+    # Why the attempt failed, with credentials redacted, or null if it succeeded.
+    error: String
 }
 
 # The state of a repository in the update schedule.
@@ -2577,6 +2790,12 @@ type Submodule {
     commit: String!
     # The path to which the submodule is checked out.
     path: String!
+    # The repository that the submodule's URL refers to, or null if it is not a repository on
+    # Sourcegraph.
+    repository: Repository
+    # The tree at the given path in the submodule's repository at the submodule's commit, or null if the
+    # submodule's repository is not on Sourcegraph or does not have the commit.
+    tree(path: String = ""): GitTree
 }
 
 # A file, directory, or other tree entry.
@@ -2732,6 +2951,15 @@ type File {
     repository: Repository!
 }
 
+# A file whose path fuzzily matches a query (see Repository.fuzzyFiles).
+type FuzzyFileMatch {
+    # The file.
+    file: GitBlob!
+    # The offsets (in characters) of the characters of the file's path that matched the
+    # characters of the query.
+    positions: [Int!]!
+}
+
 # A Git blob in a repository.
 type GitBlob implements TreeEntry & File2 {
     # The full path (relative to the repository root) of this blob.
@@ -2759,8 +2987,19 @@ type GitBlob implements TreeEntry & File2 {
     canonicalURL: String!
     # The URLs to this blob on its repository's external services.
     externalURLs: [ExternalLink!]!
-    # Blame the blob.
-    blame(startLine: Int!, endLine: Int!): [Hunk!]!
+    # Blame the blob. The commits listed in the .git-blame-ignore-revs file of the blob's commit are
+    # always ignored.
+    blame(
+        startLine: Int!
+        endLine: Int!
+        # Commits whose changes are ignored. The lines they changed are blamed on the commits that
+        # changed them before.
+        ignoreRevs: [String!]
+        # Whether to detect lines that were moved within the file.
+        detectMoves: Boolean = false
+        # Whether to detect lines that were moved or copied from other files.
+        detectCopies: Boolean = false
+    ): [Hunk!]!
     # Highlight the blob contents.
     highlight(disableTimeout: Boolean!, isLightTheme: Boolean!, highlightLongLines: Boolean = false): HighlightedFile!
     # Submodule metadata if this tree points to a submodule
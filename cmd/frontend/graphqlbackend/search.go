@@ -62,6 +62,7 @@ type SearchImplementer interface {
 	Suggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error)
 	//lint:ignore U1000 is used by graphql via reflection
 	Stats(context.Context) (*searchResultsStats, error)
+	Explain(context.Context) (*searchExplanationResolver, error)
 }
 
 // NewSearchImplementer returns a SearchImplementer that provides search results and suggestions.
@@ -85,9 +86,28 @@ func NewSearchImplementer(args *SearchArgs) (SearchImplementer, error) {
 		queryString = args.Query
 	}
 
-	q, p, err := query.Process(queryString, searchType)
-	if err != nil {
-		return alertForQuery(queryString, err), nil
+	var andOrQuery query.Node
+	if conf.AndOrQueryEnabled() {
+		andOrQuery, err = query.ProcessAndOr(args.Query, searchType)
+		if err != nil {
+			return alertForQuery(args.Query, err), nil
+		}
+	}
+
+	var q *query.Query
+	var p syntax.ParseTree
+	switch n := andOrQuery.(type) {
+	case nil:
+		q, p, err = query.Process(queryString, searchType)
+		if err != nil {
+			return alertForQuery(queryString, err), nil
+		}
+	case *query.Leaf:
+		// The operators were simplified away, so this is a regular query.
+		q, p, andOrQuery = n.Query, n.ParseTree, nil
+	default:
+		leaf := firstLeaf(n)
+		q, p = leaf.Query, leaf.ParseTree
 	}
 
 	// If the request is a paginated one, decode those arguments now.
@@ -107,10 +127,14 @@ func NewSearchImplementer(args *SearchArgs) (SearchImplementer, error) {
 	} else if args.After != nil {
 		return nil, errors.New("Search: paginated requests providing a 'after' but no 'first' is forbidden")
 	}
+	if pagination != nil && andOrQuery != nil {
+		return nil, errors.New("Search: paginated requests are not supported for queries containing AND, OR or NOT")
+	}
 
 	return &searchResolver{
 		query:         q,
 		parseTree:     p,
+		andOrQuery:    andOrQuery,
 		originalQuery: args.Query,
 		pagination:    pagination,
 		patternType:   searchType,
@@ -180,6 +204,23 @@ type searchResolver struct {
 	pagination    *searchPaginationInfo // pagination information, or nil if the request is not paginated.
 	patternType   query.SearchType
 
+	// andOrQuery is the validated search query if it contains the operators
+	// AND, OR or NOT, or nil otherwise. If it is set, query and parseTree
+	// describe its leftmost operand.
+	andOrQuery query.Node
+
+	// limitOverride, if non-zero, replaces the result limit of the query. It
+	// is set for the operands of queries with operators.
+	limitOverride int32
+
+	// exhaustive, if set, makes the search wait for all results up to its
+	// result limit, as if count: was set. It is set for aggregations.
+	exhaustive bool
+
+	// stream, if set, receives the results of every search backend as soon as
+	// the backend returns. It is set for streaming searches.
+	stream *searchStream
+
 	// Cached resolveRepositories results.
 	reposMu                   sync.Mutex
 	repoRevs, missingRepoRevs []*search.RepositoryRevisions
@@ -196,6 +237,9 @@ func (r *searchResolver) rawQuery() string {
 }
 
 func (r *searchResolver) countIsSet() bool {
+	if r.exhaustive {
+		return true
+	}
 	count, _ := r.query.StringValues(query.FieldCount)
 	max, _ := r.query.StringValues(query.FieldMax)
 	return len(count) > 0 || len(max) > 0
@@ -210,6 +254,9 @@ func (r *searchResolver) maxResults() int32 {
 		// search_pagination.go for details on why this is necessary .
 		return math.MaxInt32
 	}
+	if r.limitOverride > 0 {
+		return r.limitOverride
+	}
 	count, _ := r.query.StringValues(query.FieldCount)
 	if len(count) > 0 {
 		n, _ := strconv.Atoi(count[0])
@@ -227,6 +274,22 @@ func (r *searchResolver) maxResults() int32 {
 	return defaultMaxSearchResults
 }
 
+// selectMaxBackendResults is the number of results the backends search for
+// when the query has a select: field. Many results can have the same
+// projection, so the results are projected before they are limited to
+// maxResults.
+const selectMaxBackendResults = 5000
+
+// backendMaxResults returns the number of results each backend should search
+// for.
+func (r *searchResolver) backendMaxResults() int32 {
+	max := r.maxResults()
+	if r.query.Selector() != nil && max < selectMaxBackendResults {
+		return selectMaxBackendResults
+	}
+	return max
+}
+
 var mockResolveRepoGroups func() (map[string][]*types.Repo, error)
 
 func resolveRepoGroups(ctx context.Context) (map[string][]*types.Repo, error) {
@@ -265,6 +328,11 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, effectiveRepoF
 			tr.SetError(err)
 		} else {
 			tr.LazyPrintf("numRepoRevs: %d, numMissingRepoRevs: %d, overLimit: %v", len(repoRevs), len(missingRepoRevs), overLimit)
+			explainer := searchExplainerFromContext(ctx)
+			explainer.resolvedRepos(repoRevs, missingRepoRevs)
+			if overLimit {
+				explainer.decision("repositories: limited to the first %d matching repositories", len(repoRevs))
+			}
 		}
 		tr.Finish()
 	}()
@@ -290,6 +358,8 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, effectiveRepoF
 	archived := parseYesNoOnly(archivedStr)
 
 	commitAfter, _ := r.query.StringValue(query.FieldRepoHasCommitAfter)
+	asOf, _ := r.query.StringValue(query.FieldAsOf)
+	submodules := r.query.BoolValue(query.FieldSubmodules)
 
 	tr.LazyPrintf("resolveRepositories - start")
 	repoRevs, missingRepoRevs, overLimit, err = resolveRepositories(ctx, resolveRepoOp{
@@ -301,6 +371,8 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, effectiveRepoF
 		onlyArchived:     archived == Only || archived == True,
 		noArchived:       archived == No || archived == False,
 		commitAfter:      commitAfter,
+		asOf:             asOf,
+		submodules:       submodules,
 	})
 	tr.LazyPrintf("resolveRepositories - done")
 	if effectiveRepoFieldValues == nil {
@@ -414,6 +486,8 @@ type resolveRepoOp struct {
 	noArchived       bool
 	onlyArchived     bool
 	commitAfter      string
+	asOf             string
+	submodules       bool
 }
 
 func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions, missingRepoRevisions []*search.RepositoryRevisions, overLimit bool, err error) {
@@ -563,6 +637,14 @@ func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions,
 		repoRevisions, err = filterRepoHasCommitAfter(ctx, repoRevisions, op.commitAfter)
 	}
 
+	if op.asOf != "" && err == nil {
+		repoRevisions, err = resolveRevsAsOf(ctx, repoRevisions, op.asOf)
+	}
+
+	if op.submodules && err == nil {
+		repoRevisions, err = addSubmoduleRevs(ctx, repoRevisions)
+	}
+
 	return repoRevisions, missingRepoRevisions, overLimit, err
 }
 
@@ -664,6 +746,141 @@ func filterRepoHasCommitAfter(ctx context.Context, revisions []*search.Repositor
 	return pass, err
 }
 
+// resolveRevsAsOf replaces the revisions of each repository with the last
+// commit before the date asOf on that revision, or on the default branch if
+// no revision is specified. Repositories without commits before asOf are
+// omitted.
+func resolveRevsAsOf(ctx context.Context, revisions []*search.RepositoryRevisions, asOf string) ([]*search.RepositoryRevisions, error) {
+	var (
+		mut  sync.Mutex
+		pass = []*search.RepositoryRevisions{}
+		res  = make(chan *search.RepositoryRevisions, 100)
+		run  = parallel.NewRun(128)
+	)
+
+	goroutine.Go(func() {
+		for rev := range res {
+			if len(rev.Revs) != 0 {
+				mut.Lock()
+				pass = append(pass, rev)
+				mut.Unlock()
+			}
+			run.Release()
+		}
+	})
+
+	for _, revs := range revisions {
+		run.Acquire()
+
+		revs := revs
+		goroutine.Go(func() {
+			var specifiers []search.RevisionSpecifier
+			for _, rev := range revs.Revs {
+				if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+					run.Error(&badRequestError{fmt.Errorf("asof: can't be combined with the ref glob %q in repository %s", rev.String(), revs.Repo.Name)})
+					continue
+				}
+				commit, err := git.LastCommitBefore(ctx, revs.GitserverRepo(), asOf, rev.RevSpec)
+				if err != nil {
+					if gitserver.IsRevisionNotFound(err) || vcs.IsRepoNotExist(err) {
+						continue
+					}
+
+					run.Error(err)
+					continue
+				}
+				specifiers = append(specifiers, search.RevisionSpecifier{RevSpec: string(commit)})
+			}
+			res <- &search.RepositoryRevisions{Repo: revs.Repo, Revs: specifiers}
+		})
+	}
+
+	err := run.Wait()
+	close(res)
+
+	return pass, err
+}
+
+// addSubmoduleRevs adds the repositories of the submodules of each revision
+// in revisions at the commits the submodules are pinned to, if the submodule
+// URLs refer to repositories on Sourcegraph. Submodules of submodules are not
+// added.
+func addSubmoduleRevs(ctx context.Context, revisions []*search.RepositoryRevisions) ([]*search.RepositoryRevisions, error) {
+	submodules := make([][]git.Submodule, len(revisions))
+	run := parallel.NewRun(128)
+	for i, revs := range revisions {
+		run.Acquire()
+
+		i, revs := i, revs
+		goroutine.Go(func() {
+			defer run.Release()
+			for _, rev := range revs.Revs {
+				if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+					continue
+				}
+				revSpec := rev.RevSpec
+				if revSpec == "" {
+					revSpec = "HEAD"
+				}
+				commit, err := git.ResolveRevision(ctx, revs.GitserverRepo(), nil, revSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+				if err == nil {
+					var s []git.Submodule
+					s, err = git.ListSubmodules(ctx, revs.GitserverRepo(), commit)
+					submodules[i] = append(submodules[i], s...)
+				}
+				if err != nil && !gitserver.IsRevisionNotFound(err) && !vcs.IsRepoNotExist(err) {
+					run.Error(err)
+				}
+			}
+		})
+	}
+	if err := run.Wait(); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[api.RepoName]*search.RepositoryRevisions, len(revisions))
+	for _, revs := range revisions {
+		byName[revs.Repo.Name] = revs
+	}
+	reposByURL := map[string]*types.Repo{}
+	for _, repoSubmodules := range submodules {
+		for _, submodule := range repoSubmodules {
+			repo, ok := reposByURL[submodule.URL]
+			if !ok {
+				var err error
+				if repo, err = submoduleRepo(ctx, submodule.URL); err != nil {
+					return nil, err
+				}
+				reposByURL[submodule.URL] = repo
+			}
+			if repo == nil {
+				continue
+			}
+
+			revs, ok := byName[repo.Name]
+			if !ok {
+				revs = &search.RepositoryRevisions{Repo: repo}
+				byName[repo.Name] = revs
+				revisions = append(revisions, revs)
+			}
+			rev := search.RevisionSpecifier{RevSpec: string(submodule.CommitID)}
+			if !containsRevisionSpecifier(revs.Revs, rev) {
+				revs.Revs = append(revs.Revs, rev)
+			}
+		}
+	}
+	return revisions, nil
+}
+
+func containsRevisionSpecifier(revs []search.RevisionSpecifier, rev search.RevisionSpecifier) bool {
+	for _, r := range revs {
+		if r == rev {
+			return true
+		}
+	}
+	return false
+}
+
 func optimizeRepoPatternWithHeuristics(repoPattern string) string {
 	if envvar.SourcegraphDotComMode() && strings.HasPrefix(string(repoPattern), "github.com") {
 		repoPattern = "^" + repoPattern
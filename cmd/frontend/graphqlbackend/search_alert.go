@@ -517,4 +517,5 @@ func (a searchAlert) Results(context.Context) (*SearchResultsResolver, error) {
 func (searchAlert) Suggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error) {
 	return nil, nil
 }
-func (searchAlert) Stats(context.Context) (*searchResultsStats, error) { return nil, nil }
+func (searchAlert) Stats(context.Context) (*searchResultsStats, error)          { return nil, nil }
+func (searchAlert) Explain(context.Context) (*searchExplanationResolver, error) { return nil, nil }
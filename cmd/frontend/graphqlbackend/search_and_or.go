@@ -0,0 +1,298 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// This file contains the evaluation of search queries that contain the boolean
+// operators AND, OR and NOT (see query.ProcessAndOr). Every operand without
+// operators is searched like a regular query, and the result sets of the
+// operands are intersected (AND), unioned (OR) or subtracted (NOT) per file,
+// repository or commit.
+
+// andOperandResultLimit is the minimum number of results fetched for each
+// operand of an AND operator. The operands are intersected after they are
+// fetched, so each of them needs more results than the final result set to
+// find the matches they have in common.
+const andOperandResultLimit = 1000
+
+// firstLeaf returns the leftmost query without operators in node. It is used
+// for the parts of the search API that do not evaluate the operators, such as
+// suggestions.
+func firstLeaf(node query.Node) *query.Leaf {
+	switch n := node.(type) {
+	case *query.Leaf:
+		return n
+	case *query.Operator:
+		return firstLeaf(n.Operands[0])
+	}
+	panic("unreachable")
+}
+
+// evaluateAndOr evaluates r.andOrQuery and returns at most r.maxResults()
+// results.
+func (r *searchResolver) evaluateAndOr(ctx context.Context, forceOnlyResultType string) (res *SearchResultsResolver, err error) {
+	tr, ctx := trace.New(ctx, "graphql.SearchResults.evaluateAndOr", r.rawQuery())
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	start := time.Now()
+	limit := r.maxResults()
+	res, err = r.evaluateNode(ctx, r.andOrQuery, limit, forceOnlyResultType)
+	if res == nil {
+		return nil, err
+	}
+
+	// The results are ranked before they are limited, so that the best
+	// ranked results are kept.
+	sortResults(res.SearchResults)
+	rankResults(ctx, res.SearchResults)
+	if len(res.SearchResults) > int(limit) {
+		res.SearchResults = res.SearchResults[:limit]
+		res.limitHit = true
+	}
+	res.start = start
+	res.maxResultsCount = limit
+	res.resultCount = int32(len(res.SearchResults))
+	tr.LazyPrintf("results=%d limitHit=%v", len(res.SearchResults), res.limitHit)
+	return res, err
+}
+
+// evaluateNode evaluates node, fetching up to limit results for every query
+// without operators in it.
+func (r *searchResolver) evaluateNode(ctx context.Context, node query.Node, limit int32, forceOnlyResultType string) (*SearchResultsResolver, error) {
+	switch n := node.(type) {
+	case *query.Leaf:
+		leaf := &searchResolver{
+			query:         n.Query,
+			parseTree:     n.ParseTree,
+			originalQuery: n.ParseTree.String(),
+			patternType:   r.patternType,
+			limitOverride: limit,
+			exhaustive:    r.exhaustive,
+			zoekt:         r.zoekt,
+			searcherURLs:  r.searcherURLs,
+		}
+		return leaf.doResults(ctx, forceOnlyResultType)
+
+	case *query.Operator:
+		if n.Kind == syntax.And && limit < andOperandResultLimit {
+			limit = andOperandResultLimit
+		}
+
+		var (
+			wg       sync.WaitGroup
+			operands = make([]*SearchResultsResolver, len(n.Operands))
+			errs     = make([]error, len(n.Operands))
+		)
+		for i, operand := range n.Operands {
+			i, operand := i, operand
+			if not, ok := operand.(*query.Operator); ok && not.Kind == syntax.Not {
+				operand = not.Operands[0]
+			}
+			wg.Add(1)
+			goroutine.Go(func() {
+				defer wg.Done()
+				operands[i], errs[i] = r.evaluateNode(ctx, operand, limit, forceOnlyResultType)
+			})
+		}
+		wg.Wait()
+
+		// Like doResults, we only return an error together with partial
+		// results if there are no results at all.
+		var multiErr *multierror.Error
+		for i, err := range errs {
+			if err != nil && operands[i] == nil {
+				return nil, err
+			}
+			if err != nil {
+				multiErr = multierror.Append(multiErr, err)
+			}
+		}
+
+		var res *SearchResultsResolver
+		switch n.Kind {
+		case syntax.And:
+			var include, exclude []*SearchResultsResolver
+			for i, operand := range n.Operands {
+				if not, ok := operand.(*query.Operator); ok && not.Kind == syntax.Not {
+					exclude = append(exclude, operands[i])
+				} else {
+					include = append(include, operands[i])
+				}
+			}
+			res = intersectResults(include, exclude)
+		case syntax.Or:
+			res = unionResults(operands)
+		default:
+			return nil, fmt.Errorf("unexpected operator %s", n.Kind)
+		}
+		if len(res.SearchResults) > 0 {
+			return res, nil
+		}
+		return res, multiErr.ErrorOrNil()
+	}
+	panic("unreachable")
+}
+
+// resultKey returns the key identifying a search result across the result
+// sets of different operands.
+func resultKey(result SearchResultResolver) string {
+	switch r := result.(type) {
+	case *FileMatchResolver:
+		return "file:" + r.uri
+	case *RepositoryResolver:
+		return "repo:" + string(r.repo.Name)
+	case *commitSearchResultResolver:
+		return "commit:" + r.url
+	default:
+		repo, file := result.searchResultURIs()
+		return fmt.Sprintf("%T:%s:%s", result, repo, file)
+	}
+}
+
+// mergeResult merges the matches of b into a result for the same key as a. It
+// returns a new result and does not modify a or b.
+func mergeResult(a, b SearchResultResolver) SearchResultResolver {
+	fa, ok := a.(*FileMatchResolver)
+	if !ok {
+		return a
+	}
+	fb, ok := b.(*FileMatchResolver)
+	if !ok {
+		return a
+	}
+
+	merged := *fa
+	merged.JLimitHit = fa.JLimitHit || fb.JLimitHit
+	if len(merged.symbols) == 0 {
+		merged.symbols = fb.symbols
+	}
+
+	lines := make(map[int32]*lineMatch, len(fa.JLineMatches)+len(fb.JLineMatches))
+	for _, lm := range append(append([]*lineMatch{}, fa.JLineMatches...), fb.JLineMatches...) {
+		existing, ok := lines[lm.JLineNumber]
+		if !ok {
+			lines[lm.JLineNumber] = lm
+			continue
+		}
+		lm2 := *existing
+		lm2.JLimitHit = existing.JLimitHit || lm.JLimitHit
+		lm2.JOffsetAndLengths = mergeOffsetAndLengths(existing.JOffsetAndLengths, lm.JOffsetAndLengths)
+		lines[lm.JLineNumber] = &lm2
+	}
+	merged.JLineMatches = make([]*lineMatch, 0, len(lines))
+	for _, lm := range lines {
+		merged.JLineMatches = append(merged.JLineMatches, lm)
+	}
+	sort.Slice(merged.JLineMatches, func(i, j int) bool {
+		return merged.JLineMatches[i].JLineNumber < merged.JLineMatches[j].JLineNumber
+	})
+	return &merged
+}
+
+// mergeOffsetAndLengths returns the sorted union of two lists of match ranges
+// on the same line.
+func mergeOffsetAndLengths(a, b [][2]int32) [][2]int32 {
+	seen := make(map[[2]int32]struct{}, len(a)+len(b))
+	var merged [][2]int32
+	for _, ol := range append(append([][2]int32{}, a...), b...) {
+		if _, ok := seen[ol]; ok {
+			continue
+		}
+		seen[ol] = struct{}{}
+		merged = append(merged, ol)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i][0] < merged[j][0] })
+	return merged
+}
+
+// intersectResults returns the results that are present in all of include
+// and in none of exclude.
+func intersectResults(include, exclude []*SearchResultsResolver) *SearchResultsResolver {
+	res := &SearchResultsResolver{alert: firstAlert(include)}
+	for _, operand := range append(append([]*SearchResultsResolver{}, include...), exclude...) {
+		res.searchResultsCommon.update(operand.searchResultsCommon)
+	}
+
+	merged := make(map[string]SearchResultResolver)
+	for _, result := range include[0].SearchResults {
+		merged[resultKey(result)] = result
+	}
+	for _, operand := range include[1:] {
+		next := make(map[string]SearchResultResolver, len(merged))
+		for _, result := range operand.SearchResults {
+			key := resultKey(result)
+			if existing, ok := merged[key]; ok {
+				next[key] = mergeResult(existing, result)
+			}
+		}
+		merged = next
+	}
+	for _, operand := range exclude {
+		for _, result := range operand.SearchResults {
+			delete(merged, resultKey(result))
+		}
+	}
+
+	for _, operand := range include[0].SearchResults {
+		if result, ok := merged[resultKey(operand)]; ok {
+			res.SearchResults = append(res.SearchResults, result)
+		}
+	}
+	if len(res.SearchResults) > 0 {
+		res.alert = nil
+	}
+	return res
+}
+
+// unionResults returns the results that are present in any of operands,
+// merging the matches of results that are present in several of them.
+func unionResults(operands []*SearchResultsResolver) *SearchResultsResolver {
+	res := &SearchResultsResolver{alert: firstAlert(operands)}
+	var keys []string
+	merged := make(map[string]SearchResultResolver)
+	for _, operand := range operands {
+		res.searchResultsCommon.update(operand.searchResultsCommon)
+		for _, result := range operand.SearchResults {
+			key := resultKey(result)
+			if existing, ok := merged[key]; ok {
+				merged[key] = mergeResult(existing, result)
+			} else {
+				merged[key] = result
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range keys {
+		res.SearchResults = append(res.SearchResults, merged[key])
+	}
+	if len(res.SearchResults) > 0 {
+		res.alert = nil
+	}
+	return res
+}
+
+// firstAlert returns the first alert of the given result sets, so that e.g.
+// an alert for an operand that matched no repositories is not lost.
+func firstAlert(operands []*SearchResultsResolver) *searchAlert {
+	for _, operand := range operands {
+		if operand.alert != nil {
+			return operand.alert
+		}
+	}
+	return nil
+}
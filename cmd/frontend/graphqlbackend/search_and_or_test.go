@@ -0,0 +1,80 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestIntersectAndUnionResults(t *testing.T) {
+	repo := &types.Repo{ID: 1, Name: "r"}
+	fileMatch := func(path string, lines ...int32) *FileMatchResolver {
+		fm := &FileMatchResolver{JPath: path, Repo: repo, uri: "git://r?c#" + path}
+		for _, line := range lines {
+			fm.JLineMatches = append(fm.JLineMatches, &lineMatch{JLineNumber: line, JOffsetAndLengths: [][2]int32{{int32(line), 1}}})
+		}
+		return fm
+	}
+	results := func(rs ...SearchResultResolver) *SearchResultsResolver {
+		return &SearchResultsResolver{SearchResults: rs}
+	}
+	summary := func(res *SearchResultsResolver) map[string][]int32 {
+		m := map[string][]int32{}
+		for _, r := range res.SearchResults {
+			switch r := r.(type) {
+			case *FileMatchResolver:
+				lines := []int32{}
+				for _, lm := range r.JLineMatches {
+					lines = append(lines, lm.JLineNumber)
+				}
+				m[r.JPath] = lines
+			case *RepositoryResolver:
+				m["repo:"+r.Name()] = nil
+			}
+		}
+		return m
+	}
+
+	a := results(fileMatch("a", 1, 2), fileMatch("b", 3), &RepositoryResolver{repo: repo})
+	b := results(fileMatch("a", 2, 5), fileMatch("c", 4))
+	c := results(fileMatch("b"), &RepositoryResolver{repo: repo})
+
+	t.Run("intersect", func(t *testing.T) {
+		got := summary(intersectResults([]*SearchResultsResolver{a, b}, nil))
+		want := map[string][]int32{"a": {1, 2, 5}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("intersect with exclude", func(t *testing.T) {
+		got := summary(intersectResults([]*SearchResultsResolver{a}, []*SearchResultsResolver{c}))
+		want := map[string][]int32{"a": {1, 2}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("union", func(t *testing.T) {
+		got := summary(unionResults([]*SearchResultsResolver{a, b, c}))
+		want := map[string][]int32{"a": {1, 2, 5}, "b": {3}, "c": {4}, "repo:r": nil}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("does not modify operands", func(t *testing.T) {
+		if got := summary(a); !reflect.DeepEqual(got, map[string][]int32{"a": {1, 2}, "b": {3}, "repo:r": nil}) {
+			t.Errorf("operand was modified: %v", got)
+		}
+	})
+}
+
+func TestMergeOffsetAndLengths(t *testing.T) {
+	got := mergeOffsetAndLengths([][2]int32{{5, 1}, {1, 2}}, [][2]int32{{1, 2}, {3, 1}})
+	want := [][2]int32{{1, 2}, {3, 1}, {5, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
 // commitSearchResultResolver is a resolver for the GraphQL type `CommitSearchResult`
@@ -457,6 +458,7 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.TextParametersFo
 				return
 			}
 			repoTimedOut = repoTimedOut || ctx.Err() == context.DeadlineExceeded
+			truncated := searchErr == nil && !repoLimitHit && !repoTimedOut && historyTruncated(ctx, repoRev)
 			if searchErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 			}
@@ -466,6 +468,9 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.TextParametersFo
 				err = errors.Wrapf(searchErr, "failed to search commit diffs %s", repoRev.String())
 				cancel()
 			}
+			if truncated {
+				common.truncatedHistory = append(common.truncatedHistory, repoRev.Repo)
+			}
 			if len(results) > 0 {
 				unflattened = append(unflattened, results)
 			}
@@ -483,6 +488,18 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.TextParametersFo
 	return commitSearchResultsToSearchResults(flattened), common, nil
 }
 
+// historyTruncated returns true if commits older than the ones searched in
+// repoRev were not searched, because the repository is a shallow clone. It
+// only matters for searches which neither hit the result limit nor timed out.
+func historyTruncated(ctx context.Context, repoRev *search.RepositoryRevisions) bool {
+	shallow, err := git.IsShallow(ctx, repoRev.GitserverRepo())
+	if err != nil {
+		log15.Warn("failed to check whether repository is a shallow clone", "repo", repoRev.Repo.Name, "error", err)
+		return false
+	}
+	return shallow
+}
+
 var mockSearchCommitLogInRepos func(args *search.TextParametersForCommitParameters) ([]SearchResultResolver, *searchResultsCommon, error)
 
 // searchCommitLogInRepos searches a set of repos for matching commits.
@@ -522,6 +539,7 @@ func searchCommitLogInRepos(ctx context.Context, args *search.TextParametersForC
 				return
 			}
 			repoTimedOut = repoTimedOut || ctx.Err() == context.DeadlineExceeded
+			truncated := searchErr == nil && !repoLimitHit && !repoTimedOut && historyTruncated(ctx, repoRev)
 			if searchErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 			}
@@ -531,6 +549,9 @@ func searchCommitLogInRepos(ctx context.Context, args *search.TextParametersForC
 				err = errors.Wrapf(searchErr, "failed to search commit log %s", repoRev.String())
 				cancel()
 			}
+			if truncated {
+				common.truncatedHistory = append(common.truncatedHistory, repoRev.Repo)
+			}
 			if len(results) > 0 {
 				unflattened = append(unflattened, results)
 			}
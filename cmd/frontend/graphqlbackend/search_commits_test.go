@@ -14,6 +14,7 @@ import (
 	//"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
@@ -97,6 +98,34 @@ func TestSearchCommitsInRepo(t *testing.T) {
 	}
 }
 
+func TestSearchCommitLogInRepos_truncatedHistory(t *testing.T) {
+	git.Mocks.RawLogDiffSearch = func(opt git.RawLogDiffSearchOptions) ([]*git.LogCommitSearchResult, bool, error) {
+		return nil, true, nil
+	}
+	git.Mocks.IsShallow = func(repo gitserver.Repo) (bool, error) {
+		return repo.Name == "shallow", nil
+	}
+	defer git.ResetMocks()
+
+	q, err := query.ParseAndCheck("type:commit p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shallow := &types.Repo{ID: 1, Name: "shallow"}
+	full := &types.Repo{ID: 2, Name: "full"}
+	_, common, err := searchCommitLogInRepos(context.Background(), &search.TextParametersForCommitParameters{
+		PatternInfo: &search.CommitPatternInfo{Pattern: "p", FileMatchLimit: int32(defaultMaxSearchResults)},
+		Repos:       []*search.RepositoryRevisions{{Repo: shallow}, {Repo: full}},
+		Query:       q,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*types.Repo{shallow}; !reflect.DeepEqual(common.truncatedHistory, want) {
+		t.Errorf("got truncatedHistory %v, want %v", common.truncatedHistory, want)
+	}
+}
+
 func (r *commitSearchResultResolver) String() string {
 	return fmt.Sprintf("{commit: %+v diffPreview: %+v messagePreview: %+v}", r.commit, r.diffPreview, r.messagePreview)
 }
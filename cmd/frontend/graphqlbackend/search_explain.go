@@ -0,0 +1,345 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// This file contains search explanations, which describe how a search was
+// run: the repositories it resolved, the backend that searched each of them,
+// how long every backend took and the limits and timeouts that applied. They
+// are recorded while the search runs by a searchExplainer in the context, so
+// that the code that makes these decisions also records them.
+
+// searchExplainer records how a search is run. The zero value is ready to use,
+// and a nil *searchExplainer ignores everything it is told, so searches that
+// are not explained do not need to check for one.
+type searchExplainer struct {
+	mu        sync.Mutex
+	repos     map[api.RepoName]*explainedRepo
+	repoOrder []api.RepoName
+	backends  []*searchExplanationBackendResolver
+	decisions []string
+
+	resultTypes []string
+}
+
+type explainedRepo struct {
+	revs            []search.RevisionSpecifier
+	missingRevision bool   // the revisions of the repository could not be resolved
+	backend         string // "indexed" or "unindexed" for text search, or empty
+}
+
+type searchExplainerKey struct{}
+
+// withSearchExplainer returns a context that records how searches run with it
+// are run in e.
+func withSearchExplainer(ctx context.Context, e *searchExplainer) context.Context {
+	return context.WithValue(ctx, searchExplainerKey{}, e)
+}
+
+// searchExplainerFromContext returns the searchExplainer of ctx, or nil if the
+// search is not explained.
+func searchExplainerFromContext(ctx context.Context) *searchExplainer {
+	e, _ := ctx.Value(searchExplainerKey{}).(*searchExplainer)
+	return e
+}
+
+// decision records a limit, timeout or other decision that changed how the
+// search was run. A decision that was already recorded is ignored, since some
+// of them are made again for every result type.
+func (e *searchExplainer) decision(format string, args ...interface{}) {
+	if e == nil {
+		return
+	}
+	d := fmt.Sprintf(format, args...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, seen := range e.decisions {
+		if seen == d {
+			return
+		}
+	}
+	e.decisions = append(e.decisions, d)
+}
+
+// resolvedRepos records the repositories resolved for the search, and the
+// repositories whose revisions could not be resolved.
+func (e *searchExplainer) resolvedRepos(repoRevs, missingRepoRevs []*search.RepositoryRevisions) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	add := func(repoRev *search.RepositoryRevisions, missing bool) {
+		repo, ok := e.repos[repoRev.Repo.Name]
+		if !ok {
+			repo = &explainedRepo{}
+			if e.repos == nil {
+				e.repos = make(map[api.RepoName]*explainedRepo)
+			}
+			e.repos[repoRev.Repo.Name] = repo
+			e.repoOrder = append(e.repoOrder, repoRev.Repo.Name)
+		}
+		repo.revs = repoRev.Revs
+		repo.missingRevision = missing
+	}
+	for _, repoRev := range repoRevs {
+		add(repoRev, false)
+	}
+	for _, repoRev := range missingRepoRevs {
+		add(repoRev, true)
+	}
+}
+
+// searchedResultTypes records the types of results that the search looks for.
+func (e *searchExplainer) searchedResultTypes(resultTypes []string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+outer:
+	for _, t := range resultTypes {
+		for _, seen := range e.resultTypes {
+			if t == seen {
+				continue outer
+			}
+		}
+		e.resultTypes = append(e.resultTypes, t)
+	}
+}
+
+// reposBackend records that text search chose backend ("indexed" or
+// "unindexed") for repos.
+func (e *searchExplainer) reposBackend(repos []*search.RepositoryRevisions, backend string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, repoRev := range repos {
+		if repo, ok := e.repos[repoRev.Repo.Name]; ok {
+			repo.backend = backend
+		}
+	}
+}
+
+// backend records that the search backend name (e.g. "symbol" or
+// "text.indexed") searched the given number of repositories in duration.
+// required is whether the search waited for the backend to return before it
+// returned.
+func (e *searchExplainer) backend(name string, required bool, repos int, duration time.Duration, resultCount int, err error) {
+	if e == nil {
+		return
+	}
+	b := &searchExplanationBackendResolver{
+		name:         name,
+		required:     required,
+		repositories: int32(repos),
+		duration:     duration,
+		resultCount:  int32(resultCount),
+	}
+	if err != nil {
+		msg := err.Error()
+		b.err = &msg
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.backends = append(e.backends, b)
+}
+
+// Explain runs the search and returns how it was run. The results themselves
+// are discarded.
+func (r *searchResolver) Explain(ctx context.Context) (*searchExplanationResolver, error) {
+	e := &searchExplainer{}
+	ctx = withSearchExplainer(ctx, e)
+
+	start := time.Now()
+	timeout, err := r.searchTimeout()
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.doResults(ctx, "")
+	if err != nil && res == nil {
+		return nil, err
+	}
+
+	var fields []*searchExplanationFieldResolver
+	for field, values := range r.query.Fields {
+		for _, v := range values {
+			fields = append(fields, &searchExplanationFieldResolver{field: field, value: v.ToString(), negated: v.Not()})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].field != fields[j].field {
+			return fields[i].field < fields[j].field
+		}
+		return fields[i].value < fields[j].value
+	})
+
+	x := &searchExplanationResolver{
+		fields:      fields,
+		patternType: searchTypeName(r.patternType),
+		resultLimit: r.maxResults(),
+		timeout:     timeout,
+		elapsed:     time.Since(start),
+	}
+	if r.andOrQuery != nil {
+		x.operators = r.andOrQuery.String()
+	}
+	if res != nil {
+		x.limitHit = res.LimitHit()
+		x.resultCount = int32(len(res.SearchResults))
+		if res.alert != nil {
+			e.decision("alert: %s", res.alert.title)
+		}
+	}
+	if err != nil {
+		e.decision("error: %s", err)
+	}
+
+	status := make(map[api.RepoName]string)
+	if res != nil {
+		for _, s := range []struct {
+			repos  []*types.Repo
+			status string
+		}{
+			{res.searched, "searched"},
+			{res.cloning, "cloning"},
+			{res.missing, "missing"},
+			{res.timedout, "timedout"},
+		} {
+			for _, repo := range s.repos {
+				status[repo.Name] = s.status
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, name := range e.repoOrder {
+		repo := e.repos[name]
+		rr := &searchExplanationRepositoryResolver{name: string(name), status: "not searched"}
+		if s, ok := status[name]; ok {
+			rr.status = s
+		}
+		if repo.missingRevision {
+			rr.status = "missing revision"
+		}
+		for _, rev := range repo.revs {
+			rr.revisions = append(rr.revisions, rev.String())
+		}
+		if repo.backend != "" {
+			backend := repo.backend
+			rr.backend = &backend
+		}
+		x.repositories = append(x.repositories, rr)
+	}
+	x.resultTypes = e.resultTypes
+	x.backends = e.backends
+	x.decisions = e.decisions
+	return x, nil
+}
+
+// searchTypeName returns the name of t in the GraphQL SearchPatternType enum.
+func searchTypeName(t query.SearchType) string {
+	switch t {
+	case query.SearchTypeLiteral:
+		return "literal"
+	case query.SearchTypeStructural:
+		return "structural"
+	default:
+		return "regexp"
+	}
+}
+
+type searchExplanationResolver struct {
+	fields       []*searchExplanationFieldResolver
+	operators    string
+	patternType  string
+	resultTypes  []string
+	resultLimit  int32
+	timeout      time.Duration
+	repositories []*searchExplanationRepositoryResolver
+	backends     []*searchExplanationBackendResolver
+	decisions    []string
+	limitHit     bool
+	resultCount  int32
+	elapsed      time.Duration
+}
+
+func (r *searchExplanationResolver) Fields() []*searchExplanationFieldResolver { return r.fields }
+
+func (r *searchExplanationResolver) Operators() *string {
+	if r.operators == "" {
+		return nil
+	}
+	return &r.operators
+}
+
+func (r *searchExplanationResolver) PatternType() string   { return r.patternType }
+func (r *searchExplanationResolver) ResultTypes() []string { return r.resultTypes }
+func (r *searchExplanationResolver) ResultLimit() int32    { return r.resultLimit }
+func (r *searchExplanationResolver) Timeout() string       { return r.timeout.String() }
+func (r *searchExplanationResolver) Decisions() []string   { return r.decisions }
+func (r *searchExplanationResolver) LimitHit() bool        { return r.limitHit }
+func (r *searchExplanationResolver) ResultCount() int32    { return r.resultCount }
+func (r *searchExplanationResolver) ElapsedMilliseconds() int32 {
+	return int32(r.elapsed.Milliseconds())
+}
+
+func (r *searchExplanationResolver) Repositories() []*searchExplanationRepositoryResolver {
+	return r.repositories
+}
+
+func (r *searchExplanationResolver) Backends() []*searchExplanationBackendResolver {
+	return r.backends
+}
+
+type searchExplanationFieldResolver struct {
+	field, value string
+	negated      bool
+}
+
+func (r *searchExplanationFieldResolver) Field() string { return r.field }
+func (r *searchExplanationFieldResolver) Value() string { return r.value }
+func (r *searchExplanationFieldResolver) Negated() bool { return r.negated }
+
+type searchExplanationRepositoryResolver struct {
+	name      string
+	revisions []string
+	backend   *string
+	status    string
+}
+
+func (r *searchExplanationRepositoryResolver) Name() string        { return r.name }
+func (r *searchExplanationRepositoryResolver) Revisions() []string { return r.revisions }
+func (r *searchExplanationRepositoryResolver) Backend() *string    { return r.backend }
+func (r *searchExplanationRepositoryResolver) Status() string      { return r.status }
+
+type searchExplanationBackendResolver struct {
+	name         string
+	required     bool
+	repositories int32
+	duration     time.Duration
+	resultCount  int32
+	err          *string
+}
+
+func (r *searchExplanationBackendResolver) Name() string        { return r.name }
+func (r *searchExplanationBackendResolver) Required() bool      { return r.required }
+func (r *searchExplanationBackendResolver) Repositories() int32 { return r.repositories }
+func (r *searchExplanationBackendResolver) DurationMilliseconds() int32 {
+	return int32(r.duration.Milliseconds())
+}
+func (r *searchExplanationBackendResolver) ResultCount() int32 { return r.resultCount }
+func (r *searchExplanationBackendResolver) Error() *string     { return r.err }
@@ -0,0 +1,130 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestSearchResolver_Explain(t *testing.T) {
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		return []*types.Repo{{ID: 1, Name: "repo1"}, {ID: 2, Name: "repo2"}}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+		return []*FileMatchResolver{{JPath: "a.go", uri: "git://repo1?HEAD#a.go"}}, &searchResultsCommon{
+			repos:    []*types.Repo{{ID: 1, Name: "repo1"}, {ID: 2, Name: "repo2"}},
+			searched: []*types.Repo{{ID: 1, Name: "repo1"}},
+			timedout: []*types.Repo{{ID: 2, Name: "repo2"}},
+		}, nil
+	}
+	defer func() { mockSearchFilesInRepos = nil }()
+
+	r, err := (&schemaResolver{}).Search(&SearchArgs{Query: "repo:repo type:file -file:test foo", Version: "V2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Explain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type field struct {
+		Field, Value string
+		Negated      bool
+	}
+	var fields []field
+	for _, f := range x.Fields() {
+		fields = append(fields, field{f.Field(), f.Value(), f.Negated()})
+	}
+	wantFields := []field{
+		{"", "foo", false},
+		{"file", "test", true},
+		{"repo", "repo", false},
+		{"type", "file", false},
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("got fields %+v, want %+v", fields, wantFields)
+	}
+
+	if got, want := x.PatternType(), "literal"; got != want {
+		t.Errorf("got pattern type %q, want %q", got, want)
+	}
+	if got, want := x.ResultTypes(), []string{"file"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got result types %v, want %v", got, want)
+	}
+	if got, want := x.ResultCount(), int32(1); got != want {
+		t.Errorf("got result count %d, want %d", got, want)
+	}
+
+	var repos []string
+	for _, repo := range x.Repositories() {
+		repos = append(repos, repo.Name()+" "+repo.Status())
+	}
+	if want := []string{"repo1 searched", "repo2 timedout"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("got repositories %v, want %v", repos, want)
+	}
+
+	var backends []string
+	for _, b := range x.Backends() {
+		backends = append(backends, b.Name())
+	}
+	if want := []string{"text"}; !reflect.DeepEqual(backends, want) {
+		t.Errorf("got backends %v, want %v", backends, want)
+	}
+}
+
+func TestSearchExplainer(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		// Searches that are not explained have no explainer, which must
+		// ignore what it is told.
+		var e *searchExplainer
+		e.decision("d")
+		e.resolvedRepos([]*search.RepositoryRevisions{{Repo: &types.Repo{Name: "r"}}}, nil)
+		e.reposBackend([]*search.RepositoryRevisions{{Repo: &types.Repo{Name: "r"}}}, "indexed")
+		e.searchedResultTypes([]string{"file"})
+		e.backend("text", true, 1, 0, 0, nil)
+	})
+
+	t.Run("record", func(t *testing.T) {
+		e := &searchExplainer{}
+		ctx := withSearchExplainer(context.Background(), e)
+		if searchExplainerFromContext(ctx) != e {
+			t.Fatal("explainer not in context")
+		}
+
+		e.decision("limit %d", 1)
+		e.decision("limit %d", 1)
+		e.decision("limit %d", 2)
+		if want := []string{"limit 1", "limit 2"}; !reflect.DeepEqual(e.decisions, want) {
+			t.Errorf("got decisions %v, want %v", e.decisions, want)
+		}
+
+		e.resolvedRepos(
+			[]*search.RepositoryRevisions{{Repo: &types.Repo{Name: "a"}}, {Repo: &types.Repo{Name: "b"}}},
+			[]*search.RepositoryRevisions{{Repo: &types.Repo{Name: "c"}, Revs: []search.RevisionSpecifier{{RevSpec: "v1"}}}},
+		)
+		e.reposBackend([]*search.RepositoryRevisions{{Repo: &types.Repo{Name: "b"}}}, "unindexed")
+		if want := []api.RepoName{"a", "b", "c"}; !reflect.DeepEqual(e.repoOrder, want) {
+			t.Errorf("got repos %v, want %v", e.repoOrder, want)
+		}
+		if got := e.repos["b"].backend; got != "unindexed" {
+			t.Errorf("got backend %q, want unindexed", got)
+		}
+		if !e.repos["c"].missingRevision {
+			t.Error("want c to be missing a revision")
+		}
+
+		e.backend("text.indexed", true, 2, 0, 3, errors.New("x"))
+		if b := e.backends[0]; b.Name() != "text.indexed" || b.ResultCount() != 3 || b.Error() == nil || *b.Error() != "x" {
+			t.Errorf("unexpected backend %+v", b)
+		}
+	})
+}
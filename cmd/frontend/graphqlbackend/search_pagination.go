@@ -560,6 +560,7 @@ func sliceSearchResultsCommon(common *searchResultsCommon, firstResultRepo, last
 	final.cloning = doAppend(final.cloning, common.cloning)
 	final.missing = doAppend(final.missing, common.missing)
 	final.timedout = doAppend(final.timedout, common.timedout)
+	final.truncatedHistory = doAppend(final.truncatedHistory, common.truncatedHistory)
 	return final
 }
 
@@ -0,0 +1,283 @@
+package graphqlbackend
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// This file contains the ranking of file matches. Without it, results are
+// ordered by repository and path, so test fixtures and vendored copies of a
+// file often come before its canonical definition.
+
+// Weights of the signals that make up the score of a file match. Every signal
+// is normalized to [0, 1] (penalties to [-1, 0]) before it is weighed.
+const (
+	rankWeightDensity    = 1.0 // how many matches the file has
+	rankWeightDefinition = 2.0 // whether a match is on a symbol definition
+	rankWeightDepth      = 1.0 // how close to the repository root the file is
+	rankWeightStars      = 1.0 // how popular the repository is
+	rankWeightLowValue   = 3.0 // penalty for vendored, test and generated files
+)
+
+const (
+	// rankMaxDensityMatches is the number of matches in a file above which
+	// more matches do not increase its score.
+	rankMaxDensityMatches = 10
+
+	// rankMaxStars is the number of stars above which more stars do not
+	// increase the score of the files in a repository.
+	rankMaxStars = 100000
+
+	// rankSymbolsFileLimit is the number of best-scoring file matches whose
+	// symbol definitions are looked up in the symbols service.
+	rankSymbolsFileLimit = 50
+
+	// rankLookupTimeout bounds the time spent looking up signals that are not
+	// part of the results. Signals that are not found in time are ignored.
+	rankLookupTimeout = 500 * time.Millisecond
+)
+
+// lowValuePathPattern matches the paths of files that are less likely to be
+// what a user is looking for than other files with the same matches: vendored
+// dependencies, tests and test fixtures, and generated or minified code.
+var lowValuePathPattern = lazyregexp.New(`(^|/)(vendor|node_modules|third_party|bower_components|testdata|fixtures?|__fixtures__|__mocks__|__tests__|tests?|spec)/` +
+	`|(_test\.go|_test\.py|\.(test|spec)\.[jt]sx?|Test\.java|_spec\.rb|\.pb\.go|\.pb\.[ch]c?|_pb2\.py|\.generated\.\w+|_generated\.\w+|\.min\.(js|css))$`)
+
+// rankingSignals are the signals of a file match that are not part of the
+// match itself.
+type rankingSignals struct {
+	// stars is the number of stars of the repository on its code host.
+	stars int
+
+	// definitionLines are the 0-based lines of the file that contain a symbol
+	// definition.
+	definitionLines map[int32]bool
+}
+
+// fileMatchScore returns the relevance score of fm. File matches with higher
+// scores are ranked first.
+func fileMatchScore(fm *FileMatchResolver, signals rankingSignals) float64 {
+	matches := 0
+	definition := len(fm.symbols) > 0
+	for _, lm := range fm.JLineMatches {
+		matches += len(lm.JOffsetAndLengths)
+		if signals.definitionLines[lm.JLineNumber] {
+			definition = true
+		}
+	}
+	density := math.Min(float64(matches), rankMaxDensityMatches) / rankMaxDensityMatches
+
+	score := rankWeightDensity * density
+	if definition {
+		score += rankWeightDefinition
+	}
+	score += rankWeightDepth / float64(1+strings.Count(fm.JPath, "/"))
+	if signals.stars > 0 {
+		stars := math.Min(float64(signals.stars), rankMaxStars)
+		score += rankWeightStars * math.Log1p(stars) / math.Log1p(rankMaxStars)
+	}
+	if lowValuePathPattern.MatchString(fm.JPath) {
+		score -= rankWeightLowValue
+	}
+	return score
+}
+
+// rankingStarCounts and rankingSymbols look up signals for ranking. They are
+// declared as variables for testing.
+var (
+	rankingStarCounts = func(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int, error) {
+		return db.Repos.StarCounts(ctx, ids...)
+	}
+	rankingSymbols = backend.Symbols.ListTags
+)
+
+// rankResults orders the file matches in results by relevance (see
+// fileMatchScore). Other results keep their position, and the file matches
+// are placed in the positions of results that were file matches before, so
+// the order of the result types does not change. Ties keep the existing
+// order.
+func rankResults(ctx context.Context, results []SearchResultResolver) {
+	var (
+		positions []int
+		files     []*FileMatchResolver
+	)
+	for i, result := range results {
+		if fm, ok := result.ToFileMatch(); ok {
+			positions = append(positions, i)
+			files = append(files, fm)
+		}
+	}
+	if len(files) < 2 {
+		return
+	}
+
+	tr, ctx := trace.New(ctx, "rankResults", "")
+	defer tr.Finish()
+	ctx, cancel := context.WithTimeout(ctx, rankLookupTimeout)
+	defer cancel()
+
+	signals := make([]rankingSignals, len(files))
+	stars := rankingRepoStars(ctx, files)
+	for i, fm := range files {
+		signals[i].stars = stars[fm.Repo.ID]
+	}
+
+	// Symbol definitions require a request per repository, so we only look
+	// them up for the files that would be ranked first without them.
+	scores := make([]float64, len(files))
+	for i, fm := range files {
+		scores[i] = fileMatchScore(fm, signals[i])
+	}
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > rankSymbolsFileLimit {
+		order = order[:rankSymbolsFileLimit]
+	}
+	rankingDefinitionLines(ctx, files, order, signals)
+
+	ranked := make([]int, len(files))
+	for i := range ranked {
+		ranked[i] = i
+		scores[i] = fileMatchScore(files[i], signals[i])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i]] > scores[ranked[j]] })
+	tr.LazyPrintf("ranked %d file matches", len(files))
+
+	for i, pos := range positions {
+		results[pos] = files[ranked[i]]
+	}
+}
+
+// limitFileMatches returns the limit file matches of files with the highest
+// scores (see fileMatchScore) in their original order, and the other file
+// matches. Backends use it instead of cutting off file matches at the result
+// limit, so that the file matches which rankResults ranks first are not cut
+// off before they are ranked. Symbol definitions are only looked up for the
+// results that are ranked, so they are not used here.
+func limitFileMatches(ctx context.Context, files []*FileMatchResolver, limit int) (kept, dropped []*FileMatchResolver) {
+	if len(files) <= limit {
+		return files, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rankLookupTimeout)
+	defer cancel()
+
+	stars := rankingRepoStars(ctx, files)
+	scores := make([]float64, len(files))
+	order := make([]int, len(files))
+	for i, fm := range files {
+		scores[i] = fileMatchScore(fm, rankingSignals{stars: stars[fm.Repo.ID]})
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	keep := make([]bool, len(files))
+	for _, i := range order[:limit] {
+		keep[i] = true
+	}
+	kept = make([]*FileMatchResolver, 0, limit)
+	for i, fm := range files {
+		if keep[i] {
+			kept = append(kept, fm)
+		} else {
+			dropped = append(dropped, fm)
+		}
+	}
+	return kept, dropped
+}
+
+// rankingRepoStars returns the star counts of the repositories of files. If
+// they cannot be looked up, no repository has stars.
+func rankingRepoStars(ctx context.Context, files []*FileMatchResolver) map[api.RepoID]int {
+	seen := make(map[api.RepoID]bool)
+	var ids []api.RepoID
+	for _, fm := range files {
+		if !seen[fm.Repo.ID] {
+			seen[fm.Repo.ID] = true
+			ids = append(ids, fm.Repo.ID)
+		}
+	}
+	stars, err := rankingStarCounts(ctx, ids...)
+	if err != nil {
+		log15.Warn("search ranking: failed to get star counts", "error", err)
+		return nil
+	}
+	return stars
+}
+
+// rankingDefinitionLines sets the definition lines in signals of the files at
+// the given indexes, looking up the symbols of every repository revision
+// concurrently. Failed lookups are ignored.
+func rankingDefinitionLines(ctx context.Context, files []*FileMatchResolver, indexes []int, signals []rankingSignals) {
+	type repoCommit struct {
+		repo   api.RepoName
+		commit api.CommitID
+	}
+	byRepo := make(map[repoCommit][]int)
+	for _, i := range indexes {
+		fm := files[i]
+		if len(fm.JLineMatches) == 0 {
+			continue
+		}
+		key := repoCommit{repo: fm.Repo.Name, commit: fm.CommitID}
+		byRepo[key] = append(byRepo[key], i)
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for key, indexes := range byRepo {
+		key, indexes := key, indexes
+		paths := make([]string, len(indexes))
+		for j, i := range indexes {
+			paths[j] = regexp.QuoteMeta(files[i].JPath)
+		}
+		wg.Add(1)
+		goroutine.Go(func() {
+			defer wg.Done()
+			symbols, err := rankingSymbols(ctx, search.SymbolsParameters{
+				Repo:            key.repo,
+				CommitID:        key.commit,
+				IsCaseSensitive: true,
+				IncludePatterns: []string{"^(" + strings.Join(paths, "|") + ")$"},
+			})
+			if err != nil {
+				log15.Debug("search ranking: failed to get symbols", "repo", key.repo, "error", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, i := range indexes {
+				for _, sym := range symbols {
+					if sym.Path != files[i].JPath {
+						continue
+					}
+					if signals[i].definitionLines == nil {
+						signals[i].definitionLines = make(map[int32]bool)
+					}
+					// Symbol lines are 1-based.
+					signals[i].definitionLines[int32(sym.Line-1)] = true
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
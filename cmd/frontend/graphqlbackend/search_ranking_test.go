@@ -0,0 +1,188 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+func TestFileMatchScore(t *testing.T) {
+	file := func(path string, matches int) *FileMatchResolver {
+		fm := &FileMatchResolver{JPath: path}
+		for i := 0; i < matches; i++ {
+			fm.JLineMatches = append(fm.JLineMatches, &lineMatch{JLineNumber: int32(i), JOffsetAndLengths: [][2]int32{{0, 1}}})
+		}
+		return fm
+	}
+
+	// Each pair is ordered from the better to the worse match.
+	tests := []struct {
+		name          string
+		better, worse *FileMatchResolver
+		betterSignals rankingSignals
+		worseSignals  rankingSignals
+	}{
+		{
+			name:   "density",
+			better: file("a.go", 5),
+			worse:  file("b.go", 1),
+		},
+		{
+			name:          "definition",
+			better:        file("a.go", 1),
+			worse:         file("b.go", 5),
+			betterSignals: rankingSignals{definitionLines: map[int32]bool{0: true}},
+		},
+		{
+			name:   "depth",
+			better: file("a.go", 1),
+			worse:  file("a/b/c.go", 1),
+		},
+		{
+			name:          "stars",
+			better:        file("a.go", 1),
+			worse:         file("a.go", 1),
+			betterSignals: rankingSignals{stars: 1000},
+			worseSignals:  rankingSignals{stars: 10},
+		},
+		{
+			name:   "vendored",
+			better: file("a/b/mux.go", 1),
+			worse:  file("vendor/mux.go", 5),
+		},
+		{
+			name:   "test",
+			better: file("a/b/mux.go", 1),
+			worse:  file("mux_test.go", 5),
+		},
+		{
+			name:   "generated",
+			better: file("a/b/api.go", 1),
+			worse:  file("api.pb.go", 5),
+		},
+		{
+			name:   "fixture",
+			better: file("src/parser.js", 1),
+			worse:  file("src/__fixtures__/parser.js", 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better := fileMatchScore(tt.better, tt.betterSignals)
+			worse := fileMatchScore(tt.worse, tt.worseSignals)
+			if better <= worse {
+				t.Errorf("got score %v for %s, want more than %v for %s", better, tt.better.JPath, worse, tt.worse.JPath)
+			}
+		})
+	}
+}
+
+func TestRankResults(t *testing.T) {
+	origStarCounts, origSymbols := rankingStarCounts, rankingSymbols
+	defer func() { rankingStarCounts, rankingSymbols = origStarCounts, origSymbols }()
+
+	popular := &types.Repo{ID: 1, Name: "popular"}
+	other := &types.Repo{ID: 2, Name: "other"}
+	rankingStarCounts = func(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int, error) {
+		return map[api.RepoID]int{popular.ID: 5000}, nil
+	}
+	var (
+		mu          sync.Mutex
+		symbolsArgs []search.SymbolsParameters
+	)
+	rankingSymbols = func(ctx context.Context, args search.SymbolsParameters) ([]protocol.Symbol, error) {
+		mu.Lock()
+		symbolsArgs = append(symbolsArgs, args)
+		mu.Unlock()
+		if args.Repo != other.Name {
+			return nil, nil
+		}
+		return []protocol.Symbol{{Name: "Parse", Path: "parse.go", Line: 3}}, nil
+	}
+
+	file := func(repo *types.Repo, path string, line int32) *FileMatchResolver {
+		return &FileMatchResolver{
+			JPath:        path,
+			Repo:         repo,
+			CommitID:     "c",
+			JLineMatches: []*lineMatch{{JLineNumber: line, JOffsetAndLengths: [][2]int32{{0, 5}}}},
+		}
+	}
+	var (
+		vendored   = file(popular, "vendor/other/parse.go", 2)
+		call       = file(popular, "parse.go", 10)
+		definition = file(other, "parse.go", 2)
+		repo       = &RepositoryResolver{repo: other}
+	)
+	results := []SearchResultResolver{vendored, repo, call, definition}
+	rankResults(context.Background(), results)
+
+	want := []SearchResultResolver{definition, repo, call, vendored}
+	if !reflect.DeepEqual(results, want) {
+		var got []string
+		for _, r := range results {
+			repo, path := r.searchResultURIs()
+			got = append(got, repo+"/"+path)
+		}
+		t.Errorf("got order %v", got)
+	}
+
+	wantSymbolsArgs := map[api.RepoName]string{
+		popular.Name: `^(parse\.go|vendor/other/parse\.go)$`,
+		other.Name:   `^(parse\.go)$`,
+	}
+	if len(symbolsArgs) != len(wantSymbolsArgs) {
+		t.Fatalf("got %d symbols requests, want %d", len(symbolsArgs), len(wantSymbolsArgs))
+	}
+	for _, args := range symbolsArgs {
+		if want := wantSymbolsArgs[args.Repo]; len(args.IncludePatterns) != 1 || args.IncludePatterns[0] != want {
+			t.Errorf("got include patterns %q for %s, want %q", args.IncludePatterns, args.Repo, want)
+		}
+	}
+}
+
+func TestLimitFileMatches(t *testing.T) {
+	origStarCounts := rankingStarCounts
+	defer func() { rankingStarCounts = origStarCounts }()
+
+	popular := &types.Repo{ID: 1, Name: "popular"}
+	other := &types.Repo{ID: 2, Name: "other"}
+	rankingStarCounts = func(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int, error) {
+		return map[api.RepoID]int{popular.ID: 5000}, nil
+	}
+
+	file := func(repo *types.Repo, path string) *FileMatchResolver {
+		return &FileMatchResolver{
+			JPath:        path,
+			Repo:         repo,
+			JLineMatches: []*lineMatch{{JOffsetAndLengths: [][2]int32{{0, 5}}}},
+		}
+	}
+	var (
+		a = file(other, "a.go")
+		b = file(other, "b.go")
+		c = file(popular, "c.go")
+		d = file(popular, "d.go")
+	)
+
+	// The file matches of the popular repository come last, but they are
+	// kept in their original order.
+	kept, dropped := limitFileMatches(context.Background(), []*FileMatchResolver{a, b, c, d}, 2)
+	if want := []*FileMatchResolver{c, d}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("got kept %v, want %v", kept, want)
+	}
+	if want := []*FileMatchResolver{a, b}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("got dropped %v, want %v", dropped, want)
+	}
+
+	kept, dropped = limitFileMatches(context.Background(), []*FileMatchResolver{a, b}, 2)
+	if want := []*FileMatchResolver{a, b}; !reflect.DeepEqual(kept, want) || dropped != nil {
+		t.Errorf("under the limit: got kept %v and dropped %v, want all kept", kept, dropped)
+	}
+}
@@ -59,6 +59,10 @@ type searchResultsCommon struct {
 	// purged.
 	timedout []*types.Repo
 
+	// truncatedHistory contains repos whose history was searched only
+	// partially, because they are shallow clones.
+	truncatedHistory []*types.Repo
+
 	indexUnavailable bool // True if indexed search is enabled but was not available during this search.
 }
 
@@ -94,6 +98,10 @@ func (c *searchResultsCommon) Timedout() []*RepositoryResolver {
 	return RepositoryResolvers(c.timedout)
 }
 
+func (c *searchResultsCommon) TruncatedHistory() []*RepositoryResolver {
+	return RepositoryResolvers(c.truncatedHistory)
+}
+
 func (c *searchResultsCommon) IndexUnavailable() bool {
 	return c.indexUnavailable
 }
@@ -119,6 +127,7 @@ func (c *searchResultsCommon) update(other searchResultsCommon) {
 	c.cloning = append(c.cloning, other.cloning...)
 	c.missing = append(c.missing, other.missing...)
 	c.timedout = append(c.timedout, other.timedout...)
+	c.truncatedHistory = append(c.truncatedHistory, other.truncatedHistory...)
 	c.resultCount += other.resultCount
 
 	if c.partial == nil {
@@ -479,6 +488,10 @@ func (r *searchResolver) Results(ctx context.Context) (*SearchResultsResolver, e
 	}
 
 	rr, err := r.resultsWithTimeoutSuggestion(ctx)
+	if err == nil && r.andOrQuery == nil {
+		// Queries with operators are ranked by evaluateAndOr.
+		rankResults(ctx, rr.SearchResults)
+	}
 
 	// Record what type of response we sent back via Prometheus.
 	var status, alertType string
@@ -692,7 +705,7 @@ func (r *searchResolver) getPatternInfo(opts *getPatternInfoOptions) (*search.Te
 	}
 
 	if opts.fileMatchLimit == 0 {
-		opts.fileMatchLimit = r.maxResults()
+		opts.fileMatchLimit = r.backendMaxResults()
 	}
 
 	return getPatternInfo(r.query, opts)
@@ -789,6 +802,7 @@ func getPatternInfo(q *query.Query, opts *getPatternInfoOptions) (*search.TextPa
 		IsRegExp:                     isRegExp,
 		IsStructuralPat:              isStructuralPat,
 		IsCaseSensitive:              q.IsCaseSensitive(),
+		IsMultiline:                  isRegExp && q.IsMultiline(),
 		FileMatchLimit:               opts.fileMatchLimit,
 		Pattern:                      pattern,
 		IncludePatterns:              includePatterns,
@@ -848,13 +862,24 @@ func (r *searchResolver) searchTimeoutFieldSet() bool {
 }
 
 func (r *searchResolver) withTimeout(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	d, err := r.searchTimeout()
+	if err != nil {
+		return nil, nil, err
+	}
+	searchExplainerFromContext(ctx).decision("timeout %s (%s)", d, r.searchTimeoutReason())
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, cancel, nil
+}
+
+// searchTimeout returns the timeout of the search.
+func (r *searchResolver) searchTimeout() (time.Duration, error) {
 	d := defaultTimeout
 	timeout, _ := r.query.StringValue(query.FieldTimeout)
 	if timeout != "" {
 		var err error
 		d, err = time.ParseDuration(timeout)
 		if err != nil {
-			return nil, nil, errors.WithMessage(err, `invalid "timeout:" value (examples: "timeout:2s", "timeout:200ms")`)
+			return 0, errors.WithMessage(err, `invalid "timeout:" value (examples: "timeout:2s", "timeout:200ms")`)
 		}
 	} else if r.countIsSet() {
 		// If `count:` is set but `timeout:` is not explicitely set, use the max timeout
@@ -864,8 +889,22 @@ func (r *searchResolver) withTimeout(ctx context.Context) (context.Context, cont
 	if d.Minutes() > 1 {
 		d = maxTimeout
 	}
-	ctx, cancel := context.WithTimeout(ctx, d)
-	return ctx, cancel, nil
+	return d, nil
+}
+
+// searchTimeoutReason describes why the search has the timeout returned by
+// searchTimeout, for search explanations.
+func (r *searchResolver) searchTimeoutReason() string {
+	if timeout, _ := r.query.StringValue(query.FieldTimeout); timeout != "" {
+		if d, _ := time.ParseDuration(timeout); d > maxTimeout {
+			return "timeout: is capped"
+		}
+		return "timeout: is set"
+	}
+	if r.countIsSet() {
+		return "count: is set"
+	}
+	return "default"
 }
 
 func (r *searchResolver) determineResultTypes(args search.TextParameters, forceOnlyResultType string) (resultTypes []string, seenResultTypes map[string]struct{}) {
@@ -877,7 +916,14 @@ func (r *searchResolver) determineResultTypes(args search.TextParameters, forceO
 	} else {
 		resultTypes, _ = r.query.StringValues(query.FieldType)
 		if len(resultTypes) == 0 {
-			resultTypes = []string{"file", "path", "repo", "ref"}
+			switch selector := r.query.Selector(); {
+			case selector != nil && selector.Kind == query.SelectSymbol:
+				resultTypes = []string{"symbol"}
+			case selector != nil && selector.Kind == query.SelectCommit:
+				resultTypes = []string{"commit"}
+			default:
+				resultTypes = []string{"file", "path", "repo", "ref"}
+			}
 		}
 	}
 	seenResultTypes = make(map[string]struct{}, len(resultTypes))
@@ -955,6 +1001,10 @@ func alertOnSearchLimit(resultTypes []string, args *search.TextParameters) ([]st
 //
 // Partial results AND an error may be returned.
 func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType string) (res *SearchResultsResolver, err error) {
+	if r.andOrQuery != nil {
+		return r.evaluateAndOr(ctx, forceOnlyResultType)
+	}
+
 	tr, ctx := trace.New(ctx, "graphql.SearchResults", r.rawQuery())
 	defer func() {
 		tr.SetError(err)
@@ -1017,6 +1067,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 
 	resultTypes, seenResultTypes := r.determineResultTypes(args, forceOnlyResultType)
 	tr.LazyPrintf("resultTypes: %v", resultTypes)
+	explainer := searchExplainerFromContext(ctx)
 
 	var (
 		requiredWg sync.WaitGroup
@@ -1035,6 +1086,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		alert *searchAlert
 	)
 
+	hasOptional := false
 	waitGroup := func(required bool) *sync.WaitGroup {
 		if args.UseFullDeadline {
 			// When a custom timeout is specified, all searches are required and get the full timeout.
@@ -1043,6 +1095,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		if required {
 			return &requiredWg
 		}
+		hasOptional = true
 		return &optionalWg
 	}
 
@@ -1050,6 +1103,11 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	// This currently limits diff and commit search to a set number of
 	// repos, and removes the diff and commit resultTypes if it is breached.
 	resultTypes, alert = alertOnSearchLimit(resultTypes, &args)
+	if alert != nil {
+		explainer.decision("%s: %s", alert.title, alert.description)
+	}
+	explainer.searchedResultTypes(resultTypes)
+	explainer.decision("result limit %d", r.maxResults())
 
 	searchedFileContentsOrPaths := false
 	for _, resultType := range resultTypes {
@@ -1066,7 +1124,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				repoResults, repoCommon, err := searchRepositories(ctx, &args, r.maxResults())
+				backendStart := time.Now()
+				repoResults, repoCommon, err := searchRepositories(ctx, &args, r.backendMaxResults())
+				explainer.backend("repo", true, len(args.Repos), time.Since(backendStart), len(repoResults), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
@@ -1078,11 +1138,12 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					results = append(results, repoResults...)
 					resultsMu.Unlock()
 				}
+				commonMu.Lock()
 				if repoCommon != nil {
-					commonMu.Lock()
 					common.update(*repoCommon)
-					commonMu.Unlock()
 				}
+				r.streamResults(repoResults, &common)
+				commonMu.Unlock()
 			})
 		case "symbol":
 			wg := waitGroup(len(resultTypes) == 1)
@@ -1090,31 +1151,41 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				symbolFileMatches, symbolsCommon, err := searchSymbols(ctx, &args, int(r.maxResults()))
+				backendStart := time.Now()
+				symbolFileMatches, symbolsCommon, err := searchSymbols(ctx, &args, int(r.backendMaxResults()))
+				explainer.backend("symbol", wg == &requiredWg, len(args.Repos), time.Since(backendStart), len(symbolFileMatches), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "symbol search failed"))
 					multiErrMu.Unlock()
 				}
+				var found []SearchResultResolver
 				for _, symbolFileMatch := range symbolFileMatches {
 					key := symbolFileMatch.uri
 					fileMatchesMu.Lock()
 					if m, ok := fileMatches[key]; ok {
 						m.symbols = symbolFileMatch.symbols
+						found = append(found, m)
 					} else {
 						fileMatches[key] = symbolFileMatch
+						found = append(found, symbolFileMatch)
 						resultsMu.Lock()
 						results = append(results, symbolFileMatch)
 						resultsMu.Unlock()
 					}
 					fileMatchesMu.Unlock()
 				}
+				commonMu.Lock()
 				if symbolsCommon != nil {
-					commonMu.Lock()
 					common.update(*symbolsCommon)
-					commonMu.Unlock()
 				}
+				// Hold fileMatchesMu, since the matches may be merged with
+				// those of text search concurrently.
+				fileMatchesMu.Lock()
+				r.streamResults(found, &common)
+				fileMatchesMu.Unlock()
+				commonMu.Unlock()
 			})
 		case "file", "path":
 			if searchedFileContentsOrPaths {
@@ -1127,34 +1198,70 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				fileResults, fileCommon, err := searchFilesInRepos(ctx, &args)
+				// mergeFileResults merges file matches with those of symbol
+				// search, and returns the merged matches.
+				mergeFileResults := func(fileResults []*FileMatchResolver) []SearchResultResolver {
+					var found []SearchResultResolver
+					for _, fm := range fileResults {
+						key := fm.uri
+						fileMatchesMu.Lock()
+						m, ok := fileMatches[key]
+						if ok {
+							// merge line match results with an existing symbol result
+							m.JLimitHit = m.JLimitHit || fm.JLimitHit
+							m.JLineMatches = fm.JLineMatches
+							found = append(found, m)
+						} else {
+							fileMatches[key] = fm
+							found = append(found, fm)
+							resultsMu.Lock()
+							results = append(results, fm)
+							resultsMu.Unlock()
+						}
+						fileMatchesMu.Unlock()
+					}
+					return found
+				}
+
+				// Streaming searches send the matches of every indexed
+				// search and every call to searcher as soon as they are
+				// found, instead of waiting for the slowest one.
+				var onMatches func([]*FileMatchResolver)
+				if r.stream != nil {
+					onMatches = func(matches []*FileMatchResolver) {
+						found := mergeFileResults(matches)
+						commonMu.Lock()
+						fileMatchesMu.Lock()
+						r.streamResults(found, &common)
+						fileMatchesMu.Unlock()
+						commonMu.Unlock()
+					}
+				}
+
+				backendStart := time.Now()
+				fileResults, fileCommon, err := searchFilesInReposStream(ctx, &args, onMatches)
+				explainer.backend("text", true, len(args.Repos), time.Since(backendStart), len(fileResults), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "text search failed"))
 					multiErrMu.Unlock()
 				}
-				for _, r := range fileResults {
-					key := r.uri
-					fileMatchesMu.Lock()
-					m, ok := fileMatches[key]
-					if ok {
-						// merge line match results with an existing symbol result
-						m.JLimitHit = m.JLimitHit || r.JLimitHit
-						m.JLineMatches = r.JLineMatches
-					} else {
-						fileMatches[key] = r
-						resultsMu.Lock()
-						results = append(results, r)
-						resultsMu.Unlock()
-					}
-					fileMatchesMu.Unlock()
+				var found []SearchResultResolver
+				if onMatches == nil {
+					found = mergeFileResults(fileResults)
 				}
+				commonMu.Lock()
 				if fileCommon != nil {
-					commonMu.Lock()
 					common.update(*fileCommon)
-					commonMu.Unlock()
 				}
+				// Hold fileMatchesMu, since the matches may be merged with
+				// those of symbol search concurrently. Streaming searches
+				// already sent the matches, so only the progress is sent.
+				fileMatchesMu.Lock()
+				r.streamResults(found, &common)
+				fileMatchesMu.Unlock()
+				commonMu.Unlock()
 			})
 		case "diff":
 			wg := waitGroup(len(resultTypes) == 1)
@@ -1177,7 +1284,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					Repos:       args.Repos,
 					Query:       args.Query,
 				}
+				backendStart := time.Now()
 				diffResults, diffCommon, err := searchCommitDiffsInRepos(ctx, &args)
+				explainer.backend("diff", wg == &requiredWg, len(args.Repos), time.Since(backendStart), len(diffResults), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
@@ -1189,11 +1298,12 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					results = append(results, diffResults...)
 					resultsMu.Unlock()
 				}
+				commonMu.Lock()
 				if diffCommon != nil {
-					commonMu.Lock()
 					common.update(*diffCommon)
-					commonMu.Unlock()
 				}
+				r.streamResults(diffResults, &common)
+				commonMu.Unlock()
 			})
 		case "commit":
 			wg := waitGroup(len(resultTypes) == 1)
@@ -1217,7 +1327,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					Repos:       args.Repos,
 					Query:       args.Query,
 				}
+				backendStart := time.Now()
 				commitResults, commitCommon, err := searchCommitLogInRepos(ctx, &args)
+				explainer.backend("commit", wg == &requiredWg, len(args.Repos), time.Since(backendStart), len(commitResults), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
@@ -1229,11 +1341,12 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					results = append(results, commitResults...)
 					resultsMu.Unlock()
 				}
+				commonMu.Lock()
 				if commitCommon != nil {
-					commonMu.Lock()
 					common.update(*commitCommon)
-					commonMu.Unlock()
 				}
+				r.streamResults(commitResults, &common)
+				commonMu.Unlock()
 			})
 		case "codemod":
 			wg := waitGroup(true)
@@ -1241,7 +1354,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
+				backendStart := time.Now()
 				codemodResults, codemodCommon, err := performCodemod(ctx, &args)
+				explainer.backend("codemod", true, len(args.Repos), time.Since(backendStart), len(codemodResults), err)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
@@ -1274,7 +1389,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	// Wait for remaining optional searches to finish or get cancelled.
 	optionalWg.Wait()
 
-	timer.Stop()
+	if !timer.Stop() && hasOptional {
+		explainer.decision("optional searches were canceled %s after the search started, when the required searches had returned", budget)
+	}
 
 	tr.LazyPrintf("results=%d limitHit=%v cloning=%d missing=%d timedout=%d", len(results), common.limitHit, len(common.cloning), len(common.missing), len(common.timedout))
 
@@ -1298,8 +1415,19 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		multiErr = nil
 	}
 
+	if selector := r.query.Selector(); selector != nil {
+		results = newResultSelector(selector).project(results)
+	}
+
 	sortResults(results)
 
+	// The backends search for more results than requested when the
+	// results are projected, so limit the projected results here.
+	if max := int(r.maxResults()); r.query.Selector() != nil && len(results) > max {
+		results = results[:max]
+		common.limitHit = true
+	}
+
 	resultsResolver := SearchResultsResolver{
 		start:               start,
 		searchResultsCommon: common,
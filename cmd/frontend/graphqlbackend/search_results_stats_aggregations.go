@@ -0,0 +1,159 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// aggregationResultLimit is the maximum number of results that an aggregation
+// counts. It replaces the result limit of the query, since aggregations are
+// computed over the full result set rather than the results that are
+// displayed.
+const aggregationResultLimit = 100000
+
+type searchAggregationArgs struct {
+	By      string
+	Depth   *int32
+	Pattern *string
+}
+
+func (srs *searchResultsStats) Aggregate(ctx context.Context, args *searchAggregationArgs) (*searchAggregationResolver, error) {
+	var (
+		depth   int
+		pattern *regexp.Regexp
+	)
+	switch args.By {
+	case "REPOSITORY", "FILE_EXTENSION", "AUTHOR":
+	case "DIRECTORY":
+		if args.Depth != nil {
+			if *args.Depth <= 0 {
+				return nil, errors.New("depth must be positive")
+			}
+			depth = int(*args.Depth)
+		}
+	case "CAPTURE_GROUP":
+		if args.Pattern == nil {
+			return nil, errors.New("CAPTURE_GROUP aggregations require a pattern")
+		}
+		var err error
+		pattern, err = regexp.Compile(*args.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if pattern.NumSubexp() == 0 {
+			return nil, fmt.Errorf("pattern %q has no capture group", *args.Pattern)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported aggregation %q", args.By)
+	}
+
+	// Search again without the display limit of the query, waiting for all
+	// results as if count: was set.
+	sr := srs.sr
+	r := &searchResolver{
+		query:         sr.query,
+		parseTree:     sr.parseTree,
+		andOrQuery:    sr.andOrQuery,
+		originalQuery: sr.originalQuery,
+		patternType:   sr.patternType,
+		limitOverride: aggregationResultLimit,
+		exhaustive:    true,
+		zoekt:         sr.zoekt,
+		searcherURLs:  sr.searcherURLs,
+	}
+	res, err := r.doResults(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := aggregate(res.SearchResults, args.By, depth, pattern)
+	return &searchAggregationResolver{groups: groups, limitHit: res.LimitHit()}, nil
+}
+
+// aggregate returns the groups of results for the given kind of aggregation
+// (the SearchAggregationGroupBy GraphQL enum), ordered by descending count.
+func aggregate(results []SearchResultResolver, by string, depth int, pattern *regexp.Regexp) []*searchAggregationGroupResolver {
+	counts := make(map[string]int32)
+	for _, result := range results {
+		switch by {
+		case "REPOSITORY":
+			switch r := result.(type) {
+			case *RepositoryResolver:
+				counts[r.Name()]++
+			case *FileMatchResolver:
+				counts[string(r.Repo.Name)] += r.resultCount()
+			case *commitSearchResultResolver:
+				counts[r.commit.repo.Name()]++
+			}
+		case "DIRECTORY":
+			if fm, ok := result.(*FileMatchResolver); ok {
+				counts[directoryPrefix(fm.JPath, depth)] += fm.resultCount()
+			}
+		case "FILE_EXTENSION":
+			if fm, ok := result.(*FileMatchResolver); ok {
+				counts[path.Ext(fm.JPath)] += fm.resultCount()
+			}
+		case "AUTHOR":
+			if c, ok := result.(*commitSearchResultResolver); ok && c.commit.author.person != nil {
+				counts[c.commit.author.person.email]++
+			}
+		case "CAPTURE_GROUP":
+			if fm, ok := result.(*FileMatchResolver); ok {
+				for _, lm := range fm.JLineMatches {
+					for _, m := range pattern.FindAllStringSubmatch(lm.JPreview, -1) {
+						counts[m[1]]++
+					}
+				}
+			}
+		}
+	}
+
+	groups := make([]*searchAggregationGroupResolver, 0, len(counts))
+	for value, count := range counts {
+		groups = append(groups, &searchAggregationGroupResolver{value: value, count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].value < groups[j].value
+	})
+	return groups
+}
+
+// directoryPrefix returns the directory of the file at filePath, truncated to
+// its first depth components if depth is positive. Files at the root of a
+// repository are in the directory "/".
+func directoryPrefix(filePath string, depth int) string {
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return "/"
+	}
+	if depth > 0 {
+		if parts := strings.Split(dir, "/"); len(parts) > depth {
+			dir = strings.Join(parts[:depth], "/")
+		}
+	}
+	return dir + "/"
+}
+
+type searchAggregationResolver struct {
+	groups   []*searchAggregationGroupResolver
+	limitHit bool
+}
+
+func (r *searchAggregationResolver) Groups() []*searchAggregationGroupResolver { return r.groups }
+func (r *searchAggregationResolver) LimitHit() bool                            { return r.limitHit }
+
+type searchAggregationGroupResolver struct {
+	value string
+	count int32
+}
+
+func (r *searchAggregationGroupResolver) Value() string { return r.value }
+func (r *searchAggregationGroupResolver) Count() int32  { return r.count }
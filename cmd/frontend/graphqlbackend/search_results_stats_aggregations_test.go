@@ -0,0 +1,68 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestAggregate(t *testing.T) {
+	repoA := &types.Repo{ID: 1, Name: "a"}
+	repoB := &types.Repo{ID: 2, Name: "b"}
+	fileMatch := func(repo *types.Repo, path string, lines ...string) *FileMatchResolver {
+		fm := &FileMatchResolver{JPath: path, Repo: repo}
+		for i, line := range lines {
+			fm.JLineMatches = append(fm.JLineMatches, &lineMatch{JPreview: line, JLineNumber: int32(i)})
+		}
+		return fm
+	}
+	commit := func(repo *types.Repo, email string) *commitSearchResultResolver {
+		return &commitSearchResultResolver{commit: &GitCommitResolver{
+			repo:   &RepositoryResolver{repo: repo},
+			author: signatureResolver{person: &personResolver{email: email}},
+		}}
+	}
+	results := []SearchResultResolver{
+		fileMatch(repoA, "go.mod", `version = "1.2"`, `version = "1.3"`),
+		fileMatch(repoA, "cmd/x/main.go", `version = "1.2"`),
+		fileMatch(repoB, "cmd/y/main.go", `x`),
+		fileMatch(repoB, "README"),
+		&RepositoryResolver{repo: repoB},
+		commit(repoB, "alice@example.com"),
+	}
+
+	tests := []struct {
+		by      string
+		depth   int
+		pattern string
+		want    map[string]int32
+	}{
+		{by: "REPOSITORY", want: map[string]int32{"a": 3, "b": 4}},
+		{by: "DIRECTORY", want: map[string]int32{"/": 3, "cmd/x/": 1, "cmd/y/": 1}},
+		{by: "DIRECTORY", depth: 1, want: map[string]int32{"/": 3, "cmd/": 2}},
+		{by: "FILE_EXTENSION", want: map[string]int32{".mod": 2, ".go": 2, "": 1}},
+		{by: "AUTHOR", want: map[string]int32{"alice@example.com": 1}},
+		{by: "CAPTURE_GROUP", pattern: `version = "(\d+\.\d+)"`, want: map[string]int32{"1.2": 2, "1.3": 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.by, func(t *testing.T) {
+			var pattern *regexp.Regexp
+			if test.pattern != "" {
+				pattern = regexp.MustCompile(test.pattern)
+			}
+			groups := aggregate(results, test.by, test.depth, pattern)
+			got := make(map[string]int32, len(groups))
+			for i, g := range groups {
+				got[g.Value()] = g.Count()
+				if i > 0 && groups[i-1].Count() < g.Count() {
+					t.Errorf("groups are not ordered by descending count")
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
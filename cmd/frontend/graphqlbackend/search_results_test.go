@@ -237,6 +237,51 @@ func TestSearchResults(t *testing.T) {
 			t.Error("calledSearchSymbols")
 		}
 	})
+
+	t.Run("select repo with count smaller than matches", func(t *testing.T) {
+		db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+			return []*types.Repo{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}, nil
+		}
+		defer func() { db.Mocks = db.MockStores{} }()
+
+		var fileMatches []*FileMatchResolver
+		for _, name := range []string{"a", "a", "a", "b", "c"} {
+			fileMatches = append(fileMatches, &FileMatchResolver{
+				uri:          "git://" + name + "#file",
+				JPath:        "file",
+				JLineMatches: []*lineMatch{{JLineNumber: 1}},
+				Repo:         &types.Repo{Name: api.RepoName(name)},
+			})
+		}
+		mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+			// Like the backends, return at most FileMatchLimit matches.
+			matches := fileMatches
+			if limit := int(args.PatternInfo.FileMatchLimit); len(matches) > limit {
+				matches = matches[:limit]
+			}
+			return matches, &searchResultsCommon{}, nil
+		}
+		defer func() { mockSearchFilesInRepos = nil }()
+
+		r, err := (&schemaResolver{}).Search(&SearchArgs{Query: `type:file select:repo count:2 foo`, Version: "V2"})
+		if err != nil {
+			t.Fatal("Search:", err)
+		}
+		results, err := r.Results(context.Background())
+		if err != nil {
+			t.Fatal("Results:", err)
+		}
+		var got []string
+		for _, result := range results.SearchResults {
+			got = append(got, string(result.(*RepositoryResolver).repo.Name))
+		}
+		if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if !results.LimitHit() {
+			t.Error("expected limit to be hit")
+		}
+	})
 }
 
 func BenchmarkSearchResults(b *testing.B) {
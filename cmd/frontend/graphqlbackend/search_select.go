@@ -0,0 +1,105 @@
+package graphqlbackend
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// resultSelector projects search results as described by the select: field
+// of a query (see query.Selector) and removes duplicates. It remembers the
+// results it returned, so that results are also deduplicated across the
+// batches of a streaming search.
+type resultSelector struct {
+	selector *query.Selector
+	seen     map[string]struct{}
+}
+
+func newResultSelector(selector *query.Selector) *resultSelector {
+	return &resultSelector{selector: selector, seen: make(map[string]struct{})}
+}
+
+// project returns the projections of results that were not returned by an
+// earlier call. Results that have no projection, such as commits for
+// select:repo, are dropped.
+func (s *resultSelector) project(results []SearchResultResolver) []SearchResultResolver {
+	var projected []SearchResultResolver
+	for _, result := range results {
+		p, key := s.projectResult(result)
+		if p == nil {
+			continue
+		}
+		if _, ok := s.seen[key]; ok {
+			continue
+		}
+		s.seen[key] = struct{}{}
+		projected = append(projected, p)
+	}
+	return projected
+}
+
+// projectResult returns the projection of result and the key that identifies
+// duplicates of it, or nil if result has no projection.
+func (s *resultSelector) projectResult(result SearchResultResolver) (SearchResultResolver, string) {
+	switch s.selector.Kind {
+	case query.SelectRepo:
+		var repo *RepositoryResolver
+		switch r := result.(type) {
+		case *RepositoryResolver:
+			repo = r
+		case *FileMatchResolver:
+			repo = &RepositoryResolver{repo: r.Repo}
+		case *commitSearchResultResolver:
+			repo = r.commit.repo
+		default:
+			return nil, ""
+		}
+		return repo, resultKey(repo)
+
+	case query.SelectFile:
+		fm, ok := result.(*FileMatchResolver)
+		if !ok {
+			return nil, ""
+		}
+		file := *fm
+		file.JLineMatches = nil
+		file.JLimitHit = false
+		file.symbols = nil
+		return &file, resultKey(&file)
+
+	case query.SelectSymbol:
+		fm, ok := result.(*FileMatchResolver)
+		if !ok {
+			return nil, ""
+		}
+		var symbols []*searchSymbolResult
+		for _, sym := range fm.symbols {
+			kind := strings.ToLower(ctagsKindToLSPSymbolKind(sym.symbol.Kind).String())
+			if s.selector.Field == "" || kind == s.selector.Field {
+				symbols = append(symbols, sym)
+			}
+		}
+		if len(symbols) == 0 {
+			return nil, ""
+		}
+		file := *fm
+		file.JLineMatches = nil
+		file.symbols = symbols
+		return &file, resultKey(&file)
+
+	case query.SelectCommit:
+		commit, ok := result.(*commitSearchResultResolver)
+		if !ok {
+			return nil, ""
+		}
+		if s.selector.Field == "author" {
+			// Commits are sorted by date, so we return the newest commit of
+			// every author.
+			if person := commit.commit.author.person; person != nil {
+				return commit, "author:" + strings.ToLower(person.email)
+			}
+		}
+		return commit, resultKey(commit)
+	}
+	return nil, ""
+}
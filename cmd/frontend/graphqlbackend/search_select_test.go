@@ -0,0 +1,96 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+func TestResultSelector(t *testing.T) {
+	repoA := &types.Repo{ID: 1, Name: "a"}
+	repoB := &types.Repo{ID: 2, Name: "b"}
+	fileMatch := func(repo *types.Repo, path string, symbolKinds ...string) *FileMatchResolver {
+		fm := &FileMatchResolver{
+			JPath:        path,
+			JLineMatches: []*lineMatch{{JLineNumber: 1}},
+			Repo:         repo,
+			uri:          "git://" + string(repo.Name) + "#" + path,
+		}
+		for _, kind := range symbolKinds {
+			fm.symbols = append(fm.symbols, &searchSymbolResult{symbol: protocol.Symbol{Name: kind, Kind: kind}})
+		}
+		return fm
+	}
+	commit := func(repo *types.Repo, oid, email string) *commitSearchResultResolver {
+		return &commitSearchResultResolver{
+			commit: &GitCommitResolver{
+				repo:   &RepositoryResolver{repo: repo},
+				oid:    GitObjectID(oid),
+				author: signatureResolver{person: &personResolver{email: email}},
+			},
+			url: "/" + string(repo.Name) + "/-/commit/" + oid,
+		}
+	}
+	results := []SearchResultResolver{
+		fileMatch(repoA, "x.go", "func", "struct"),
+		fileMatch(repoA, "y.go"),
+		&RepositoryResolver{repo: repoB},
+		commit(repoB, "c1", "alice@example.com"),
+		commit(repoB, "c2", "Alice@example.com"),
+		commit(repoA, "c3", "bob@example.com"),
+	}
+
+	summary := func(results []SearchResultResolver) []string {
+		var s []string
+		for _, r := range results {
+			switch r := r.(type) {
+			case *RepositoryResolver:
+				s = append(s, "repo:"+r.Name())
+			case *FileMatchResolver:
+				entry := "file:" + r.JPath
+				if len(r.JLineMatches) > 0 {
+					entry += " lines"
+				}
+				for _, sym := range r.symbols {
+					entry += " " + sym.symbol.Name
+				}
+				s = append(s, entry)
+			case *commitSearchResultResolver:
+				s = append(s, "commit:"+string(r.commit.oid))
+			}
+		}
+		return s
+	}
+
+	tests := []struct {
+		selector query.Selector
+		want     []string
+	}{
+		{query.Selector{Kind: query.SelectRepo}, []string{"repo:a", "repo:b"}},
+		{query.Selector{Kind: query.SelectFile}, []string{"file:x.go", "file:y.go"}},
+		{query.Selector{Kind: query.SelectSymbol}, []string{"file:x.go func struct"}},
+		{query.Selector{Kind: query.SelectSymbol, Field: "function"}, []string{"file:x.go func"}},
+		{query.Selector{Kind: query.SelectSymbol, Field: "enum"}, nil},
+		{query.Selector{Kind: query.SelectCommit}, []string{"commit:c1", "commit:c2", "commit:c3"}},
+		{query.Selector{Kind: query.SelectCommit, Field: "author"}, []string{"commit:c1", "commit:c3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.selector.String(), func(t *testing.T) {
+			got := summary(newResultSelector(&test.selector).project(results))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("deduplicates across calls", func(t *testing.T) {
+		s := newResultSelector(&query.Selector{Kind: query.SelectRepo})
+		s.project(results[:1])
+		if got := summary(s.project(results)); !reflect.DeepEqual(got, []string{"repo:b"}) {
+			t.Errorf("got %v, want [repo:b]", got)
+		}
+	})
+}
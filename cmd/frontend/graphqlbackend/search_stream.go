@@ -0,0 +1,325 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// This file contains streaming search, which sends the results of every search
+// backend to the client as soon as the backend returns instead of waiting for
+// the slowest one. It is served over HTTP by the search stream endpoint of
+// cmd/frontend/internal/httpapi.
+
+// SearchStreamEvent is an event sent during a streaming search. Name is one of
+// "filematches", "repomatches", "commitmatches", "progress" or "alert", and
+// Data is the JSON-encodable payload of the event.
+type SearchStreamEvent struct {
+	Name string
+	Data interface{}
+}
+
+// StreamSearch runs the search described by args like the search field of the
+// GraphQL API, but calls send with the results of every search backend as soon
+// as that backend returns. Text search results are sent for every indexed
+// search and every repository searched by searcher, so that they are not held
+// back by the slowest repository. The query's result limit and timeout apply
+// as usual:
+// once the limit is reached, the remaining backends are canceled. Calls to send
+// are serialized, and the last event sent is a "progress" event with done set.
+//
+// A file that matches several result types (e.g. both symbol and text search)
+// may be sent more than once. Later events for the same file supersede earlier
+// ones.
+//
+// Pagination is not supported, so args.First and args.After are ignored.
+func StreamSearch(ctx context.Context, args *SearchArgs, send func(SearchStreamEvent)) error {
+	impl, err := NewSearchImplementer(&SearchArgs{
+		Version:     args.Version,
+		PatternType: args.PatternType,
+		Query:       args.Query,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &searchStream{send: send, cancel: cancel, start: time.Now()}
+	r, ok := impl.(*searchResolver)
+	if !ok {
+		// The query is invalid, so we got an alert instead of a resolver.
+		rr, err := impl.Results(ctx)
+		if err != nil {
+			return err
+		}
+		s.alert(rr.alert)
+		s.done(&rr.searchResultsCommon)
+		return nil
+	}
+
+	s.limit = r.maxResults()
+	if selector := r.query.Selector(); selector != nil {
+		s.selector = newResultSelector(selector)
+	}
+	r.stream = s
+	rr, err := r.doResults(ctx, "")
+	if err != nil && !(err == context.Canceled && s.limitHit) {
+		if err != context.DeadlineExceeded || s.count > 0 {
+			return err
+		}
+		// Same as resultsWithTimeoutSuggestion, but only if we have not
+		// already streamed partial results.
+		usedTime := time.Since(s.start)
+		s.alert(alertForTimeout(usedTime, longer(2, usedTime), r))
+		s.done(&searchResultsCommon{})
+		return nil
+	}
+	if rr == nil {
+		rr = &SearchResultsResolver{}
+	}
+
+	if r.andOrQuery != nil {
+		// The operands of queries with operators are not streamed, since the
+		// results of an operand may be removed by the others.
+		s.results(rr.SearchResults, &rr.searchResultsCommon)
+	}
+	s.alert(rr.alert)
+	s.done(&rr.searchResultsCommon)
+	return nil
+}
+
+// streamResults sends the results found by a search backend to r.stream, if
+// this is a streaming search. The caller must hold the lock that guards common.
+func (r *searchResolver) streamResults(results []SearchResultResolver, common *searchResultsCommon) {
+	if r.stream != nil {
+		r.stream.results(results, common)
+	}
+}
+
+// searchStream sends the results found by the backends of a streaming search.
+// Its methods are called with the lock that guards the statistics of the search
+// held, so they are never called concurrently.
+type searchStream struct {
+	send   func(SearchStreamEvent)
+	cancel context.CancelFunc
+	start  time.Time
+
+	// selector projects results as described by the select: field of the
+	// query, or is nil if it is not set.
+	selector *resultSelector
+
+	limit      int32 // the maximum number of results to send
+	count      int32 // the number of results sent so far
+	matchCount int32 // the number of matches in the results sent so far
+	limitHit   bool  // whether results were dropped because of limit
+}
+
+// results sends the results found by a backend, followed by the progress of
+// the search so far as described by common.
+func (s *searchStream) results(results []SearchResultResolver, common *searchResultsCommon) {
+	if s.selector != nil {
+		results = s.selector.project(results)
+	}
+	if remaining := int(s.limit - s.count); len(results) > remaining {
+		results = results[:remaining]
+		s.limitHit = true
+		s.cancel()
+	}
+	s.count += int32(len(results))
+
+	var (
+		files   []*streamFileMatch
+		repos   []*streamRepoMatch
+		commits []*streamCommitMatch
+	)
+	for _, result := range results {
+		s.matchCount += result.resultCount()
+		if fm, ok := result.ToFileMatch(); ok {
+			files = append(files, newStreamFileMatch(fm))
+		} else if repo, ok := result.ToRepository(); ok {
+			repos = append(repos, &streamRepoMatch{Repository: repo.Name(), URL: repo.URL()})
+		} else if commit, ok := result.ToCommitSearchResult(); ok {
+			commits = append(commits, newStreamCommitMatch(commit))
+		}
+	}
+	if len(files) > 0 {
+		s.send(SearchStreamEvent{Name: "filematches", Data: files})
+	}
+	if len(repos) > 0 {
+		s.send(SearchStreamEvent{Name: "repomatches", Data: repos})
+	}
+	if len(commits) > 0 {
+		s.send(SearchStreamEvent{Name: "commitmatches", Data: commits})
+	}
+	s.send(SearchStreamEvent{Name: "progress", Data: s.progress(common, false)})
+}
+
+// alert sends alert, if it is not nil.
+func (s *searchStream) alert(alert *searchAlert) {
+	if alert == nil {
+		return
+	}
+	a := &streamAlert{Title: alert.title, Description: alert.description}
+	for _, q := range alert.proposedQueries {
+		a.ProposedQueries = append(a.ProposedQueries, &streamProposedQuery{Description: q.description, Query: q.Query()})
+	}
+	s.send(SearchStreamEvent{Name: "alert", Data: a})
+}
+
+// done sends the final progress of the search.
+func (s *searchStream) done(common *searchResultsCommon) {
+	s.send(SearchStreamEvent{Name: "progress", Data: s.progress(common, true)})
+}
+
+func (s *searchStream) progress(common *searchResultsCommon, done bool) *streamProgress {
+	return &streamProgress{
+		Done:                done,
+		MatchCount:          s.matchCount,
+		ElapsedMilliseconds: time.Since(s.start).Milliseconds(),
+		LimitHit:            s.limitHit || common.limitHit,
+		RepositoriesCount:   len(repoNames(common.repos)),
+		Searched:            len(repoNames(common.searched)),
+		Indexed:             len(repoNames(common.indexed)),
+		Cloning:             repoNames(common.cloning),
+		Missing:             repoNames(common.missing),
+		Timedout:            repoNames(common.timedout),
+		TruncatedHistory:    repoNames(common.truncatedHistory),
+	}
+}
+
+// repoNames returns the sorted, deduplicated names of repos. The backends of a
+// search each report the repositories they searched, so a repository may occur
+// several times.
+func repoNames(repos []*types.Repo) []string {
+	seen := make(map[string]struct{}, len(repos))
+	var names []string
+	for _, repo := range repos {
+		name := string(repo.Name)
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type streamFileMatch struct {
+	Repository  string             `json:"repository"`
+	Revision    string             `json:"revision,omitempty"`
+	Commit      string             `json:"commit"`
+	Path        string             `json:"path"`
+	LimitHit    bool               `json:"limitHit"`
+	LineMatches []*streamLineMatch `json:"lineMatches,omitempty"`
+	Symbols     []*streamSymbol    `json:"symbols,omitempty"`
+}
+
+type streamLineMatch struct {
+	Preview          string     `json:"preview"`
+	LineNumber       int32      `json:"lineNumber"`
+	OffsetAndLengths [][2]int32 `json:"offsetAndLengths"`
+}
+
+type streamSymbol struct {
+	Name          string `json:"name"`
+	ContainerName string `json:"containerName,omitempty"`
+	Kind          string `json:"kind"`
+	Line          int    `json:"line"`
+}
+
+type streamRepoMatch struct {
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+}
+
+type streamCommitMatch struct {
+	Repository string `json:"repository"`
+	Commit     string `json:"commit"`
+	URL        string `json:"url"`
+	Label      string `json:"label"`
+	Detail     string `json:"detail"`
+
+	// Content is the commit message or diff the query matched, and Ranges are
+	// the matches in it as (line, character, length) triples.
+	Content string     `json:"content"`
+	Ranges  [][3]int32 `json:"ranges"`
+}
+
+type streamProgress struct {
+	Done                bool     `json:"done"`
+	MatchCount          int32    `json:"matchCount"`
+	ElapsedMilliseconds int64    `json:"elapsedMilliseconds"`
+	LimitHit            bool     `json:"limitHit"`
+	RepositoriesCount   int      `json:"repositoriesCount"`
+	Searched            int      `json:"searched"`
+	Indexed             int      `json:"indexed"`
+	Cloning             []string `json:"cloning,omitempty"`
+	Missing             []string `json:"missing,omitempty"`
+	Timedout            []string `json:"timedout,omitempty"`
+	TruncatedHistory    []string `json:"truncatedHistory,omitempty"`
+}
+
+type streamAlert struct {
+	Title           string                 `json:"title"`
+	Description     string                 `json:"description,omitempty"`
+	ProposedQueries []*streamProposedQuery `json:"proposedQueries,omitempty"`
+}
+
+type streamProposedQuery struct {
+	Description string `json:"description,omitempty"`
+	Query       string `json:"query"`
+}
+
+func newStreamFileMatch(fm *FileMatchResolver) *streamFileMatch {
+	m := &streamFileMatch{
+		Repository: string(fm.Repo.Name),
+		Commit:     string(fm.CommitID),
+		Path:       fm.JPath,
+		LimitHit:   fm.JLimitHit,
+	}
+	if fm.InputRev != nil {
+		m.Revision = *fm.InputRev
+	}
+	for _, lm := range fm.JLineMatches {
+		m.LineMatches = append(m.LineMatches, &streamLineMatch{
+			Preview:          lm.JPreview,
+			LineNumber:       lm.JLineNumber,
+			OffsetAndLengths: lm.JOffsetAndLengths,
+		})
+	}
+	for _, sym := range fm.symbols {
+		m.Symbols = append(m.Symbols, &streamSymbol{
+			Name:          sym.symbol.Name,
+			ContainerName: sym.symbol.Parent,
+			Kind:          (&symbolResolver{symbol: sym.symbol}).Kind(),
+			Line:          sym.symbol.Line,
+		})
+	}
+	return m
+}
+
+func newStreamCommitMatch(r *commitSearchResultResolver) *streamCommitMatch {
+	m := &streamCommitMatch{
+		Repository: r.commit.repo.Name(),
+		Commit:     string(r.commit.oid),
+		URL:        r.url,
+		Label:      r.label,
+		Detail:     r.detail,
+	}
+	preview := r.diffPreview
+	if preview == nil {
+		preview = r.messagePreview
+	}
+	if preview != nil {
+		m.Content = preview.value
+		for _, h := range preview.highlights {
+			m.Ranges = append(m.Ranges, [3]int32{h.line, h.character, h.length})
+		}
+	}
+	return m
+}
@@ -0,0 +1,67 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSearchStreamResults(t *testing.T) {
+	var events []SearchStreamEvent
+	canceled := false
+	s := &searchStream{
+		send:   func(e SearchStreamEvent) { events = append(events, e) },
+		cancel: func() { canceled = true },
+		limit:  2,
+	}
+	repo := &types.Repo{ID: 1, Name: "r"}
+	common := &searchResultsCommon{
+		repos:    []*types.Repo{repo, repo},
+		searched: []*types.Repo{repo},
+	}
+
+	s.results([]SearchResultResolver{&RepositoryResolver{repo: repo}}, common)
+	if canceled {
+		t.Fatal("canceled before the limit was reached")
+	}
+	s.results([]SearchResultResolver{
+		&FileMatchResolver{JPath: "a", Repo: repo, CommitID: "c"},
+		&FileMatchResolver{JPath: "b", Repo: repo, CommitID: "c"},
+	}, common)
+	if !canceled {
+		t.Fatal("not canceled after the limit was reached")
+	}
+
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	if want := []string{"repomatches", "progress", "filematches", "progress"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got events %v, want %v", names, want)
+	}
+	if files := events[2].Data.([]*streamFileMatch); len(files) != 1 || files[0].Path != "a" {
+		t.Errorf("got file matches %+v, want only a", files)
+	}
+	progress := events[3].Data.(*streamProgress)
+	if !progress.LimitHit || progress.MatchCount != 2 || progress.RepositoriesCount != 1 || progress.Searched != 1 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+}
+
+func TestStreamSearch_InvalidQuery(t *testing.T) {
+	var events []SearchStreamEvent
+	err := StreamSearch(context.Background(), &SearchArgs{Version: "V1", Query: "zz:b"}, func(e SearchStreamEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Name != "alert" || events[1].Name != "progress" {
+		t.Fatalf("got events %+v, want an alert and the final progress", events)
+	}
+	if !events[1].Data.(*streamProgress).Done {
+		t.Error("final progress is not done")
+	}
+}
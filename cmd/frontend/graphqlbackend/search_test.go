@@ -12,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
@@ -395,3 +396,66 @@ func Test_QuoteSuggestions(t *testing.T) {
 		}
 	})
 }
+
+func TestAddSubmoduleRevs(t *testing.T) {
+	resetMocks()
+	defer git.ResetMocks()
+	db.Mocks.ExternalServices.List = func(opt db.ExternalServicesListOptions) ([]*types.ExternalService, error) {
+		return nil, nil
+	}
+	lib := &types.Repo{ID: 2, Name: "github.com/a/lib"}
+	db.Mocks.Repos.GetByName = func(ctx context.Context, name api.RepoName) (*types.Repo, error) {
+		if name == lib.Name {
+			return lib, nil
+		}
+		return nil, &errcode.Mock{IsNotFound: true}
+	}
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return api.CommitID("commit-" + spec), nil
+	}
+	git.Mocks.ListSubmodules = func(commit api.CommitID) ([]git.Submodule, error) {
+		switch commit {
+		case "commit-HEAD":
+			return []git.Submodule{
+				{Path: "vendor/lib", URL: "https://github.com/a/lib", CommitID: "pinned1"},
+				{Path: "vendor/missing", URL: "https://github.com/a/missing", CommitID: "pinned2"},
+				{Path: "vendor/unknown", URL: "https://example.com/unknown", CommitID: "pinned3"},
+				{Path: "vendor/unlisted", CommitID: "pinned4"},
+			}, nil
+		case "commit-v1":
+			return []git.Submodule{
+				{Path: "vendor/lib", URL: "https://github.com/a/lib", CommitID: "pinned1"},
+				{Path: "vendor/lib2", URL: "git@github.com:a/lib.git", CommitID: "pinned5"},
+			}, nil
+		}
+		return nil, nil
+	}
+
+	parent := &types.Repo{ID: 1, Name: "github.com/a/parent"}
+	revisions := []*search.RepositoryRevisions{
+		{Repo: parent, Revs: []search.RevisionSpecifier{{RevSpec: ""}, {RevSpec: "v1"}, {RefGlob: "refs/tags/*"}}},
+		{Repo: &types.Repo{ID: 3, Name: "github.com/a/other"}, Revs: []search.RevisionSpecifier{{RevSpec: "v2"}}},
+	}
+	got, err := addSubmoduleRevs(context.Background(), revisions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotRevs []string
+	for _, revs := range got {
+		for _, rev := range revs.Revs {
+			gotRevs = append(gotRevs, string(revs.Repo.Name)+"@"+rev.String())
+		}
+	}
+	want := []string{
+		"github.com/a/parent@",
+		"github.com/a/parent@v1",
+		"github.com/a/parent@*refs/tags/*",
+		"github.com/a/other@v2",
+		"github.com/a/lib@pinned1",
+		"github.com/a/lib@pinned5",
+	}
+	if !reflect.DeepEqual(gotRevs, want) {
+		t.Errorf("got revisions %v, want %v", gotRevs, want)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
@@ -227,6 +228,9 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	if p.IsCaseSensitive {
 		q.Set("IsCaseSensitive", "true")
 	}
+	if p.IsMultiline {
+		q.Set("IsMultiline", "true")
+	}
 	if p.PathPatternsAreRegExps {
 		q.Set("PathPatternsAreRegExps", "true")
 	}
@@ -328,7 +332,7 @@ func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool,
 	}
 
 	r := struct {
-		Matches     []*FileMatchResolver
+		Matches     []*searcherFileMatch
 		LimitHit    bool
 		DeadlineHit bool
 	}{}
@@ -339,7 +343,68 @@ func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool,
 	if r.DeadlineHit {
 		err = context.DeadlineExceeded
 	}
-	return r.Matches, r.LimitHit, err
+	matches := make([]*FileMatchResolver, len(r.Matches))
+	for i, m := range r.Matches {
+		matches[i] = m.FileMatchResolver
+		if len(m.MultilineMatches) > 0 {
+			matches[i].JLineMatches = multilineMatchesToLineMatches(m.MultilineMatches)
+		}
+	}
+	return matches, r.LimitHit, err
+}
+
+// searcherFileMatch is a file match in the response of searcher. Multiline
+// regexp searches return MultilineMatches instead of LineMatches.
+type searcherFileMatch struct {
+	*FileMatchResolver
+	MultilineMatches []*multilineMatch
+}
+
+// multilineMatch is the struct used by searcher to return a match that may
+// span several lines (see protocol.MultilineMatch).
+type multilineMatch struct {
+	Preview    string
+	Start, End struct {
+		Line, Column int32
+	}
+}
+
+// multilineMatchesToLineMatches splits matches that span several lines into a
+// lineMatch for each line, like searcher does for regexp searches that are
+// not multiline. Matches on the same line are merged into one lineMatch.
+func multilineMatchesToLineMatches(matches []*multilineMatch) []*lineMatch {
+	var (
+		lineMatches []*lineMatch
+		byLine      = make(map[int32]*lineMatch)
+	)
+	for _, m := range matches {
+		for i, line := range strings.Split(m.Preview, "\n") {
+			lineNumber := m.Start.Line + int32(i)
+			if lineNumber > m.End.Line {
+				break
+			}
+			start, end := int32(0), int32(utf8.RuneCountInString(line))
+			if lineNumber == m.Start.Line {
+				start = m.Start.Column
+			}
+			if lineNumber == m.End.Line {
+				end = m.End.Column
+			}
+			if end <= start && m.Start.Line != m.End.Line {
+				// Don't highlight the empty remainder of a line the match
+				// ends on (e.g. after a trailing newline).
+				continue
+			}
+			lm, ok := byLine[lineNumber]
+			if !ok {
+				lm = &lineMatch{JPreview: line, JLineNumber: lineNumber}
+				byLine[lineNumber] = lm
+				lineMatches = append(lineMatches, lm)
+			}
+			lm.JOffsetAndLengths = append(lm.JOffsetAndLengths, [2]int32{start, end - start})
+		}
+	}
+	return lineMatches
 }
 
 type searcherError struct {
@@ -455,8 +520,21 @@ var mockSearchFilesInRepos func(args *search.TextParameters) ([]*FileMatchResolv
 
 // searchFilesInRepos searches a set of repos for a pattern.
 func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res []*FileMatchResolver, common *searchResultsCommon, err error) {
+	return searchFilesInReposStream(ctx, args, nil)
+}
+
+// searchFilesInReposStream is like searchFilesInRepos, but if onMatches is not
+// nil, it is also called with the matches of every indexed search and every
+// call to searcher as soon as they are found. Calls to onMatches are
+// serialized. It may be called with more file matches than are returned,
+// since the returned file matches are limited.
+func searchFilesInReposStream(ctx context.Context, args *search.TextParameters, onMatches func([]*FileMatchResolver)) (res []*FileMatchResolver, common *searchResultsCommon, err error) {
 	if mockSearchFilesInRepos != nil {
-		return mockSearchFilesInRepos(args)
+		res, common, err = mockSearchFilesInRepos(args)
+		if onMatches != nil && len(res) > 0 {
+			onMatches(res)
+		}
+		return res, common, err
 	}
 
 	tr, ctx := trace.New(ctx, "searchFilesInRepos", fmt.Sprintf("query: %+v, numRepoRevs: %d", args.PatternInfo, len(args.Repos)))
@@ -465,10 +543,12 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		tr.Finish()
 	}()
 
+	rankCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	common = &searchResultsCommon{partial: make(map[api.RepoName]struct{})}
+	explainer := searchExplainerFromContext(ctx)
 
 	var (
 		searcherRepos = args.Repos
@@ -484,13 +564,18 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				log15.Warn("zoektIndexedRepos failed", "error", err)
 			}
 			common.indexUnavailable = true
+			explainer.decision("indexed search is unavailable, so all repositories are searched without an index")
 			err = nil
 		}
 	}
 
+	// Zoekt only indexes the default branch, so structural search over
+	// historical snapshots (asof:) runs on searcher without an index.
+	unindexedStructural := args.PatternInfo.IsStructuralPat && isAsOfSearch(args.Query)
+
 	// if there are no indexed repos and this is a structural search
 	// query, there will be no results. Raise a friendly alert.
-	if len(zoektRepos) == 0 && args.PatternInfo.IsStructuralPat {
+	if len(zoektRepos) == 0 && args.PatternInfo.IsStructuralPat && !unindexedStructural {
 		return nil, nil, errors.New("no indexed repositories for structural search")
 	}
 
@@ -523,15 +608,19 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				common.missing[i] = r.Repo
 			}
 			tr.LazyPrintf("index:only, ignoring %d unindexed repos", len(searcherRepos))
+			explainer.decision("index:only, ignoring %d unindexed repositories", len(searcherRepos))
 			searcherRepos = nil
 		case No, False:
 			tr.LazyPrintf("index:no, bypassing zoekt (using searcher) for %d indexed repos", len(zoektRepos))
+			explainer.decision("index:no, searching %d indexed repositories without the index", len(zoektRepos))
 			searcherRepos = append(searcherRepos, zoektRepos...)
 			zoektRepos = nil
 		default:
 			return nil, common, fmt.Errorf("invalid index:%q (valid values are: yes, only, no)", index)
 		}
 	}
+	explainer.reposBackend(zoektRepos, "indexed")
+	explainer.reposBackend(searcherRepos, "unindexed")
 
 	var (
 		// TODO: convert wg to an errgroup
@@ -541,6 +630,11 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		unflattened       [][]*FileMatchResolver
 		flattenedSize     int
 		overLimitCanceled bool // canceled because we were over the limit
+
+		// For explaining the search: when the last call to searcher returned,
+		// and how many matches searcher found.
+		searcherEnd     time.Time
+		searcherMatches int
 	)
 
 	// addMatches assumes the caller holds mu.
@@ -553,12 +647,16 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 			})
 			unflattened = append(unflattened, matches)
 			flattenedSize += len(matches)
+			if onMatches != nil {
+				onMatches(matches)
+			}
 
 			// Stop searching once we have found enough matches. This does
 			// lead to potentially unstable result ordering, but is worth
 			// it for the performance benefit.
 			if flattenedSize > int(args.PatternInfo.FileMatchLimit) {
 				tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize, args.PatternInfo.FileMatchLimit)
+				explainer.decision("text search was canceled after finding more than %d file matches", args.PatternInfo.FileMatchLimit)
 				overLimitCanceled = true
 				common.limitHit = true
 				cancel()
@@ -588,6 +686,7 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		} else {
 			// When searching many repos, don't wait long for any single repo to fetch.
 			fetchTimeout = 500 * time.Millisecond
+			explainer.decision("unindexed repositories that are not fetched within %s are skipped", fetchTimeout)
 		}
 
 		if len(searcherRepos) > 0 {
@@ -650,6 +749,8 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 					}
 					mu.Lock()
 					defer mu.Unlock()
+					searcherEnd = time.Now()
+					searcherMatches += len(matches)
 					if ctx.Err() == nil {
 						common.searched = append(common.searched, repoRev.Repo)
 					}
@@ -680,6 +781,7 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		return nil
 	} // ends callSearcherOverRepos
 
+	start := time.Now()
 	wg.Add(1)
 	go func() {
 		// TODO limitHit, handleRepoSearchResult
@@ -693,6 +795,9 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		} else {
 			matches, limitHit, reposLimitHit, err = zoektSearchHEADOnlyFiles(ctx, args, zoektRepos, false, time.Since)
 		}
+		if len(zoektRepos) > 0 {
+			explainer.backend("text.indexed", true, len(zoektRepos), time.Since(start), len(matches), err)
+		}
 		mu.Lock()
 		defer mu.Unlock()
 		if ctx.Err() == nil {
@@ -762,8 +867,9 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		}
 	}()
 
-	// This guard disables unindexed structural search for now.
-	if !args.PatternInfo.IsStructuralPat {
+	// This guard disables unindexed structural search for now, except for
+	// historical snapshots.
+	if !args.PatternInfo.IsStructuralPat || unindexedStructural {
 		if err := callSearcherOverRepos(searcherRepos, nil); err != nil {
 			mu.Lock()
 			searchErr = err
@@ -772,14 +878,31 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 	}
 
 	wg.Wait()
+	if !searcherEnd.IsZero() {
+		explainer.backend("text.unindexed", true, len(searcherRepos), searcherEnd.Sub(start), searcherMatches, searchErr)
+	}
 	if searchErr != nil {
 		return nil, common, searchErr
 	}
 
-	flattened := flattenFileMatches(unflattened, int(args.PatternInfo.FileMatchLimit))
+	// The file matches are limited after they are ranked, so that the best
+	// ranked file matches are kept (see limitFileMatches). ctx is canceled
+	// once we are over the limit, so the signals are looked up with rankCtx.
+	flattened := flattenFileMatches(unflattened, flattenedSize)
+	flattened, _ = limitFileMatches(rankCtx, flattened, int(args.PatternInfo.FileMatchLimit))
 	return flattened, common, nil
 }
 
+// isAsOfSearch reports whether q searches historical snapshots of
+// repositories with the asof: field.
+func isAsOfSearch(q *query.Query) bool {
+	if q == nil {
+		return false
+	}
+	asOf, _ := q.StringValue(query.FieldAsOf)
+	return asOf != ""
+}
+
 func flattenFileMatches(unflattened [][]*FileMatchResolver, fileMatchLimit int) []*FileMatchResolver {
 	// Return early so we don't have to worry about empty lists in later
 	// calculations.
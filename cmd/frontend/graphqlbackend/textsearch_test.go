@@ -102,6 +102,20 @@ func TestQueryToZoektQuery(t *testing.T) {
 			},
 			Query: "(foo).*?(bar) case:no",
 		},
+		{
+			Name: "multiline regex",
+			Pattern: &search.TextPatternInfo{
+				IsRegExp:                     true,
+				IsCaseSensitive:              false,
+				IsMultiline:                  true,
+				Pattern:                      "(foo).*?(bar)",
+				IncludePatterns:              nil,
+				ExcludePattern:               "",
+				PathPatternsAreRegExps:       true,
+				PathPatternsAreCaseSensitive: false,
+			},
+			Query: "(?s:(foo).*?(bar)) case:no",
+		},
 		{
 			Name: "path",
 			Pattern: &search.TextPatternInfo{
@@ -174,6 +188,29 @@ func TestQueryToZoektQuery(t *testing.T) {
 	}
 }
 
+func TestMultilineMatchesToLineMatches(t *testing.T) {
+	match := func(preview string, startLine, startColumn, endLine, endColumn int32) *multilineMatch {
+		m := &multilineMatch{Preview: preview}
+		m.Start.Line, m.Start.Column = startLine, startColumn
+		m.End.Line, m.End.Column = endLine, endColumn
+		return m
+	}
+	got := multilineMatchesToLineMatches([]*multilineMatch{
+		match("func a() {\n}", 2, 0, 3, 1),
+		match("}", 3, 0, 4, 0),
+		match("x := ü + y", 5, 5, 5, 6),
+		match("x := ü + y", 5, 9, 5, 10),
+	})
+	want := []*lineMatch{
+		{JPreview: "func a() {", JLineNumber: 2, JOffsetAndLengths: [][2]int32{{0, 10}}},
+		{JPreview: "}", JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 1}, {0, 1}}},
+		{JPreview: "x := ü + y", JLineNumber: 5, JOffsetAndLengths: [][2]int32{{5, 1}, {9, 1}}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func queryEqual(a, b zoektquery.Q) bool {
 	sortChildren := func(q zoektquery.Q) zoektquery.Q {
 		switch s := q.(type) {
@@ -371,6 +408,58 @@ func TestSearchFilesInRepos(t *testing.T) {
 	}
 }
 
+func TestSearchFilesInReposStream(t *testing.T) {
+	// foo/slow only returns once the matches of foo/fast were passed to
+	// onMatches, so the search only finishes if the matches of every repo
+	// are passed on as soon as they are found.
+	fastStreamed := make(chan struct{})
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		if repo.Name == "foo/slow" {
+			select {
+			case <-fastStreamed:
+			case <-time.After(5 * time.Second):
+				return nil, false, errors.New("foo/fast was not streamed")
+			}
+		}
+		return []*FileMatchResolver{{uri: "git://" + string(repo.Name) + "?" + rev + "#main.go", Repo: repo}}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.TextParameters{
+		PatternInfo: &search.TextPatternInfo{
+			FileMatchLimit: defaultMaxSearchResults,
+			Pattern:        "foo",
+		},
+		Repos:        makeRepositoryRevisions("foo/slow", "foo/fast"),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+		SearcherURLs: endpoint.Static("test"),
+	}
+
+	var streamed []api.RepoName
+	results, _, err := searchFilesInReposStream(context.Background(), args, func(matches []*FileMatchResolver) {
+		for _, m := range matches {
+			streamed = append(streamed, m.Repo.Name)
+			if m.Repo.Name == "foo/fast" {
+				close(fastStreamed)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+	if want := []api.RepoName{"foo/fast", "foo/slow"}; !reflect.DeepEqual(streamed, want) {
+		t.Errorf("got streamed matches of %v, want %v", streamed, want)
+	}
+}
+
 func TestSearchFilesInRepos_multipleRevsPerRepo(t *testing.T) {
 	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
 		repoName := repo.Name
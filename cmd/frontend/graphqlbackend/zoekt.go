@@ -170,27 +170,6 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 
 	maxLineMatches := 25 + k
 	maxLineFragmentMatches := 3 + k
-	if limit := int(args.PatternInfo.FileMatchLimit); len(resp.Files) > limit {
-		// List of files we cut out from the Zoekt response because they exceed the file match limit on the Sourcegraph end.
-		// We use this to get a list of repositories that do not have complete results.
-		fileMatchesInSkippedRepos := resp.Files[limit:]
-		resp.Files = resp.Files[:limit]
-
-		if !limitHit {
-			// Zoekt evaluated all files and repositories, but Zoekt returned more file matches
-			// than the limit we set on Sourcegraph, so we cut out more results.
-
-			// Generate a list of repositories that had results cut because they exceeded the file match limit set on Sourcegraph.
-			for _, file := range fileMatchesInSkippedRepos {
-				if _, ok := reposLimitHit[file.Repository]; !ok {
-					reposLimitHit[file.Repository] = struct{}{}
-				}
-			}
-		}
-
-		limitHit = true
-	}
-
 	matches := make([]*FileMatchResolver, len(resp.Files))
 	for i, file := range resp.Files {
 		fileLimitHit := false
@@ -256,6 +235,27 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 		}
 	}
 
+	if limit := int(args.PatternInfo.FileMatchLimit); len(matches) > limit {
+		// Cut out the file matches that exceed the file match limit on the Sourcegraph end. The
+		// best ranked file matches are kept, rather than the first ones in the Zoekt response.
+		var skipped []*FileMatchResolver
+		matches, skipped = limitFileMatches(ctx, matches, limit)
+
+		if !limitHit {
+			// Zoekt evaluated all files and repositories, but Zoekt returned more file matches
+			// than the limit we set on Sourcegraph, so we cut out more results.
+
+			// Generate a list of repositories that had results cut because they exceeded the file match limit set on Sourcegraph.
+			for _, fm := range skipped {
+				if _, ok := reposLimitHit[string(fm.Repo.Name)]; !ok {
+					reposLimitHit[string(fm.Repo.Name)] = struct{}{}
+				}
+			}
+		}
+
+		limitHit = true
+	}
+
 	return matches, limitHit, reposLimitHit, nil
 }
 
@@ -349,12 +349,24 @@ func noOpAnyChar(re *syntax.Regexp) {
 }
 
 func parseRe(pattern string, filenameOnly bool, queryIsCaseSensitive bool) (zoektquery.Q, error) {
+	return parseReFlags(pattern, filenameOnly, queryIsCaseSensitive, false)
+}
+
+// parseReFlags is like parseRe. If multiline is true, "." in pattern also
+// matches newlines, like in multiline searcher searches.
+func parseReFlags(pattern string, filenameOnly, queryIsCaseSensitive, multiline bool) (zoektquery.Q, error) {
 	// these are the flags used by zoekt, which differ to searcher.
-	re, err := syntax.Parse(pattern, syntax.ClassNL|syntax.PerlX|syntax.UnicodeGroups)
+	flags := syntax.ClassNL | syntax.PerlX | syntax.UnicodeGroups
+	if multiline {
+		flags |= syntax.DotNL
+	}
+	re, err := syntax.Parse(pattern, flags)
 	if err != nil {
 		return nil, err
 	}
-	noOpAnyChar(re)
+	if !multiline {
+		noOpAnyChar(re)
+	}
 	// zoekt decides to use its literal optimization at the query parser
 	// level, so we check if our regex can just be a literal.
 	if re.Op == syntax.OpLiteral {
@@ -384,7 +396,7 @@ func queryToZoektQuery(query *search.TextPatternInfo, isSymbol bool) (zoektquery
 	var err error
 	if query.IsRegExp {
 		fileNameOnly := query.PatternMatchesPath && !query.PatternMatchesContent
-		q, err = parseRe(query.Pattern, fileNameOnly, query.IsCaseSensitive)
+		q, err = parseReFlags(query.Pattern, fileNameOnly, query.IsCaseSensitive, query.IsMultiline)
 		if err != nil {
 			return nil, err
 		}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"os/user"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -265,8 +266,9 @@ func serviceConnections() conftypes.ServiceConnections {
 		}
 
 		serviceConnectionsVal = conftypes.ServiceConnections{
-			GitServers:  gitServers(),
-			PostgresDSN: dbutil.PostgresDSN(username, os.Getenv),
+			GitServers:        gitServers(),
+			GitServerReplicas: gitServerReplicas(),
+			PostgresDSN:       dbutil.PostgresDSN(username, os.Getenv),
 		}
 	})
 	return serviceConnectionsVal
@@ -283,3 +285,16 @@ func gitServers() []string {
 	}
 	return strings.Fields(v)
 }
+
+func gitServerReplicas() int {
+	v := os.Getenv("SRC_GIT_SERVER_REPLICAS")
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log15.Error("SRC_GIT_SERVER_REPLICAS is not a positive integer, storing each repo on a single gitserver", "value", v)
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// blameStreamServer serves the blame of a file as server-sent events, so that
+// blames of large files can be shown before git blame finishes. The query
+// parameters are repo, rev (the default branch if empty), path, startLine and
+// endLine (the whole file if 0), ignoreRev (repeated), detectMoves and
+// detectCopies.
+//
+// Every hunk is sent as a "hunk" event with the JSON-encoded git.Hunk as
+// data, in the order git blame outputs them, which is not the order of their
+// lines. The stream ends with a "done" event, preceded by an "error" event if
+// the blame failed.
+type blameStreamServer struct {
+	// ResolveRev resolves rev in the named repo, returning an error if the
+	// actor cannot read the repo. Declared as a field for testing.
+	ResolveRev func(ctx context.Context, repo api.RepoName, rev string) (gitserver.Repo, api.CommitID, error)
+
+	// Blame is git.StreamBlameFile. Declared as a field for testing.
+	Blame func(ctx context.Context, repo gitserver.Repo, path string, opt *git.BlameOptions, onHunk func(*git.Hunk) error) error
+}
+
+// resolveBlameRev is the default blameStreamServer.ResolveRev.
+func resolveBlameRev(ctx context.Context, name api.RepoName, rev string) (gitserver.Repo, api.CommitID, error) {
+	// 🚨 SECURITY: Looking up the repository checks that the actor can read
+	// it.
+	repo, err := backend.Repos.GetByName(ctx, name)
+	if err != nil {
+		return gitserver.Repo{}, "", err
+	}
+	commitID, err := backend.Repos.ResolveRev(ctx, repo, rev)
+	if err != nil {
+		return gitserver.Repo{}, "", err
+	}
+	return gitserver.Repo{Name: repo.Name}, commitID, nil
+}
+
+func (h *blameStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "http flushing not supported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	repoName, path := api.RepoName(q.Get("repo")), q.Get("path")
+	if repoName == "" || path == "" {
+		http.Error(w, "repo and path are required", http.StatusBadRequest)
+		return
+	}
+	opt := &git.BlameOptions{
+		IgnoreRevs:   q["ignoreRev"],
+		DetectMoves:  q.Get("detectMoves") == "true",
+		DetectCopies: q.Get("detectCopies") == "true",
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"startLine", &opt.StartLine}, {"endLine", &opt.EndLine}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+				return
+			}
+			*p.value = n
+		}
+	}
+
+	repo, commitID, err := h.ResolveRev(r.Context(), repoName, q.Get("rev"))
+	if err != nil {
+		http.Error(w, err.Error(), errcode.HTTP(err))
+		return
+	}
+	opt.NewestCommit = commitID
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, data interface{}) error {
+		if err := writeSearchEvent(w, event, data); err != nil {
+			log15.Debug("blame stream: failed to write event", "event", event, "error", err)
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	err = h.Blame(r.Context(), repo, path, opt, func(hunk *git.Hunk) error {
+		// Returning the error stops the blame once the client has gone away.
+		return send("hunk", hunk)
+	})
+	if err != nil {
+		send("error", &struct {
+			Message string `json:"message"`
+		}{Message: err.Error()})
+	}
+	send("done", struct{}{})
+}
@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestServeBlameStream(t *testing.T) {
+	var gotOpt *git.BlameOptions
+	h := &blameStreamServer{
+		ResolveRev: func(ctx context.Context, repo api.RepoName, rev string) (gitserver.Repo, api.CommitID, error) {
+			if repo != "github.com/foo/bar" || rev != "main" {
+				t.Errorf("got repo %q and rev %q", repo, rev)
+			}
+			return gitserver.Repo{Name: repo}, "c", nil
+		},
+		Blame: func(ctx context.Context, repo gitserver.Repo, path string, opt *git.BlameOptions, onHunk func(*git.Hunk) error) error {
+			if path != "a/b.go" {
+				t.Errorf("got path %q", path)
+			}
+			gotOpt = opt
+			for i := 1; i <= 2; i++ {
+				if err := onHunk(&git.Hunk{
+					StartLine: i, EndLine: i + 1, StartByte: 2 * (i - 1), EndByte: 2 * i, CommitID: "d",
+					Author:  git.Signature{Name: "a", Email: "a@a.com", Date: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+					Message: "m",
+				}); err != nil {
+					return err
+				}
+			}
+			return errors.New("boom")
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/blame/stream?repo=github.com/foo/bar&rev=main&path=a/b.go&startLine=1&endLine=2&ignoreRev=x&ignoreRev=y&detectMoves=true", nil))
+
+	if got, want := w.Header().Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+	wantOpt := &git.BlameOptions{NewestCommit: "c", StartLine: 1, EndLine: 2, IgnoreRevs: []string{"x", "y"}, DetectMoves: true}
+	if !reflect.DeepEqual(gotOpt, wantOpt) {
+		t.Errorf("got options %+v, want %+v", gotOpt, wantOpt)
+	}
+	want := `event: hunk
+data: {"StartLine":1,"EndLine":2,"StartByte":0,"EndByte":2,"CommitID":"d","Author":{"Name":"a","Email":"a@a.com","Date":"2006-01-02T15:04:05Z"},"Message":"m"}
+
+event: hunk
+data: {"StartLine":2,"EndLine":3,"StartByte":2,"EndByte":4,"CommitID":"d","Author":{"Name":"a","Email":"a@a.com","Date":"2006-01-02T15:04:05Z"},"Message":"m"}
+
+event: error
+data: {"message":"boom"}
+
+event: done
+data: {}
+
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got body\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestServeBlameStream_badRequest(t *testing.T) {
+	h := &blameStreamServer{
+		ResolveRev: func(ctx context.Context, repo api.RepoName, rev string) (gitserver.Repo, api.CommitID, error) {
+			return gitserver.Repo{}, "", &errcode.Mock{IsNotFound: true}
+		},
+		Blame: func(context.Context, gitserver.Repo, string, *git.BlameOptions, func(*git.Hunk) error) error {
+			t.Fatal("unexpected blame")
+			return nil
+		},
+	}
+	for url, want := range map[string]int{
+		"/blame/stream?path=a":                    400,
+		"/blame/stream?repo=r":                    400,
+		"/blame/stream?repo=r&path=a&startLine=x": 400,
+		"/blame/stream?repo=r&path=a&startLine=1": 404,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != want {
+			t.Errorf("%s: got status %d, want %d", url, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// serveGitInfoRefs and serveGitUploadPack proxy git smart HTTP requests to the
+// gitserver that stores the repository, so that internal services can clone
+// and fetch from gitserver instead of the code host. Requests must be
+// authenticated with an access token.
+func serveGitInfoRefs(w http.ResponseWriter, r *http.Request) error {
+	return serveGitSmartHTTP(w, r, "info/refs")
+}
+
+func serveGitUploadPack(w http.ResponseWriter, r *http.Request) error {
+	return serveGitSmartHTTP(w, r, "git-upload-pack")
+}
+
+func serveGitSmartHTTP(w http.ResponseWriter, r *http.Request, op string) error {
+	// 🚨 SECURITY: Requests without an access token have the internal actor of
+	// the internal API, which bypasses repository permissions, so they are
+	// rejected. Looking up the repository checks that the token's user can
+	// read it.
+	if a := actor.FromContext(r.Context()); !a.IsAuthenticated() || a.Internal {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Sourcegraph"`)
+		http.Error(w, "Git requires an access token.", http.StatusUnauthorized)
+		return nil
+	}
+
+	repo, err := backend.Repos.GetByName(r.Context(), api.RepoName(mux.Vars(r)["RepoName"]))
+	if err != nil {
+		return err
+	}
+
+	addr := gitserver.DefaultClient.AddrForRepo(r.Context(), repo.Name)
+	director := func(req *http.Request) {
+		req.URL.Scheme = "http"
+		req.URL.Host = addr
+		req.URL.Path = "/git/" + string(repo.Name) + "/" + op
+		req.Header.Del("Authorization")
+	}
+
+	// Git clients check the content type, so we use gitserver's instead of
+	// the JSON content type of internal API responses.
+	w.Header().Del("Content-Type")
+	gitserver.DefaultReverseProxy.ServeHTTP(repo.Name, r.Method, op, director, w, r)
+	return nil
+}
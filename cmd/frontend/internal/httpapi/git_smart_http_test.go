@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/httpapi/router"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/conf/conftypes"
+)
+
+func TestServeGitSmartHTTP(t *testing.T) {
+	var gotPath, gotProtocol, gotAuthorization string
+	gitserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path + "?" + r.URL.RawQuery
+		gotProtocol = r.Header.Get("Git-Protocol")
+		gotAuthorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		_, _ = w.Write([]byte("refs"))
+	}))
+	defer gitserver.Close()
+
+	conf.Mock(&conf.Unified{
+		ServiceConnections: conftypes.ServiceConnections{
+			GitServers: []string{strings.TrimPrefix(gitserver.URL, "http://")},
+		},
+	})
+	defer conf.Mock(nil)
+
+	backend.Mocks.Repos.GetByName = func(ctx context.Context, name api.RepoName) (*types.Repo, error) {
+		if name != "github.com/foo/bar" {
+			return nil, repoNotFoundErr{}
+		}
+		return &types.Repo{ID: 1, Name: name}, nil
+	}
+	defer func() { backend.Mocks = backend.MockServices{} }()
+
+	internalHandler := NewInternalHandler(router.NewInternal(mux.NewRouter()), nil)
+	// The access token middleware sets the actor of the token's user.
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHandler.ServeHTTP(w, r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: 1})))
+	})
+
+	t.Run("info/refs", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/git/github.com/foo/bar/info/refs?service=git-upload-pack", nil)
+		req.Header.Set("Git-Protocol", "version=2")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		if want := "/git/github.com/foo/bar/info/refs?service=git-upload-pack"; gotPath != want {
+			t.Errorf("got gitserver path %q, want %q", gotPath, want)
+		}
+		if gotProtocol != "version=2" {
+			t.Errorf("got Git-Protocol %q, want version=2", gotProtocol)
+		}
+		if got, want := rec.Header().Get("Content-Type"), "application/x-git-upload-pack-advertisement"; got != want {
+			t.Errorf("got content type %q, want %q", got, want)
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if string(body) != "refs" {
+			t.Errorf("got body %q, want %q", body, "refs")
+		}
+	})
+
+	t.Run("git-upload-pack", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/git/github.com/foo/bar/git-upload-pack", strings.NewReader("0000"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		if want := "/git/github.com/foo/bar/git-upload-pack?"; gotPath != want {
+			t.Errorf("got gitserver path %q, want %q", gotPath, want)
+		}
+		if gotAuthorization != "" {
+			t.Errorf("got Authorization header %q sent to gitserver", gotAuthorization)
+		}
+	})
+
+	t.Run("repository not found", func(t *testing.T) {
+		gotPath = ""
+		req := httptest.NewRequest("GET", "/git/github.com/foo/secret/info/refs?service=git-upload-pack", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+		}
+		if gotPath != "" {
+			t.Errorf("got request to gitserver %q", gotPath)
+		}
+	})
+
+	for _, a := range []*actor.Actor{nil, {Internal: true}} {
+		t.Run(fmt.Sprintf("unauthenticated actor %+v", a), func(t *testing.T) {
+			gotPath = ""
+			req := httptest.NewRequest("GET", "/git/github.com/foo/bar/info/refs?service=git-upload-pack", nil)
+			if a != nil {
+				req = req.WithContext(actor.WithActor(req.Context(), a))
+			}
+			rec := httptest.NewRecorder()
+			internalHandler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("got status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if gotPath != "" {
+				t.Errorf("got request to gitserver %q", gotPath)
+			}
+		})
+	}
+}
+
+type repoNotFoundErr struct{}
+
+func (repoNotFoundErr) Error() string  { return "repository not found" }
+func (repoNotFoundErr) NotFound() bool { return true }
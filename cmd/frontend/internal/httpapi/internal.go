@@ -357,10 +357,11 @@ func serveSavedQueriesSetInfo(w http.ResponseWriter, r *http.Request) error {
 		return errors.Wrap(err, "Decode")
 	}
 	err = db.QueryRunnerState.Set(r.Context(), &db.SavedQueryInfo{
-		Query:        info.Query,
-		LastExecuted: info.LastExecuted,
-		LatestResult: info.LatestResult,
-		ExecDuration: info.ExecDuration,
+		Query:         info.Query,
+		LastExecuted:  info.LastExecuted,
+		LatestResult:  info.LatestResult,
+		ExecDuration:  info.ExecDuration,
+		ResultMatches: info.ResultMatches,
 	})
 	if err != nil {
 		return errors.Wrap(err, "SavedQueries.Set")
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// searchStreamServer serves streaming search results as server-sent events
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html). The query
+// parameters are q (the search query), v (the search version, V2 by default)
+// and t (the pattern type, which overrides the version).
+//
+// Every event produced by graphqlbackend.StreamSearch is sent with its name
+// and JSON-encoded data. The stream ends with a "done" event, preceded by an
+// "error" event if the search failed.
+type searchStreamServer struct {
+	// Search is graphqlbackend.StreamSearch. Declared as a field for testing.
+	Search func(context.Context, *graphqlbackend.SearchArgs, func(graphqlbackend.SearchStreamEvent)) error
+}
+
+func (h *searchStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "http flushing not supported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	args := &graphqlbackend.SearchArgs{
+		Version: q.Get("v"),
+		Query:   q.Get("q"),
+	}
+	if args.Version == "" {
+		args.Version = "V2"
+	}
+	if t := q.Get("t"); t != "" {
+		args.PatternType = &t
+	}
+	if args.Query == "" {
+		http.Error(w, "no query found", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// writeErr is the first error writing to the client. Once the client has
+	// gone away, the request context is canceled and the search stops, so we
+	// just stop writing.
+	var writeErr error
+	send := func(event string, data interface{}) {
+		if writeErr != nil {
+			return
+		}
+		if writeErr = writeSearchEvent(w, event, data); writeErr != nil {
+			log15.Debug("search stream: failed to write event", "event", event, "error", writeErr)
+			return
+		}
+		flusher.Flush()
+	}
+
+	err := h.Search(r.Context(), args, func(event graphqlbackend.SearchStreamEvent) {
+		send(event.Name, event.Data)
+	})
+	if err != nil {
+		send("error", &struct {
+			Message string `json:"message"`
+		}{Message: err.Error()})
+	}
+	send("done", struct{}{})
+}
+
+// writeSearchEvent writes a single server-sent event.
+func writeSearchEvent(w http.ResponseWriter, event string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+	return err
+}
@@ -0,0 +1,62 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+)
+
+func TestServeSearchStream(t *testing.T) {
+	var gotArgs *graphqlbackend.SearchArgs
+	h := &searchStreamServer{
+		Search: func(ctx context.Context, args *graphqlbackend.SearchArgs, send func(graphqlbackend.SearchStreamEvent)) error {
+			gotArgs = args
+			send(graphqlbackend.SearchStreamEvent{Name: "repomatches", Data: []string{"a"}})
+			send(graphqlbackend.SearchStreamEvent{Name: "progress", Data: map[string]bool{"done": true}})
+			return errors.New("boom")
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/search/stream?q=foo&t=regexp", nil))
+
+	if got, want := w.Header().Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+	if gotArgs.Query != "foo" || gotArgs.Version != "V2" || gotArgs.PatternType == nil || *gotArgs.PatternType != "regexp" {
+		t.Errorf("unexpected search args %+v", gotArgs)
+	}
+	want := `event: repomatches
+data: ["a"]
+
+event: progress
+data: {"done":true}
+
+event: error
+data: {"message":"boom"}
+
+event: done
+data: {}
+
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got body\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestServeSearchStream_NoQuery(t *testing.T) {
+	h := &searchStreamServer{
+		Search: func(context.Context, *graphqlbackend.SearchArgs, func(graphqlbackend.SearchStreamEvent)) error {
+			t.Fatal("unexpected search")
+			return nil
+		},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/search/stream", nil))
+	if w.Code != 400 {
+		t.Errorf("got status %d, want 400", w.Code)
+	}
+}
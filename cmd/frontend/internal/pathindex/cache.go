@@ -0,0 +1,131 @@
+package pathindex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/diskcache"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// CacheDir is the location on disk that indexes are cached. It is
+// configurable so that in production we can point it into CACHE_DIR.
+var CacheDir = "/tmp/pathindex-cache"
+
+// MaxCacheSizeBytes is the maximum size of the indexes cached on disk. The
+// least recently used indexes are evicted when it is exceeded.
+var MaxCacheSizeBytes int64 = 1 << 30
+
+// memoryCacheSize is the number of indexes kept in memory. Fuzzy finding is
+// interactive, so the same index is usually searched for every keystroke.
+const memoryCacheSize = 20
+
+var (
+	storeOnce sync.Once
+	store     *diskcache.Store
+
+	memoryMu    sync.Mutex
+	memoryCache = lru.New(memoryCacheSize)
+)
+
+// Get returns the index of the paths of the files in repo at commit, which
+// must be an absolute commit ID. The index is built on the first call for a
+// commit and cached.
+func Get(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (*Index, error) {
+	key := string(repo.Name) + "@" + string(commit)
+
+	memoryMu.Lock()
+	x, ok := memoryCache.Get(key)
+	memoryMu.Unlock()
+	if ok {
+		return x.(*Index), nil
+	}
+
+	storeOnce.Do(func() {
+		store = &diskcache.Store{
+			Dir:               CacheDir,
+			Component:         "pathindex",
+			BackgroundTimeout: 2 * time.Minute,
+		}
+		go watchAndEvict()
+	})
+
+	f, err := store.Open(ctx, key, func(ctx context.Context) (io.ReadCloser, error) {
+		paths, err := git.ListFiles(ctx, repo, commit)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(NewIndex(paths).data)), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pathindex: %s", err)
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{data: data}
+
+	memoryMu.Lock()
+	memoryCache.Add(key, index)
+	memoryMu.Unlock()
+	return index, nil
+}
+
+// watchAndEvict is a loop which periodically checks the size of the cache and
+// evicts/deletes items if the store gets too large.
+func watchAndEvict() {
+	if MaxCacheSizeBytes == 0 {
+		return
+	}
+
+	for {
+		time.Sleep(10 * time.Second)
+		stats, err := store.Evict(MaxCacheSizeBytes)
+		if err != nil {
+			log.Printf("pathindex: failed to Evict: %s", err)
+			continue
+		}
+		cacheSizeBytes.Set(float64(stats.CacheSize))
+		evictions.Add(float64(stats.Evicted))
+	}
+}
+
+var (
+	cacheSizeBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "frontend_pathindex",
+		Name:      "cache_size_bytes",
+		Help:      "The total size of cached path indexes on disk.",
+	})
+	evictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "frontend_pathindex",
+		Name:      "evictions",
+		Help:      "The total number of path indexes evicted from the cache.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cacheSizeBytes)
+	prometheus.MustRegister(evictions)
+}
+
+// resetMemoryCache empties the in-memory cache of indexes. It is used by tests.
+func resetMemoryCache() {
+	memoryMu.Lock()
+	memoryCache = lru.New(memoryCacheSize)
+	memoryMu.Unlock()
+}
@@ -0,0 +1,204 @@
+// Package pathindex implements fuzzy matching of the file paths of a
+// repository, for jumping to a file by a partial name.
+//
+// The paths of a repository at a commit are listed once and cached on disk as
+// an index (see Get). Matching an index is a linear scan, which is fast enough
+// for repositories with hundreds of thousands of files.
+package pathindex
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// An Index is the list of file paths of a repository at a commit.
+type Index struct {
+	// data is the sorted paths, each terminated by a newline. Paths that
+	// contain a newline are left out (see NewIndex).
+	data []byte
+}
+
+// NewIndex returns an index of paths. Paths that contain a newline are left
+// out, because they would be read back as several paths. Git allows them, but
+// they are very rare.
+func NewIndex(paths []string) *Index {
+	sorted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !strings.Contains(p, "\n") {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Strings(sorted)
+	var buf bytes.Buffer
+	for _, p := range sorted {
+		buf.WriteString(p)
+		buf.WriteByte('\n')
+	}
+	return &Index{data: buf.Bytes()}
+}
+
+// Len returns the number of paths in the index.
+func (x *Index) Len() int {
+	return bytes.Count(x.data, []byte{'\n'})
+}
+
+// A Match is a path that matches a query.
+type Match struct {
+	Path string
+
+	// Score is the relevance of the match. Matches with higher scores are
+	// better.
+	Score int
+
+	// Offsets are the offsets (in characters) of the characters of Path that
+	// matched the characters of the query.
+	Offsets []int
+}
+
+// Search returns at most limit paths that contain the characters of query in
+// order (case-insensitively, ignoring whitespace), best matches first.
+// Matches are ranked by their score (see score), and then by path length, so
+// that shorter paths with the same score come first.
+func (x *Index) Search(query string, limit int) []Match {
+	q := make([]byte, 0, len(query))
+	for i := 0; i < len(query); i++ {
+		if c := query[i]; c != ' ' && c != '\t' {
+			q = append(q, toLower(c))
+		}
+	}
+	if len(q) == 0 || limit <= 0 {
+		return nil
+	}
+
+	var (
+		matches   []Match
+		positions = make([]int, len(q))
+		reverse   = make([]int, len(q))
+	)
+	for data := x.data; len(data) > 0; {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data)
+		}
+		path := data[:end]
+		data = data[end+1:]
+
+		// The characters of the query are usually found in several places
+		// of a path. We score two alignments: the leftmost one, and the
+		// rightmost one, which favors the file name over its directories.
+		if !matchForward(path, q, positions) {
+			continue
+		}
+		matchBackward(path, q, reverse)
+		s, offsets := score(path, positions), positions
+		if rs := score(path, reverse); rs > s {
+			s, offsets = rs, reverse
+		}
+
+		m := Match{Path: string(path), Score: s, Offsets: make([]int, len(offsets))}
+		for i, pos := range offsets {
+			m.Offsets[i] = utf8.RuneCount(path[:pos])
+		}
+		matches = append(matches, m)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) < len(b.Path)
+		}
+		return a.Path < b.Path
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// matchForward sets positions to the leftmost positions in path of the
+// characters of q, and reports whether all characters of q were found.
+func matchForward(path, q []byte, positions []int) bool {
+	i := 0
+	for pos := 0; pos < len(path) && i < len(q); pos++ {
+		if toLower(path[pos]) == q[i] {
+			positions[i] = pos
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+// matchBackward sets positions to the rightmost positions in path of the
+// characters of q. All characters of q must occur in path in order.
+func matchBackward(path, q []byte, positions []int) {
+	i := len(q) - 1
+	for pos := len(path) - 1; pos >= 0 && i >= 0; pos-- {
+		if toLower(path[pos]) == q[i] {
+			positions[i] = pos
+			i--
+		}
+	}
+}
+
+// Score bonuses and penalties of a match. The bonuses make "fb" match
+// "foo_bar.go" better than "fab.go", and "main.go" match "cmd/main.go" better
+// than "main/cmd.go".
+const (
+	scoreMatch       = 1 // every matched character
+	scoreConsecutive = 4 // the previous character of the path matched too
+	scoreBoundary    = 6 // first character of a path component or word
+	scoreFileName    = 2 // in the last path component
+	scoreGapMax      = 3 // maximum penalty for skipped characters between matches
+)
+
+// score returns the score of matching the characters of path at positions.
+func score(path []byte, positions []int) int {
+	fileNameStart := bytes.LastIndexByte(path, '/') + 1
+	s := 0
+	for i, pos := range positions {
+		s += scoreMatch
+		if i > 0 {
+			if gap := pos - positions[i-1] - 1; gap == 0 {
+				s += scoreConsecutive
+			} else if gap < scoreGapMax {
+				s -= gap
+			} else {
+				s -= scoreGapMax
+			}
+		}
+		if isBoundary(path, pos) {
+			s += scoreBoundary
+		}
+		if pos >= fileNameStart {
+			s += scoreFileName
+		}
+	}
+	return s
+}
+
+// isBoundary reports whether the character at pos starts a path component or
+// a word, such as "b" in "foo/bar", "foo_bar", "foo.bar" and "fooBar".
+func isBoundary(path []byte, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	switch prev, c := path[pos-1], path[pos]; {
+	case prev == '/' || prev == '_' || prev == '-' || prev == '.' || prev == ' ':
+		return true
+	case 'a' <= prev && prev <= 'z' && 'A' <= c && c <= 'Z':
+		return true
+	}
+	return false
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
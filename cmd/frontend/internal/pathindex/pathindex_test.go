@@ -0,0 +1,116 @@
+package pathindex
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestIndex_Search(t *testing.T) {
+	index := NewIndex([]string{
+		"README.md",
+		"cmd/main.go",
+		"main/cmd.go",
+		"fab.go",
+		"foo_bar.go",
+		"internal/search/query/parser.go",
+		"internal/search/query/parser_test.go",
+		"web/src/SearchPage.tsx",
+		"vendor/github.com/x/parser/parse.go",
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "fb", want: []string{"foo_bar.go", "fab.go"}},
+		{query: "main.go", want: []string{"cmd/main.go", "main/cmd.go"}},
+		{query: "qparser", want: []string{"internal/search/query/parser.go", "internal/search/query/parser_test.go"}},
+		{query: "SP", want: []string{"web/src/SearchPage.tsx", "internal/search/query/parser.go"}},
+		{query: "readme", want: []string{"README.md"}},
+		{query: "q p", want: []string{"internal/search/query/parser.go", "internal/search/query/parser_test.go"}},
+		{query: "xyz", want: nil},
+		{query: "", want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			var got []string
+			for _, m := range index.Search(test.query, 2) {
+				got = append(got, m.Path)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewIndex_newlines(t *testing.T) {
+	index := NewIndex([]string{"a.go", "b\nc.go", "d.go"})
+	if got, want := index.Len(), 2; got != want {
+		t.Errorf("got %d paths, want %d", got, want)
+	}
+	var got []string
+	for _, m := range index.Search("go", 10) {
+		got = append(got, m.Path)
+	}
+	if want := []string{"a.go", "d.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIndex_Search_offsets(t *testing.T) {
+	index := NewIndex([]string{"dir/fooBar.go", "dir/ünï.go"})
+	for query, want := range map[string][]int{
+		"fb":  {4, 7},
+		"dfb": {0, 4, 7},
+		"n.g": {5, 7, 8},
+	} {
+		matches := index.Search(query, 1)
+		if len(matches) != 1 {
+			t.Fatalf("%q: got %d matches, want 1", query, len(matches))
+		}
+		if !reflect.DeepEqual(matches[0].Offsets, want) {
+			t.Errorf("%q: got offsets %v in %q, want %v", query, matches[0].Offsets, matches[0].Path, want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pathindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CacheDir = dir
+	defer resetMemoryCache()
+
+	calls := 0
+	git.Mocks.ListFiles = func(commit api.CommitID) ([]string, error) {
+		calls++
+		return []string{"b.go", "a/c.go"}, nil
+	}
+	defer func() { git.Mocks.ListFiles = nil }()
+
+	repo := gitserver.Repo{Name: "r"}
+	commit := api.CommitID("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	for i := 0; i < 2; i++ {
+		resetMemoryCache()
+		index, err := Get(context.Background(), repo, commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := index.Len(), 2; got != want {
+			t.Errorf("got %d paths, want %d", got, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls to git.ListFiles, want 1 (the index should be cached on disk)", calls)
+	}
+}
@@ -29,6 +29,8 @@ var (
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
 	wantPctFree       = env.Get("SRC_REPOS_DESIRED_PERCENT_FREE", "10", "Target percentage of free space on disk.")
 	janitorInterval   = env.Get("SRC_REPOS_JANITOR_INTERVAL", "1m", "Interval between cleanup runs")
+	hostname          = env.Get("HOSTNAME", "", "Hostname of this gitserver, used to find its address in SRC_GIT_SERVERS to move repos between gitservers when SRC_GIT_SERVERS changes.")
+	bundlesDir        = env.Get("SRC_REPOS_BUNDLES_DIR", "", "Dir containing git bundles to seed repos from when they are cloned, at <dir>/<repo>/*.bundle.")
 )
 
 func main() {
@@ -51,6 +53,8 @@ func main() {
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
 		DesiredPercentFree:      wantPctFree2,
+		Hostname:                hostname,
+		BundlesDir:              bundlesDir,
 	}
 	gitserver.RegisterMetrics()
 
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// Repos can be exported as git bundles and imported from them, so that
+// mirrored repos can be backed up and moved to air-gapped instances without
+// cloning them from the code hosts again.
+//
+// The /bundle endpoint writes a bundle of all refs of a repo. A bundle can be
+// incremental: given the commits the refs of a previous bundle pointed to, it
+// only contains the objects that are not reachable from them.
+//
+// When BundlesDir is set, repos are seeded from the bundles in
+// BundlesDir/<repo>/*.bundle before they are fetched from the code host. The
+// bundles are applied in lexical order of their names, so incremental bundles
+// should be named to sort after the bundles they build on, such as by the time
+// they were created.
+
+var reposClonedFromBundles = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "repos_cloned_from_bundles",
+	Help:      "number of repos seeded from bundles in the bundles directory",
+})
+
+func init() {
+	prometheus.MustRegister(reposClonedFromBundles)
+}
+
+// commitIDPattern matches full commit IDs.
+var commitIDPattern = lazyregexp.New(`^[0-9a-f]{40}$`)
+
+// handleBundle writes a bundle of all refs of the repo given by the repo
+// query parameter. If since query parameters are given, they are the commits
+// the refs of a previous bundle pointed to, and the bundle only contains
+// objects that are not reachable from them. It responds with 204 No Content
+// if there are no such objects.
+//
+// Since the bundle is streamed, errors of git bundle are reported in the
+// X-Bundle-Error trailer.
+func (s *Server) handleBundle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("repo") == "" {
+		http.Error(w, "repo is required", http.StatusBadRequest)
+		return
+	}
+	repo := protocol.NormalizeRepo(api.RepoName(q.Get("repo")))
+	dir := s.dir(repo)
+	if !repoCloned(dir) {
+		http.Error(w, "repo not cloned", http.StatusNotFound)
+		return
+	}
+	for _, commit := range q["since"] {
+		if !commitIDPattern.MatchString(commit) {
+			http.Error(w, fmt.Sprintf("invalid commit ID %q", commit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), longGitCommandTimeout)
+	defer cancel()
+
+	since, err := existingCommits(ctx, dir, q["since"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	args := []string{"bundle", "create", "-q", "-", "--all"}
+	if len(since) > 0 {
+		// git refuses to create an empty bundle, but only after it wrote the
+		// bundle header.
+		changed, err := hasCommitsSince(ctx, dir, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !changed {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		args = append(args, "--not")
+		args = append(args, since...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = string(dir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-git-bundle")
+	w.Header().Set("Trailer", "X-Bundle-Error")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, stdout); err != nil {
+		log15.Warn("failed to write bundle", "repo", repo, "error", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		w.Header().Set("X-Bundle-Error", fmt.Sprintf("%s (stderr: %q)", err, stderr.String()))
+	}
+}
+
+// existingCommits returns the commits that exist in the repo at dir.
+// Commits of a previous bundle may have been removed since by force pushes
+// and garbage collection.
+func existingCommits(ctx context.Context, dir GitDir, commits []string) ([]string, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"rev-list", "--no-walk", "--ignore-missing"}, append(commits, "--")...)...)
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, wrapCmdError(cmd, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// hasCommitsSince reports whether any ref of the repo at dir points to a
+// commit that is not reachable from the commits in since.
+func hasCommitsSince(ctx context.Context, dir GitDir, since []string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"rev-list", "-n", "1", "--all", "--not"}, append(since, "--")...)...)
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return false, wrapCmdError(cmd, err)
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// repoBundles returns the paths of the bundles to seed repo from, in the
+// order in which they are applied.
+func (s *Server) repoBundles(repo api.RepoName) []string {
+	if s.BundlesDir == "" {
+		return nil
+	}
+	dir := filepath.Join(s.BundlesDir, filepath.FromSlash(string(protocol.NormalizeRepo(repo))))
+	paths, err := filepath.Glob(filepath.Join(dir, "*.bundle"))
+	if err != nil {
+		log15.Warn("failed to list bundles", "repo", repo, "dir", dir, "error", err)
+		return nil
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// cloneFromBundles clones repo into tmpPath from the bundles in BundlesDir,
+// and then fetches what changed since from url, the remote URL of repo. It
+// reports whether it cloned repo. If it did not, repo must be cloned from the
+// code host. If the fetch from url fails, the repo is cloned from the
+// bundles only, and the next update of the repo fetches the changes.
+func (s *Server) cloneFromBundles(ctx context.Context, repo api.RepoName, url, tmpPath string, progress io.Writer) bool {
+	bundles := s.repoBundles(repo)
+	if len(bundles) == 0 {
+		return false
+	}
+
+	log15.Info("cloning repo from bundles", "repo", repo, "bundles", bundles)
+	fail := func(msg string, err error, output []byte) bool {
+		log15.Warn(msg+", cloning it from the code host", "repo", repo, "error", err, "output", string(output))
+		_ = os.RemoveAll(tmpPath)
+		return false
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", bundles[0], tmpPath)
+	if output, err := runWith(ctx, cmd, false, progress); err != nil {
+		return fail("failed to clone repo from bundle", err, output)
+	}
+	for _, bundle := range bundles[1:] {
+		cmd = exec.CommandContext(ctx, "git", "fetch", "--progress", bundle, "+refs/*:refs/*")
+		cmd.Dir = tmpPath
+		if output, err := runWith(ctx, cmd, false, progress); err != nil {
+			return fail("failed to fetch from bundle "+filepath.Base(bundle), err, output)
+		}
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "remote", "set-url", "origin", "--", url)
+	cmd.Dir = tmpPath
+	if output, err := runWith(ctx, cmd, false, nil); err != nil {
+		return fail("failed to set remote URL of repo cloned from bundles", err, output)
+	}
+	reposClonedFromBundles.Inc()
+
+	cmd = exec.CommandContext(ctx, "git", "fetch", "--progress", "--prune", "origin", "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*", "+refs/sourcegraph/*:refs/sourcegraph/*")
+	cmd.Dir = tmpPath
+	if output, err := runWithRemoteOpts(ctx, cmd, progress); err != nil {
+		// 🚨 SECURITY: The output could include the remote URL, which may
+		// contain a sensitive token.
+		redactor := newURLRedactor(url)
+		log15.Warn("failed to fetch repo cloned from bundles", "repo", repo, "error", redactor.redact(err.Error()), "output", redactor.redact(string(output)))
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestHandleBundle(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	first := makeRepoWithCommit(t, src)
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	h := s.Handler()
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(context.Background(), repo, src, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// bundle requests a bundle and returns the path of the bundle file if
+	// the response is 200 OK.
+	bundle := func(repo string, since ...string) (int, string) {
+		t.Helper()
+		q := url.Values{"repo": {repo}, "since": since}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/bundle?"+q.Encode(), nil))
+		resp := rec.Result()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, ""
+		}
+		if msg := resp.Trailer.Get("X-Bundle-Error"); msg != "" {
+			t.Fatalf("bundle failed: %s", msg)
+		}
+		f, err := ioutil.TempFile(root, "*.bundle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.Write(rec.Body.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, f.Name()
+	}
+
+	status, full := bundle(string(repo))
+	if status != http.StatusOK {
+		t.Fatalf("got status %d, want 200", status)
+	}
+	if got := gitOutput(t, root, "bundle", "list-heads", full); !strings.Contains(got, first+" refs/heads/master") {
+		t.Errorf("got heads %q, want refs/heads/master at %s", got, first)
+	}
+
+	// Nothing changed since the full bundle.
+	if status, _ := bundle(string(repo), first); status != http.StatusNoContent {
+		t.Errorf("got status %d for an empty bundle, want 204", status)
+	}
+
+	gitOutput(t, src, "commit", "--allow-empty", "-m", "second")
+	if err := s.doRepoUpdate2(repo, src); err != nil {
+		t.Fatal(err)
+	}
+	status, incremental := bundle(string(repo), first)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d for an incremental bundle, want 200", status)
+	}
+	if got := gitOutput(t, src, "bundle", "verify", incremental); !strings.Contains(got, "requires this ref") || !strings.Contains(got, first) {
+		t.Errorf("got %q, want the incremental bundle to require %s", got, first)
+	}
+
+	// Commits that do not exist are ignored, so the bundle is full.
+	status, missing := bundle(string(repo), strings.Repeat("0", 40))
+	if status != http.StatusOK {
+		t.Fatalf("got status %d, want 200", status)
+	}
+	if got := gitOutput(t, src, "bundle", "verify", missing); !strings.Contains(got, "records a complete history") {
+		t.Errorf("got %q, want a complete bundle", got)
+	}
+
+	for _, tc := range []struct {
+		repo  string
+		since []string
+		want  int
+	}{
+		{"", nil, http.StatusBadRequest},
+		{"example.com/not/cloned", nil, http.StatusNotFound},
+		{string(repo), []string{"--output=x"}, http.StatusBadRequest},
+	} {
+		if status, _ := bundle(tc.repo, tc.since...); status != tc.want {
+			t.Errorf("repo %q since %v: got status %d, want %d", tc.repo, tc.since, status, tc.want)
+		}
+	}
+}
+
+func TestCloneRepo_fromBundles(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	first := makeRepoWithCommit(t, src)
+
+	repo := api.RepoName("example.com/foo/bar")
+	bundles := filepath.Join(root, "bundles")
+	repoBundles := filepath.Join(bundles, string(repo))
+	if err := os.MkdirAll(repoBundles, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, src, "bundle", "create", filepath.Join(repoBundles, "1.bundle"), "--all")
+	// The backup has a ref that the code host does not have, which tells us
+	// that the repo was seeded from the bundles.
+	gitOutput(t, src, "commit", "--allow-empty", "-m", "second")
+	gitOutput(t, src, "update-ref", "refs/backup/only-in-bundle", "HEAD")
+	gitOutput(t, src, "bundle", "create", filepath.Join(repoBundles, "2.bundle"), "--all", "^"+first)
+	gitOutput(t, src, "update-ref", "-d", "refs/backup/only-in-bundle")
+	gitOutput(t, src, "commit", "--allow-empty", "-m", "third")
+
+	s := &Server{ReposDir: filepath.Join(root, "repos"), BundlesDir: bundles}
+	_ = s.Handler()
+	if _, err := s.cloneRepo(context.Background(), repo, src, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	dir := string(s.dir(repo))
+	if got := gitOutput(t, dir, "log", "--format=%s", "master"); got != "third\nsecond\nhello" {
+		t.Errorf("got commits %q, want all commits of the code host", got)
+	}
+	if got := gitOutput(t, dir, "for-each-ref", "--format=%(refname)", "refs/backup"); got != "refs/backup/only-in-bundle" {
+		t.Errorf("got backup refs %q, want the ref from the bundle", got)
+	}
+	if got := gitOutput(t, dir, "remote", "get-url", "origin"); got != src {
+		t.Errorf("got remote URL %q, want %q", got, src)
+	}
+
+	// Repos are cloned from the code host if the bundles are unusable.
+	if err := ioutil.WriteFile(filepath.Join(repoBundles, "1.bundle"), []byte("not a bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.cloneRepo(context.Background(), repo, src, &cloneOptions{Block: true, Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitOutput(t, dir, "log", "--format=%s", "master"); got != "third\nsecond\nhello" {
+		t.Errorf("got commits %q, want all commits of the code host", got)
+	}
+	if got := gitOutput(t, dir, "for-each-ref", "--format=%(refname)", "refs/backup"); got != "" {
+		t.Errorf("got backup refs %q, want none", got)
+	}
+}
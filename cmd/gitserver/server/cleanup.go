@@ -56,9 +56,11 @@ var reposRecloned = prometheus.NewCounter(prometheus.CounterOpts{
 // cleanupRepos walks the repos directory and performs maintenance tasks:
 //
 // 1. Remove corrupt repos.
-// 2. Remove stale lock files.
-// 3. Remove inactive repos on sourcegraph.com
-// 4. Reclone repos after a while. (simulate git gc)
+// 2. Remove repos stored on another gitserver since the gitserver addresses changed.
+// 3. Remove stale lock files.
+// 4. Remove inactive repos on sourcegraph.com
+// 5. Reclone repos after a while. (simulate git gc)
+// 6. Write commit-graphs, pack objects and write bitmaps when due.
 func (s *Server) cleanupRepos() {
 	bCtx, bCancel := s.serverContext()
 	defer bCancel()
@@ -79,6 +81,30 @@ func (s *Server) cleanupRepos() {
 		return true, nil
 	}
 
+	maybeRemoveMoved := func(dir GitDir) (done bool, err error) {
+		// Another gitserver stores the repo since the gitserver addresses
+		// changed. We keep our copy until it has cloned the repo, since it
+		// clones the repo from us.
+		repo := s.name(dir)
+		owner := s.currentOwner(repo)
+		if owner == "" {
+			return false, nil
+		}
+		ctx, cancel := context.WithTimeout(bCtx, time.Minute)
+		defer cancel()
+		cloned, err := isClonedOnPeer(ctx, owner, repo)
+		if err != nil || !cloned {
+			return false, err
+		}
+
+		log15.Info("removing repo stored on another gitserver", "repo", repo, "owner", owner)
+		if err := s.removeRepoDirectory(dir); err != nil {
+			return true, err
+		}
+		reposMoved.Inc()
+		return true, nil
+	}
+
 	ensureGitAttributes := func(dir GitDir) (done bool, err error) {
 		return false, setGitAttributes(dir)
 	}
@@ -129,13 +155,46 @@ func (s *Server) cleanupRepos() {
 			return false, errors.Wrap(err, "failed to get remote URL")
 		}
 
-		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true}); err != nil {
+		// Keep cloning the repo as it was cloned before.
+		filter, depth, err := repoCloneStrategy(dir)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get clone strategy")
+		}
+
+		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{
+			Block:     true,
+			Overwrite: true,
+			LFS:       lfsEnabled(dir),
+			Filter:    filter,
+			Depth:     depth,
+		}); err != nil {
 			return true, err
 		}
 		reposRecloned.Inc()
 		return true, nil
 	}
 
+	maintenanceDeadline := time.Now().Add(maintenanceBudget)
+	maybeMaintain := func(dir GitDir) (done bool, err error) {
+		if time.Now().After(maintenanceDeadline) {
+			return false, nil
+		}
+		reason, err := maintenanceDue(dir, time.Now())
+		if err != nil || reason == "" {
+			return false, err
+		}
+
+		ctx, cancel := context.WithTimeout(bCtx, longGitCommandTimeout)
+		defer cancel()
+
+		log15.Debug("running git maintenance", "repo", dir, "reason", reason)
+		ok, err := s.maintainRepoLocked(ctx, dir)
+		if !ok {
+			log15.Debug("skipped git maintenance of locked repo", "repo", dir)
+		}
+		return false, err
+	}
+
 	removeStaleLocks := func(dir GitDir) (done bool, err error) {
 		gitDir := string(dir)
 
@@ -176,6 +235,9 @@ func (s *Server) cleanupRepos() {
 	cleanups := []cleanupFn{
 		// Do some sanity checks on the repository.
 		{"maybe remove corrupt", maybeRemoveCorrupt},
+		// Remove repos that moved to another gitserver when the gitserver
+		// addresses changed, once it has them.
+		{"maybe remove moved", maybeRemoveMoved},
 		// If git is interrupted it can leave lock files lying around. It does
 		// not clean these up, and instead fails commands.
 		{"remove stale locks", removeStaleLocks},
@@ -187,6 +249,10 @@ func (s *Server) cleanupRepos() {
 		// these problems. git gc is slow and resource intensive. It is
 		// cheaper and faster to just reclone the repository.
 		{"maybe reclone", maybeReclone},
+		// Without commit-graphs and bitmaps, git log and rev-list are slow on
+		// large repos. Incrementally write them, so they stay up to date
+		// between reclones.
+		{"maybe maintain", maybeMaintain},
 	}
 
 	err := bestEffortWalk(s.ReposDir, func(dir string, fi os.FileInfo) error {
@@ -461,7 +527,8 @@ func dirSize(d string) (int64, error) {
 // partial state in the event of server restart or concurrent modifications to
 // the directory.
 //
-// Additionally it removes parent empty directories up until s.ReposDir.
+// Additionally it removes parent empty directories up until s.ReposDir, and
+// the update history of the repo.
 func (s *Server) removeRepoDirectory(gitDir GitDir) error {
 	dir := string(gitDir)
 
@@ -478,6 +545,10 @@ func (s *Server) removeRepoDirectory(gitDir GitDir) error {
 	// Everything after this point is just cleanup, so any error that occurs
 	// should not be returned, just logged.
 
+	if err := s.removeUpdateHistory(s.name(gitDir)); err != nil {
+		log15.Warn("failed to remove update history", "dir", dir, "error", err)
+	}
+
 	// Cleanup empty parent directories. We just attempt to remove and if we
 	// have a failure we assume it's due to the directory having other
 	// children. If we checked first we could race with someone else adding a
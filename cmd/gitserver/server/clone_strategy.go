@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+)
+
+// Very large repos can be cloned partially (without some objects, usually
+// file contents) or shallowly (without old history), so that they finish
+// cloning within longGitCommandTimeout.
+//
+// Partial clones record the filter they were cloned with in the git config
+// of the origin remote, and git itself fetches missing objects from the
+// origin remote when they are needed. Shallow clones record the depth they
+// were cloned with in sourcegraph.cloneDepth.
+
+// validCloneFilter matches the partial clone filters repos can be cloned
+// with. It is kept in sync with the cloneStrategies option of code host
+// connections.
+var validCloneFilter = lazyregexp.New(`^(blob:none|blob:limit=[0-9]+[kmg]?|tree:0)$`)
+
+// cloneStrategyArgs returns the arguments to git clone which clone a repo as
+// specified by opts.
+func cloneStrategyArgs(opts *cloneOptions) ([]string, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	var args []string
+	if opts.Filter != "" {
+		if !validCloneFilter.MatchString(opts.Filter) {
+			return nil, fmt.Errorf("invalid partial clone filter %q", opts.Filter)
+		}
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.Depth < 0 {
+		return nil, fmt.Errorf("invalid shallow clone depth %d", opts.Depth)
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(opts.Depth))
+	}
+	return args, nil
+}
+
+// setCloneDepth records the depth of a shallow clone. A depth of 0 means the
+// repo was cloned with its full history.
+func setCloneDepth(dir GitDir, depth int) error {
+	if depth == 0 {
+		return gitConfigUnset(dir, "sourcegraph.cloneDepth")
+	}
+	return gitConfigSet(dir, "sourcegraph.cloneDepth", strconv.Itoa(depth))
+}
+
+// repoCloneStrategy returns the partial clone filter and the shallow clone
+// depth the repo at dir was cloned with.
+func repoCloneStrategy(dir GitDir) (filter string, depth int, err error) {
+	filter, err = gitConfigGet(dir, "remote.origin.partialclonefilter")
+	if err != nil {
+		return "", 0, err
+	}
+	value, err := gitConfigGet(dir, "sourcegraph.cloneDepth")
+	if err != nil {
+		return "", 0, err
+	}
+	if value = strings.TrimSpace(value); value != "" {
+		depth, err = strconv.Atoi(value)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "invalid cloneDepth")
+		}
+	}
+	return strings.TrimSpace(filter), depth, nil
+}
+
+// isPartialClone returns true if objects of the repo at dir may be missing.
+// It does not run git, since it is checked for every exec.
+func isPartialClone(dir GitDir) bool {
+	promisorPacks, _ := filepath.Glob(dir.Path("objects", "pack", "*.promisor"))
+	return len(promisorPacks) > 0
+}
+
+// fetchMissingObjects fetches the objects of the tree of treeish which are
+// missing in the partial clone at dir. Git would otherwise fetch them one at
+// a time when they are read, for example by git archive.
+func fetchMissingObjects(ctx context.Context, dir GitDir, treeish string) error {
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--objects", "--missing=print", "--no-walk", treeish, "--")
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to list missing objects")
+	}
+
+	var missing bytes.Buffer
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "?") {
+			missing.WriteString(line[1:] + "\n")
+		}
+	}
+	if missing.Len() == 0 {
+		return nil
+	}
+
+	// This is how git fetches missing objects itself, except that it fetches
+	// all of them at once.
+	cmd = exec.CommandContext(ctx, "git", "-c", "fetch.negotiationAlgorithm=noop", "fetch", "origin", "--no-tags", "--no-write-fetch-head", "--recurse-submodules=no", "--filter=blob:none", "--stdin")
+	cmd.Dir = string(dir)
+	cmd.Stdin = &missing
+	if output, err := runWithRemoteOpts(ctx, cmd, nil); err != nil {
+		// 🚨 SECURITY: The output could include the remote URL, which may
+		// contain a sensitive token.
+		msg := fmt.Sprintf("%s with output %q", err, output)
+		if remoteURL, _ := repoRemoteURL(ctx, dir); remoteURL != "" {
+			msg = newURLRedactor(remoteURL).redact(msg)
+		}
+		return errors.Errorf("failed to fetch missing objects: %s", msg)
+	}
+	return nil
+}
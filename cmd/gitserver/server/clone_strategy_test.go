@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestCloneRepo_strategy(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	makeRepoWithCommit(t, src)
+	gitOutput(t, src, "config", "uploadpack.allowFilter", "true")
+	commit := func(name string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+		gitOutput(t, src, "add", name)
+		gitOutput(t, src, "commit", "-m", name)
+	}
+	commit("a.txt")
+	commit("b.txt")
+	// Shallow clones from local paths are not supported.
+	remoteURL := "file://" + src
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	_ = s.Handler()
+	repo := api.RepoName("example.com/foo/bar")
+	dir := s.dir(repo)
+	if _, err := s.cloneRepo(context.Background(), repo, remoteURL, &cloneOptions{Block: true, Filter: "blob:none", Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkClone := func(wantCommits string) {
+		t.Helper()
+		filter, depth, err := repoCloneStrategy(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter != "blob:none" || depth != 1 {
+			t.Errorf("got filter %q and depth %d, want blob:none and 1", filter, depth)
+		}
+		if !isPartialClone(dir) {
+			t.Error("want partial clone")
+		}
+		if got := gitOutput(t, string(dir), "log", "--format=%s"); got != wantCommits {
+			t.Errorf("got commits %q, want %q", got, wantCommits)
+		}
+		if missing := missingObjects(t, dir); len(missing) == 0 {
+			t.Error("want missing objects")
+		}
+	}
+	checkClone("b.txt")
+
+	// Fetches keep the repo partial, and fetch the new history only.
+	commit("c.txt")
+	if err := s.doRepoUpdate2(repo, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	checkClone("c.txt\nb.txt")
+
+	if err := fetchMissingObjects(context.Background(), dir, "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if missing := missingObjects(t, dir, "--no-walk"); len(missing) != 0 {
+		t.Errorf("got missing objects %v in HEAD, want none", missing)
+	}
+}
+
+func TestCloneStrategyArgs(t *testing.T) {
+	for _, tc := range []struct {
+		opts    *cloneOptions
+		want    string
+		wantErr bool
+	}{
+		{nil, "", false},
+		{&cloneOptions{}, "", false},
+		{&cloneOptions{Filter: "blob:none", Depth: 100}, "--filter=blob:none --depth=100", false},
+		{&cloneOptions{Filter: "blob:limit=1m"}, "--filter=blob:limit=1m", false},
+		{&cloneOptions{Filter: "sparse:oid=HEAD"}, "", true},
+		{&cloneOptions{Depth: -1}, "", true},
+	} {
+		args, err := cloneStrategyArgs(tc.opts)
+		if got := strings.Join(args, " "); got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("%+v: got %q and error %v, want %q", tc.opts, got, err, tc.want)
+		}
+	}
+}
+
+// missingObjects returns the IDs of objects reachable from HEAD which are
+// missing in the partial clone at dir, without fetching them.
+func missingObjects(t *testing.T, dir GitDir, args ...string) []string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"rev-list", "--objects", "--missing=print"}, append(args, "HEAD")...)...)
+	cmd.Dir = string(dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	var missing []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "?") {
+			missing = append(missing, line[1:])
+		}
+	}
+	return missing
+}
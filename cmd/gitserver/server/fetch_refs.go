@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Some code hosts keep refs outside of the namespaces we fetch, which are too
+// many to fetch all of them, such as the refs/changes/* refs of Gerrit
+// changes. repo-updater requests the ones that should be kept up to date, such
+// as the refs of open changes, which are recorded in sourcegraph.fetchRefs and
+// fetched along with the branches and tags. A clone fetches all refs, so the
+// refs in selectiveRefNamespaces which were not requested are deleted after a
+// clone.
+
+// selectiveRefNamespaces are the namespaces of refs which are only kept if they
+// are requested.
+var selectiveRefNamespaces = []string{"refs/changes/"}
+
+// repoFetchRefs returns the refs that are fetched for the repo at dir in
+// addition to branches and tags.
+func repoFetchRefs(dir GitDir) ([]string, error) {
+	value, err := gitConfigGet(dir, "sourcegraph.fetchRefs")
+	if err != nil || strings.TrimSpace(value) == "" {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+// setFetchRefs records the refs that are fetched for the repo at dir in
+// addition to branches and tags.
+func setFetchRefs(dir GitDir, refs []string) error {
+	if len(refs) == 0 {
+		return gitConfigUnset(dir, "sourcegraph.fetchRefs")
+	}
+	return gitConfigSet(dir, "sourcegraph.fetchRefs", strings.Join(refs, " "))
+}
+
+// updateFetchRefs records refs as the refs that are fetched for the repo at
+// dir, and deletes the refs that were fetched before but are no longer
+// listed, such as the refs of Gerrit changes which were merged.
+func updateFetchRefs(ctx context.Context, dir GitDir, refs []string) error {
+	for _, ref := range refs {
+		if !validFetchRef(ref) {
+			return errors.Errorf("invalid ref %q", ref)
+		}
+	}
+
+	current, err := repoFetchRefs(dir)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		keep[ref] = true
+	}
+	var stale []string
+	for _, ref := range current {
+		if !keep[ref] {
+			stale = append(stale, ref)
+		}
+	}
+	if len(stale) == 0 && len(current) == len(refs) {
+		return nil
+	}
+
+	if err := deleteRefs(ctx, dir, stale); err != nil {
+		return errors.Wrap(err, "failed to delete stale refs")
+	}
+	return setFetchRefs(dir, refs)
+}
+
+// pruneUnrequestedRefs deletes the refs in selectiveRefNamespaces of the repo at
+// dir which are not in refs. It is called after a clone, which fetches all of
+// them, such as the refs of every patch set of every Gerrit change.
+func pruneUnrequestedRefs(ctx context.Context, dir GitDir, refs []string) error {
+	keep := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		keep[ref] = true
+	}
+
+	args := append([]string{"for-each-ref", "--format=%(refname)"}, selectiveRefNamespaces...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to list refs")
+	}
+
+	var unrequested []string
+	for _, ref := range strings.Split(string(out), "\n") {
+		if ref != "" && !keep[ref] {
+			unrequested = append(unrequested, ref)
+		}
+	}
+	return errors.Wrap(deleteRefs(ctx, dir, unrequested), "failed to delete unrequested refs")
+}
+
+// deleteRefs deletes refs from the repo at dir.
+func deleteRefs(ctx context.Context, dir GitDir, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	var stdin strings.Builder
+	for _, ref := range refs {
+		stdin.WriteString("delete " + ref + "\n")
+	}
+	cmd := exec.CommandContext(ctx, "git", "update-ref", "--stdin")
+	cmd.Dir = string(dir)
+	cmd.Stdin = strings.NewReader(stdin.String())
+	if err := cmd.Run(); err != nil {
+		return wrapCmdError(cmd, err)
+	}
+	return nil
+}
+
+// fetchRefspecs returns the refspecs which fetch refs into the same refs.
+func fetchRefspecs(refs []string) []string {
+	refspecs := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		refspecs = append(refspecs, "+"+ref+":"+ref)
+	}
+	return refspecs
+}
+
+// validFetchRef returns true if ref is a full ref name outside of the
+// namespaces we fetch anyway, which can't be mistaken for a git option.
+func validFetchRef(ref string) bool {
+	if !strings.HasPrefix(ref, "refs/") || strings.ContainsAny(ref, " \t\n*:") {
+		return false
+	}
+	for _, ns := range []string{"refs/heads/", "refs/tags/", "refs/pull/", "refs/sourcegraph/"} {
+		if strings.HasPrefix(ref, ns) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestFetchRefs(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	makeRepoWithCommit(t, src)
+	gitOutput(t, src, "update-ref", "refs/changes/01/1/1", "HEAD")
+	gitOutput(t, src, "update-ref", "refs/changes/02/2/1", "HEAD")
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	_ = s.Handler()
+	repo := api.RepoName("example.com/foo/bar")
+	dir := s.dir(repo)
+	if _, err := s.cloneRepo(context.Background(), repo, src, &cloneOptions{Block: true, Refs: []string{"refs/changes/01/1/1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRefs := func(want []string) {
+		t.Helper()
+		if got, err := repoFetchRefs(dir); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("got fetched refs %v and error %v, want %v", got, err, want)
+		}
+	}
+	checkRefs([]string{"refs/changes/01/1/1"})
+
+	// The clone fetched every change ref, but only the requested ones are kept.
+	if got, want := gitOutput(t, string(dir), "for-each-ref", "--format=%(refname)", "refs/changes/"), "refs/changes/01/1/1"; got != want {
+		t.Errorf("got refs %q after the clone, want %q", got, want)
+	}
+
+	// A new change is fetched, and the ref of the change which is no longer
+	// requested is deleted.
+	gitOutput(t, src, "commit", "--allow-empty", "-m", "change 3")
+	gitOutput(t, src, "update-ref", "refs/changes/03/3/1", "HEAD")
+	gitOutput(t, src, "reset", "--hard", "HEAD~")
+	if err := updateFetchRefs(context.Background(), dir, []string{"refs/changes/03/3/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.doRepoUpdate2(repo, src); err != nil {
+		t.Fatal(err)
+	}
+	checkRefs([]string{"refs/changes/03/3/1"})
+
+	got := gitOutput(t, string(dir), "for-each-ref", "--format=%(refname)", "refs/changes/")
+	if want := "refs/changes/03/3/1"; got != want {
+		t.Errorf("got refs %q, want %q", got, want)
+	}
+	if got, want := gitOutput(t, string(dir), "log", "-1", "--format=%s", "refs/changes/03/3/1"), "change 3"; got != want {
+		t.Errorf("got commit %q for the new change, want %q", got, want)
+	}
+
+	if err := updateFetchRefs(context.Background(), dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkRefs(nil)
+}
+
+func TestCloneRepo_withoutRefs(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	makeRepoWithCommit(t, src)
+	gitOutput(t, src, "update-ref", "refs/changes/01/1/1", "HEAD")
+	gitOutput(t, src, "update-ref", "refs/notes/commits", "HEAD")
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	_ = s.Handler()
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(context.Background(), repo, src, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the change refs are pruned.
+	got := gitOutput(t, string(s.dir(repo)), "for-each-ref", "--format=%(refname)", "refs/changes/", "refs/notes/")
+	if want := "refs/notes/commits"; got != want {
+		t.Errorf("got refs %q, want %q", got, want)
+	}
+}
+
+func TestValidFetchRef(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"refs/changes/45/12345/3": true,
+		"refs/notes/commits":      true,
+		"refs/heads/master":       false,
+		"refs/tags/v1":            false,
+		"refs/changes/*":          false,
+		"refs/a:refs/b":           false,
+		"--upload-pack=evil":      false,
+		"HEAD":                    false,
+	} {
+		if got := validFetchRef(ref); got != want {
+			t.Errorf("%s: got %t, want %t", ref, got, want)
+		}
+	}
+	if err := updateFetchRefs(context.Background(), GitDir("/nonexistent"), []string{"refs/heads/master"}); err == nil || !strings.Contains(err.Error(), "invalid ref") {
+		t.Errorf("got error %v, want invalid ref", err)
+	}
+}
@@ -0,0 +1,532 @@
+package server
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/lfs"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func init() {
+	prometheus.MustRegister(lfsObjectsFetched)
+	prometheus.MustRegister(lfsFetchErrors)
+}
+
+var lfsObjectsFetched = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "lfs_objects_fetched",
+	Help:      "number of Git LFS objects fetched from code hosts",
+})
+
+var lfsFetchErrors = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "lfs_fetch_errors",
+	Help:      "number of failed fetches of Git LFS objects of a repo",
+})
+
+const (
+	// lfsMaxFileSize is the largest LFS object we fetch, unless its path
+	// matches the search.largeFiles site configuration. It is the same limit
+	// searcher applies to the files it searches.
+	lfsMaxFileSize = 1 << 20 // 1MB
+
+	// lfsMaxStoreSize bounds the total size of the LFS objects we store per
+	// repo.
+	lfsMaxStoreSize = 1 << 30 // 1GB
+
+	// lfsBatchSize is the number of objects we ask for in a single request
+	// to the LFS batch API.
+	lfsBatchSize = 100
+)
+
+var lfsHTTPClient = func() httpcli.Doer {
+	// We don't use the cache of the external HTTP client, since LFS objects
+	// can be large and we store them ourselves.
+	cli, err := httpcli.NewFactory(
+		httpcli.NewMiddleware(httpcli.ContextErrorMiddleware),
+		httpcli.ExternalTransportOpt,
+	).Doer()
+	if err != nil {
+		log15.Error("failed to create LFS HTTP client", "error", err)
+		return http.DefaultClient
+	}
+	return cli
+}()
+
+// lfsEnabled returns true if LFS objects are fetched for the repo at dir.
+func lfsEnabled(dir GitDir) bool {
+	value, _ := gitConfigGet(dir, "sourcegraph.lfs")
+	return strings.TrimSpace(value) == "true"
+}
+
+// setLFSEnabled sets whether LFS objects are fetched for the repo at dir. The
+// stored objects are removed when it is disabled.
+func setLFSEnabled(dir GitDir, enabled bool) error {
+	if enabled {
+		return gitConfigSet(dir, "sourcegraph.lfs", "true")
+	}
+	if err := gitConfigUnset(dir, "sourcegraph.lfs"); err != nil {
+		return err
+	}
+	return os.RemoveAll(lfsObjectsDir(dir))
+}
+
+func lfsObjectsDir(dir GitDir) string {
+	return dir.Path("lfs", "objects")
+}
+
+// lfsPointer is a pointer file found in a tree.
+type lfsPointer struct {
+	Path string
+	lfs.Pointer
+}
+
+// lfsPointers returns the LFS pointer files in the tree of treeish.
+func lfsPointers(ctx context.Context, dir GitDir, treeish string) ([]lfsPointer, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-tree", "-r", "-l", "-z", treeish)
+	cmd.Dir = string(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(wrapCmdError(cmd, err), "failed to list files")
+	}
+
+	// Only small blobs can be pointers. Each entry is of the form
+	// "<mode> SP <type> SP <object> SP+ <size> TAB <path>".
+	var paths, oids []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		i := strings.IndexByte(entry, '\t')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:i])
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		if size, err := strconv.Atoi(fields[3]); err != nil || size > lfs.MaxPointerSize {
+			continue
+		}
+		paths = append(paths, entry[i+1:])
+		oids = append(oids, fields[2])
+	}
+	if len(oids) == 0 {
+		return nil, nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = string(dir)
+	cmd.Stdin = strings.NewReader(strings.Join(oids, "\n") + "\n")
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(wrapCmdError(cmd, err), "failed to read blobs")
+	}
+
+	// The output for each blob is "<oid> SP <type> SP <size> LF <content> LF".
+	var pointers []lfsPointer
+	r := bufio.NewReader(bytes.NewReader(out))
+	for _, path := range paths {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read blob header")
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("unexpected blob header %q", header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unexpected blob header %q", header)
+		}
+		content := make([]byte, size+1)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, errors.Wrap(err, "failed to read blob")
+		}
+		if p, ok := lfs.ParsePointer(content[:size]); ok {
+			pointers = append(pointers, lfsPointer{Path: path, Pointer: p})
+		}
+	}
+	return pointers, nil
+}
+
+// wantedLFSObjects returns the objects of pointers that we store. Large
+// objects are skipped like searcher skips large files, and the total size is
+// bounded by lfsMaxStoreSize.
+func wantedLFSObjects(pointers []lfsPointer, largeFilePatterns []string) []lfs.Pointer {
+	sort.Slice(pointers, func(i, j int) bool { return pointers[i].Path < pointers[j].Path })
+
+	var wanted []lfs.Pointer
+	seen := map[string]bool{}
+	var total int64
+	for _, p := range pointers {
+		if seen[p.OID] {
+			continue
+		}
+		if p.Size > lfsMaxFileSize && !matchesAnyPattern(p.Path, largeFilePatterns) {
+			continue
+		}
+		if total+p.Size > lfsMaxStoreSize {
+			continue
+		}
+		seen[p.OID] = true
+		total += p.Size
+		wanted = append(wanted, p.Pointer)
+	}
+	return wanted
+}
+
+// matchesAnyPattern is like the check searcher uses for search.largeFiles.
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if m, _ := filepath.Match(strings.TrimSpace(pattern), name); m {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchLFSObjects fetches the LFS objects of the default branch of the repo
+// at dir from remoteURL, and removes stored objects which are no longer
+// needed.
+func fetchLFSObjects(ctx context.Context, dir GitDir, remoteURL string) error {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD")
+	cmd.Dir = string(dir)
+	if err := cmd.Run(); err != nil {
+		// The repo is empty.
+		return nil
+	}
+	pointers, err := lfsPointers(ctx, dir, "HEAD")
+	if err != nil {
+		return err
+	}
+	wanted := wantedLFSObjects(pointers, conf.Get().SearchLargeFiles)
+
+	objectsDir := lfsObjectsDir(dir)
+	keep := make(map[string]bool, len(wanted))
+	var missing []lfs.Pointer
+	for _, p := range wanted {
+		keep[p.OID] = true
+		if _, err := os.Stat(lfs.ObjectPath(objectsDir, p.OID)); os.IsNotExist(err) {
+			missing = append(missing, p)
+		}
+	}
+
+	var fetchErr error
+	if len(missing) > 0 {
+		fetchErr = downloadLFSObjects(ctx, objectsDir, remoteURL, missing)
+	}
+
+	// Remove the objects of files which were changed or removed.
+	err = bestEffortWalk(objectsDir, func(path string, fi os.FileInfo) error {
+		if fi.IsDir() || keep[fi.Name()] {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return err
+}
+
+// lfsEndpoint returns the LFS API endpoint of the remote, and the
+// credentials in the remote URL.
+//
+// See https://github.com/git-lfs/git-lfs/blob/master/docs/api/server-discovery.md.
+func lfsEndpoint(remoteURL string) (string, *url.Userinfo, error) {
+	u, err := url.Parse(remoteURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil, errors.New("Git LFS is only supported for HTTP(S) remote URLs")
+	}
+	user := u.User
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(u.Path, ".git") {
+		u.Path += ".git"
+	}
+	u.Path += "/info/lfs"
+	return u.String(), user, nil
+}
+
+type lfsBatchRequest struct {
+	Operation string         `json:"operation"`
+	Transfers []string       `json:"transfers"`
+	Objects   []lfsBatchSpec `json:"objects"`
+}
+
+type lfsBatchSpec struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfsBatchSpec
+		Actions struct {
+			Download *struct {
+				Href   string            `json:"href"`
+				Header map[string]string `json:"header"`
+			} `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// downloadLFSObjects downloads objects to objectsDir with the LFS batch API.
+//
+// See https://github.com/git-lfs/git-lfs/blob/master/docs/api/batch.md.
+func downloadLFSObjects(ctx context.Context, objectsDir, remoteURL string, objects []lfs.Pointer) error {
+	endpoint, user, err := lfsEndpoint(remoteURL)
+	if err != nil {
+		return err
+	}
+
+	for len(objects) > 0 {
+		batch := objects
+		if len(batch) > lfsBatchSize {
+			batch = batch[:lfsBatchSize]
+		}
+		objects = objects[len(batch):]
+
+		req := lfsBatchRequest{Operation: "download", Transfers: []string{"basic"}}
+		for _, p := range batch {
+			req.Objects = append(req.Objects, lfsBatchSpec{OID: p.OID, Size: p.Size})
+		}
+		var resp lfsBatchResponse
+		if err := lfsBatch(ctx, endpoint, user, &req, &resp); err != nil {
+			return err
+		}
+
+		for _, obj := range resp.Objects {
+			if obj.Error != nil {
+				// The object is missing on the code host, we show the pointer.
+				log15.Debug("LFS object not available", "oid", obj.OID, "code", obj.Error.Code, "message", obj.Error.Message)
+				continue
+			}
+			if obj.Actions.Download == nil || !lfs.IsValidOID(obj.OID) {
+				continue
+			}
+			if err := downloadLFSObject(ctx, objectsDir, obj.lfsBatchSpec, obj.Actions.Download.Href, obj.Actions.Download.Header); err != nil {
+				return errors.Wrapf(err, "failed to download LFS object %s", obj.OID)
+			}
+			lfsObjectsFetched.Inc()
+		}
+	}
+	return nil
+}
+
+func lfsBatch(ctx context.Context, endpoint string, user *url.Userinfo, req *lfsBatchRequest, resp *lfsBatchResponse) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest("POST", endpoint+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", "application/vnd.git-lfs+json")
+	r.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	if user != nil {
+		password, _ := user.Password()
+		r.SetBasicAuth(user.Username(), password)
+	}
+
+	res, err := lfsHTTPClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "LFS batch request failed")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("LFS batch request failed with status %d", res.StatusCode)
+	}
+	return errors.Wrap(json.NewDecoder(res.Body).Decode(resp), "invalid LFS batch response")
+}
+
+// downloadLFSObject downloads the object to objectsDir, checking that its
+// content matches the object ID.
+func downloadLFSObject(ctx context.Context, objectsDir string, obj lfsBatchSpec, href string, header map[string]string) error {
+	r, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	res, err := lfsHTTPClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("status %d", res.StatusCode)
+	}
+
+	path := lfs.ObjectPath(objectsDir, obj.OID)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), obj.OID+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(res.Body, obj.Size+1))
+	if err != nil {
+		return err
+	}
+	if n != obj.Size || hex.EncodeToString(h.Sum(nil)) != obj.OID {
+		return errors.New("content does not match the object ID")
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// openLFSObject opens the stored object oid of the repo at dir.
+func openLFSObject(dir GitDir, oid string) (*os.File, error) {
+	if !lfs.IsValidOID(oid) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(lfs.ObjectPath(lfsObjectsDir(dir), oid))
+}
+
+// handleLFSObject serves a stored LFS object. It responds with 404 if the
+// object is not stored.
+func (s *Server) handleLFSObject(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	repo := protocol.NormalizeRepo(api.RepoName(q.Get("repo")))
+	f, err := openLFSObject(s.dir(repo), q.Get("oid"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "LFS object not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		log15.Error("failed to serve LFS object", "repo", repo, "error", err)
+	}
+}
+
+// lfsArchiveWriter replaces LFS pointer files in the tar archive written by
+// git archive with the stored objects.
+type lfsArchiveWriter struct {
+	http.ResponseWriter
+	dir  GitDir
+	pw   *io.PipeWriter
+	done chan struct{}
+}
+
+func newLFSArchiveWriter(w http.ResponseWriter, dir GitDir) *lfsArchiveWriter {
+	return &lfsArchiveWriter{ResponseWriter: w, dir: dir}
+}
+
+func (w *lfsArchiveWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		// Only the archive is rewritten, not errors.
+		pr, pw := io.Pipe()
+		w.pw = pw
+		w.done = make(chan struct{})
+		go func() {
+			defer close(w.done)
+			if err := smudgeTar(w.ResponseWriter, pr, w.dir); err != nil {
+				log15.Error("failed to replace LFS pointers in archive", "repo", w.dir, "error", err)
+			}
+			// Unblock git archive, which pads the archive after the end
+			// marker.
+			_, _ = io.Copy(ioutil.Discard, pr)
+		}()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *lfsArchiveWriter) Write(p []byte) (int, error) {
+	if w.pw == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.pw.Write(p)
+}
+
+// Close waits until the archive is written.
+func (w *lfsArchiveWriter) Close() {
+	if w.pw != nil {
+		w.pw.Close()
+		<-w.done
+	}
+}
+
+// smudgeTar copies the tar archive r to w, replacing LFS pointer files with
+// the objects stored for the repo at dir.
+func smudgeTar(w io.Writer, r io.Reader, dir GitDir) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		var object *os.File
+		var body io.Reader = tr
+		if hdr.Typeflag == tar.TypeReg && hdr.Size <= lfs.MaxPointerSize {
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			body = bytes.NewReader(content)
+			if p, ok := lfs.ParsePointer(content); ok {
+				if object, err = openLFSObject(dir, p.OID); err == nil {
+					hdr.Size = p.Size
+					body = object
+				}
+			}
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err == nil {
+			_, err = io.Copy(tw, body)
+		}
+		if object != nil {
+			object.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/lfs"
+)
+
+func TestFetchLFSObjects(t *testing.T) {
+	small := []byte("small file stored with LFS\n")
+	large := bytes.Repeat([]byte("x"), lfsMaxFileSize+1)
+	missing := []byte("missing on the code host\n")
+	objects := map[string][]byte{}
+	for _, content := range [][]byte{small, large} {
+		objects[oidOf(content)] = content
+	}
+
+	var gotAuth string
+	var downloaded []string
+	lfsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/foo/bar.git/info/lfs/objects/batch":
+			_, password, _ := r.BasicAuth()
+			gotAuth = password
+			var req lfsBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var resp []interface{}
+			for _, obj := range req.Objects {
+				if _, ok := objects[obj.OID]; !ok {
+					resp = append(resp, map[string]interface{}{"oid": obj.OID, "size": obj.Size, "error": map[string]interface{}{"code": 404, "message": "not found"}})
+					continue
+				}
+				resp = append(resp, map[string]interface{}{
+					"oid":     obj.OID,
+					"size":    obj.Size,
+					"actions": map[string]interface{}{"download": map[string]interface{}{"href": "http://" + r.Host + "/objects/" + obj.OID}},
+				})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"objects": resp})
+		case strings.HasPrefix(r.URL.Path, "/objects/"):
+			oid := strings.TrimPrefix(r.URL.Path, "/objects/")
+			downloaded = append(downloaded, oid)
+			_, _ = w.Write(objects[oid])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer lfsServer.Close()
+	remoteURL := strings.Replace(lfsServer.URL, "http://", "http://user:secret@", 1) + "/foo/bar"
+
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	makeRepoWithCommit(t, src)
+	for name, content := range map[string][]byte{
+		"small.txt":   pointerOf(small),
+		"large.bin":   pointerOf(large),
+		"missing.txt": pointerOf(missing),
+		"README":      []byte("not stored with LFS\n"),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), content, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitOutput(t, src, "add", ".")
+	gitOutput(t, src, "commit", "-m", "lfs")
+	gitOutput(t, root, "clone", "--mirror", src, "repo.git")
+	dir := GitDir(filepath.Join(root, "repo.git"))
+
+	if err := fetchLFSObjects(context.Background(), dir, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "secret" {
+		t.Errorf("got password %q, want the one in the remote URL", gotAuth)
+	}
+	if want := []string{oidOf(small)}; !reflect.DeepEqual(storedLFSObjects(t, dir), want) {
+		t.Errorf("got stored objects %v, want %v", storedLFSObjects(t, dir), want)
+	}
+
+	// Objects are not downloaded again, and are removed once they are not
+	// needed anymore.
+	downloaded = nil
+	if err := fetchLFSObjects(context.Background(), dir, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if len(downloaded) != 0 {
+		t.Errorf("got downloads %v, want none", downloaded)
+	}
+	gitOutput(t, src, "rm", "small.txt")
+	gitOutput(t, src, "commit", "-m", "remove")
+	gitOutput(t, string(dir), "fetch", "origin", "+refs/heads/*:refs/heads/*")
+	if err := fetchLFSObjects(context.Background(), dir, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedLFSObjects(t, dir); len(got) != 0 {
+		t.Errorf("got stored objects %v, want none", got)
+	}
+}
+
+func TestLFSEndpoint(t *testing.T) {
+	for remoteURL, want := range map[string]string{
+		"https://github.com/foo/bar":            "https://github.com/foo/bar.git/info/lfs",
+		"https://token@github.com/foo/bar.git/": "https://github.com/foo/bar.git/info/lfs",
+		"git@github.com:foo/bar.git":            "",
+		"ssh://git@bitbucket.example.com/a/b":   "",
+	} {
+		got, _, err := lfsEndpoint(remoteURL)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("%s: got %q, %v, want %q", remoteURL, got, err, want)
+		}
+	}
+}
+
+func TestHandleArchive_lfs(t *testing.T) {
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	s := &Server{ReposDir: reposDir}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	repo := api.RepoName("example.com/foo/bar")
+	work := filepath.Dir(string(s.dir(repo)))
+	makeRepoWithCommit(t, work)
+	content := []byte("stored with LFS\n")
+	notStored := pointerOf([]byte("not stored\n"))
+	if err := ioutil.WriteFile(filepath.Join(work, "a.txt"), pointerOf(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(work, "b.txt"), notStored, 0666); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, work, "add", ".")
+	gitOutput(t, work, "commit", "-m", "lfs")
+	dir := s.dir(repo)
+	writeLFSObject(t, dir, content)
+
+	archive := func() map[string]string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/archive?repo=" + string(repo) + "&treeish=HEAD&format=tar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		files := map[string]string{}
+		tr := tar.NewReader(resp.Body)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[hdr.Name] = string(b)
+		}
+		return files
+	}
+
+	// Pointers are kept unless LFS is enabled.
+	if got := archive()["a.txt"]; got != string(pointerOf(content)) {
+		t.Errorf("LFS disabled: got a.txt %q, want pointer", got)
+	}
+
+	if err := setLFSEnabled(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	files := archive()
+	if files["a.txt"] != string(content) {
+		t.Errorf("got a.txt %q, want %q", files["a.txt"], content)
+	}
+	if files["b.txt"] != string(notStored) {
+		t.Errorf("got b.txt %q, want pointer", files["b.txt"])
+	}
+
+	// The object is also served on its own.
+	for oid, want := range map[string]int{oidOf(content): http.StatusOK, oidOf([]byte("not stored\n")): http.StatusNotFound, "../../config": http.StatusNotFound} {
+		resp, err := http.Get(ts.URL + "/lfs-object?repo=" + string(repo) + "&oid=" + oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("lfs-object %s: got status %d, want %d", oid, resp.StatusCode, want)
+		}
+	}
+}
+
+func oidOf(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func pointerOf(content []byte) []byte {
+	return []byte(fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oidOf(content), len(content)))
+}
+
+func writeLFSObject(t *testing.T, dir GitDir, content []byte) {
+	t.Helper()
+	path := lfs.ObjectPath(lfsObjectsDir(dir), oidOf(content))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func storedLFSObjects(t *testing.T, dir GitDir) []string {
+	t.Helper()
+	var oids []string
+	err := filepath.Walk(lfsObjectsDir(dir), func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			oids = append(oids, fi.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(oids)
+	return oids
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func init() {
+	prometheus.MustRegister(maintenanceRuns)
+	prometheus.MustRegister(maintenanceStepDuration)
+}
+
+var maintenanceRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "maintenance_runs",
+	Help:      "number of git maintenance runs on repos, by status",
+}, []string{"status"})
+
+var maintenanceStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "maintenance_step_duration_seconds",
+	Help:      "time taken by each step of git maintenance",
+	Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+}, []string{"step"})
+
+const (
+	// maintenancePackLimit is the number of packfiles after which a repo is
+	// maintained regardless of when it was last maintained. Each fetch can
+	// add a packfile, so frequently fetched repos are maintained more often.
+	maintenancePackLimit = 20
+
+	// maintenanceBudget is how long a single janitor run may spend starting
+	// maintenance runs. Repos which are due once it is used up are
+	// maintained during a later janitor run.
+	maintenanceBudget = 10 * time.Minute
+)
+
+// maintenanceInterval returns how often a repo whose objects take up size
+// bytes is maintained. Large repos benefit most from commit-graphs and
+// bitmaps, so they are maintained more often.
+func maintenanceInterval(size int64) time.Duration {
+	const M = 1024 * 1024
+	switch {
+	case size >= 1024*M:
+		return 6 * time.Hour
+	case size >= 100*M:
+		return 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
+
+// maintenanceSteps are the git commands run to maintain a repo, in order. A
+// step is skipped if Args returns no arguments.
+// They only ever add to the existing commit-graph and packfiles, so they are
+// cheap compared to git gc even on large repos.
+var maintenanceSteps = []struct {
+	Name string
+	Args func(dir GitDir) ([]string, error)
+}{
+	{"commit-graph", staticArgs("commit-graph", "write", "--reachable", "--split")},
+	// Pack loose objects. Incremental packs can't have bitmaps, those are
+	// written for the multi-pack-index below.
+	{"repack", staticArgs("repack", "-d", "-l", "--no-write-bitmap-index")},
+	{"multi-pack-index-write", staticArgs("multi-pack-index", "write")},
+	{"multi-pack-index-expire", staticArgs("multi-pack-index", "expire")},
+	{"multi-pack-index-repack", func(dir GitDir) ([]string, error) {
+		batchSize, err := multiPackIndexBatchSize(dir)
+		if err != nil || batchSize == 0 {
+			return nil, err
+		}
+		return []string{"multi-pack-index", "repack", "--batch-size=" + strconv.FormatInt(batchSize, 10)}, nil
+	}},
+	{"multi-pack-index-bitmap", staticArgs("multi-pack-index", "write", "--bitmap")},
+}
+
+func staticArgs(args ...string) func(GitDir) ([]string, error) {
+	return func(GitDir) ([]string, error) { return args, nil }
+}
+
+// multiPackIndexBatchSize returns the batch size for git multi-pack-index
+// repack. Like git maintenance's incremental-repack task, it is one more than
+// the size of the second largest packfile, so that all smaller packfiles are
+// combined without rewriting the largest one.
+func multiPackIndexBatchSize(dir GitDir) (int64, error) {
+	sizes, err := packSizes(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(sizes) < 2 {
+		// There is nothing to combine.
+		return 0, nil
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+	const maxBatchSize = 1<<32 - 1
+	if sizes[1] >= maxBatchSize {
+		return maxBatchSize, nil
+	}
+	return sizes[1] + 1, nil
+}
+
+// packSizes returns the sizes in bytes of the packfiles of the repo.
+func packSizes(dir GitDir) ([]int64, error) {
+	paths, err := filepath.Glob(dir.Path("objects", "pack", "*.pack"))
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]int64, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, fi.Size())
+	}
+	return sizes, nil
+}
+
+// maintenanceDue returns a non-empty reason if the repo at dir should be
+// maintained now.
+func maintenanceDue(dir GitDir, now time.Time) (string, error) {
+	status, err := getMaintenanceStatus(dir)
+	if err != nil {
+		return "", err
+	}
+	if status != nil {
+		// Nothing changed since the last run.
+		lastFetched, err := repoLastFetched(dir)
+		if err != nil {
+			return "", err
+		}
+		if lastFetched.Before(status.LastRun) {
+			return "", nil
+		}
+	}
+
+	sizes, err := packSizes(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(sizes) >= maintenancePackLimit {
+		return "packs", nil
+	}
+	if status == nil {
+		return "never", nil
+	}
+
+	size, err := dirSize(dir.Path("objects"))
+	if err != nil {
+		return "", err
+	}
+	interval := maintenanceInterval(size)
+	// Add a jitter to spread out maintenance of repos cloned at the same
+	// time.
+	if now.Sub(status.LastRun) > interval+jitterDuration(string(dir), interval/4) {
+		return "old", nil
+	}
+	return "", nil
+}
+
+// maintainRepoLocked runs maintainRepo on the repo at dir while holding its
+// lock, so that it is not cloned or fetched concurrently. It waits for a
+// running fetch of the repo, but skips the repo and returns false if it is
+// locked, such as while it is being cloned.
+func (s *Server) maintainRepoLocked(ctx context.Context, dir GitDir) (bool, error) {
+	lock, ok := s.locker.TryAcquire(dir, "running git maintenance")
+	if !ok {
+		return false, nil
+	}
+	defer lock.Release()
+
+	mu := s.repoUpdateLock(s.name(dir)).mu
+	mu.Lock()
+	defer mu.Unlock()
+
+	return true, maintainRepo(ctx, dir)
+}
+
+// maintainRepo runs the maintenance steps on the repo at dir, and records
+// the outcome in its git config.
+func maintainRepo(ctx context.Context, dir GitDir) error {
+	start := time.Now()
+	err := runMaintenanceSteps(ctx, dir)
+
+	status := protocol.MaintenanceStatus{
+		LastRun:  start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		status.Error = err.Error()
+		maintenanceRuns.WithLabelValues("failure").Inc()
+	} else {
+		maintenanceRuns.WithLabelValues("success").Inc()
+	}
+	if err2 := setMaintenanceStatus(dir, status); err2 != nil {
+		log15.Warn("failed to store maintenance status", "repo", dir, "error", err2)
+	}
+	return err
+}
+
+func runMaintenanceSteps(ctx context.Context, dir GitDir) error {
+	for _, step := range maintenanceSteps {
+		args, err := step.Args(dir)
+		if err != nil {
+			return errors.Wrapf(err, "maintenance step %s", step.Name)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		start := time.Now()
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = string(dir)
+		out, err := cmd.CombinedOutput()
+		maintenanceStepDuration.WithLabelValues(step.Name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			return errors.Wrapf(wrapCmdError(cmd, err), "maintenance step %s failed with output %q", step.Name, out)
+		}
+	}
+	return nil
+}
+
+// setMaintenanceStatus stores the status of the most recent maintenance run
+// in the git config of the repo.
+func setMaintenanceStatus(dir GitDir, status protocol.MaintenanceStatus) error {
+	if err := gitConfigSet(dir, "sourcegraph.maintenanceTimestamp", strconv.FormatInt(status.LastRun.Unix(), 10)); err != nil {
+		return err
+	}
+	if err := gitConfigSet(dir, "sourcegraph.maintenanceDuration", status.Duration.String()); err != nil {
+		return err
+	}
+	if status.Error == "" {
+		return gitConfigUnset(dir, "sourcegraph.maintenanceError")
+	}
+	return gitConfigSet(dir, "sourcegraph.maintenanceError", status.Error)
+}
+
+// getMaintenanceStatus returns the status of the most recent maintenance run
+// of the repo, or nil if it was never maintained.
+func getMaintenanceStatus(dir GitDir) (*protocol.MaintenanceStatus, error) {
+	value, err := gitConfigGet(dir, "sourcegraph.maintenanceTimestamp")
+	if err != nil || value == "" {
+		return nil, err
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid maintenanceTimestamp")
+	}
+	status := &protocol.MaintenanceStatus{LastRun: time.Unix(sec, 0)}
+
+	value, err = gitConfigGet(dir, "sourcegraph.maintenanceDuration")
+	if err != nil {
+		return nil, err
+	}
+	if value != "" {
+		// A bad duration is not worth failing over.
+		status.Duration, _ = time.ParseDuration(strings.TrimSpace(value))
+	}
+
+	value, err = gitConfigGet(dir, "sourcegraph.maintenanceError")
+	if err != nil {
+		return nil, err
+	}
+	status.Error = strings.TrimSpace(value)
+	return status, nil
+}
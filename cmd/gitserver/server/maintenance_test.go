@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintainRepo(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	src := filepath.Join(root, "src")
+	makeRepoWithCommit(t, src)
+	gitOutput(t, root, "clone", "--mirror", src, "repo.git")
+	dir := GitDir(filepath.Join(root, "repo.git"))
+
+	// A fresh clone is maintained once.
+	now := time.Now()
+	if reason, err := maintenanceDue(dir, now); err != nil || reason != "never" {
+		t.Fatalf("got reason %q and error %v, want never", reason, err)
+	}
+	if err := maintainRepo(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		dir.Path("objects", "info", "commit-graphs", "commit-graph-chain"),
+		dir.Path("objects", "pack", "multi-pack-index"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+	if bitmaps, _ := filepath.Glob(dir.Path("objects", "pack", "multi-pack-index-*.bitmap")); len(bitmaps) != 1 {
+		t.Errorf("got bitmaps %q, want 1", bitmaps)
+	}
+
+	status, err := getMaintenanceStatus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil || status.Error != "" || now.Sub(status.LastRun) > time.Minute {
+		t.Fatalf("got status %+v, want a recent successful run", status)
+	}
+
+	// It is not due again until it is fetched and the interval passed.
+	orig := repoLastFetched
+	defer func() { repoLastFetched = orig }()
+	for _, tc := range []struct {
+		name        string
+		lastFetched time.Time
+		now         time.Time
+		want        string
+	}{
+		{"not fetched", status.LastRun.Add(-time.Hour), status.LastRun.Add(365 * 24 * time.Hour), ""},
+		{"fetched", status.LastRun.Add(time.Hour), status.LastRun.Add(2 * time.Hour), ""},
+		{"fetched and old", status.LastRun.Add(time.Hour), status.LastRun.Add(10 * 24 * time.Hour), "old"},
+	} {
+		repoLastFetched = func(GitDir) (time.Time, error) { return tc.lastFetched, nil }
+		if got, err := maintenanceDue(dir, tc.now); err != nil || got != tc.want {
+			t.Errorf("%s: got reason %q and error %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestMaintainRepo_error(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	makeRepoWithCommit(t, root)
+	dir := GitDir(filepath.Join(root, ".git"))
+
+	orig := maintenanceSteps
+	defer func() { maintenanceSteps = orig }()
+	maintenanceSteps = append(maintenanceSteps[:1:1], struct {
+		Name string
+		Args func(dir GitDir) ([]string, error)
+	}{"bad", staticArgs("not-a-git-command")})
+
+	if err := maintainRepo(context.Background(), dir); err == nil {
+		t.Fatal("expected error")
+	}
+	status, err := getMaintenanceStatus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil || !strings.Contains(status.Error, "maintenance step bad failed") {
+		t.Errorf("got status %+v, want failed run", status)
+	}
+}
+
+func TestMaintainRepoLocked(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	makeRepoWithCommit(t, filepath.Join(root, "repo"))
+	dir := GitDir(filepath.Join(root, "repo", ".git"))
+
+	s := &Server{ReposDir: root}
+	s.Handler()
+
+	// A repo which is being cloned is skipped.
+	lock, _ := s.locker.TryAcquire(dir, "cloning")
+	if ok, err := s.maintainRepoLocked(context.Background(), dir); ok || err != nil {
+		t.Fatalf("got %v and error %v for locked repo, want skipped", ok, err)
+	}
+	if status, err := getMaintenanceStatus(dir); err != nil || status != nil {
+		t.Fatalf("got status %+v and error %v, want no run", status, err)
+	}
+	lock.Release()
+
+	if ok, err := s.maintainRepoLocked(context.Background(), dir); !ok || err != nil {
+		t.Fatalf("got %v and error %v, want maintained", ok, err)
+	}
+	if _, locked := s.locker.Status(dir); locked {
+		t.Error("repo is still locked after maintenance")
+	}
+}
+
+func TestMultiPackIndexBatchSize(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	dir := GitDir(root)
+	if err := os.MkdirAll(dir.Path("objects", "pack"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		size int
+		want int64
+	}{
+		{100, 0},
+		{10, 11},
+		{50, 51},
+	} {
+		name := dir.Path("objects", "pack", fmt.Sprintf("pack-%d.pack", tc.size))
+		if err := ioutil.WriteFile(name, make([]byte, tc.size), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := multiPackIndexBatchSize(dir); err != nil || got != tc.want {
+			t.Errorf("after adding pack of size %d: got %d and error %v, want %d", tc.size, got, err, tc.want)
+		}
+	}
+}
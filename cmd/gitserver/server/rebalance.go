@@ -0,0 +1,381 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// The gitserver addresses (SRC_GIT_SERVERS) determine which gitserver stores
+// a repo. When gitservers are added or removed, many repos move to another
+// gitserver. Instead of cloning them from the code host again, the new owner
+// of a repo clones it from its previous owner, which it finds with the
+// addresses before the change. The previous owner deletes its copy in the
+// janitor once the new owner has cloned it.
+//
+// Gitservers that are removed are usually shut down right away, so the repos
+// they stored are cloned from the code host.
+//
+// With replication (SRC_GIT_SERVER_REPLICAS), each repo is stored on several
+// gitservers (see gitserver.AddrsForRepoIn), and each of them owns the repo.
+// Changing the number of replicas moves repos like changing the addresses.
+
+// addrsFileName is the name of the file in ReposDir that stores the gitserver
+// addresses, so that we still know the previous addresses after a restart.
+const addrsFileName = ".gitserver-addrs.json"
+
+// gitserverAddrs are the addresses of the gitservers that this gitserver has
+// seen.
+type gitserverAddrs struct {
+	// Current are the current addresses.
+	Current []string `json:"current"`
+
+	// Previous are the addresses before they last changed, or nil if they are
+	// not known.
+	Previous []string `json:"previous,omitempty"`
+
+	// Replicas and PreviousReplicas are the current and previous number of
+	// gitservers that store each repo.
+	Replicas         int `json:"replicas,omitempty"`
+	PreviousReplicas int `json:"previousReplicas,omitempty"`
+}
+
+// changed reports whether the addresses or the number of replicas differ
+// from current and replicas.
+func (a gitserverAddrs) changed(current []string, replicas int) bool {
+	return !reflect.DeepEqual(a.Current, current) || a.Replicas != replicas
+}
+
+var (
+	reposClonedFromPeer = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repos_cloned_from_peer",
+		Help:      "number of repos cloned from the gitserver that stored them before the gitserver addresses changed",
+	})
+	reposMoved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repos_moved",
+		Help:      "number of repos removed because another gitserver stores them after the gitserver addresses changed",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(reposClonedFromPeer)
+	prometheus.MustRegister(reposMoved)
+}
+
+// peerClient is the HTTP client used to talk to other gitservers.
+var peerClient = &http.Client{Timeout: 10 * time.Second}
+
+// updateAddrs records that the gitserver addresses and the number of replicas
+// are now current. If they changed, the ones before the change become the
+// previous ones.
+//
+// A gitserver that has never seen any addresses, such as a gitserver that was
+// just added, asks the other gitservers for the previous addresses.
+func (s *Server) updateAddrs(ctx context.Context, current []string, replicas int) {
+	s.addrsUpdateMu.Lock()
+	defer s.addrsUpdateMu.Unlock()
+
+	addrs, err := s.readAddrs()
+	if err != nil {
+		log15.Warn("failed to read gitserver addresses", "error", err)
+	}
+	if addrs.Current == nil {
+		previous, previousReplicas := s.peerPreviousAddrs(ctx, current, replicas)
+		addrs = gitserverAddrs{Current: current, Previous: previous, Replicas: replicas, PreviousReplicas: previousReplicas}
+	} else if addrs.changed(current, replicas) {
+		log15.Info("gitserver addresses changed", "previous", addrs.Current, "current", current, "previousReplicas", addrs.Replicas, "replicas", replicas)
+		addrs = gitserverAddrs{Current: current, Previous: addrs.Current, Replicas: replicas, PreviousReplicas: addrs.Replicas}
+	}
+
+	s.addrsMu.Lock()
+	s.addrs = addrs
+	s.addrsMu.Unlock()
+
+	if err := s.writeAddrs(addrs); err != nil {
+		log15.Warn("failed to write gitserver addresses", "error", err)
+	}
+}
+
+// peerPreviousAddrs asks the other gitservers for the addresses and the
+// number of replicas before they changed to current and replicas. It returns
+// nil if no gitserver knows them.
+func (s *Server) peerPreviousAddrs(ctx context.Context, current []string, replicas int) ([]string, int) {
+	self := s.selfAddr(current)
+	for _, addr := range current {
+		if addr == self {
+			continue
+		}
+		peer, err := getPeerAddrs(ctx, addr)
+		if err != nil {
+			log15.Debug("failed to get gitserver addresses from peer", "peer", addr, "error", err)
+			continue
+		}
+		switch {
+		case peer.Current == nil:
+		case peer.changed(current, replicas):
+			// The peer has not seen the change yet.
+			return peer.Current, peer.Replicas
+		case peer.Previous != nil:
+			return peer.Previous, peer.PreviousReplicas
+		}
+	}
+	return nil, 0
+}
+
+func getPeerAddrs(ctx context.Context, addr string) (*gitserverAddrs, error) {
+	req, err := http.NewRequest("GET", "http://"+addr+"/gitserver-addrs", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := peerClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	var addrs gitserverAddrs
+	if err := json.NewDecoder(resp.Body).Decode(&addrs); err != nil {
+		return nil, err
+	}
+	return &addrs, nil
+}
+
+// handleGitserverAddrs returns the gitserver addresses that this gitserver has
+// seen, so that gitservers that were just added can find the previous owners
+// of their repos.
+func (s *Server) handleGitserverAddrs(w http.ResponseWriter, r *http.Request) {
+	s.addrsMu.Lock()
+	addrs := s.addrs
+	s.addrsMu.Unlock()
+	if err := json.NewEncoder(w).Encode(addrs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) readAddrs() (gitserverAddrs, error) {
+	var addrs gitserverAddrs
+	b, err := ioutil.ReadFile(filepath.Join(s.ReposDir, addrsFileName))
+	if os.IsNotExist(err) {
+		return addrs, nil
+	} else if err != nil {
+		return addrs, err
+	}
+	err = json.Unmarshal(b, &addrs)
+	return addrs, err
+}
+
+func (s *Server) writeAddrs(addrs gitserverAddrs) error {
+	b, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that we never read a partially
+	// written file.
+	path := filepath.Join(s.ReposDir, addrsFileName)
+	if err := ioutil.WriteFile(path+".tmp", b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// selfAddr returns the address of this gitserver in addrs, which is the
+// address whose host is s.Hostname or a domain name in it (as in
+// "gitserver-0.gitserver:3178" for the hostname "gitserver-0"). It returns ""
+// if the address is not found.
+func (s *Server) selfAddr(addrs []string) string {
+	if s.Hostname == "" {
+		return ""
+	}
+	for _, addr := range addrs {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		if host == s.Hostname || strings.HasPrefix(host, s.Hostname+".") {
+			return addr
+		}
+	}
+	return ""
+}
+
+// previousOwner returns the address of a gitserver that stored repo before
+// the gitserver addresses last changed, if repo was not stored on this
+// gitserver. Otherwise it returns "".
+func (s *Server) previousOwner(repo api.RepoName) string {
+	s.addrsMu.Lock()
+	addrs := s.addrs
+	s.addrsMu.Unlock()
+
+	self := s.selfAddr(addrs.Current)
+	if self == "" || !contains(gitserver.AddrsForRepoIn(addrs.Current, addrs.Replicas, repo), self) {
+		return ""
+	}
+	previous := gitserver.AddrsForRepoIn(addrs.Previous, addrs.PreviousReplicas, repo)
+	if len(previous) == 0 || contains(previous, self) {
+		return ""
+	}
+	return previous[0]
+}
+
+// currentOwner returns the address of a gitserver that stores repo, if it is
+// not stored on this gitserver. Otherwise, or if the address of this gitserver
+// is not known, it returns "".
+func (s *Server) currentOwner(repo api.RepoName) string {
+	s.addrsMu.Lock()
+	addrs := s.addrs
+	s.addrsMu.Unlock()
+
+	self := s.selfAddr(addrs.Current)
+	if self == "" {
+		return ""
+	}
+	if owners := gitserver.AddrsForRepoIn(addrs.Current, addrs.Replicas, repo); !contains(owners, self) {
+		return owners[0]
+	}
+	return ""
+}
+
+func contains(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneFromPeer clones repo into tmpPath from the gitserver that stored it
+// before the gitserver addresses changed, and sets its remote URL to url. It
+// reports whether it cloned repo. If it did not, repo must be cloned from the
+// code host.
+//
+// The previous owner writes a bundle of all refs of repo, which is cloned like
+// a remote.
+func (s *Server) cloneFromPeer(ctx context.Context, repo api.RepoName, url, tmpPath string, progress io.Writer) bool {
+	peer := s.previousOwner(repo)
+	if peer == "" {
+		return false
+	}
+
+	log15.Info("cloning repo from previous gitserver", "repo", repo, "peer", peer)
+	bundlePath := filepath.Join(filepath.Dir(tmpPath), "peer.bundle")
+	if err := fetchPeerBundle(ctx, peer, repo, bundlePath); err != nil {
+		log15.Warn("failed to fetch repo from previous gitserver, cloning it from the code host", "repo", repo, "peer", peer, "error", err)
+		return false
+	}
+	defer os.Remove(bundlePath)
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", bundlePath, tmpPath)
+	if output, err := runWith(ctx, cmd, false, progress); err != nil {
+		log15.Warn("failed to clone repo from previous gitserver, cloning it from the code host", "repo", repo, "peer", peer, "error", err, "output", string(output))
+		_ = os.RemoveAll(tmpPath)
+		return false
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "remote", "set-url", "origin", url)
+	cmd.Dir = tmpPath
+	if output, err := runWith(ctx, cmd, false, nil); err != nil {
+		log15.Warn("failed to set remote URL of repo cloned from previous gitserver", "repo", repo, "error", err, "output", string(output))
+		_ = os.RemoveAll(tmpPath)
+		return false
+	}
+	reposClonedFromPeer.Inc()
+	return true
+}
+
+// fetchPeerBundle writes a bundle of all refs of repo on the gitserver at addr
+// to path. It does not trigger a clone of repo on that gitserver.
+func fetchPeerBundle(ctx context.Context, addr string, repo api.RepoName, path string) (err error) {
+	b, err := json.Marshal(&protocol.ExecRequest{
+		Repo: repo,
+		Args: []string{"bundle", "create", "-", "--all"},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", "http://"+addr+"/exec", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	// Bundles of large repos take a while, so we don't use peerClient and its
+	// timeout.
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return err
+	}
+
+	// The exit status of git bundle is only known once its output is read.
+	if status := resp.Trailer.Get("X-Exec-Exit-Status"); status != "0" {
+		return errors.Errorf("git bundle failed with exit status %s: %s", status, resp.Trailer.Get("X-Exec-Stderr"))
+	}
+	return nil
+}
+
+// isClonedOnPeer reports whether the gitserver at addr has cloned repo.
+func isClonedOnPeer(ctx context.Context, addr string, repo api.RepoName) (bool, error) {
+	b, err := json.Marshal(&protocol.IsRepoClonedRequest{Repo: repo})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest("POST", "http://"+addr+"/is-repo-cloned", bytes.NewReader(b))
+	if err != nil {
+		return false, err
+	}
+	resp, err := peerClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
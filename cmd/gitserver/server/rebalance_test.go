@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+func TestSelfAddr(t *testing.T) {
+	addrs := []string{"gitserver-1.gitserver:3178", "gitserver-10.gitserver:3178", "gitserver-2:3178"}
+	for hostname, want := range map[string]string{
+		"gitserver-1":  "gitserver-1.gitserver:3178",
+		"gitserver-10": "gitserver-10.gitserver:3178",
+		"gitserver-2":  "gitserver-2:3178",
+		"gitserver-3":  "",
+		"":             "",
+	} {
+		s := &Server{Hostname: hostname}
+		if got := s.selfAddr(addrs); got != want {
+			t.Errorf("hostname %q: got %q, want %q", hostname, got, want)
+		}
+	}
+}
+
+func TestUpdateAddrs(t *testing.T) {
+	ctx := context.Background()
+
+	// A gitserver that has not seen the change yet.
+	peerDir, cleanup := tmpDir(t)
+	defer cleanup()
+	peer := &Server{ReposDir: peerDir}
+	peer.addrs = gitserverAddrs{Current: []string{"gitserver-0:3178"}}
+	ts := httptest.NewServer(peer.Handler())
+	defer ts.Close()
+	peerAddr := strings.TrimPrefix(ts.URL, "http://")
+
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	s := &Server{ReposDir: reposDir, Hostname: "gitserver-1"}
+
+	// We just started, so we ask our peer.
+	current := []string{peerAddr, "gitserver-1:3178"}
+	s.updateAddrs(ctx, current, 0)
+	want := gitserverAddrs{Current: current, Previous: []string{"gitserver-0:3178"}}
+	if !reflect.DeepEqual(s.addrs, want) {
+		t.Errorf("got %+v, want %+v", s.addrs, want)
+	}
+
+	// The addresses are stored, so they are kept when we restart.
+	s = &Server{ReposDir: reposDir, Hostname: "gitserver-1"}
+	s.updateAddrs(ctx, current, 0)
+	if !reflect.DeepEqual(s.addrs, want) {
+		t.Errorf("after restart: got %+v, want %+v", s.addrs, want)
+	}
+
+	next := []string{peerAddr, "gitserver-1:3178", "gitserver-2:3178"}
+	s.updateAddrs(ctx, next, 0)
+	want = gitserverAddrs{Current: next, Previous: current}
+	if !reflect.DeepEqual(s.addrs, want) {
+		t.Errorf("after change: got %+v, want %+v", s.addrs, want)
+	}
+}
+
+func TestCloneRepo_fromPeer(t *testing.T) {
+	ctx := context.Background()
+	repo := api.RepoName("example.com/foo/bar")
+
+	// The previous owner has the repo.
+	peerDir, cleanup := tmpDir(t)
+	defer cleanup()
+	peer := &Server{ReposDir: peerDir}
+	ts := httptest.NewServer(peer.Handler())
+	defer ts.Close()
+	peerAddr := strings.TrimPrefix(ts.URL, "http://")
+	wantCommit := makeRepoWithCommit(t, filepath.Dir(string(peer.dir(repo))))
+
+	testRepoExists = func(ctx context.Context, url string) error { return nil }
+	defer func() { testRepoExists = nil }()
+
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	s := &Server{ReposDir: reposDir}
+	s.Handler() // Handler as a side-effect sets up Server
+	s.Hostname = "gitserver-1"
+	s.addrs = gitserverAddrs{Current: []string{"gitserver-1:3178"}, Previous: []string{peerAddr}}
+
+	// The code host URL does not exist, so the repo must come from the peer.
+	remoteURL := "https://example.com/foo/bar"
+	if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := s.dir(repo)
+	if got := gitOutput(t, string(dir), "rev-parse", "HEAD"); got != wantCommit {
+		t.Errorf("got commit %q, want %q", got, wantCommit)
+	}
+	if got := gitOutput(t, string(dir), "remote", "get-url", "origin"); got != remoteURL {
+		t.Errorf("got remote URL %q, want %q", got, remoteURL)
+	}
+}
+
+func TestCleanupMoved(t *testing.T) {
+	// The new owner of the repos has cloned only one of them.
+	ownerDir, cleanup := tmpDir(t)
+	defer cleanup()
+	owner := &Server{ReposDir: ownerDir}
+	ts := httptest.NewServer(owner.Handler())
+	defer ts.Close()
+	ownerAddr := strings.TrimPrefix(ts.URL, "http://")
+
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	s := &Server{ReposDir: reposDir}
+	s.Handler() // Handler as a side-effect sets up Server
+	s.Hostname = "gitserver-0"
+	s.addrs = gitserverAddrs{Current: []string{"gitserver-0:3178", ownerAddr}}
+
+	// Find repos stored on each gitserver.
+	var kept, moved, notCloned api.RepoName
+	for i := 0; kept == "" || moved == "" || notCloned == ""; i++ {
+		repo := api.RepoName(fmt.Sprintf("example.com/repo%d", i))
+		switch {
+		case gitserver.AddrForRepoIn(s.addrs.Current, repo) != ownerAddr:
+			kept = repo
+		case moved == "":
+			moved = repo
+		default:
+			notCloned = repo
+		}
+	}
+	for _, repo := range []api.RepoName{kept, moved, notCloned} {
+		makeRepoWithCommit(t, filepath.Dir(string(s.dir(repo))))
+	}
+	makeRepoWithCommit(t, filepath.Dir(string(owner.dir(moved))))
+
+	s.cleanupRepos()
+
+	for repo, wantExists := range map[api.RepoName]bool{kept: true, moved: false, notCloned: true} {
+		_, err := os.Stat(string(s.dir(repo)))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s: got exists %v, want %v", repo, exists, wantExists)
+		}
+	}
+}
+
+// makeRepoWithCommit creates a repo with a commit in dir, and returns the
+// commit ID.
+func makeRepoWithCommit(t *testing.T, dir string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "."},
+		{"commit", "--allow-empty", "-m", "hello"},
+	} {
+		gitOutput(t, dir, args...)
+	}
+	return gitOutput(t, dir, "rev-parse", "HEAD")
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_COMMITTER_NAME=a",
+		"GIT_COMMITTER_EMAIL=a@a.com",
+		"GIT_AUTHOR_NAME=a",
+		"GIT_AUTHOR_EMAIL=a@a.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestOwners_replicas(t *testing.T) {
+	s := &Server{Hostname: "gitserver-1"}
+	current := []string{"gitserver-0:3178", "gitserver-1:3178", "gitserver-2:3178"}
+	s.addrs = gitserverAddrs{Current: current, Replicas: 2, Previous: current}
+
+	for i := 0; i < 20; i++ {
+		repo := api.RepoName(fmt.Sprintf("example.com/repo%d", i))
+		owners := gitserver.AddrsForRepoIn(current, 2, repo)
+		stored := owners[0] == "gitserver-1:3178" || owners[1] == "gitserver-1:3178"
+
+		if got := s.currentOwner(repo); stored && got != "" || !stored && got != owners[0] {
+			t.Errorf("%s stored on %v: got current owner %q", repo, owners, got)
+		}
+
+		// Without replication before, replicas clone the repo from the
+		// gitserver that stored it.
+		wantPrevious := ""
+		if stored && owners[0] != "gitserver-1:3178" {
+			wantPrevious = owners[0]
+		}
+		if got := s.previousOwner(repo); got != wantPrevious {
+			t.Errorf("%s stored on %v: got previous owner %q, want %q", repo, owners, got, wantPrevious)
+		}
+	}
+}
@@ -47,6 +47,17 @@ func (s *Server) repoInfo(ctx context.Context, repo api.RepoName) (*protocol.Rep
 		} else {
 			resp.LastChanged = &lastChanged
 		}
+
+		if maintenance, err := getMaintenanceStatus(dir); err != nil {
+			log15.Warn("error getting maintenance status", "repo", repo, "err", err)
+		} else {
+			resp.Maintenance = maintenance
+		}
+	}
+	if history, err := s.readUpdateHistory(repo); err != nil {
+		log15.Warn("error reading update history", "repo", repo, "err", err)
+	} else {
+		resp.UpdateHistory = history
 	}
 	return &resp, nil
 }
@@ -116,6 +116,17 @@ type Server struct {
 	// DiskSizer tells how much disk is free and how large the disk is.
 	DiskSizer DiskSizer
 
+	// Hostname is the hostname of this gitserver, which is used to find its
+	// address in the gitserver addresses. If it is empty or not found, repos
+	// are not moved from or to other gitservers when the addresses change
+	// (see rebalance.go).
+	Hostname string
+
+	// BundlesDir is the path to a directory of git bundles that repos are
+	// seeded from when they are cloned (see bundle.go). If it is empty,
+	// repos are cloned from the code host.
+	BundlesDir string
+
 	// skipCloneForTests is set by tests to avoid clones.
 	skipCloneForTests bool
 
@@ -138,6 +149,12 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	addrsUpdateMu sync.Mutex // serializes updateAddrs
+	addrsMu       sync.Mutex // protects addrs
+	addrs         gitserverAddrs
+
+	updateHistoryMu sync.Mutex // serializes changes to the update history files
 }
 
 type locks struct {
@@ -154,7 +171,7 @@ func shortGitCommandTimeout(args []string) time.Duration {
 		return time.Minute
 	}
 	switch args[0] {
-	case "archive":
+	case "archive", "bundle":
 		// This is a long time, but this never blocks a user request for this
 		// long. Even repos that are not that large can take a long time, for
 		// example a search over all repos in an organization may have several
@@ -222,6 +239,18 @@ func (s *Server) Handler() http.Handler {
 		s.cloneableLimiter.SetLimit(limit)
 	})
 
+	if s.Hostname != "" {
+		conf.Watch(func() {
+			addrs := conf.Get().ServiceConnections.GitServers
+			replicas := conf.Get().ServiceConnections.GitServerReplicas
+			go func() {
+				ctx, cancel := s.serverContext()
+				defer cancel()
+				s.updateAddrs(ctx, addrs, replicas)
+			}()
+		})
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/archive", s.handleArchive)
 	mux.HandleFunc("/exec", s.handleExec)
@@ -234,6 +263,10 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/repo-update", s.handleRepoUpdate)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
+	mux.HandleFunc("/gitserver-addrs", s.handleGitserverAddrs)
+	mux.HandleFunc("/git/", s.handleGit)
+	mux.HandleFunc("/lfs-object", s.handleLFSObject)
+	mux.HandleFunc("/bundle", s.handleBundle)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -319,11 +352,12 @@ func (s *Server) tempDir(prefix string) (name string, err error) {
 }
 
 func (s *Server) ignorePath(path string) bool {
-	// We ignore any path which starts with .tmp in ReposDir
+	// We ignore any path which starts with .tmp in ReposDir, and the update
+	// history.
 	if filepath.Dir(path) != s.ReposDir {
 		return false
 	}
-	return strings.HasPrefix(filepath.Base(path), tempDirName)
+	return strings.HasPrefix(filepath.Base(path), tempDirName) || filepath.Base(path) == updateHistoryDirName
 }
 
 func (s *Server) handleIsRepoCloneable(w http.ResponseWriter, r *http.Request) {
@@ -403,7 +437,13 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		// optimistically, we assume that our cloning attempt might
 		// succeed.
 		resp.CloneInProgress = true
-		_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{Block: true})
+		_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{
+			Block:  true,
+			LFS:    req.LFS,
+			Filter: req.CloneFilter,
+			Depth:  req.CloneDepth,
+			Refs:   req.Refs,
+		})
 		if err != nil {
 			log15.Warn("error cloning repo", "repo", req.Repo, "err", err)
 			resp.Error = err.Error()
@@ -412,7 +452,40 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		resp.Cloned = true
 		var statusErr, updateErr error
 
-		if debounce(req.Repo, req.Since) {
+		if req.LFS != lfsEnabled(dir) {
+			if err := setLFSEnabled(dir, req.LFS); err != nil {
+				log15.Warn("failed to set whether to fetch LFS objects", "repo", req.Repo, "error", err)
+			}
+		}
+
+		// Fetching can't turn a full clone into a partial or shallow one or
+		// vice versa, so the repo is recloned in the background instead.
+		recloning := false
+		if filter, depth, err := repoCloneStrategy(dir); err != nil {
+			log15.Warn("failed to get clone strategy", "repo", req.Repo, "error", err)
+		} else if (filter != req.CloneFilter || depth != req.CloneDepth) && req.URL != "" && !useRefspecOverrides() {
+			log15.Info("recloning repo with changed clone strategy", "repo", req.Repo, "filter", req.CloneFilter, "depth", req.CloneDepth)
+			_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{
+				Overwrite: true,
+				LFS:       req.LFS,
+				Filter:    req.CloneFilter,
+				Depth:     req.CloneDepth,
+				Refs:      req.Refs,
+			})
+			if err != nil {
+				log15.Warn("error recloning repo", "repo", req.Repo, "error", err)
+			} else {
+				recloning = true
+			}
+		}
+
+		if !recloning {
+			if err := updateFetchRefs(ctx, dir, req.Refs); err != nil {
+				log15.Warn("failed to update fetched refs", "repo", req.Repo, "error", err)
+			}
+		}
+
+		if !recloning && debounce(req.Repo, req.Since) {
 			updateErr = s.doRepoUpdate(ctx, req.Repo, req.URL)
 		}
 
@@ -496,6 +569,25 @@ func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
 	req.Args = append(req.Args, treeish, "--")
 	req.Args = append(req.Args, paths...)
 
+	// Fetch the missing file contents of partial clones at once, instead of
+	// one at a time while archiving.
+	if dir := s.dir(protocol.NormalizeRepo(req.Repo)); len(paths) == 0 && isPartialClone(dir) {
+		ctx, cancel := context.WithTimeout(r.Context(), longGitCommandTimeout)
+		err := fetchMissingObjects(ctx, dir, treeish)
+		cancel()
+		if err != nil {
+			log15.Warn("failed to fetch missing objects for archive", "repo", repo, "treeish", treeish, "error", err)
+		}
+	}
+
+	// git archive writes LFS pointer files. We replace them with the stored
+	// LFS objects in tar archives, which is the format used to search.
+	if dir := s.dir(protocol.NormalizeRepo(req.Repo)); format == "tar" && lfsEnabled(dir) {
+		aw := newLFSArchiveWriter(w, dir)
+		defer aw.Close()
+		w = aw
+	}
+
 	s.exec(w, r, req)
 }
 
@@ -676,6 +768,12 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 	cmd.Dir = string(dir)
 	cmd.Stdout = stdoutW
 	cmd.Stderr = stderrW
+	if isPartialClone(dir) {
+		// Git fetches missing objects from the code host when the command
+		// reads them.
+		cmd.Env = os.Environ()
+		configureRemoteGitCommand(cmd, tlsExternal().(*tlsConfig))
+	}
 
 	exitStatus, execErr = runCommand(ctx, cmd)
 
@@ -724,6 +822,20 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// LFS will fetch Git LFS objects after cloning, and on later fetches.
+	LFS bool
+
+	// Filter is the partial clone filter to clone with, such as blob:none.
+	Filter string
+
+	// Depth is the number of recent commits to clone shallowly. 0 clones
+	// the full history.
+	Depth int
+
+	// Refs are refs to fetch on later fetches in addition to branches and
+	// tags, see updateFetchRefs.
+	Refs []string
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -752,8 +864,11 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		return "", err // err will be a context error
 	}
 	defer cancel()
+	attempt := startUpdateAttempt("clone")
 	if err := s.isCloneable(ctx, url); err != nil {
-		return "", fmt.Errorf("error cloning repo: repo %s not cloneable: %s", repo, redactor.redact(err.Error()))
+		err = fmt.Errorf("error cloning repo: repo %s not cloneable: %s", repo, redactor.redact(err.Error()))
+		s.finishUpdateAttempt(repo, attempt, url, err)
+		return "", err
 	}
 
 	// Mark this repo as currently being cloned. We have to check again if someone else isn't already
@@ -774,7 +889,7 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 	// We clone to a temporary location first to avoid having incomplete
 	// clones in the repo tree. This also avoids leaving behind corrupt clones
 	// if the clone is interrupted.
-	doClone := func(ctx context.Context) error {
+	doClone := func(ctx context.Context) (err error) {
 		defer lock.Release()
 
 		ctx, cancel1, err := s.acquireCloneLimiter(ctx)
@@ -807,6 +922,14 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		tmpPath = filepath.Join(tmpPath, ".git")
 		tmp := GitDir(tmpPath)
 
+		attempt := startUpdateAttempt("clone")
+		defer func() { s.finishUpdateAttempt(repo, attempt, url, err) }()
+
+		strategyArgs, err := cloneStrategyArgs(opts)
+		if err != nil {
+			return err
+		}
+
 		var cmd *exec.Cmd
 		if useRefspecOverrides() {
 			cmd, err = refspecOverridesCloneCmd(ctx, url, tmpPath)
@@ -814,7 +937,8 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 				return err
 			}
 		} else {
-			cmd = exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", url, tmpPath)
+			args := append([]string{"clone", "--mirror", "--progress"}, strategyArgs...)
+			cmd = exec.CommandContext(ctx, "git", append(args, url, tmpPath)...)
 		}
 		// see issue #7322: skip LFS content in repositories with Git LFS configured
 		cmd.Env = append(cmd.Env, "GIT_LFS_SKIP_SMUDGE=1")
@@ -824,8 +948,21 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		defer pw.Close()
 		go readCloneProgress(redactor, lock, pr)
 
-		if output, err := runWithRemoteOpts(ctx, cmd, pw); err != nil {
-			return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+		// Repos that were stored on another gitserver before the gitserver
+		// addresses changed are cloned from there, and repos that have
+		// bundles in BundlesDir are seeded from them, which is cheaper for
+		// the code host. Refspec overrides are only applied by cloning from
+		// the code host, and partial clones can only fetch missing objects
+		// from the code host.
+		if useRefspecOverrides() || len(strategyArgs) > 0 || (!s.cloneFromPeer(ctx, repo, url, tmpPath, pw) && !s.cloneFromBundles(ctx, repo, url, tmpPath, pw)) {
+			output, err := runWithRemoteOpts(ctx, cmd, pw)
+			attempt.output = output
+			if err != nil {
+				return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+			}
+		}
+		if size, err := dirSize(tmp.Path("objects")); err == nil {
+			attempt.Bytes = size
 		}
 
 		removeBadRefs(ctx, tmp)
@@ -840,6 +977,42 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 			return err
 		}
 
+		if opts != nil && opts.Depth > 0 && !useRefspecOverrides() {
+			if err := setCloneDepth(tmp, opts.Depth); err != nil {
+				return err
+			}
+		}
+
+		var refs []string
+		if opts != nil {
+			refs = opts.Refs
+		}
+		// Refspec overrides may fetch the refs which are otherwise only
+		// fetched if requested.
+		if !useRefspecOverrides() {
+			if err := pruneUnrequestedRefs(ctx, tmp, refs); err != nil {
+				return err
+			}
+		}
+		if len(refs) > 0 {
+			if err := updateFetchRefs(ctx, tmp, refs); err != nil {
+				return err
+			}
+		}
+
+		if opts != nil && opts.LFS {
+			if err := setLFSEnabled(tmp, true); err != nil {
+				return err
+			}
+			lock.SetStatus("fetching Git LFS objects")
+			if err := fetchLFSObjects(ctx, tmp, url); err != nil {
+				// The repo is usable without LFS objects, we fetch them again
+				// on the next update.
+				lfsFetchErrors.Inc()
+				log15.Warn("failed to fetch LFS objects", "repo", repo, "error", redactor.redact(err.Error()))
+			}
+		}
+
 		if overwrite {
 			// remove the current repo by putting it into our temporary directory
 			err := renameAndSync(dstPath, filepath.Join(filepath.Dir(tmpPath), "old"))
@@ -1047,13 +1220,10 @@ func init() {
 
 var headBranchPattern = lazyregexp.New(`HEAD branch: (.+?)\n`)
 
-func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string) error {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "Server.doRepoUpdate")
-	span.SetTag("repo", repo)
-	span.SetTag("url", url)
-	defer span.Finish()
-
+// repoUpdateLock returns the locks of repo which serialize its updates.
+func (s *Server) repoUpdateLock(repo api.RepoName) *locks {
 	s.repoUpdateLocksMu.Lock()
+	defer s.repoUpdateLocksMu.Unlock()
 	l, ok := s.repoUpdateLocks[repo]
 	if !ok {
 		l = &locks{
@@ -1062,9 +1232,20 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 		}
 		s.repoUpdateLocks[repo] = l
 	}
+	return l
+}
+
+func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Server.doRepoUpdate")
+	span.SetTag("repo", repo)
+	span.SetTag("url", url)
+	defer span.Finish()
+
+	l := s.repoUpdateLock(repo)
+	s.repoUpdateLocksMu.Lock()
 	once := l.once
-	mu := l.mu
 	s.repoUpdateLocksMu.Unlock()
+	mu := l.mu
 
 	// doRepoUpdate2 can block longer than our context deadline. done will
 	// close when its done. We can return when either done is closed or our
@@ -1254,7 +1435,7 @@ func computeRefHash(dir GitDir) ([]byte, error) {
 	return hash, nil
 }
 
-func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
+func (s *Server) doRepoUpdate2(repo api.RepoName, url string) (err error) {
 	// background context.
 	ctx, cancel1 := s.serverContext()
 	defer cancel1()
@@ -1268,6 +1449,9 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	repo = protocol.NormalizeRepo(repo)
 	dir := s.dir(repo)
 
+	attempt := startUpdateAttempt("fetch")
+	defer func() { s.finishUpdateAttempt(repo, attempt, url, err) }()
+
 	// If URL is not set, we can also use the last known working URL (set as the remote origin).
 	var urlIsGitRemote bool
 	if url == "" {
@@ -1310,7 +1494,20 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	} else if useRefspecOverrides() {
 		cmd = refspecOverridesFetchCmd(ctx, url)
 	} else {
-		cmd = exec.CommandContext(ctx, "git", "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*", "+refs/sourcegraph/*:refs/sourcegraph/*")
+		args := []string{"fetch", "--prune"}
+		// Partial clones keep omitting the objects they were cloned without.
+		if filter, _, err := repoCloneStrategy(dir); err != nil {
+			log15.Warn("failed to get clone strategy", "repo", repo, "error", err)
+		} else if filter != "" {
+			args = append(args, "--filter="+filter)
+		}
+		args = append(args, url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*", "+refs/sourcegraph/*:refs/sourcegraph/*")
+		if refs, err := repoFetchRefs(dir); err != nil {
+			log15.Warn("failed to get fetched refs", "repo", repo, "error", err)
+		} else {
+			args = append(args, fetchRefspecs(refs)...)
+		}
+		cmd = exec.CommandContext(ctx, "git", args...)
 	}
 	cmd.Dir = string(dir)
 
@@ -1320,10 +1517,16 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// when the cleanup happens, just that it does.
 	defer s.cleanTmpFiles(dir)
 
-	if output, err := runWith(ctx, cmd, configRemoteOpts, nil); err != nil {
+	sizeBefore, _ := dirSize(dir.Path("objects"))
+	output, err := runWith(ctx, cmd, configRemoteOpts, nil)
+	attempt.output = output
+	if err != nil {
 		log15.Error("Failed to update", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "failed to update")
 	}
+	if sizeAfter, err := dirSize(dir.Path("objects")); err == nil && sizeAfter > sizeBefore {
+		attempt.Bytes = sizeAfter - sizeBefore
+	}
 
 	removeBadRefs(ctx, dir)
 
@@ -1337,7 +1540,7 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// try to fetch HEAD from origin
 	cmd = exec.CommandContext(ctx, "git", "remote", "show", url)
 	cmd.Dir = path.Join(s.ReposDir, string(repo))
-	output, err := runWithRemoteOpts(ctx, cmd, nil)
+	output, err = runWithRemoteOpts(ctx, cmd, nil)
 	if err != nil {
 		log15.Error("Failed to fetch remote info", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "failed to fetch remote info")
@@ -1376,6 +1579,14 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		log15.Error("Failed to set HEAD", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "Failed to set HEAD")
 	}
+
+	// LFS objects are best-effort, the repo is usable without them.
+	if lfsEnabled(dir) {
+		if err := fetchLFSObjects(ctx, dir, url); err != nil {
+			lfsFetchErrors.Inc()
+			log15.Warn("failed to fetch LFS objects", "repo", repo, "error", newURLRedactor(url).redact(err.Error()))
+		}
+	}
 	return nil
 }
 
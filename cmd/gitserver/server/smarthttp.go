@@ -0,0 +1,115 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// handleGit serves the repos on this gitserver over the read-only git smart
+// HTTP protocol, at /git/{repo}/info/refs and /git/{repo}/git-upload-pack.
+// Protocol version 2 is used if the client asks for it with the Git-Protocol
+// header.
+//
+// Other services clone and fetch through the frontend's internal API, which
+// checks repository permissions.
+func (s *Server) handleGit(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/git/")
+	var repo string
+	var advertiseRefs bool
+	switch {
+	case strings.HasSuffix(path, "/info/refs") && r.Method == http.MethodGet:
+		if service := r.URL.Query().Get("service"); service != "git-upload-pack" {
+			http.Error(w, fmt.Sprintf("unsupported service %q", service), http.StatusForbidden)
+			return
+		}
+		repo = strings.TrimSuffix(path, "/info/refs")
+		advertiseRefs = true
+	case strings.HasSuffix(path, "/git-upload-pack") && r.Method == http.MethodPost:
+		repo = strings.TrimSuffix(path, "/git-upload-pack")
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	dir := s.dir(api.RepoName(repo))
+	if !repoCloned(dir) {
+		http.Error(w, "repository not found", http.StatusNotFound)
+		return
+	}
+
+	args := []string{"upload-pack", "--stateless-rpc"}
+	if advertiseRefs {
+		args = append(args, "--advertise-refs")
+	}
+	cmd := exec.CommandContext(r.Context(), "git", append(args, string(dir))...)
+	gitProtocol := r.Header.Get("Git-Protocol")
+	if gitProtocol != "" {
+		if !gitProtocolRegex.MatchString(gitProtocol) {
+			http.Error(w, "invalid Git-Protocol header", http.StatusBadRequest)
+			return
+		}
+		cmd.Env = append(os.Environ(), "GIT_PROTOCOL="+gitProtocol)
+	}
+
+	if advertiseRefs {
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Protocol version 0 and 1 clients expect the service to be
+		// announced before the refs, which git upload-pack does not do
+		// itself. Version 2 starts with the capability advertisement.
+		if !isGitProtocolV2(gitProtocol) {
+			_, _ = io.WriteString(w, pktLine("# service=git-upload-pack\n")+"0000")
+		}
+	} else {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gzr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer gzr.Close()
+			body = gzr
+		}
+		cmd.Stdin = body
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-result")
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	var stderr strings.Builder
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The response has already started, so we can only log the error.
+		log15.Error("git upload-pack failed", "repo", repo, "error", err, "stderr", stderr.String())
+	}
+}
+
+// gitProtocolRegex matches valid values of the Git-Protocol header, which is a
+// colon-separated list of keys and optional values, as in "version=2".
+var gitProtocolRegex = lazyregexp.New(`^[a-zA-Z0-9._-]+(=[a-zA-Z0-9._-]*)?(:[a-zA-Z0-9._-]+(=[a-zA-Z0-9._-]*)?)*$`)
+
+// isGitProtocolV2 reports whether the Git-Protocol header value asks for
+// protocol version 2.
+func isGitProtocolV2(gitProtocol string) bool {
+	for _, param := range strings.Split(gitProtocol, ":") {
+		if param == "version=2" {
+			return true
+		}
+	}
+	return false
+}
+
+// pktLine encodes s as a git pkt-line.
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
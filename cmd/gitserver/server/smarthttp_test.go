@@ -0,0 +1,82 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestHandleGit(t *testing.T) {
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	s := &Server{ReposDir: reposDir}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	repo := api.RepoName("example.com/foo/bar")
+	wantCommit := makeRepoWithCommit(t, filepath.Dir(string(s.dir(repo))))
+
+	clonesDir, cleanup := tmpDir(t)
+	defer cleanup()
+
+	t.Run("clone", func(t *testing.T) {
+		for _, version := range []string{"0", "1", "2"} {
+			gitOutput(t, clonesDir, "-c", "protocol.version="+version, "clone", ts.URL+"/git/"+string(repo), "v"+version)
+			if got := gitOutput(t, filepath.Join(clonesDir, "v"+version), "rev-parse", "HEAD"); got != wantCommit {
+				t.Errorf("protocol version %s: got commit %q, want %q", version, got, wantCommit)
+			}
+		}
+	})
+
+	get := func(t *testing.T, path string, header http.Header) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("protocol v2 advertisement", func(t *testing.T) {
+		code, body := get(t, "/git/example.com/foo/bar/info/refs?service=git-upload-pack", http.Header{"Git-Protocol": {"version=2"}})
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		if !strings.HasPrefix(body, "000eversion 2\n") {
+			t.Errorf("got body %q, want protocol v2 capability advertisement", body)
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"receive-pack", "/git/example.com/foo/bar/info/refs?service=git-receive-pack", nil, http.StatusForbidden},
+		{"not cloned", "/git/example.com/foo/baz/info/refs?service=git-upload-pack", nil, http.StatusNotFound},
+		{"invalid Git-Protocol", "/git/example.com/foo/bar/info/refs?service=git-upload-pack", http.Header{"Git-Protocol": {"version=2 --upload-pack=x"}}, http.StatusBadRequest},
+		{"unknown path", "/git/example.com/foo/bar/objects/info/packs", nil, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _ := get(t, tc.path, tc.header); code != tc.want {
+				t.Errorf("got status %d, want %d", code, tc.want)
+			}
+		})
+	}
+}
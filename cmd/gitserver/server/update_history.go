@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Gitserver keeps the most recent attempts to clone and fetch each repo, so
+// that site admins can find out why a repo stopped updating without reading
+// the gitserver logs. They are stored in a JSON file per repo under
+// ReposDir/.update-history rather than in the repo, so that attempts to clone
+// a repo that was never cloned are kept too.
+
+// updateHistoryDirName is the name of the directory in ReposDir that stores
+// the update history of repos.
+const updateHistoryDirName = ".update-history"
+
+const (
+	// maxUpdateHistory is the number of attempts kept per repo.
+	maxUpdateHistory = 20
+
+	// maxUpdateAttemptOutput is the number of bytes kept of the output and
+	// the error of each attempt.
+	maxUpdateAttemptOutput = 2048
+)
+
+// updateAttempt is an attempt to clone or fetch a repo that is in progress.
+type updateAttempt struct {
+	protocol.RepoUpdateAttempt
+
+	// output is the output of git, which is redacted and truncated when the
+	// attempt is recorded.
+	output []byte
+}
+
+func startUpdateAttempt(kind string) *updateAttempt {
+	return &updateAttempt{RepoUpdateAttempt: protocol.RepoUpdateAttempt{Kind: kind, Start: time.Now()}}
+}
+
+// finishUpdateAttempt records the attempt a to update repo, which failed
+// with err if err is not nil.
+//
+// 🚨 SECURITY: remoteURL is redacted from the output and the error, since it
+// may contain a sensitive token.
+func (s *Server) finishUpdateAttempt(repo api.RepoName, a *updateAttempt, remoteURL string, err error) {
+	a.End = time.Now()
+	a.ExitStatus = exitStatus(err)
+	redact := func(s string) string { return s }
+	if remoteURL != "" {
+		redact = newURLRedactor(remoteURL).redact
+	}
+	// The end of the output is kept, since that is where git reports errors.
+	a.Stderr = lastBytes(redact(string(a.output)), maxUpdateAttemptOutput)
+	if err != nil {
+		a.Error = firstBytes(redact(err.Error()), maxUpdateAttemptOutput)
+	}
+
+	if err := s.appendUpdateHistory(repo, a.RepoUpdateAttempt); err != nil {
+		log15.Warn("failed to record update attempt", "repo", repo, "error", err)
+	}
+}
+
+// firstBytes returns at most the first n bytes of s, without splitting a
+// UTF-8 encoded character.
+func firstBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// lastBytes returns at most the last n bytes of s, without splitting a UTF-8
+// encoded character.
+func lastBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return s[i:]
+}
+
+// exitStatus returns the exit status of the git command that failed with
+// err, 0 if err is nil, or -1 if err is not the error of a git command that
+// exited.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := errors.Cause(err).(*exec.ExitError); ok && e.ExitCode() >= 0 {
+		return e.ExitCode()
+	}
+	return -1
+}
+
+func (s *Server) updateHistoryPath(repo api.RepoName) string {
+	return filepath.Join(s.ReposDir, updateHistoryDirName, string(protocol.NormalizeRepo(repo))+".json")
+}
+
+// appendUpdateHistory adds attempt to the update history of repo, dropping
+// the oldest attempts if there are more than maxUpdateHistory.
+func (s *Server) appendUpdateHistory(repo api.RepoName, attempt protocol.RepoUpdateAttempt) error {
+	s.updateHistoryMu.Lock()
+	defer s.updateHistoryMu.Unlock()
+
+	history, err := s.readUpdateHistory(repo)
+	if err != nil {
+		// Start over rather than never recording attempts again.
+		log15.Warn("failed to read update history", "repo", repo, "error", err)
+		history = nil
+	}
+	history = append([]protocol.RepoUpdateAttempt{attempt}, history...)
+	if len(history) > maxUpdateHistory {
+		history = history[:maxUpdateHistory]
+	}
+
+	b, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	path := s.updateHistoryPath(repo)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that we never read a partially
+	// written file.
+	if err := ioutil.WriteFile(path+".tmp", b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readUpdateHistory returns the most recent attempts to clone and fetch repo,
+// most recent first.
+func (s *Server) readUpdateHistory(repo api.RepoName) ([]protocol.RepoUpdateAttempt, error) {
+	b, err := ioutil.ReadFile(s.updateHistoryPath(repo))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var history []protocol.RepoUpdateAttempt
+	err = json.Unmarshal(b, &history)
+	return history, err
+}
+
+// removeUpdateHistory removes the update history of repo. It is called when
+// repo is removed from disk (see removeRepoDirectory).
+func (s *Server) removeUpdateHistory(repo api.RepoName) error {
+	s.updateHistoryMu.Lock()
+	defer s.updateHistoryMu.Unlock()
+
+	err := os.Remove(s.updateHistoryPath(repo))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -35,6 +35,12 @@ const gqlSearchQuery = `query Search(
 				__typename
 				... on FileMatch {
 					resource
+					repository {
+						name
+					}
+					file {
+						path
+					}
 					limitHit
 					lineMatches {
 						preview
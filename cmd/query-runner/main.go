@@ -142,12 +142,6 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 		// No need to run this query because there will be nobody to notify.
 		return nil
 	}
-	if !strings.Contains(query.Query, "type:diff") && !strings.Contains(query.Query, "type:commit") {
-		// TODO(slimsag): we temporarily do not support non-commit search
-		// queries, since those do not support the after:"time" operator.
-		return nil
-	}
-
 	info, err := api.InternalClient.SavedQueriesGetInfo(ctx, query.Query)
 	if err != nil {
 		return errors.Wrap(err, "SavedQueriesGetInfo")
@@ -175,18 +169,26 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 		}
 	}
 
-	// Construct a new query which finds search results introduced after the
-	// last time we queried.
-	var latestKnownResult time.Time
-	if info != nil {
-		latestKnownResult = info.LatestResult
+	commitQuery := isCommitQuery(query.Query)
+	var newQuery string
+	if commitQuery {
+		// Construct a new query which finds search results introduced after the
+		// last time we queried.
+		var latestKnownResult time.Time
+		if info != nil {
+			latestKnownResult = info.LatestResult
+		} else {
+			// We've never executed this search query before, so use the current
+			// time. We'll most certainly find nothing, which is okay.
+			latestKnownResult = time.Now()
+		}
+		afterTime := latestKnownResult.UTC().Format(time.RFC3339)
+		newQuery = strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
 	} else {
-		// We've never executed this search query before, so use the current
-		// time. We'll most certainly find nothing, which is okay.
-		latestKnownResult = time.Now()
+		// Other queries do not support after:, so we search for all results
+		// and compare them with the results of the last execution.
+		newQuery = withResultLimit(query.Query)
 	}
-	afterTime := latestKnownResult.UTC().Format(time.RFC3339)
-	newQuery := strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
 	if debugPretendSavedQueryResultsExist {
 		debugPretendSavedQueryResultsExist = false
 		newQuery = query.Query
@@ -198,12 +200,36 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	// constantly and potentially causing harm to the system. We'll retry at
 	// our normal interval, regardless of errors.
 	v, execDuration, searchErr := performSearch(ctx, newQuery)
-	if err := api.InternalClient.SavedQueriesSetInfo(ctx, &api.SavedQueryInfo{
+	var matches, added, removed []*api.SavedQueryMatch
+	if searchErr == nil {
+		matches, searchErr = resultMatches(v)
+	}
+	newInfo := &api.SavedQueryInfo{
 		Query:        query.Query,
 		LastExecuted: time.Now(),
-		LatestResult: latestResultTime(info, v, searchErr),
 		ExecDuration: execDuration,
-	}); err != nil {
+	}
+	switch {
+	case commitQuery:
+		newInfo.LatestResult = latestResultTime(info, v, searchErr)
+		added = matches
+	case searchErr != nil:
+		// Keep the matches of the last execution to compare with the next one.
+		if info != nil {
+			newInfo.LatestResult, newInfo.ResultMatches = info.LatestResult, info.ResultMatches
+		}
+	default:
+		newInfo.LatestResult, newInfo.ResultMatches = time.Now(), matches
+		if info != nil && info.ResultMatches != nil {
+			// The first execution has nothing to compare with, so it never
+			// notifies.
+			added, removed = diffMatches(info.ResultMatches, matches)
+			if len(added) == 0 && len(removed) == 0 {
+				newInfo.LatestResult = info.LatestResult
+			}
+		}
+	}
+	if err := api.InternalClient.SavedQueriesSetInfo(ctx, newInfo); err != nil {
 		return errors.Wrap(err, "SavedQueriesSetInfo")
 	}
 
@@ -215,7 +241,7 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	// that we don't block other search queries from running in sequence (which
 	// is done intentionally, to ensure no overloading of searcher/gitserver).
 	go func() {
-		if err := notify(context.Background(), spec, query, newQuery, v); err != nil {
+		if err := notify(context.Background(), spec, query, newQuery, v, added, removed); err != nil {
 			log15.Error("executor: failed to send notifications", "error", err)
 		}
 	}()
@@ -275,12 +301,14 @@ func latestResultTime(prevInfo *api.SavedQueryInfo, v *gqlSearchResponse, search
 
 var externalURL *url.URL
 
-// notify handles sending notifications for new search results.
-func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSavedQuery, newQuery string, results *gqlSearchResponse) error {
-	if len(results.Data.Search.Results.Results) == 0 {
+// notify handles sending notifications for new search results. added and
+// removed are the matches that appeared and disappeared since the last
+// execution of the query.
+func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSavedQuery, newQuery string, results *gqlSearchResponse, added, removed []*api.SavedQueryMatch) error {
+	if len(added) == 0 && len(removed) == 0 {
 		return nil
 	}
-	log15.Info("sending notifications", "new_results", len(results.Data.Search.Results.Results), "description", query.Description)
+	log15.Info("sending notifications", "new_matches", len(added), "removed_matches", len(removed), "description", query.Description)
 
 	// Determine which users to notify.
 	recipients, err := getNotificationRecipients(ctx, spec, query)
@@ -294,6 +322,8 @@ func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSave
 		query:      query,
 		newQuery:   newQuery,
 		results:    results,
+		added:      added,
+		removed:    removed,
 		recipients: recipients,
 	}
 
@@ -308,6 +338,8 @@ type notifier struct {
 	query      api.ConfigSavedQuery
 	newQuery   string
 	results    *gqlSearchResponse
+	added      []*api.SavedQueryMatch // matches that appeared since the last execution
+	removed    []*api.SavedQueryMatch // matches that disappeared since the last execution
 	recipients recipients
 }
 
@@ -316,20 +348,29 @@ const (
 	utmSourceSlack = "saved-search-slack"
 )
 
-func searchURL(query, utmSource string) string {
+// getExternalURL returns the external URL of Sourcegraph, or nil if it cannot
+// be determined.
+func getExternalURL() *url.URL {
 	if externalURL == nil {
 		// Determine the external URL.
 		externalURLStr, err := api.InternalClient.ExternalURL(context.Background())
 		if err != nil {
 			log15.Error("failed to get ExternalURL", err)
-			return ""
+			return nil
 		}
 		externalURL, err = url.Parse(externalURLStr)
 		if err != nil {
 			log15.Error("failed to parse ExternalURL", err)
-			return ""
+			return nil
 		}
 	}
+	return externalURL
+}
+
+func searchURL(query, utmSource string) string {
+	if getExternalURL() == nil {
+		return ""
+	}
 
 	// Construct URL to the search query.
 	u := externalURL.ResolveReference(&url.URL{Path: "search"})
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+const (
+	// maxResultMatches is the maximum number of matches of a saved query that
+	// are stored to find the matches that appeared or disappeared.
+	maxResultMatches = 1000
+
+	// maxNotificationMatches is the maximum number of new and removed matches
+	// that are listed in a notification.
+	maxNotificationMatches = 10
+
+	// maxPreviewLength is the maximum length of a stored match preview.
+	maxPreviewLength = 200
+)
+
+// isCommitQuery reports whether query searches commits or diffs. These queries
+// support the after: field, so we only search for the results since the last
+// execution. Other queries are searched in full and compared with the matches
+// of the last execution.
+func isCommitQuery(query string) bool {
+	return strings.Contains(query, "type:diff") || strings.Contains(query, "type:commit")
+}
+
+// withResultLimit returns query with a count: field, so that all (up to
+// maxResultMatches) of its results are compared instead of the default
+// number.
+func withResultLimit(query string) string {
+	if strings.Contains(query, "count:") {
+		return query
+	}
+	return fmt.Sprintf("%s count:%d", query, maxResultMatches)
+}
+
+// gqlResult is the subset of the fields of a search result (see
+// gqlSearchQuery) that we use to find matches.
+type gqlResult struct {
+	Typename   string `json:"__typename"`
+	Repository struct {
+		Name string
+	}
+	File struct {
+		Path string
+	}
+	LineMatches []struct {
+		Preview    string
+		LineNumber int32
+	}
+	Commit struct {
+		Repository struct {
+			Name string
+		}
+		OID     string
+		Message string
+	}
+}
+
+// resultMatches returns the matches of the search results in v. File matches
+// have a match for every line, or a single match without a line if only their
+// path matched.
+func resultMatches(v *gqlSearchResponse) ([]*api.SavedQueryMatch, error) {
+	matches := []*api.SavedQueryMatch{}
+	for _, result := range v.Data.Search.Results.Results {
+		// Results are decoded into generic values because of extractTime, so
+		// we decode them again into gqlResult.
+		b, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		var r gqlResult
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, err
+		}
+
+		switch r.Typename {
+		case "FileMatch":
+			if len(r.LineMatches) == 0 {
+				matches = append(matches, &api.SavedQueryMatch{Repository: r.Repository.Name, Path: r.File.Path})
+			}
+			for _, lm := range r.LineMatches {
+				matches = append(matches, &api.SavedQueryMatch{
+					Repository: r.Repository.Name,
+					Path:       r.File.Path,
+					LineNumber: lm.LineNumber,
+					Preview:    truncate(lm.Preview),
+				})
+			}
+		case "CommitSearchResult":
+			matches = append(matches, &api.SavedQueryMatch{
+				Repository: r.Commit.Repository.Name,
+				Commit:     r.Commit.OID,
+				Preview:    truncate(firstLine(r.Commit.Message)),
+			})
+		}
+		if len(matches) >= maxResultMatches {
+			return matches[:maxResultMatches], nil
+		}
+	}
+	return matches, nil
+}
+
+// diffMatches returns the matches of new that are not in old, and the matches
+// of old that are not in new, compared by their fingerprints.
+func diffMatches(old, new []*api.SavedQueryMatch) (added, removed []*api.SavedQueryMatch) {
+	fingerprints := func(matches []*api.SavedQueryMatch) map[string]bool {
+		m := make(map[string]bool, len(matches))
+		for _, match := range matches {
+			m[match.Fingerprint()] = true
+		}
+		return m
+	}
+	oldFingerprints, newFingerprints := fingerprints(old), fingerprints(new)
+	for _, m := range new {
+		if !oldFingerprints[m.Fingerprint()] {
+			added = append(added, m)
+		}
+	}
+	for _, m := range old {
+		if !newFingerprints[m.Fingerprint()] {
+			removed = append(removed, m)
+		}
+	}
+	return added, removed
+}
+
+// changeSummary describes the new and removed matches of a notification, as in
+// "3 new results, 1 removed".
+func changeSummary(newCount string, removedCount int) string {
+	plural := func(count string) string {
+		if count == "1" {
+			return ""
+		}
+		return "s"
+	}
+	removed := strconv.Itoa(removedCount)
+	switch {
+	case removedCount == 0:
+		return fmt.Sprintf("%s new result%s", newCount, plural(newCount))
+	case newCount == "0":
+		return fmt.Sprintf("%s removed result%s", removed, plural(removed))
+	default:
+		return fmt.Sprintf("%s new result%s, %s removed", newCount, plural(newCount), removed)
+	}
+}
+
+// notificationMatch is a match as it is listed in notifications.
+type notificationMatch struct {
+	URL     string
+	Label   string // e.g. "github.com/foo/bar › cmd/main.go:12"
+	Preview string
+}
+
+// notificationMatches returns the first maxNotificationMatches matches for
+// listing in a notification, and the number of matches that were left out.
+func notificationMatches(matches []*api.SavedQueryMatch, utmSource string) (list []*notificationMatch, more int) {
+	if len(matches) > maxNotificationMatches {
+		matches, more = matches[:maxNotificationMatches], len(matches)-maxNotificationMatches
+	}
+	for _, m := range matches {
+		nm := &notificationMatch{Preview: strings.TrimSpace(m.Preview)}
+		switch {
+		case m.Commit != "":
+			commit := m.Commit
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+			nm.Label = fmt.Sprintf("%s › %s", m.Repository, commit)
+			nm.URL = sourcegraphURL(m.Repository+"/-/commit/"+m.Commit, "", utmSource)
+		case m.Preview != "":
+			nm.Label = fmt.Sprintf("%s › %s:%d", m.Repository, m.Path, m.LineNumber+1)
+			nm.URL = sourcegraphURL(m.Repository+"/-/blob/"+m.Path, fmt.Sprintf("L%d", m.LineNumber+1), utmSource)
+		default:
+			nm.Label = fmt.Sprintf("%s › %s", m.Repository, m.Path)
+			nm.URL = sourcegraphURL(m.Repository+"/-/blob/"+m.Path, "", utmSource)
+		}
+		list = append(list, nm)
+	}
+	return list, more
+}
+
+// sourcegraphURL returns the URL to the given path and fragment on
+// Sourcegraph, or an empty string if the external URL is not known.
+func sourcegraphURL(path, fragment, utmSource string) string {
+	base := getExternalURL()
+	if base == nil {
+		return ""
+	}
+	u := base.ResolveReference(&url.URL{Path: path, Fragment: fragment})
+	u.RawQuery = url.Values{"utm_source": []string{utmSource}}.Encode()
+	return u.String()
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func truncate(s string) string {
+	if len(s) <= maxPreviewLength {
+		return s
+	}
+	// Don't cut a UTF-8 sequence in half.
+	i := maxPreviewLength
+	for i > 0 && s[i]&0xC0 == 0x80 {
+		i--
+	}
+	return s[:i] + "…"
+}
+
+// summary describes the new and removed matches of the notification.
+func (n *notifier) summary() string {
+	newCount := strconv.Itoa(len(n.added))
+	if isCommitQuery(n.query.Query) {
+		// Commit queries are not run with a count:, so they may have more
+		// results than we found.
+		newCount = n.results.Data.Search.Results.ApproximateResultCount
+	}
+	return changeSummary(newCount, len(n.removed))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestResultMatches(t *testing.T) {
+	var v gqlSearchResponse
+	if err := json.Unmarshal([]byte(`{"data": {"search": {"results": {"results": [
+		{
+			"__typename": "FileMatch",
+			"repository": {"name": "r"},
+			"file": {"path": "a.go"},
+			"lineMatches": [
+				{"preview": "foo()", "lineNumber": 3},
+				{"preview": "foo := 1", "lineNumber": 7}
+			]
+		},
+		{"__typename": "FileMatch", "repository": {"name": "r"}, "file": {"path": "foo.go"}},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "r"}, "oid": "abc", "message": "Fix foo\n\nDetails"}
+		}
+	]}}}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resultMatches(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*api.SavedQueryMatch{
+		{Repository: "r", Path: "a.go", LineNumber: 3, Preview: "foo()"},
+		{Repository: "r", Path: "a.go", LineNumber: 7, Preview: "foo := 1"},
+		{Repository: "r", Path: "foo.go"},
+		{Repository: "r", Commit: "abc", Preview: "Fix foo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffMatches(t *testing.T) {
+	var (
+		kept    = &api.SavedQueryMatch{Repository: "r", Path: "a.go", LineNumber: 3, Preview: "foo()"}
+		moved   = &api.SavedQueryMatch{Repository: "r", Path: "a.go", LineNumber: 5, Preview: "foo()  "}
+		gone    = &api.SavedQueryMatch{Repository: "r", Path: "a.go", LineNumber: 7, Preview: "foo := 1"}
+		changed = &api.SavedQueryMatch{Repository: "r", Path: "a.go", LineNumber: 7, Preview: "foo := 2"}
+		newFile = &api.SavedQueryMatch{Repository: "r", Path: "foo.go"}
+	)
+	added, removed := diffMatches([]*api.SavedQueryMatch{kept, gone}, []*api.SavedQueryMatch{moved, changed, newFile})
+	if want := []*api.SavedQueryMatch{changed, newFile}; !reflect.DeepEqual(added, want) {
+		t.Errorf("got added %+v, want %+v", added, want)
+	}
+	if want := []*api.SavedQueryMatch{gone}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("got removed %+v, want %+v", removed, want)
+	}
+}
+
+func TestChangeSummary(t *testing.T) {
+	tests := []struct {
+		newCount string
+		removed  int
+		want     string
+	}{
+		{newCount: "1", want: "1 new result"},
+		{newCount: "30+", want: "30+ new results"},
+		{newCount: "0", removed: 1, want: "1 removed result"},
+		{newCount: "2", removed: 3, want: "2 new results, 3 removed"},
+	}
+	for _, test := range tests {
+		if got := changeSummary(test.newCount, test.removed); got != test.want {
+			t.Errorf("changeSummary(%q, %d) = %q, want %q", test.newCount, test.removed, got, test.want)
+		}
+	}
+}
+
+func TestNotificationMatches(t *testing.T) {
+	var err error
+	externalURL, err = url.Parse("https://sourcegraph.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { externalURL = nil }()
+
+	var matches []*api.SavedQueryMatch
+	for i := 0; i < maxNotificationMatches+2; i++ {
+		matches = append(matches, &api.SavedQueryMatch{Repository: "r", Path: "a.go", LineNumber: int32(i), Preview: " foo() "})
+	}
+	matches[1] = &api.SavedQueryMatch{Repository: "r", Commit: "0123456789abcdef", Preview: "Fix foo"}
+
+	list, more := notificationMatches(matches, utmSourceEmail)
+	if more != 2 {
+		t.Errorf("got %d more matches, want 2", more)
+	}
+	if len(list) != maxNotificationMatches {
+		t.Fatalf("got %d matches, want %d", len(list), maxNotificationMatches)
+	}
+	want := []*notificationMatch{
+		{URL: "https://sourcegraph.example.com/r/-/blob/a.go?utm_source=saved-search-email#L1", Label: "r › a.go:1", Preview: "foo()"},
+		{URL: "https://sourcegraph.example.com/r/-/commit/0123456789abcdef?utm_source=saved-search-email", Label: "r › 0123456", Preview: "Fix foo"},
+	}
+	if !reflect.DeepEqual(list[:2], want) {
+		t.Errorf("got %+v, want %+v", list[:2], want)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	log15 "gopkg.in/inconshreveable/log15.v2"
 
@@ -11,17 +12,32 @@ import (
 )
 
 func (n *notifier) slackNotify(ctx context.Context) {
-	plural := ""
-	if n.results.Data.Search.Results.ApproximateResultCount != "1" {
-		plural = "s"
-	}
-
-	text := fmt.Sprintf(`*%s* new result%s found for saved search <%s|"%s">`,
-		n.results.Data.Search.Results.ApproximateResultCount,
-		plural,
+	var b strings.Builder
+	fmt.Fprintf(&b, `*%s* for saved search <%s|"%s">`,
+		n.summary(),
 		searchURL(n.newQuery, utmSourceSlack),
 		n.query.Description,
 	)
+	writeMatches := func(title string, matches []*api.SavedQueryMatch) {
+		if len(matches) == 0 {
+			return
+		}
+		list, more := notificationMatches(matches, utmSourceSlack)
+		fmt.Fprintf(&b, "\n\n%s", title)
+		for _, m := range list {
+			fmt.Fprintf(&b, "\n• <%s|%s>", m.URL, slackEscape(m.Label))
+			if m.Preview != "" {
+				fmt.Fprintf(&b, " `%s`", slackEscape(strings.ReplaceAll(m.Preview, "`", "'")))
+			}
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, "\n…and %d more", more)
+		}
+	}
+	writeMatches("New results:", n.added)
+	writeMatches("Removed results:", n.removed)
+	text := b.String()
+
 	for _, recipient := range n.recipients {
 		if err := slackNotify(ctx, recipient, text, n.query.SlackWebhookURL); err != nil {
 			log15.Error("Failed to post Slack notification message.", "recipient", recipient, "text", text, "error", err)
@@ -31,6 +47,12 @@ func (n *notifier) slackNotify(ctx context.Context) {
 	logEvent(0, "SavedSearchSlackNotificationSent", "results")
 }
 
+// slackEscape escapes the characters that have a special meaning in Slack
+// messages.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
 func slackNotifySubscribed(ctx context.Context, recipient *recipient, query api.SavedQuerySpecAndConfig) error {
 	text := fmt.Sprintf(`Slack notifications enabled for the saved search <%s|"%s">. Notifications will be sent here when new results are available.`,
 		searchURL(query.Config.Query, utmSourceSlack),
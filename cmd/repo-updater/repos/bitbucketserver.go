@@ -29,6 +29,7 @@ type BitbucketServerSource struct {
 	config          *schema.BitbucketServerConnection
 	exclude         map[string]bool
 	excludePatterns []*regexp.Regexp
+	cloneStrategies cloneStrategies
 	client          *bitbucketserver.Client
 }
 
@@ -82,6 +83,13 @@ func newBitbucketServerSource(svc *ExternalService, c *schema.BitbucketServerCon
 		}
 	}
 
+	var cloneStrategies cloneStrategies
+	for _, cs := range c.CloneStrategies {
+		if err := cloneStrategies.add(cs.Pattern, cs.Filter, cs.Depth); err != nil {
+			return nil, err
+		}
+	}
+
 	client := bitbucketserver.NewClient(baseURL, cli)
 	client.Token = c.Token
 	client.Username = c.Username
@@ -92,6 +100,7 @@ func newBitbucketServerSource(svc *ExternalService, c *schema.BitbucketServerCon
 		config:          c,
 		exclude:         exclude,
 		excludePatterns: excludePatterns,
+		cloneStrategies: cloneStrategies,
 		client:          client,
 	}, nil
 }
@@ -278,6 +287,8 @@ func (s BitbucketServerSource) makeRepo(repo *bitbucketserver.Repo, isArchived b
 		}
 	}
 
+	cloneFilter, cloneDepth := s.cloneStrategies.match(project + "/" + repo.Slug)
+
 	// Repo Links
 	// var links *protocol.RepoLinks
 	// for _, l := range repo.Links.Self {
@@ -317,8 +328,11 @@ func (s BitbucketServerSource) makeRepo(repo *bitbucketserver.Repo, isArchived b
 		Private:     !repo.Public,
 		Sources: map[string]*SourceInfo{
 			urn: {
-				ID:       urn,
-				CloneURL: cloneURL,
+				ID:          urn,
+				CloneURL:    cloneURL,
+				LFS:         s.config.GitLFS,
+				CloneFilter: cloneFilter,
+				CloneDepth:  cloneDepth,
 			},
 		},
 		Metadata: repo,
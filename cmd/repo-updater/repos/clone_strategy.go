@@ -0,0 +1,36 @@
+package repos
+
+import "regexp"
+
+// A cloneStrategy selects how gitserver clones the repos whose names match
+// pattern. See the cloneStrategies option of code host connections.
+type cloneStrategy struct {
+	pattern *regexp.Regexp
+	filter  string
+	depth   int
+}
+
+// cloneStrategies are the clone strategies of a code host connection, in
+// order of precedence.
+type cloneStrategies []cloneStrategy
+
+func (cs *cloneStrategies) add(pattern, filter string, depth int) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	*cs = append(*cs, cloneStrategy{pattern: re, filter: filter, depth: depth})
+	return nil
+}
+
+// match returns the partial clone filter and shallow clone depth of the first
+// strategy that matches name. They are empty if no strategy matches, in which
+// case the repo is cloned fully.
+func (cs cloneStrategies) match(name string) (filter string, depth int) {
+	for _, s := range cs {
+		if s.pattern.MatchString(name) {
+			return s.filter, s.depth
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,236 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// A GerritSource yields repositories from a single Gerrit connection configured
+// in Sourcegraph via the external services configuration.
+type GerritSource struct {
+	svc             *ExternalService
+	config          *schema.GerritConnection
+	exclude         map[string]bool
+	excludePatterns []*regexp.Regexp
+	baseURL         *url.URL // URL with a trailing slash
+	client          *gerrit.Client
+}
+
+// NewGerritSource returns a new GerritSource from the given external service.
+func NewGerritSource(svc *ExternalService, cf *httpcli.Factory) (*GerritSource, error) {
+	var c schema.GerritConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, fmt.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+	return newGerritSource(svc, &c, cf)
+}
+
+func newGerritSource(svc *ExternalService, c *schema.GerritConnection, cf *httpcli.Factory) (*GerritSource, error) {
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = extsvc.NormalizeBaseURL(baseURL)
+
+	if cf == nil {
+		cf = httpcli.NewExternalHTTPClientFactory()
+	}
+
+	var opts []httpcli.Opt
+	if c.Certificate != "" {
+		opts = append(opts, httpcli.NewCertPoolOpt(c.Certificate))
+	}
+
+	cli, err := cf.Doer(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make(map[string]bool, len(c.Exclude))
+	var excludePatterns []*regexp.Regexp
+	for _, r := range c.Exclude {
+		if r.Name != "" {
+			exclude[r.Name] = true
+		}
+
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			excludePatterns = append(excludePatterns, re)
+		}
+	}
+
+	return &GerritSource{
+		svc:             svc,
+		config:          c,
+		exclude:         exclude,
+		excludePatterns: excludePatterns,
+		baseURL:         baseURL,
+		client:          gerrit.NewClient(baseURL, c.Username, c.Password, cli),
+	}, nil
+}
+
+// ListRepos returns all Gerrit projects accessible to all connections configured
+// in Sourcegraph via the external services configuration.
+func (s GerritSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	s.listAllRepos(ctx, results)
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s GerritSource) ExternalServices() ExternalServices {
+	return ExternalServices{s.svc}
+}
+
+func (s GerritSource) makeRepo(p *gerrit.Project, refs []string) *Repo {
+	urn := s.svc.URN()
+	return &Repo{
+		Name: string(reposource.GerritRepoName(
+			s.config.RepositoryPathPattern,
+			s.baseURL.Hostname(),
+			p.Name,
+		)),
+		URI: string(reposource.GerritRepoName(
+			"",
+			s.baseURL.Hostname(),
+			p.Name,
+		)),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          p.ID,
+			ServiceType: gerrit.ServiceType,
+			ServiceID:   s.baseURL.String(),
+		},
+		Description: p.Description,
+		Archived:    p.State == "READ_ONLY",
+		Sources: map[string]*SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: s.authenticatedRemoteURL(p),
+				Refs:     refs,
+			},
+		},
+		Metadata: p,
+	}
+}
+
+// authenticatedRemoteURL returns the project's Git remote URL with the configured
+// HTTP credentials inserted in the URL userinfo.
+func (s *GerritSource) authenticatedRemoteURL(p *gerrit.Project) string {
+	u := *s.baseURL
+	if s.config.Username == "" {
+		u.Path += p.Name
+		return u.String()
+	}
+	// Like the REST API, authenticated Git requests are made to the /a/ endpoints.
+	u.Path += "a/" + p.Name
+	u.User = url.UserPassword(s.config.Username, s.config.Password)
+	return u.String()
+}
+
+func (s *GerritSource) excludes(p *gerrit.Project) bool {
+	if s.exclude[p.Name] || p.State == "HIDDEN" {
+		return true
+	}
+
+	for _, re := range s.excludePatterns {
+		if re.MatchString(p.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GerritSource) listAllRepos(ctx context.Context, results chan SourceResult) {
+	var projects []*gerrit.Project
+	seen := make(map[string]bool)
+	add := func(ps ...*gerrit.Project) {
+		for _, p := range ps {
+			if !seen[p.Name] && !s.excludes(p) {
+				projects = append(projects, p)
+				seen[p.Name] = true
+			}
+		}
+	}
+
+	// List the projects selected individually
+	for _, name := range s.config.Projects {
+		p, err := s.client.Project(ctx, name)
+		if err != nil {
+			if gerrit.IsNotFound(err) {
+				log15.Warn("skipping missing gerrit.projects entry:", "name", name, "err", err)
+				continue
+			}
+			results <- SourceResult{Source: s, Err: errors.Wrapf(err, "gerrit.projects: item=%q", name)}
+			continue
+		}
+		add(p)
+	}
+
+	// List all projects matching the project queries that are visible to the account
+	for _, query := range s.config.ProjectQuery {
+		for start, more := 0, true; more; {
+			var ps []*gerrit.Project
+			var err error
+			ps, more, err = s.client.QueryProjects(ctx, query, start)
+			if err != nil {
+				results <- SourceResult{Source: s, Err: errors.Wrapf(err, "gerrit.projectQuery: item=%q, start=%d", query, start)}
+				break
+			}
+			add(ps...)
+			start += len(ps)
+		}
+	}
+
+	// The projects are not listed without their change refs, since the refs
+	// that are not listed are deleted from the repos.
+	refs, err := s.listChangeRefs(ctx, seen)
+	if err != nil {
+		results <- SourceResult{Source: s, Err: err}
+		return
+	}
+
+	for _, p := range projects {
+		results <- SourceResult{Source: s, Repo: s.makeRepo(p, refs[p.Name])}
+	}
+}
+
+// listChangeRefs returns the sorted refs of the current patch sets of the changes
+// matching the change query by the names of their projects, for the given projects.
+func (s *GerritSource) listChangeRefs(ctx context.Context, projects map[string]bool) (map[string][]string, error) {
+	if s.config.ChangeQuery == "" || len(projects) == 0 {
+		return nil, nil
+	}
+
+	refs := make(map[string][]string)
+	for start, more := 0, true; more; {
+		changes, next, err := s.client.QueryChanges(ctx, s.config.ChangeQuery, start)
+		if err != nil {
+			return nil, errors.Wrapf(err, "gerrit.changeQuery: item=%q, start=%d", s.config.ChangeQuery, start)
+		}
+		for _, c := range changes {
+			if ref := c.CurrentRef(); ref != "" && projects[c.Project] {
+				refs[c.Project] = append(refs[c.Project], ref)
+			}
+		}
+		start, more = start+len(changes), next
+	}
+
+	for _, rs := range refs {
+		sort.Strings(rs)
+	}
+	return refs, nil
+}
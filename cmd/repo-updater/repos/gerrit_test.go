@@ -0,0 +1,200 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// newFakeGerrit returns a server that implements the parts of the Gerrit REST API that
+// the GerritSource uses. The projects matching the query state:active are listed on two
+// pages, and the change query status:broken fails.
+func newFakeGerrit(t testing.TB) *httptest.Server {
+	project := func(name, state string) string {
+		return fmt.Sprintf(`{"id":%q,"name":%q,"state":%q}`, strings.Replace(name, "/", "%2F", -1), name, state)
+	}
+	change := func(project string, number, patchSet int) string {
+		return fmt.Sprintf(`{"project":%q,"_number":%d,"current_revision":"c%d","revisions":{"c%d":{"_number":%d,"ref":"refs/changes/%02d/%d/%d"}}}`,
+			project, number, number, number, patchSet, number%100, number, patchSet)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("S"))
+		var body string
+		switch r.URL.EscapedPath() {
+		case "/a/projects/":
+			if r.URL.Query().Get("query") != "state:active" {
+				body = "[]"
+			} else if start == 0 {
+				body = fmt.Sprintf(`[%s,%s]`, project("platform/build", "ACTIVE"), strings.TrimSuffix(project("platform/secret-plans", "ACTIVE"), "}")+`,"_more_projects":true}`)
+			} else {
+				body = fmt.Sprintf(`[%s,%s]`, project("tools/repo", "ACTIVE"), project("tools/hidden", "HIDDEN"))
+			}
+		case "/a/projects/archive%2Fold":
+			body = project("archive/old", "READ_ONLY")
+		case "/a/projects/tools%2Frepo":
+			body = project("tools/repo", "ACTIVE")
+		case "/a/changes/":
+			if r.URL.Query().Get("q") == "status:broken" {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if r.URL.Query().Get("q") != "status:open" || r.URL.Query().Get("o") != "CURRENT_REVISION" {
+				t.Errorf("unexpected change query %q", r.URL.RawQuery)
+			}
+			body = fmt.Sprintf(`[%s,%s,%s]`, change("tools/repo", 12345, 3), change("platform/build", 101, 1), change("platform/secret-plans", 7, 2))
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, ")]}'\n"+body)
+	}))
+}
+
+func TestGerritSource_ListRepos(t *testing.T) {
+	srv := newFakeGerrit(t)
+	defer srv.Close()
+
+	svc := &ExternalService{ID: 1, Kind: "GERRIT"}
+	src, err := newGerritSource(svc, &schema.GerritConnection{
+		Url:          srv.URL,
+		Username:     "admin",
+		Password:     "secret",
+		Projects:     []string{"archive/old", "tools/repo", "missing"},
+		ProjectQuery: []string{"state:active"},
+		Exclude: []*schema.ExcludedGerritProject{
+			{Pattern: "secret"},
+		},
+		ChangeQuery: "status:open",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := listAll(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	host = host[:strings.Index(host, ":")]
+	want := []string{host + "/archive/old", host + "/platform/build", host + "/tools/repo"}
+	have := Repos(repos).Names()
+	sort.Strings(have)
+	if !reflect.DeepEqual(have, want) {
+		t.Error(cmp.Diff(have, want))
+	}
+
+	for _, r := range repos {
+		src := r.Sources[svc.URN()]
+		switch r.Name {
+		case host + "/tools/repo":
+			if want := (api.ExternalRepoSpec{ID: "tools%2Frepo", ServiceType: gerrit.ServiceType, ServiceID: srv.URL + "/"}); r.ExternalRepo != want {
+				t.Errorf("got external repo %+v, want %+v", r.ExternalRepo, want)
+			}
+			if have, want := src.CloneURL, "http://admin:secret@"+strings.TrimPrefix(srv.URL, "http://")+"/a/tools/repo"; have != want {
+				t.Errorf("got clone URL %q, want %q", have, want)
+			}
+			if have, want := src.Refs, []string{"refs/changes/45/12345/3"}; !reflect.DeepEqual(have, want) {
+				t.Errorf("got refs %v, want %v", have, want)
+			}
+		case host + "/archive/old":
+			if !r.Archived || len(src.Refs) != 0 {
+				t.Errorf("got archived %t and refs %v for a read-only project without changes", r.Archived, src.Refs)
+			}
+		}
+	}
+}
+
+func TestGerritSource_ListRepos_unauthorized(t *testing.T) {
+	srv := newFakeGerrit(t)
+	defer srv.Close()
+
+	src, err := newGerritSource(&ExternalService{ID: 1, Kind: "GERRIT"}, &schema.GerritConnection{
+		Url:          srv.URL,
+		Username:     "admin",
+		Password:     "wrong",
+		ProjectQuery: []string{"state:active"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := listAll(context.Background(), src); err == nil || !strings.Contains(err.Error(), "code=401") {
+		t.Errorf("got error %v, want unauthorized", err)
+	}
+}
+
+func TestGerritSource_ListRepos_changeQueryError(t *testing.T) {
+	srv := newFakeGerrit(t)
+	defer srv.Close()
+
+	src, err := newGerritSource(&ExternalService{ID: 1, Kind: "GERRIT"}, &schema.GerritConnection{
+		Url:          srv.URL,
+		Username:     "admin",
+		Password:     "secret",
+		ProjectQuery: []string{"state:active"},
+		ChangeQuery:  "status:broken",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Listing the projects without their change refs would delete the refs
+	// from the repos.
+	repos, err := listAll(context.Background(), src)
+	if err == nil || !strings.Contains(err.Error(), "gerrit.changeQuery") {
+		t.Errorf("got error %v, want change query error", err)
+	}
+	if len(repos) != 0 {
+		t.Errorf("got repos %v, want none", Repos(repos).Names())
+	}
+}
+
+func TestExternalService_excludeGerritRepos(t *testing.T) {
+	svc := &ExternalService{
+		Kind: "GERRIT",
+		Config: formatJSON(t, `
+		{
+			// Some comment
+			"url": "https://gerrit.mycorp.com",
+			"projectQuery": ["state:active"],
+			"exclude": [{"name": "All-Projects"}]
+		}`),
+	}
+
+	err := svc.Exclude(
+		&Repo{Metadata: &gerrit.Project{ID: "All-Projects", Name: "All-Projects"}},
+		&Repo{Metadata: &gerrit.Project{ID: "platform%2Fbuild", Name: "platform/build"}},
+		&Repo{Metadata: &gerrit.Project{ID: "platform%2Fbuild", Name: "platform/build"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := formatJSON(t, `
+	{
+		// Some comment
+		"url": "https://gerrit.mycorp.com",
+		"projectQuery": ["state:active"],
+		"exclude": [{"name": "All-Projects"}, {"name": "platform/build"}]
+	}`)
+	if svc.Config != want {
+		t.Error(cmp.Diff(svc.Config, want))
+	}
+}
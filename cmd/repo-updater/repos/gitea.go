@@ -0,0 +1,261 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// A GiteaSource yields repositories from a single Gitea or Gogs connection configured
+// in Sourcegraph via the external services configuration.
+type GiteaSource struct {
+	svc             *ExternalService
+	config          *schema.GiteaConnection
+	exclude         map[string]bool
+	excludePatterns []*regexp.Regexp
+	baseURL         *url.URL // URL with a trailing slash
+	client          *gitea.Client
+}
+
+// NewGiteaSource returns a new GiteaSource from the given external service.
+func NewGiteaSource(svc *ExternalService, cf *httpcli.Factory) (*GiteaSource, error) {
+	var c schema.GiteaConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, fmt.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+	return newGiteaSource(svc, &c, cf)
+}
+
+func newGiteaSource(svc *ExternalService, c *schema.GiteaConnection, cf *httpcli.Factory) (*GiteaSource, error) {
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = extsvc.NormalizeBaseURL(baseURL)
+
+	if cf == nil {
+		cf = httpcli.NewExternalHTTPClientFactory()
+	}
+
+	var opts []httpcli.Opt
+	if c.Certificate != "" {
+		opts = append(opts, httpcli.NewCertPoolOpt(c.Certificate))
+	}
+
+	cli, err := cf.Doer(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make(map[string]bool, len(c.Exclude))
+	var excludePatterns []*regexp.Regexp
+	for _, r := range c.Exclude {
+		if r.Name != "" {
+			exclude[strings.ToLower(r.Name)] = true
+		}
+
+		if r.Id != 0 {
+			exclude[strconv.Itoa(r.Id)] = true
+		}
+
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			excludePatterns = append(excludePatterns, re)
+		}
+	}
+
+	return &GiteaSource{
+		svc:             svc,
+		config:          c,
+		exclude:         exclude,
+		excludePatterns: excludePatterns,
+		baseURL:         baseURL,
+		client:          gitea.NewClient(baseURL, c.Token, cli),
+	}, nil
+}
+
+// ListRepos returns all Gitea repositories accessible to all connections configured
+// in Sourcegraph via the external services configuration.
+func (s GiteaSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	s.listAllRepos(ctx, results)
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s GiteaSource) ExternalServices() ExternalServices {
+	return ExternalServices{s.svc}
+}
+
+func (s GiteaSource) makeRepo(r *gitea.Repo) *Repo {
+	urn := s.svc.URN()
+	return &Repo{
+		Name: string(reposource.GiteaRepoName(
+			s.config.RepositoryPathPattern,
+			s.baseURL.Hostname(),
+			r.FullName,
+		)),
+		URI: string(reposource.GiteaRepoName(
+			"",
+			s.baseURL.Hostname(),
+			r.FullName,
+		)),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          strconv.FormatInt(r.ID, 10),
+			ServiceType: gitea.ServiceType,
+			ServiceID:   s.baseURL.String(),
+		},
+		Description: r.Description,
+		Fork:        r.Fork,
+		Archived:    r.Archived,
+		Private:     r.Private,
+		Sources: map[string]*SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: s.authenticatedRemoteURL(r),
+			},
+		},
+		Metadata: r,
+	}
+}
+
+// authenticatedRemoteURL returns the repository's Git remote URL with the configured
+// access token inserted in the URL userinfo.
+func (s *GiteaSource) authenticatedRemoteURL(repo *gitea.Repo) string {
+	if s.config.GitURLType == "ssh" {
+		return repo.SSHURL // SSH authentication must be provided out-of-band
+	}
+	u, err := url.Parse(repo.CloneURL)
+	if err != nil {
+		log15.Warn("Error adding authentication to Gitea repository Git remote URL.", "url", repo.CloneURL, "error", err)
+		return repo.CloneURL
+	}
+	// Both Gitea and Gogs accept an access token as the username with this password.
+	u.User = url.UserPassword(s.config.Token, "x-oauth-basic")
+	return u.String()
+}
+
+func (s *GiteaSource) excludes(r *gitea.Repo) bool {
+	if s.exclude[strings.ToLower(r.FullName)] ||
+		s.exclude[strconv.FormatInt(r.ID, 10)] {
+		return true
+	}
+
+	for _, re := range s.excludePatterns {
+		if re.MatchString(r.FullName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GiteaSource) listAllRepos(ctx context.Context, results chan SourceResult) {
+	type batch struct {
+		repos []*gitea.Repo
+		err   error
+	}
+
+	ch := make(chan batch)
+
+	var wg sync.WaitGroup
+
+	// listPages sends all pages of a paginated list of repositories to ch.
+	listPages := func(kind, name string, list func(ctx context.Context, name string, page int) ([]*gitea.Repo, int, error)) {
+		for page := 1; page != 0; {
+			repos, next, err := list(ctx, name, page)
+			if err != nil {
+				ch <- batch{err: errors.Wrapf(err, "gitea.%s: item=%q, page=%d", kind, name, page)}
+				break
+			}
+
+			ch <- batch{repos: repos}
+			page = next
+		}
+	}
+
+	// List all repositories of the organizations selected that the token has access to
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for _, org := range s.config.Orgs {
+			listPages("orgs", org, s.client.OrgRepos)
+		}
+	}()
+
+	// List all repositories of the users selected that the token has access to
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for _, user := range s.config.Users {
+			listPages("users", user, s.client.UserRepos)
+		}
+	}()
+
+	// List the repositories selected individually
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for _, nameWithOwner := range s.config.Repos {
+			i := strings.Index(nameWithOwner, "/")
+			if i < 0 {
+				ch <- batch{err: errors.Errorf("gitea.repos: invalid repository name %q", nameWithOwner)}
+				continue
+			}
+
+			repo, err := s.client.Repo(ctx, nameWithOwner[:i], nameWithOwner[i+1:])
+			if err != nil {
+				if gitea.IsNotFound(err) {
+					log15.Warn("skipping missing gitea.repos entry:", "name", nameWithOwner, "err", err)
+					continue
+				}
+				ch <- batch{err: errors.Wrapf(err, "gitea.repos: item=%q", nameWithOwner)}
+				continue
+			}
+
+			ch <- batch{repos: []*gitea.Repo{repo}}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	seen := make(map[int64]bool)
+	for r := range ch {
+		if r.err != nil {
+			results <- SourceResult{Source: s, Err: r.err}
+			continue
+		}
+
+		for _, repo := range r.repos {
+			// Discard repositories without any commits, which can't be cloned
+			if repo.Empty {
+				continue
+			}
+
+			if !seen[repo.ID] && !s.excludes(repo) {
+				results <- SourceResult{Source: s, Repo: s.makeRepo(repo)}
+				seen[repo.ID] = true
+			}
+		}
+	}
+}
@@ -30,6 +30,7 @@ type GithubSource struct {
 	config          *schema.GitHubConnection
 	exclude         map[string]bool
 	excludePatterns []*regexp.Regexp
+	cloneStrategies cloneStrategies
 	githubDotCom    bool
 	baseURL         *url.URL
 	client          *github.Client
@@ -100,11 +101,19 @@ func newGithubSource(svc *ExternalService, c *schema.GitHubConnection, cf *httpc
 		}
 	}
 
+	var cloneStrategies cloneStrategies
+	for _, cs := range c.CloneStrategies {
+		if err := cloneStrategies.add(cs.Pattern, cs.Filter, cs.Depth); err != nil {
+			return nil, err
+		}
+	}
+
 	return &GithubSource{
 		svc:              svc,
 		config:           c,
 		exclude:          exclude,
 		excludePatterns:  excludePatterns,
+		cloneStrategies:  cloneStrategies,
 		baseURL:          baseURL,
 		githubDotCom:     githubDotCom,
 		client:           github.NewClient(apiURL, c.Token, cli),
@@ -265,6 +274,7 @@ func (s GithubSource) GetRepo(ctx context.Context, nameWithOwner string) (*Repo,
 
 func (s GithubSource) makeRepo(r *github.Repository) *Repo {
 	urn := s.svc.URN()
+	cloneFilter, cloneDepth := s.cloneStrategies.match(r.NameWithOwner)
 	return &Repo{
 		Name: string(reposource.GitHubRepoName(
 			s.config.RepositoryPathPattern,
@@ -283,8 +293,11 @@ func (s GithubSource) makeRepo(r *github.Repository) *Repo {
 		Private:      r.IsPrivate,
 		Sources: map[string]*SourceInfo{
 			urn: {
-				ID:       urn,
-				CloneURL: s.authenticatedRemoteURL(r),
+				ID:          urn,
+				CloneURL:    s.authenticatedRemoteURL(r),
+				LFS:         s.config.GitLFS,
+				CloneFilter: cloneFilter,
+				CloneDepth:  cloneDepth,
 			},
 		},
 		Metadata: r,
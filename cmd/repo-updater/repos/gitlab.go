@@ -15,6 +15,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	"github.com/sourcegraph/sourcegraph/schema"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -25,6 +26,7 @@ type GitLabSource struct {
 	svc                 *ExternalService
 	config              *schema.GitLabConnection
 	exclude             map[string]bool
+	cloneStrategies     cloneStrategies
 	baseURL             *url.URL // URL with path /api/v4 (no trailing slash)
 	nameTransformations reposource.NameTransformations
 	client              *gitlab.Client
@@ -77,10 +79,18 @@ func newGitLabSource(svc *ExternalService, c *schema.GitLabConnection, cf *httpc
 		return nil, err
 	}
 
+	var cloneStrategies cloneStrategies
+	for _, cs := range c.CloneStrategies {
+		if err := cloneStrategies.add(cs.Pattern, cs.Filter, cs.Depth); err != nil {
+			return nil, err
+		}
+	}
+
 	return &GitLabSource{
 		svc:                 svc,
 		config:              c,
 		exclude:             exclude,
+		cloneStrategies:     cloneStrategies,
 		baseURL:             baseURL,
 		nameTransformations: nts,
 		client:              gitlab.NewClientProvider(baseURL, cli).GetPATClient(c.Token, ""),
@@ -90,7 +100,15 @@ func newGitLabSource(svc *ExternalService, c *schema.GitLabConnection, cf *httpc
 // ListRepos returns all GitLab repositories accessible to all connections configured
 // in Sourcegraph via the external services configuration.
 func (s GitLabSource) ListRepos(ctx context.Context, results chan SourceResult) {
-	s.listAllProjects(ctx, results)
+	s.listAllProjects(ctx, time.Time{}, results)
+}
+
+// ListReposSince returns the GitLab repositories selected by the projects of the
+// connection and those matching its project queries which had activity since the
+// given time, as filtered with the last_activity_after parameter. Changes to projects
+// which don't count as activity are picked up by the next call to ListRepos.
+func (s GitLabSource) ListReposSince(ctx context.Context, since time.Time, results chan SourceResult) {
+	s.listAllProjects(ctx, since, results)
 }
 
 // GetRepo returns the GitLab repository with the given pathWithNamespace.
@@ -112,8 +130,136 @@ func (s GitLabSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
+var _ ChangesetSource = GitLabSource{}
+
+// CreateChangeset creates the given *Changeset in the code host as a merge
+// request.
+func (s GitLabSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	var exists bool
+	proj := c.Repo.Metadata.(*gitlab.Project)
+
+	mr, err := s.client.CreateMergeRequest(ctx, &gitlab.CreateMergeRequestInput{
+		ProjectID:    proj.ID,
+		SourceBranch: git.AbbreviateRef(c.HeadRef),
+		TargetBranch: git.AbbreviateRef(c.BaseRef),
+		Title:        c.Title,
+		Description:  c.Body,
+	})
+
+	if err != nil {
+		if err != gitlab.ErrMergeRequestAlreadyExists {
+			return exists, err
+		}
+		mr, err = s.client.GetOpenMergeRequestByRefs(ctx, proj.ID, git.AbbreviateRef(c.HeadRef), git.AbbreviateRef(c.BaseRef))
+		if err != nil {
+			return exists, errors.Wrap(err, "fetching existing MR")
+		}
+		exists = true
+	}
+
+	c.Changeset.Metadata = mr
+	c.Changeset.ExternalID = strconv.Itoa(mr.IID)
+	c.Changeset.ExternalServiceType = gitlab.ServiceType
+
+	return exists, nil
+}
+
+// CloseChangeset closes the merge request of the given *Changeset on the code
+// host and updates the Metadata column in the *campaigns.Changeset to the newly
+// closed merge request.
+func (s GitLabSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+
+	updated, err := s.client.UpdateMergeRequest(ctx, &gitlab.UpdateMergeRequestInput{
+		ProjectID:  mr.ProjectID,
+		IID:        mr.IID,
+		StateEvent: "close",
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Changeset.Metadata = withMergeRequestDetails(updated, mr)
+
+	return nil
+}
+
+// LoadChangesets loads the latest state of the given Changesets from the codehost.
+func (s GitLabSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	var notFound []*Changeset
+
+	for i := range cs {
+		proj := cs[i].Repo.Metadata.(*gitlab.Project)
+		iid, err := strconv.Atoi(cs[i].ExternalID)
+		if err != nil {
+			return errors.Wrap(err, "parsing changeset external id")
+		}
+
+		mr, err := s.client.LoadMergeRequest(ctx, proj.ID, iid)
+		if err != nil {
+			if gitlab.IsNotFound(err) {
+				notFound = append(notFound, cs[i])
+				if cs[i].Changeset.Metadata == nil {
+					cs[i].Changeset.Metadata = &gitlab.MergeRequest{ProjectID: proj.ID, IID: iid}
+				}
+				continue
+			}
+
+			return err
+		}
+
+		cs[i].Changeset.ExternalBranch = mr.SourceBranch
+		cs[i].Changeset.ExternalUpdatedAt = mr.UpdatedAt
+		cs[i].Changeset.Metadata = mr
+	}
+
+	if len(notFound) > 0 {
+		return ChangesetsNotFoundError{Changesets: notFound}
+	}
+
+	return nil
+}
+
+// UpdateChangeset updates the merge request of the given *Changeset in the
+// code host.
+func (s GitLabSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	mr, ok := c.Changeset.Metadata.(*gitlab.MergeRequest)
+	if !ok {
+		return errors.New("Changeset is not a GitLab merge request")
+	}
+
+	updated, err := s.client.UpdateMergeRequest(ctx, &gitlab.UpdateMergeRequestInput{
+		ProjectID:    mr.ProjectID,
+		IID:          mr.IID,
+		Title:        c.Title,
+		Description:  c.Body,
+		TargetBranch: git.AbbreviateRef(c.BaseRef),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Changeset.Metadata = withMergeRequestDetails(updated, mr)
+
+	return nil
+}
+
+// withMergeRequestDetails copies the notes, pipelines and approvals, which
+// aren't returned when a merge request is updated, of the old merge request
+// into the updated one until the changeset is synced again.
+func withMergeRequestDetails(updated, old *gitlab.MergeRequest) *gitlab.MergeRequest {
+	updated.Notes = old.Notes
+	updated.Pipelines = old.Pipelines
+	updated.ApprovedBy = old.ApprovedBy
+	return updated
+}
+
 func (s GitLabSource) makeRepo(proj *gitlab.Project) *Repo {
 	urn := s.svc.URN()
+	cloneFilter, cloneDepth := s.cloneStrategies.match(proj.PathWithNamespace)
 	return &Repo{
 		Name: string(reposource.GitLabRepoName(
 			s.config.RepositoryPathPattern,
@@ -134,8 +280,11 @@ func (s GitLabSource) makeRepo(proj *gitlab.Project) *Repo {
 		Private:      proj.Visibility == "private",
 		Sources: map[string]*SourceInfo{
 			urn: {
-				ID:       urn,
-				CloneURL: s.authenticatedRemoteURL(proj),
+				ID:          urn,
+				CloneURL:    s.authenticatedRemoteURL(proj),
+				LFS:         s.config.GitLFS,
+				CloneFilter: cloneFilter,
+				CloneDepth:  cloneDepth,
 			},
 		},
 		Metadata: proj,
@@ -165,7 +314,7 @@ func (s *GitLabSource) excludes(p *gitlab.Project) bool {
 	return s.exclude[p.PathWithNamespace] || s.exclude[strconv.Itoa(p.ID)]
 }
 
-func (s *GitLabSource) listAllProjects(ctx context.Context, results chan SourceResult) {
+func (s *GitLabSource) listAllProjects(ctx context.Context, since time.Time, results chan SourceResult) {
 	type batch struct {
 		projs []*gitlab.Project
 		err   error
@@ -229,7 +378,7 @@ func (s *GitLabSource) listAllProjects(ctx context.Context, results chan SourceR
 		go func(projectQuery string) {
 			defer wg.Done()
 
-			url, err := projectQueryToURL(projectQuery, perPage) // first page URL
+			url, err := projectQueryToURL(projectQuery, perPage, since) // first page URL
 			if err != nil {
 				ch <- batch{err: errors.Wrapf(err, "invalid GitLab projectQuery=%q", projectQuery)}
 				return
@@ -280,7 +429,7 @@ func (s *GitLabSource) listAllProjects(ctx context.Context, results chan SourceR
 
 var schemeOrHostNotEmptyErr = errors.New("scheme and host should be empty")
 
-func projectQueryToURL(projectQuery string, perPage int) (string, error) {
+func projectQueryToURL(projectQuery string, perPage int, since time.Time) (string, error) {
 	// If all we have is the URL query, prepend "projects"
 	if strings.HasPrefix(projectQuery, "?") {
 		projectQuery = "projects" + projectQuery
@@ -297,6 +446,9 @@ func projectQueryToURL(projectQuery string, perPage int) (string, error) {
 	}
 	q := u.Query()
 	q.Set("per_page", strconv.Itoa(perPage))
+	if !since.IsZero() {
+		q.Set("last_activity_after", since.UTC().Format(time.RFC3339))
+	}
 	u.RawQuery = q.Encode()
 
 	return u.String(), nil
@@ -7,10 +7,13 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
@@ -22,6 +25,7 @@ func Test_projectQueryToURL(t *testing.T) {
 	tests := []struct {
 		projectQuery string
 		perPage      int
+		since        time.Time
 		expURL       string
 		expErr       error
 	}{{
@@ -44,6 +48,11 @@ func Test_projectQueryToURL(t *testing.T) {
 		projectQuery: "",
 		perPage:      100,
 		expURL:       "projects?per_page=100",
+	}, {
+		projectQuery: "?membership=true",
+		perPage:      100,
+		since:        time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)),
+		expURL:       "projects?last_activity_after=2020-01-02T02%3A04%3A05Z&membership=true&per_page=100",
 	}, {
 		projectQuery: "https://somethingelse.com/foo/bar",
 		perPage:      100,
@@ -52,7 +61,7 @@ func Test_projectQueryToURL(t *testing.T) {
 
 	for _, test := range tests {
 		t.Logf("Test case %+v", test)
-		url, err := projectQueryToURL(test.projectQuery, test.perPage)
+		url, err := projectQueryToURL(test.projectQuery, test.perPage, test.since)
 		if url != test.expURL {
 			t.Errorf("expected %v, got %v", test.expURL, url)
 		}
@@ -212,3 +221,163 @@ func TestGitLabSource_makeRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestGitLabSource_CreateChangeset(t *testing.T) {
+	repo := &Repo{
+		Metadata: &gitlab.Project{ProjectCommon: gitlab.ProjectCommon{ID: 1}},
+	}
+
+	existing := &gitlab.MergeRequest{IID: 3, ProjectID: 1, SourceBranch: "campaigns/existing"}
+
+	gitlab.MockCreateMergeRequest = func(c *gitlab.Client, ctx context.Context, in *gitlab.CreateMergeRequestInput) (*gitlab.MergeRequest, error) {
+		if in.SourceBranch == existing.SourceBranch {
+			return nil, gitlab.ErrMergeRequestAlreadyExists
+		}
+		return &gitlab.MergeRequest{
+			IID:          2,
+			ProjectID:    in.ProjectID,
+			Title:        in.Title,
+			Description:  in.Description,
+			SourceBranch: in.SourceBranch,
+			TargetBranch: in.TargetBranch,
+		}, nil
+	}
+	gitlab.MockGetOpenMergeRequestByRefs = func(c *gitlab.Client, ctx context.Context, projectID int, source, target string) (*gitlab.MergeRequest, error) {
+		if projectID != 1 || source != existing.SourceBranch || target != "master" {
+			return nil, fmt.Errorf("unexpected merge request lookup %d %q %q", projectID, source, target)
+		}
+		return existing, nil
+	}
+	defer func() {
+		gitlab.MockCreateMergeRequest = nil
+		gitlab.MockGetOpenMergeRequestByRefs = nil
+	}()
+
+	src, err := NewGitLabSource(&ExternalService{
+		Kind:   "GITLAB",
+		Config: marshalJSON(t, &schema.GitLabConnection{Url: "https://gitlab.com"}),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		head   string
+		want   *gitlab.MergeRequest
+		exists bool
+	}{
+		{
+			name: "created",
+			head: "refs/heads/campaigns/new",
+			want: &gitlab.MergeRequest{
+				IID:          2,
+				ProjectID:    1,
+				Title:        "Title",
+				Description:  "Body",
+				SourceBranch: "campaigns/new",
+				TargetBranch: "master",
+			},
+		},
+		{
+			name:   "already exists",
+			head:   "refs/heads/campaigns/existing",
+			want:   existing,
+			exists: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cs := &Changeset{
+				Title:     "Title",
+				Body:      "Body",
+				BaseRef:   "refs/heads/master",
+				HeadRef:   tc.head,
+				Repo:      repo,
+				Changeset: &campaigns.Changeset{},
+			}
+
+			exists, err := src.CreateChangeset(context.Background(), cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exists != tc.exists {
+				t.Errorf("exists: have %t, want %t", exists, tc.exists)
+			}
+
+			if diff := cmp.Diff(cs.Changeset.Metadata, tc.want); diff != "" {
+				t.Errorf("metadata: %s", diff)
+			}
+
+			if have, want := cs.Changeset.ExternalID, strconv.Itoa(tc.want.IID); have != want {
+				t.Errorf("external id: have %q, want %q", have, want)
+			}
+		})
+	}
+}
+
+func TestGitLabSource_LoadChangesets(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	gitlab.MockGetMergeRequest = func(c *gitlab.Client, ctx context.Context, projectID, iid int) (*gitlab.MergeRequest, error) {
+		if iid != 2 {
+			return nil, gitlab.ErrNotFound
+		}
+		return &gitlab.MergeRequest{IID: iid, ProjectID: projectID, SourceBranch: "campaigns/fix", UpdatedAt: now}, nil
+	}
+	gitlab.MockListMergeRequestNotes = func(c *gitlab.Client, ctx context.Context, projectID, iid int) ([]*gitlab.Note, error) {
+		return []*gitlab.Note{{ID: 1, Body: "merged", System: true}}, nil
+	}
+	gitlab.MockListMergeRequestPipelines = func(c *gitlab.Client, ctx context.Context, projectID, iid int) ([]*gitlab.Pipeline, error) {
+		return []*gitlab.Pipeline{{ID: 1, Status: gitlab.PipelineStatusSuccess}}, nil
+	}
+	gitlab.MockListMergeRequestApprovers = func(c *gitlab.Client, ctx context.Context, projectID, iid int) ([]gitlab.Author, error) {
+		return []gitlab.Author{{Username: "jane"}}, nil
+	}
+	defer func() {
+		gitlab.MockGetMergeRequest = nil
+		gitlab.MockListMergeRequestNotes = nil
+		gitlab.MockListMergeRequestPipelines = nil
+		gitlab.MockListMergeRequestApprovers = nil
+	}()
+
+	src, err := NewGitLabSource(&ExternalService{
+		Kind:   "GITLAB",
+		Config: marshalJSON(t, &schema.GitLabConnection{Url: "https://gitlab.com"}),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &Repo{
+		Metadata: &gitlab.Project{ProjectCommon: gitlab.ProjectCommon{ID: 1}},
+	}
+	found := &Changeset{Repo: repo, Changeset: &campaigns.Changeset{ExternalID: "2"}}
+	deleted := &Changeset{Repo: repo, Changeset: &campaigns.Changeset{ExternalID: "3"}}
+
+	err = src.LoadChangesets(context.Background(), found, deleted)
+	if nf, ok := err.(ChangesetsNotFoundError); !ok || len(nf.Changesets) != 1 || nf.Changesets[0] != deleted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &gitlab.MergeRequest{
+		IID:          2,
+		ProjectID:    1,
+		SourceBranch: "campaigns/fix",
+		UpdatedAt:    now,
+		Notes:        []*gitlab.Note{{ID: 1, Body: "merged", System: true}},
+		Pipelines:    []*gitlab.Pipeline{{ID: 1, Status: gitlab.PipelineStatusSuccess}},
+		ApprovedBy:   []gitlab.Author{{Username: "jane"}},
+	}
+	if diff := cmp.Diff(found.Changeset.Metadata, want); diff != "" {
+		t.Errorf("metadata: %s", diff)
+	}
+
+	if have, want := found.Changeset.ExternalBranch, "campaigns/fix"; have != want {
+		t.Errorf("external branch: have %q, want %q", have, want)
+	}
+
+	if have := found.Changeset.ExternalUpdatedAt; !have.Equal(now) {
+		t.Errorf("external updated at: have %s, want %s", have, now)
+	}
+}
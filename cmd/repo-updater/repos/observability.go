@@ -22,11 +22,15 @@ type ErrorLogger interface {
 // with error logging, Prometheus metrics and tracing.
 func ObservedSource(l ErrorLogger, m SourceMetrics) func(Source) Source {
 	return func(s Source) Source {
-		return &observedSource{
+		o := &observedSource{
 			Source:  s,
 			metrics: m,
 			log:     l,
 		}
+		if is, ok := s.(IncrementalSource); ok {
+			return &observedIncrementalSource{observedSource: o, inner: is}
+		}
+		return o
 	}
 }
 
@@ -100,6 +104,10 @@ func NewSourceMetrics() SourceMetrics {
 
 // ListRepos calls into the inner Source registers the observed results.
 func (o *observedSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	o.listRepos(ctx, results, o.Source.ListRepos)
+}
+
+func (o *observedSource) listRepos(ctx context.Context, results chan SourceResult, list func(context.Context, chan SourceResult)) {
 	var (
 		err   error
 		count float64
@@ -113,7 +121,7 @@ func (o *observedSource) ListRepos(ctx context.Context, results chan SourceResul
 
 	uncounted := make(chan SourceResult)
 	go func() {
-		o.Source.ListRepos(ctx, uncounted)
+		list(ctx, uncounted)
 		close(uncounted)
 	}()
 
@@ -127,6 +135,21 @@ func (o *observedSource) ListRepos(ctx context.Context, results chan SourceResul
 	}
 }
 
+// An observedIncrementalSource is an observedSource which wraps an
+// IncrementalSource, so that it's still incrementally listed.
+type observedIncrementalSource struct {
+	*observedSource
+	inner IncrementalSource
+}
+
+// ListReposSince lists the repos the inner IncrementalSource sends since the
+// given time and registers the observed results.
+func (o *observedIncrementalSource) ListReposSince(ctx context.Context, since time.Time, results chan SourceResult) {
+	o.observedSource.listRepos(ctx, results, func(ctx context.Context, results chan SourceResult) {
+		o.inner.ListReposSince(ctx, since, results)
+	})
+}
+
 // NewObservedStore wraps the given Store with error logging,
 // Prometheus metrics and tracing.
 func NewObservedStore(
@@ -107,6 +107,11 @@ type configuredRepo2 struct {
 	URL  string
 	ID   api.RepoID
 	Name api.RepoName
+	LFS  bool
+
+	CloneFilter string
+	CloneDepth  int
+	Refs        []string
 }
 
 // notifyChanBuffer controls the buffer size of notification channels.
@@ -206,9 +211,33 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 	}
 }
 
-// requestRepoUpdate sends a request to gitserver to request an update.
+// requestRepoUpdate sends a request to gitserver to request an update. If
+// repos are replicated, the request is sent to every gitserver that stores
+// the repo, and the response of the first one is returned.
 var requestRepoUpdate = func(ctx context.Context, repo *configuredRepo2, since time.Duration) (*gitserverprotocol.RepoUpdateResponse, error) {
-	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL}, since)
+	cli := gitserver.DefaultClient
+	r := gitserver.Repo{Name: repo.Name, URL: repo.URL}
+	opts := gitserver.RepoUpdateOptions{
+		LFS:         repo.LFS,
+		CloneFilter: repo.CloneFilter,
+		CloneDepth:  repo.CloneDepth,
+		Refs:        repo.Refs,
+	}
+
+	addrs := cli.AddrsForRepo(ctx, repo.Name)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, addr := range addrs[1:] {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if _, err := cli.RequestRepoUpdateOn(ctx, addr, r, since, opts); err != nil {
+				schedError.Inc()
+				log15.Warn("error requesting repo update of replica", "uri", repo.Name, "addr", addr, "err", err)
+			}
+		}(addr)
+	}
+	return cli.RequestRepoUpdateOn(ctx, addrs[0], r, since, opts)
 }
 
 // configuredLimiter returns a mutable limiter that is
@@ -242,17 +271,21 @@ func (s *updateScheduler) UpdateFromDiff(diff Diff) {
 		s.upsert(r, true)
 	}
 
-	known := len(diff.Added) + len(diff.Modified)
 	for _, r := range diff.Unmodified {
 		if r.IsDeleted() {
 			s.remove(r)
 			continue
 		}
 
-		known++
 		s.upsert(r, false)
 	}
 
+	// The diff may be of a single external service's repos, so the scheduled
+	// repos are counted instead.
+	s.schedule.mu.Lock()
+	known := len(s.schedule.index)
+	s.schedule.mu.Unlock()
+
 	schedKnownRepos.Set(float64(known))
 }
 
@@ -285,7 +318,10 @@ func configuredRepo2FromRepo(r *Repo) *configuredRepo2 {
 	repo := configuredRepo2{
 		ID:   r.ID,
 		Name: api.RepoName(r.Name),
+		LFS:  r.LFS(),
+		Refs: r.Refs(),
 	}
+	repo.CloneFilter, repo.CloneDepth = r.CloneStrategy()
 
 	if urls := r.CloneURLs(); len(urls) > 0 {
 		repo.URL = urls[0]
@@ -294,14 +330,17 @@ func configuredRepo2FromRepo(r *Repo) *configuredRepo2 {
 	return &repo
 }
 
-// UpdateOnce causes a single update of the given repository.
+// UpdateOnce causes a single update of the given repository from url, or
+// from its first clone URL if url is empty.
 // It neither adds nor removes the repo from the schedule.
-func (s *updateScheduler) UpdateOnce(id api.RepoID, name api.RepoName, url string) {
-	repo := &configuredRepo2{
-		ID:   id,
-		Name: name,
-		URL:  url,
+func (s *updateScheduler) UpdateOnce(r *Repo, url string) {
+	// The options come from the repo instead of the schedule, so that repos
+	// which aren't scheduled aren't updated with a different clone strategy.
+	repo := configuredRepo2FromRepo(r)
+	if url != "" {
+		repo.URL = url
 	}
+
 	schedManualFetch.Inc()
 	s.updateQueue.enqueue(repo, priorityHigh)
 }
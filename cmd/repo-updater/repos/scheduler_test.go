@@ -654,6 +654,34 @@ func Test_updateScheduler_UpdateFromDiff(t *testing.T) {
 	}
 }
 
+func TestUpdateScheduler_UpdateOnce(t *testing.T) {
+	_, stop := startRecording()
+	defer stop()
+
+	s := NewUpdateScheduler()
+	s.UpdateOnce(&Repo{
+		ID:   1,
+		Name: "a",
+		Sources: map[string]*SourceInfo{
+			"a": {CloneURL: "a.com", CloneFilter: "blob:none", Refs: []string{"refs/changes/01/1/1"}},
+		},
+	}, "")
+
+	verifyQueue(t, s, []*repoUpdate{
+		{
+			Repo: &configuredRepo2{
+				ID:          1,
+				Name:        "a",
+				URL:         "a.com",
+				CloneFilter: "blob:none",
+				Refs:        []string{"refs/changes/01/1/1"},
+			},
+			Priority: priorityHigh,
+			Seq:      1,
+		},
+	})
+}
+
 func TestSchedule_upsert(t *testing.T) {
 	a := &configuredRepo2{ID: 1, Name: "a", URL: "a.com"}
 	a2 := &configuredRepo2{ID: 1, Name: "a2", URL: "a2.com"}
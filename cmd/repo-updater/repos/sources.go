@@ -57,6 +57,10 @@ func NewSource(svc *ExternalService, cf *httpcli.Factory) (Source, error) {
 		return NewGithubSource(svc, cf)
 	case "gitlab":
 		return NewGitLabSource(svc, cf)
+	case "gitea":
+		return NewGiteaSource(svc, cf)
+	case "gerrit":
+		return NewGerritSource(svc, cf)
 	case "bitbucketserver":
 		return NewBitbucketServerSource(svc, cf)
 	case "bitbucketcloud":
@@ -87,6 +91,28 @@ type Source interface {
 	ExternalServices() ExternalServices
 }
 
+// An IncrementalSource is a Source which can also list only the repos which
+// changed since a given time, such as by filtering on the time of their last
+// activity on the code host.
+type IncrementalSource interface {
+	Source
+	// ListReposSince sends the repos which were added or changed since the
+	// given time over the passed in channel as SourceResults. Since repos
+	// which were deleted aren't sent, syncing these repos never deletes any.
+	ListReposSince(context.Context, time.Time, chan SourceResult)
+}
+
+// sinceSource is a Source which lists the repos of an IncrementalSource that
+// changed since the given time.
+type sinceSource struct {
+	IncrementalSource
+	since time.Time
+}
+
+func (s sinceSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	s.ListReposSince(ctx, s.since, results)
+}
+
 // A ChangesetSource can load the latest state of a list of Changesets.
 type ChangesetSource interface {
 	// LoadChangesets loads the given Changesets from the sources and updates
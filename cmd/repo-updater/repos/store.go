@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
@@ -42,6 +45,9 @@ type StoreListReposArgs struct {
 	Kinds []string
 	// ExternalRepos of repos to list. When zero-valued, this is omitted from the predicate set.
 	ExternalRepos []api.ExternalRepoSpec
+	// ExternalServiceIDs of the external services that the listed repos are sourced from.
+	// When zero-valued, this is omitted from the predicate set.
+	ExternalServiceIDs []int64
 	// Limit the total number of repos returned. Zero means no limit
 	Limit int64
 	// PerPage determines the number of repos returned on each page. Zero means it defaults to 10000.
@@ -336,6 +342,13 @@ AND deleted_at IS NULL
 ORDER BY id ASC LIMIT %s
 `
 
+const listRepoExternalServiceIDsPredicate = `
+EXISTS (
+  SELECT 1 FROM jsonb_object_keys(sources) k
+  WHERE split_part(k, ':', 3) IN (%s)
+)
+`
+
 func listReposQuery(args StoreListReposArgs) paginatedQuery {
 	var preds []*sqlf.Query
 
@@ -374,6 +387,14 @@ func listReposQuery(args StoreListReposArgs) paginatedQuery {
 		preds = append(preds, sqlf.Sprintf("(%s)", sqlf.Join(er, "\n OR ")))
 	}
 
+	if len(args.ExternalServiceIDs) > 0 {
+		ids := make([]*sqlf.Query, 0, len(args.ExternalServiceIDs))
+		for _, id := range args.ExternalServiceIDs {
+			ids = append(ids, sqlf.Sprintf("%s", strconv.FormatInt(id, 10)))
+		}
+		preds = append(preds, sqlf.Sprintf(listRepoExternalServiceIDsPredicate, sqlf.Join(ids, ",")))
+	}
+
 	if len(preds) == 0 {
 		preds = append(preds, sqlf.Sprintf("TRUE"))
 	}
@@ -839,6 +860,10 @@ func scanRepo(r *Repo, s scanner) error {
 		r.Metadata = new(github.Repository)
 	case "gitlab":
 		r.Metadata = new(gitlab.Project)
+	case "gitea":
+		r.Metadata = new(gitea.Repo)
+	case "gerrit":
+		r.Metadata = new(gerrit.Project)
 	case "bitbucketserver":
 		r.Metadata = new(bitbucketserver.Repo)
 	case "bitbucketcloud":
@@ -963,6 +963,23 @@ func testStoreListRepos(store repos.Store) func(*testing.T) {
 		repos: repos.Assert.ReposEqual(&github, &gitlab),
 	})
 
+	{
+		stored := repos.Repos{&github, &gitlab, &gitoliteRepo}.With(func(r *repos.Repo) {
+			urn := "extsvc:" + strings.ToLower(r.ExternalRepo.ServiceType) + ":" + r.ExternalRepo.ID
+			r.Sources = map[string]*repos.SourceInfo{urn: {ID: urn, CloneURL: r.Name}}
+		})
+		testCases = append(testCases, testCase{
+			name:   "returns repos by the ids of their external services",
+			stored: stored,
+			args: func(repos.Repos) repos.StoreListReposArgs {
+				return repos.StoreListReposArgs{
+					ExternalServiceIDs: []int64{123},
+				}
+			},
+			repos: repos.Assert.ReposEqual(stored[1]),
+		})
+	}
+
 	testCases = append(testCases, testCase{
 		name:   "use or",
 		stored: repositories,
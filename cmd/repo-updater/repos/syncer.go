@@ -11,6 +11,7 @@ import (
 	otlog "github.com/opentracing/opentracing-go/log"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -29,7 +30,8 @@ type Syncer struct {
 	// Sourcegraph.com
 	FailFullSync bool
 
-	// Synced is sent a collection of Repos that were synced by Sync (only if Synced is non-nil)
+	// Synced is sent a collection of Repos that were synced by Sync, SyncExternalService
+	// or Run (only if Synced is non-nil)
 	Synced chan Diff
 
 	// SubsetSynced is sent a collection of Repos that were synced by SubsetSync (only if SubsetSynced is non-nil)
@@ -42,45 +44,159 @@ type Syncer struct {
 	Now func() time.Time
 
 	// lastSyncErr contains the last error returned by the Sourcer in each
-	// Sync. It's reset with each Sync and if the sync produced no error, it's
-	// set to nil.
+	// Sync, or by listing the external services in Run. It's reset with each
+	// Sync and if the sync produced no error, it's set to nil.
 	lastSyncErr   error
 	lastSyncErrMu sync.Mutex
 
+	// syncStates contains the state of the last sync of each external
+	// service by its ID.
+	syncStates   map[int64]*SyncState
+	syncStatesMu sync.Mutex
+
+	// storeMu serializes the writes of the syncs of different external
+	// services, which may source the same repos.
+	storeMu sync.Mutex
+
 	syncSignal signal
 }
 
-// Run runs the Sync at the specified interval.
-func (s *Syncer) Run(pctx context.Context, interval time.Duration) error {
-	for pctx.Err() == nil {
-		ctx, cancel := contextWithSignalCancel(pctx, s.syncSignal.Watch())
+// SyncState is the state of the last sync of the repositories of an external
+// service. It is only kept in memory, so it is lost when repo-updater restarts.
+type SyncState struct {
+	ExternalServiceID int64
+	StartedAt         time.Time
+	FinishedAt        time.Time
+	// NextSyncAt is zero if no next sync is scheduled.
+	NextSyncAt time.Time
+	// Incremental is true if only the repos which changed since the previous
+	// sync were synced.
+	Incremental bool
+	// Err is nil if the sync succeeded.
+	Err error
+	// The numbers of repos in each state of the diff of the sync.
+	Added, Modified, Deleted, Unmodified int
+}
 
-		if err := s.Sync(ctx); err != nil && s.Logger != nil {
-			s.Logger.Error("Syncer", "error", err)
+// externalServicesPollInterval is how often Run lists the external services to
+// pick up the added, updated and deleted ones.
+const externalServicesPollInterval = time.Minute
+
+// fullSyncInterval is how often the repos of an external service with an
+// IncrementalSource are synced in full, which deletes the repos that were
+// deleted on the code host and picks up the changes the incremental syncs missed.
+const fullSyncInterval = 6 * time.Hour
+
+// Run syncs the repositories of each external service in its own goroutine at
+// the specified interval. Added and updated external services are synced within
+// externalServicesPollInterval, or right away after TriggerSync, and the sources
+// of deleted external services are removed from their repositories.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) error {
+	workers := make(map[int64]*syncWorker)
+	defer func() {
+		for _, w := range workers {
+			w.stop()
 		}
+	}()
 
-		sleep(ctx, interval)
+	// The sources of the external services which were deleted before Run was
+	// started are removed on the first successful listing.
+	stale := true
 
-		cancel()
+	for ctx.Err() == nil {
+		svcs, err := s.Store.ListExternalServices(ctx, StoreListExternalServicesArgs{})
+		if err != nil {
+			err = errors.Wrap(err, "syncer.run.store.list-external-services")
+		} else {
+			listed := make(map[int64]bool, len(svcs))
+			for _, svc := range svcs {
+				listed[svc.ID] = true
+				if w := workers[svc.ID]; w != nil && w.svc.UpdatedAt.Equal(svc.UpdatedAt) {
+					continue
+				} else if w != nil {
+					w.stop()
+				}
+				workers[svc.ID] = s.startWorker(ctx, svc, interval)
+			}
+
+			for id, w := range workers {
+				if !listed[id] {
+					w.stop()
+					delete(workers, id)
+					s.deleteSyncState(id)
+					stale = true
+				}
+			}
+
+			if stale {
+				err = s.removeStaleSources(ctx, listed)
+				stale = err != nil
+			}
+		}
+
+		s.setOrResetLastSyncErr(&err)
+		if err != nil && s.Logger != nil {
+			s.Logger.Error("Syncer", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-s.syncSignal.Watch():
+		case <-time.After(externalServicesPollInterval):
+		}
 	}
 
-	return pctx.Err()
+	return ctx.Err()
 }
 
-// contextWithSignalCancel will return a context which will be cancelled if
-// signal fires. Callers need to call cancel when done.
-func contextWithSignalCancel(ctx context.Context, signal <-chan struct{}) (context.Context, context.CancelFunc) {
+// A syncWorker periodically syncs the repositories of an external service.
+type syncWorker struct {
+	svc    *ExternalService
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop stops the worker and waits for its sync to return.
+func (w *syncWorker) stop() {
+	w.cancel()
+	<-w.done
+}
+
+// startWorker starts a syncWorker that syncs the repositories of the given
+// external service right away and then at the specified interval. After the
+// first full sync, the repositories are synced incrementally if the external
+// service's Source is an IncrementalSource, and in full every fullSyncInterval.
+func (s *Syncer) startWorker(ctx context.Context, svc *ExternalService, interval time.Duration) *syncWorker {
 	ctx, cancel := context.WithCancel(ctx)
+	w := &syncWorker{svc: svc, cancel: cancel, done: make(chan struct{})}
 
 	go func() {
-		select {
-		case <-ctx.Done():
-		case <-signal:
-			cancel()
+		defer close(w.done)
+
+		var since, lastFullSync time.Time
+		for ctx.Err() == nil {
+			began := s.Now()
+			if began.Sub(lastFullSync) >= fullSyncInterval {
+				since = time.Time{}
+			}
+
+			if err := s.SyncExternalService(ctx, svc, since); err != nil {
+				if s.Logger != nil {
+					s.Logger.Error("Syncer", "external_service_id", svc.ID, "error", err)
+				}
+			} else {
+				if since.IsZero() {
+					lastFullSync = began
+				}
+				since = began
+			}
+
+			s.setNextSyncAt(svc.ID, s.Now().Add(interval))
+			sleep(ctx, interval)
 		}
 	}()
 
-	return ctx, cancel
+	return w
 }
 
 // sleep is a context aware time.Sleep
@@ -91,13 +207,13 @@ func sleep(ctx context.Context, d time.Duration) {
 	}
 }
 
-// TriggerSync will run Sync now. If a sync is currently running it is
-// cancelled.
+// TriggerSync makes Run pick up the changes to the external services now, so
+// that the added and updated external services are synced right away.
 func (s *Syncer) TriggerSync() {
 	s.syncSignal.Trigger()
 }
 
-// Sync synchronizes the repositories.
+// Sync synchronizes the repositories of all external services at once.
 func (s *Syncer) Sync(ctx context.Context) (err error) {
 	var diff Diff
 
@@ -153,6 +269,160 @@ func (s *Syncer) Sync(ctx context.Context) (err error) {
 	return nil
 }
 
+// SyncExternalService synchronizes the repositories of the given external
+// service, keeping the sources of other external services of the stored
+// repositories. If since is non-zero and the external service's Source is an
+// IncrementalSource, only the repositories which changed since then are synced
+// and no repositories are deleted.
+func (s *Syncer) SyncExternalService(ctx context.Context, svc *ExternalService, since time.Time) (err error) {
+	var diff Diff
+	state := SyncState{ExternalServiceID: svc.ID, StartedAt: s.Now()}
+
+	ctx, save := s.observe(ctx, "Syncer.SyncExternalService", svc.URN())
+	defer save(&diff, &err)
+	defer func() { s.setSyncState(state, diff, err) }()
+
+	if s.FailFullSync {
+		return errors.New("Syncer is not enabled")
+	}
+
+	srcs, err := s.Sourcer(svc)
+	if err != nil {
+		return errors.Wrap(err, "syncer.sync-external-service.sourcer")
+	}
+
+	var src Source = srcs
+	if len(srcs) == 1 && !since.IsZero() {
+		if is, ok := srcs[0].(IncrementalSource); ok {
+			src, state.Incremental = sinceSource{IncrementalSource: is, since: since}, true
+		}
+	}
+
+	var streamingInserter func(*Repo)
+	if s.DisableStreaming || state.Incremental {
+		streamingInserter = func(*Repo) {} //noop
+	} else {
+		streamingInserter, err = s.makeNewRepoInserter(ctx)
+		if err != nil {
+			return errors.Wrap(err, "syncer.sync-external-service.streaming")
+		}
+	}
+
+	var sourced Repos
+	{
+		ctx, cancel := context.WithTimeout(ctx, sourceTimeout)
+		sourced, err = listAll(ctx, src, streamingInserter)
+		cancel()
+	}
+	if err != nil {
+		return errors.Wrap(err, "syncer.sync-external-service.sourced")
+	}
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	store := s.Store
+	if tr, ok := s.Store.(Transactor); ok {
+		var txs TxStore
+		if txs, err = tr.Transact(ctx); err != nil {
+			return errors.Wrap(err, "syncer.sync-external-service.transact")
+		}
+		defer txs.Done(&err)
+		store = txs
+	}
+
+	// An incremental sync only affects the stored repos related to the
+	// sourced ones, while a full sync also affects all the stored repos
+	// sourced from the external service.
+	args := StoreListReposArgs{
+		Names:         sourced.Names(),
+		ExternalRepos: sourced.ExternalRepos(),
+		UseOr:         true,
+	}
+	if !state.Incremental {
+		args.ExternalServiceIDs = []int64{svc.ID}
+	}
+	var stored Repos
+	if len(args.Names) > 0 || len(args.ExternalServiceIDs) > 0 {
+		stored, err = store.ListRepos(ctx, args)
+	}
+	if err != nil {
+		return errors.Wrap(err, "syncer.sync-external-service.store.list-repos")
+	}
+
+	diff = newExternalServiceDiff(svc, sourced, stored, state.Incremental)
+	upserts := s.upserts(diff)
+
+	if err = store.UpsertRepos(ctx, upserts...); err != nil {
+		return errors.Wrap(err, "syncer.sync-external-service.store.upsert-repos")
+	}
+
+	if s.Synced != nil {
+		s.Synced <- diff
+	}
+
+	return nil
+}
+
+// removeStaleSources removes the sources of the external services which aren't
+// listed from the stored repositories, deleting the ones left without sources.
+func (s *Syncer) removeStaleSources(ctx context.Context, listed map[int64]bool) (err error) {
+	var diff Diff
+
+	ctx, save := s.observe(ctx, "Syncer.RemoveStaleSources", "")
+	defer save(&diff, &err)
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	store := s.Store
+	if tr, ok := s.Store.(Transactor); ok {
+		var txs TxStore
+		if txs, err = tr.Transact(ctx); err != nil {
+			return errors.Wrap(err, "syncer.remove-stale-sources.transact")
+		}
+		defer txs.Done(&err)
+		store = txs
+	}
+
+	stored, err := store.ListRepos(ctx, StoreListReposArgs{})
+	if err != nil {
+		return errors.Wrap(err, "syncer.remove-stale-sources.store.list-repos")
+	}
+
+	for _, r := range stored {
+		modified := false
+		for id, src := range r.Sources {
+			if svcID := src.ExternalServiceID(); svcID > 0 && !listed[svcID] {
+				delete(r.Sources, id)
+				modified = true
+			}
+		}
+
+		switch {
+		case !modified:
+		case len(r.Sources) == 0:
+			diff.Deleted = append(diff.Deleted, r)
+		default:
+			diff.Modified = append(diff.Modified, r)
+		}
+	}
+
+	if len(diff.Deleted) == 0 && len(diff.Modified) == 0 {
+		return nil
+	}
+
+	if err = store.UpsertRepos(ctx, s.upserts(diff)...); err != nil {
+		return errors.Wrap(err, "syncer.remove-stale-sources.store.upsert-repos")
+	}
+
+	if s.Synced != nil {
+		s.Synced <- diff
+	}
+
+	return nil
+}
+
 // SyncSubset runs the syncer on a subset of the stored repositories. It will
 // only sync the repositories with the same name or external service spec as
 // sourcedSubset repositories.
@@ -344,6 +614,80 @@ func NewDiff(sourced, stored []*Repo) (diff Diff) {
 	return diff
 }
 
+// newExternalServiceDiff returns a diff from the given repos sourced from the
+// given external service and the stored repos. Only the stored repos which are
+// sourced from the external service or related to the sourced repos are part of
+// the diff, and they keep the sources of other external services. Unless the
+// sync is incremental, the stored repos which weren't sourced lose the external
+// service's source, and are deleted if they are left without sources.
+//
+// A sourced repo with the name of a stored repo which has another external ID
+// replaces it if the stored repo has no other sources, and is skipped otherwise.
+func newExternalServiceDiff(svc *ExternalService, sourced, stored []*Repo, incremental bool) (diff Diff) {
+	urn := svc.URN()
+
+	// Sort sourced so we pick determinstically
+	sort.Sort(Repos(sourced))
+
+	byID := make(map[api.ExternalRepoSpec]*Repo, len(sourced))
+	byName := make(map[string]*Repo, len(sourced))
+	for _, r := range sourced {
+		k := strings.ToLower(r.Name)
+		if byID[r.ExternalRepo] != nil || byName[k] != nil {
+			continue
+		}
+		byID[r.ExternalRepo], byName[k] = r, r
+	}
+
+	seenID := make(map[api.ExternalRepoSpec]bool, len(stored))
+	taken := make(map[string]bool, len(stored))
+
+	for _, old := range stored {
+		k := strings.ToLower(old.Name)
+
+		if src := byID[old.ExternalRepo]; src != nil {
+			sources := make(map[string]*SourceInfo, len(old.Sources)+len(src.Sources))
+			for id, info := range old.Sources {
+				if id != urn {
+					sources[id] = info
+				}
+			}
+			for id, info := range src.Sources {
+				sources[id] = info
+			}
+			src.Sources = sources
+
+			if old.Update(src) {
+				diff.Modified = append(diff.Modified, old)
+			} else {
+				diff.Unmodified = append(diff.Unmodified, old)
+			}
+			seenID[old.ExternalRepo] = true
+			continue
+		}
+
+		_, sourcedFrom := old.Sources[urn]
+		switch {
+		case sourcedFrom && len(old.Sources) == 1 && (!incremental || byName[k] != nil):
+			diff.Deleted = append(diff.Deleted, old)
+		case sourcedFrom && !incremental:
+			delete(old.Sources, urn)
+			diff.Modified = append(diff.Modified, old)
+			taken[k] = true
+		default:
+			taken[k] = true
+		}
+	}
+
+	for _, r := range byID {
+		if !seenID[r.ExternalRepo] && !taken[strings.ToLower(r.Name)] {
+			diff.Added = append(diff.Added, r)
+		}
+	}
+
+	return diff
+}
+
 func merge(o, n *Repo) {
 	for id, src := range o.Sources {
 		n.Sources[id] = src
@@ -425,6 +769,93 @@ func (s *Syncer) LastSyncError() error {
 	return s.lastSyncErr
 }
 
+func (s *Syncer) setSyncState(state SyncState, d Diff, err error) {
+	state.FinishedAt = s.Now()
+	state.Err = err
+	state.Added = len(d.Added)
+	state.Modified = len(d.Modified)
+	state.Deleted = len(d.Deleted)
+
+	// The repos which were streamed into the store during the sync are
+	// unmodified by its diff, but count as added.
+	for _, r := range d.Unmodified {
+		if r.CreatedAt.Before(state.StartedAt) {
+			state.Unmodified++
+		} else {
+			state.Added++
+		}
+	}
+
+	s.syncStatesMu.Lock()
+	defer s.syncStatesMu.Unlock()
+
+	if s.syncStates == nil {
+		s.syncStates = make(map[int64]*SyncState)
+	}
+	s.syncStates[state.ExternalServiceID] = &state
+}
+
+func (s *Syncer) setNextSyncAt(id int64, at time.Time) {
+	s.syncStatesMu.Lock()
+	defer s.syncStatesMu.Unlock()
+
+	if state := s.syncStates[id]; state != nil {
+		state.NextSyncAt = at
+	}
+}
+
+func (s *Syncer) deleteSyncState(id int64) {
+	s.syncStatesMu.Lock()
+	defer s.syncStatesMu.Unlock()
+
+	delete(s.syncStates, id)
+}
+
+// SyncStates returns the states of the last syncs of the external services,
+// sorted by their IDs.
+func (s *Syncer) SyncStates() []SyncState {
+	s.syncStatesMu.Lock()
+	defer s.syncStatesMu.Unlock()
+
+	states := make([]SyncState, 0, len(s.syncStates))
+	for _, state := range s.syncStates {
+		states = append(states, *state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ExternalServiceID < states[j].ExternalServiceID
+	})
+
+	return states
+}
+
+// SyncStateInfo returns the state of the last sync of the external service with
+// the given ID.
+func (s *Syncer) SyncStateInfo(id int64) *protocol.ExternalServiceSyncStateResult {
+	var result protocol.ExternalServiceSyncStateResult
+
+	s.syncStatesMu.Lock()
+	if state := s.syncStates[id]; state != nil {
+		result.State = &protocol.ExternalServiceSyncState{
+			ExternalServiceID: state.ExternalServiceID,
+			StartedAt:         state.StartedAt,
+			FinishedAt:        state.FinishedAt,
+			NextSyncAt:        state.NextSyncAt,
+			Incremental:       state.Incremental,
+			Added:             state.Added,
+			Modified:          state.Modified,
+			Deleted:           state.Deleted,
+			Unmodified:        state.Unmodified,
+		}
+		if state.Err != nil {
+			result.State.Error = state.Err.Error()
+		}
+	}
+	s.syncStatesMu.Unlock()
+
+	return &result
+}
+
 func (s *Syncer) observe(ctx context.Context, family, title string) (context.Context, func(*Diff, *error)) {
 	began := s.Now()
 	tr, ctx := trace.New(ctx, family, title)
@@ -707,6 +707,242 @@ func testSyncSubset(s repos.Store) func(*testing.T) {
 	}
 }
 
+func TestSyncer_SyncExternalService(t *testing.T) {
+	t.Parallel()
+
+	svc1 := &repos.ExternalService{ID: 1, Kind: "GITHUB"}
+	svc2 := &repos.ExternalService{ID: 2, Kind: "GITHUB"}
+
+	clock := repos.NewFakeClock(time.Now(), time.Second)
+	since := clock.Now()
+
+	repo := func(name, id string, urns ...string) *repos.Repo {
+		return (&repos.Repo{
+			Name:     name,
+			Metadata: &github.Repository{},
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          id,
+				ServiceID:   "https://github.com/",
+				ServiceType: "github",
+			},
+		}).With(repos.Opt.RepoSources(urns...))
+	}
+	changed := func(r *repos.Repo) *repos.Repo {
+		r = r.Clone()
+		r.Description, r.UpdatedAt = "changed", since.Add(time.Minute)
+		return r
+	}
+
+	foo := repo("github.com/org/foo", "foo-id")
+	bar := repo("github.com/org/bar", "bar-id")
+
+	for _, tc := range []struct {
+		name  string
+		src   repos.Source
+		since time.Time
+		// stored repos to prepare the store with
+		stored repos.Repos
+		// sources of the stored repos by their names after the sync
+		sources map[string][]string
+		state   repos.SyncState
+	}{
+		{
+			name:    "new repo is added",
+			src:     repos.NewFakeSource(svc1, nil, foo),
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN()}},
+			state:   repos.SyncState{Added: 1},
+		},
+		{
+			name:    "sources of other external services are kept",
+			src:     repos.NewFakeSource(svc1, nil, changed(foo)),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN(), svc2.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN(), svc2.URN()}},
+			state:   repos.SyncState{Modified: 1},
+		},
+		{
+			name:    "repo which isn't sourced anymore loses the source",
+			src:     repos.NewFakeSource(svc1, nil),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN(), svc2.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc2.URN()}},
+			state:   repos.SyncState{Modified: 1},
+		},
+		{
+			name:    "repo which isn't sourced anymore is deleted without other sources",
+			src:     repos.NewFakeSource(svc1, nil, bar),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN())), bar.With(repos.Opt.RepoSources(svc1.URN()))},
+			sources: map[string][]string{"github.com/org/bar": {svc1.URN()}},
+			state:   repos.SyncState{Deleted: 1, Unmodified: 1},
+		},
+		{
+			name:    "repos of other external services are left alone",
+			src:     repos.NewFakeSource(svc1, nil, foo),
+			stored:  repos.Repos{bar.With(repos.Opt.RepoSources(svc2.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN()}, "github.com/org/bar": {svc2.URN()}},
+			state:   repos.SyncState{Added: 1},
+		},
+		{
+			name:    "incremental sync only syncs changed repos and doesn't delete",
+			src:     repos.NewFakeIncrementalSource(svc1, nil, changed(foo), bar),
+			since:   since,
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN())), bar.With(repos.Opt.RepoSources(svc1.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN()}, "github.com/org/bar": {svc1.URN()}},
+			state:   repos.SyncState{Incremental: true, Modified: 1},
+		},
+		{
+			name:    "incremental source is synced in full without since",
+			src:     repos.NewFakeIncrementalSource(svc1, nil, bar),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN())), bar.With(repos.Opt.RepoSources(svc1.URN()))},
+			sources: map[string][]string{"github.com/org/bar": {svc1.URN()}},
+			state:   repos.SyncState{Deleted: 1, Unmodified: 1},
+		},
+		{
+			name:    "repo with the name of another external service's repo is skipped",
+			src:     repos.NewFakeSource(svc1, nil, repo("github.com/org/foo", "other-id")),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc2.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc2.URN()}},
+			state:   repos.SyncState{},
+		},
+		{
+			name:    "repo with the name of the external service's repo replaces it",
+			src:     repos.NewFakeIncrementalSource(svc1, nil, changed(repo("github.com/org/foo", "other-id"))),
+			since:   since,
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN()}},
+			state:   repos.SyncState{Incremental: true, Added: 1, Deleted: 1},
+		},
+		{
+			name:    "source error aborts sync",
+			src:     repos.NewFakeSource(svc1, errors.New("boom")),
+			stored:  repos.Repos{foo.With(repos.Opt.RepoSources(svc1.URN()))},
+			sources: map[string][]string{"github.com/org/foo": {svc1.URN()}},
+			state: repos.SyncState{
+				Err: errors.New("syncer.sync-external-service.sourced: 1 error occurred:\n\t* boom\n\n"),
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := new(repos.FakeStore)
+			if err := store.UpsertRepos(ctx, tc.stored.Clone()...); err != nil {
+				t.Fatalf("failed to prepare store: %v", err)
+			}
+
+			syncer := &repos.Syncer{
+				Store:   store,
+				Sourcer: repos.NewFakeSourcer(nil, tc.src),
+				Now:     clock.Now,
+			}
+			err := syncer.SyncExternalService(ctx, svc1, tc.since)
+			if have, want := fmt.Sprint(err), fmt.Sprint(tc.state.Err); err != nil && have != want {
+				t.Errorf("have error %q, want %q", have, want)
+			}
+
+			stored, err := store.ListRepos(ctx, repos.StoreListReposArgs{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sources := make(map[string][]string, len(stored))
+			for _, r := range stored {
+				for urn := range r.Sources {
+					sources[r.Name] = append(sources[r.Name], urn)
+				}
+				sort.Strings(sources[r.Name])
+			}
+			if diff := cmp.Diff(tc.sources, sources); diff != "" {
+				t.Errorf("stored repo sources (-want +got): %s", diff)
+			}
+
+			states := syncer.SyncStates()
+			if len(states) != 1 {
+				t.Fatalf("have %d sync states, want 1", len(states))
+			}
+			have := states[0]
+			if have.ExternalServiceID != svc1.ID || !have.FinishedAt.After(have.StartedAt) {
+				t.Errorf("have sync state %+v for the wrong external service or time", have)
+			}
+			want := tc.state
+			want.ExternalServiceID, want.StartedAt, want.FinishedAt = have.ExternalServiceID, have.StartedAt, have.FinishedAt
+			if fmt.Sprint(have.Err) != fmt.Sprint(want.Err) {
+				t.Errorf("have sync error %v, want %v", have.Err, want.Err)
+			}
+			have.Err, want.Err = nil, nil
+			if diff := cmp.Diff(want, have); diff != "" {
+				t.Errorf("sync state (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncer_Run(t *testing.T) {
+	t.Parallel()
+
+	svc := &repos.ExternalService{ID: 1, Kind: "GITHUB"}
+	deleted := &repos.ExternalService{ID: 2, Kind: "GITHUB"}
+
+	ctx := context.Background()
+	store := new(repos.FakeStore)
+	if err := store.UpsertExternalServices(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+
+	foo := (&repos.Repo{
+		Name:     "github.com/org/foo",
+		Metadata: &github.Repository{},
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "foo-id",
+			ServiceID:   "https://github.com/",
+			ServiceType: "github",
+		},
+	})
+	bar := foo.With(func(r *repos.Repo) {
+		r.Name, r.ExternalRepo.ID = "github.com/org/bar", "bar-id"
+	})
+	stored := repos.Repos{
+		foo.With(repos.Opt.RepoSources(svc.URN(), deleted.URN())),
+		bar.With(repos.Opt.RepoSources(deleted.URN())),
+	}
+	if err := store.UpsertRepos(ctx, stored...); err != nil {
+		t.Fatal(err)
+	}
+
+	syncer := &repos.Syncer{
+		Store:            store,
+		Sourcer:          repos.NewFakeSourcer(nil, repos.NewFakeSource(svc, nil, foo)),
+		DisableStreaming: true,
+		Now:              time.Now,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- syncer.Run(ctx, time.Hour) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(syncer.SyncStates()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("external service wasn't synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("have error %v, want %v", err, context.Canceled)
+	}
+
+	state := syncer.SyncStates()[0]
+	if state.ExternalServiceID != svc.ID || state.Err != nil {
+		t.Errorf("have sync state %+v", state)
+	}
+
+	have, err := store.ListRepos(ctx, repos.StoreListReposArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 1 || have[0].Name != foo.Name || len(have[0].Sources) != 1 || have[0].Sources[svc.URN()] == nil {
+		t.Errorf("have stored repos %+v, want only %s sourced from %s", have, foo.Name, svc.URN())
+	}
+}
+
 func TestDiff(t *testing.T) {
 	t.Parallel()
 
@@ -64,6 +64,30 @@ func (s FakeSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
+// FakeIncrementalSource is a fake implementation of IncrementalSource to be
+// used in tests.
+type FakeIncrementalSource struct {
+	*FakeSource
+}
+
+// NewFakeIncrementalSource returns an instance of FakeIncrementalSource with
+// the given urn, error and repos.
+func NewFakeIncrementalSource(svc *ExternalService, err error, rs ...*Repo) FakeIncrementalSource {
+	return FakeIncrementalSource{NewFakeSource(svc, err, rs...)}
+}
+
+// ListReposSince returns the Repos that FakeIncrementalSource was instantiated
+// with which were updated after the given time, as well as the error, if any.
+func (s FakeIncrementalSource) ListReposSince(ctx context.Context, since time.Time, results chan SourceResult) {
+	var rs []*Repo
+	for _, r := range s.repos {
+		if r.UpdatedAt.After(since) {
+			rs = append(rs, r)
+		}
+	}
+	NewFakeSource(s.svc, s.err, rs...).ListRepos(ctx, results)
+}
+
 // FakeStore is a fake implementation of Store to be used in tests.
 type FakeStore struct {
 	ListExternalServicesError   error // error to be returned in ListExternalServices
@@ -234,6 +258,11 @@ func (s FakeStore) ListRepos(ctx context.Context, args StoreListReposArgs) ([]*R
 		externalRepos[spec] = true
 	}
 
+	externalServiceIDs := make(map[int64]bool, len(args.ExternalServiceIDs))
+	for _, id := range args.ExternalServiceIDs {
+		externalServiceIDs[id] = true
+	}
+
 	set := make(map[*Repo]bool, len(s.repoByID))
 	repos := make(Repos, 0, len(s.repoByID))
 	for _, r := range s.repoByID {
@@ -258,6 +287,13 @@ func (s FakeStore) ListRepos(ctx context.Context, args StoreListReposArgs) ([]*R
 		if len(externalRepos) > 0 {
 			preds = append(preds, externalRepos[r.ExternalRepo])
 		}
+		if len(externalServiceIDs) > 0 {
+			sourced := false
+			for _, src := range r.Sources {
+				sourced = sourced || externalServiceIDs[src.ExternalServiceID()]
+			}
+			preds = append(preds, sourced)
+		}
 
 		if (args.UseOr && evalOr(preds...)) || (!args.UseOr && evalAnd(preds...)) {
 			repos = append(repos, r)
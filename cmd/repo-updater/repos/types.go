@@ -16,6 +16,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
@@ -92,8 +94,12 @@ func (e ExternalService) Configuration() (cfg interface{}, _ error) {
 		cfg = &schema.AWSCodeCommitConnection{}
 	case "bitbucketserver":
 		cfg = &schema.BitbucketServerConnection{}
+	case "gerrit":
+		cfg = &schema.GerritConnection{}
 	case "github":
 		cfg = &schema.GitHubConnection{}
+	case "gitea":
+		cfg = &schema.GiteaConnection{}
 	case "gitlab":
 		cfg = &schema.GitLabConnection{}
 	case "gitolite":
@@ -116,6 +122,10 @@ func (e *ExternalService) Exclude(rs ...*Repo) error {
 		return e.excludeGithubRepos(rs...)
 	case "gitlab":
 		return e.excludeGitLabRepos(rs...)
+	case "gitea":
+		return e.excludeGiteaRepos(rs...)
+	case "gerrit":
+		return e.excludeGerritRepos(rs...)
 	case "bitbucketserver":
 		return e.excludeBitbucketServerRepos(rs...)
 	case "awscodecommit":
@@ -241,6 +251,85 @@ func (e *ExternalService) excludeGitLabRepos(rs ...*Repo) error {
 	})
 }
 
+// excludeGiteaRepos changes the configuration of a Gitea external service to exclude the
+// given repos from being synced.
+func (e *ExternalService) excludeGiteaRepos(rs ...*Repo) error {
+	if len(rs) == 0 {
+		return nil
+	}
+
+	return e.config("gitea", func(v interface{}) (string, interface{}, error) {
+		c := v.(*schema.GiteaConnection)
+		set := make(map[string]bool, len(c.Exclude)*2)
+		for _, ex := range c.Exclude {
+			if ex.Id != 0 {
+				set[strconv.Itoa(ex.Id)] = true
+			}
+
+			if ex.Name != "" {
+				set[strings.ToLower(ex.Name)] = true
+			}
+		}
+
+		for _, r := range rs {
+			repo, ok := r.Metadata.(*gitea.Repo)
+			if !ok {
+				continue
+			}
+
+			name := strings.ToLower(repo.FullName)
+			id := strconv.FormatInt(repo.ID, 10)
+
+			if !set[name] && !set[id] {
+				c.Exclude = append(c.Exclude, &schema.ExcludedGiteaRepo{
+					Name: repo.FullName,
+					Id:   int(repo.ID),
+				})
+
+				set[id] = true
+				set[name] = true
+			}
+		}
+
+		return "exclude", c.Exclude, nil
+	})
+}
+
+// excludeGerritRepos changes the configuration of a Gerrit external service to exclude the
+// given projects from being synced.
+func (e *ExternalService) excludeGerritRepos(rs ...*Repo) error {
+	if len(rs) == 0 {
+		return nil
+	}
+
+	return e.config("gerrit", func(v interface{}) (string, interface{}, error) {
+		c := v.(*schema.GerritConnection)
+		set := make(map[string]bool, len(c.Exclude))
+		for _, ex := range c.Exclude {
+			if ex.Name != "" {
+				set[ex.Name] = true
+			}
+		}
+
+		for _, r := range rs {
+			p, ok := r.Metadata.(*gerrit.Project)
+			if !ok {
+				continue
+			}
+
+			if !set[p.Name] {
+				c.Exclude = append(c.Exclude, &schema.ExcludedGerritProject{
+					Name: p.Name,
+				})
+
+				set[p.Name] = true
+			}
+		}
+
+		return "exclude", c.Exclude, nil
+	})
+}
+
 // excludeBitbucketServerRepos changes the configuration of a BitbucketServer external service to exclude the
 // given repos from being synced.
 func (e *ExternalService) excludeBitbucketServerRepos(rs ...*Repo) error {
@@ -462,8 +551,12 @@ func (e ExternalService) schema() string {
 		return schema.AWSCodeCommitSchemaJSON
 	case "bitbucketserver":
 		return schema.BitbucketServerSchemaJSON
+	case "gerrit":
+		return schema.GerritSchemaJSON
 	case "github":
 		return schema.GitHubSchemaJSON
+	case "gitea":
+		return schema.GiteaSchemaJSON
 	case "gitlab":
 		return schema.GitLabSchemaJSON
 	case "gitolite":
@@ -570,6 +663,15 @@ type Repo struct {
 type SourceInfo struct {
 	ID       string
 	CloneURL string
+	// LFS is whether Git LFS objects are fetched for repos of this source.
+	LFS bool `json:",omitempty"`
+	// CloneFilter and CloneDepth select a partial or shallow clone of the
+	// repo, see the cloneStrategies option of code host connections.
+	CloneFilter string `json:",omitempty"`
+	CloneDepth  int    `json:",omitempty"`
+	// Refs are refs to fetch in addition to branches and tags, such as the
+	// refs of open Gerrit changes.
+	Refs []string `json:",omitempty"`
 }
 
 // ExternalServiceID returns the ID of the external service this
@@ -599,6 +701,58 @@ func (r *Repo) CloneURLs() []string {
 	return urls
 }
 
+// LFS returns true if Git LFS objects should be fetched for the repo,
+// which is the case if any of its sources enables it.
+func (r *Repo) LFS() bool {
+	for _, src := range r.Sources {
+		if src != nil && src.LFS {
+			return true
+		}
+	}
+	return false
+}
+
+// CloneStrategy returns the partial clone filter and shallow clone depth of
+// the repo. If several sources of the repo select a clone strategy, the one
+// of the source with the lowest ID is used.
+func (r *Repo) CloneStrategy() (filter string, depth int) {
+	ids := make([]string, 0, len(r.Sources))
+	for id, src := range r.Sources {
+		if src != nil && (src.CloneFilter != "" || src.CloneDepth != 0) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return "", 0
+	}
+	sort.Strings(ids)
+	src := r.Sources[ids[0]]
+	return src.CloneFilter, src.CloneDepth
+}
+
+// Refs returns the sorted refs to fetch for the repo in addition to branches
+// and tags, which are the union of the refs of its sources.
+func (r *Repo) Refs() []string {
+	set := make(map[string]bool)
+	for _, src := range r.Sources {
+		if src == nil {
+			continue
+		}
+		for _, ref := range src.Refs {
+			set[ref] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	refs := make([]string, 0, len(set))
+	for ref := range set {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
 // ExternalServiceIDs returns the IDs of the external services this
 // repo belongs to.
 func (r *Repo) ExternalServiceIDs() []int64 {
@@ -1,6 +1,8 @@
 package repos
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -459,6 +461,40 @@ func TestPick(t *testing.T) {
 	}
 }
 
+func TestRepo_CloneStrategy(t *testing.T) {
+	var cs cloneStrategies
+	for _, s := range []struct {
+		pattern, filter string
+		depth           int
+	}{
+		{"^org/monorepo$", "blob:none", 0},
+		{"^org/", "", 100},
+	} {
+		if err := cs.add(s.pattern, s.filter, s.depth); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := &Repo{Sources: map[string]*SourceInfo{}}
+	for i, name := range []string{"other/repo", "org/monorepo", "org/repo"} {
+		info := &SourceInfo{ID: fmt.Sprintf("extsvc:github:%d", i)}
+		info.CloneFilter, info.CloneDepth = cs.match(name)
+		repo.Sources[info.ID] = info
+	}
+	if filter, depth := repo.CloneStrategy(); filter != "blob:none" || depth != 0 {
+		t.Errorf("got filter %q and depth %d, want the strategy of the first source with one", filter, depth)
+	}
+
+	delete(repo.Sources, "extsvc:github:1")
+	if filter, depth := repo.CloneStrategy(); filter != "" || depth != 100 {
+		t.Errorf("got filter %q and depth %d, want depth 100", filter, depth)
+	}
+
+	if err := cs.add("(", "", 1); err == nil {
+		t.Error("want error for invalid pattern")
+	}
+}
+
 func formatJSON(t testing.TB, s string) string {
 	formatted, err := jsonc.Format(s, nil)
 	if err != nil {
@@ -467,3 +503,20 @@ func formatJSON(t testing.TB, s string) string {
 
 	return formatted
 }
+
+func TestRepo_Refs(t *testing.T) {
+	repo := &Repo{Sources: map[string]*SourceInfo{
+		"extsvc:gerrit:1": {ID: "extsvc:gerrit:1", Refs: []string{"refs/changes/45/12345/3", "refs/changes/01/101/1"}},
+		"extsvc:gerrit:2": {ID: "extsvc:gerrit:2", Refs: []string{"refs/changes/01/101/1"}},
+		"extsvc:other:3":  {ID: "extsvc:other:3"},
+	}}
+	if have, want := repo.Refs(), []string{"refs/changes/01/101/1", "refs/changes/45/12345/3"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("got refs %v, want %v", have, want)
+	}
+
+	delete(repo.Sources, "extsvc:gerrit:1")
+	delete(repo.Sources, "extsvc:gerrit:2")
+	if have := repo.Refs(); have != nil {
+		t.Errorf("got refs %v, want none", have)
+	}
+}
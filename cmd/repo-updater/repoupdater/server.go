@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +18,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
@@ -37,7 +40,7 @@ type Server struct {
 		GetRepo(ctx context.Context, projectWithNamespace string) (*repos.Repo, error)
 	}
 	Scheduler interface {
-		UpdateOnce(id api.RepoID, name api.RepoName, url string)
+		UpdateOnce(repo *repos.Repo, url string)
 		ScheduleInfo(id api.RepoID) *protocol.RepoUpdateSchedulerInfoResult
 	}
 	GitserverClient interface {
@@ -61,6 +64,7 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/enqueue-repo-update", s.handleEnqueueRepoUpdate)
 	mux.HandleFunc("/exclude-repo", s.handleExcludeRepo)
 	mux.HandleFunc("/sync-external-service", s.handleExternalServiceSync)
+	mux.HandleFunc("/external-service-sync-state", s.handleExternalServiceSyncState)
 	mux.HandleFunc("/status-messages", s.handleStatusMessages)
 	mux.HandleFunc("/enqueue-changeset-sync", s.handleEnqueueChangesetSync)
 	return mux
@@ -287,7 +291,7 @@ func (s *Server) enqueueRepoUpdate(ctx context.Context, req *protocol.RepoUpdate
 			req.URL = urls[0]
 		}
 	}
-	s.Scheduler.UpdateOnce(repo.ID, req.Repo, req.URL)
+	s.Scheduler.UpdateOnce(repo, req.URL)
 
 	return &protocol.RepoUpdateResponse{
 		ID:   repo.ID,
@@ -332,6 +336,16 @@ func (s *Server) handleExternalServiceSync(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+func (s *Server) handleExternalServiceSyncState(w http.ResponseWriter, r *http.Request) {
+	var args protocol.ExternalServiceSyncStateArgs
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respond(w, http.StatusOK, s.Syncer.SyncStateInfo(args.ExternalServiceID))
+}
+
 func externalServiceValidate(ctx context.Context, req *protocol.ExternalServiceSyncRequest) error {
 	if req.ExternalService.DeletedAt != nil {
 		// We don't need to check deleted services.
@@ -491,25 +505,12 @@ func (s *Server) handleStatusMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if e := s.Syncer.LastSyncError(); e != nil {
-		if multiErr, ok := errors.Cause(e).(*multierror.Error); ok {
-			for _, e := range multiErr.Errors {
-				if sourceErr, ok := e.(*repos.SourceError); ok {
-					resp.Messages = append(resp.Messages, protocol.StatusMessage{
-						ExternalServiceSyncError: &protocol.ExternalServiceSyncError{
-							Message:           sourceErr.Err.Error(),
-							ExternalServiceId: sourceErr.ExtSvc.ID,
-						},
-					})
-				} else {
-					resp.Messages = append(resp.Messages, protocol.StatusMessage{
-						SyncError: &protocol.SyncError{Message: e.Error()},
-					})
-				}
-			}
-		} else {
-			resp.Messages = append(resp.Messages, protocol.StatusMessage{
-				SyncError: &protocol.SyncError{Message: e.Error()},
-			})
+		resp.Messages = append(resp.Messages, syncErrorMessages(e, 0)...)
+	}
+
+	for _, state := range s.Syncer.SyncStates() {
+		if state.Err != nil {
+			resp.Messages = append(resp.Messages, syncErrorMessages(state.Err, state.ExternalServiceID)...)
 		}
 	}
 
@@ -518,6 +519,40 @@ func (s *Server) handleStatusMessages(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, resp)
 }
 
+// syncErrorMessages returns the status messages of the given sync error. The
+// errors which aren't a SourceError are attributed to the external service with
+// the given ID, unless it's zero.
+func syncErrorMessages(e error, externalServiceID int64) (msgs []protocol.StatusMessage) {
+	errs := []error{e}
+	if multiErr, ok := errors.Cause(e).(*multierror.Error); ok {
+		errs = multiErr.Errors
+	}
+
+	for _, e := range errs {
+		if sourceErr, ok := e.(*repos.SourceError); ok {
+			msgs = append(msgs, protocol.StatusMessage{
+				ExternalServiceSyncError: &protocol.ExternalServiceSyncError{
+					Message:           sourceErr.Err.Error(),
+					ExternalServiceId: sourceErr.ExtSvc.ID,
+				},
+			})
+		} else if externalServiceID != 0 {
+			msgs = append(msgs, protocol.StatusMessage{
+				ExternalServiceSyncError: &protocol.ExternalServiceSyncError{
+					Message:           e.Error(),
+					ExternalServiceId: externalServiceID,
+				},
+			})
+		} else {
+			msgs = append(msgs, protocol.StatusMessage{
+				SyncError: &protocol.SyncError{Message: e.Error()},
+			})
+		}
+	}
+
+	return msgs
+}
+
 func (s *Server) computeNotClonedCount(ctx context.Context) (uint64, error) {
 	// Coarse lock so we single flight the expensive computation.
 	s.notClonedCountMu.Lock()
@@ -621,6 +656,39 @@ func newRepoInfo(r *repos.Repo) (*protocol.RepoInfo, error) {
 			Blob:   pathAppend(proj.WebURL, "/blob/{rev}/{path}"),
 			Commit: pathAppend(proj.WebURL, "/commit/{commit}"),
 		}
+	case "gitea":
+		repo := r.Metadata.(*gitea.Repo)
+		if repo.HTMLURL == "" {
+			break
+		}
+
+		// Both Gitea and Gogs resolve a branch, tag or commit ID in place of {rev}.
+		info.Links = &protocol.RepoLinks{
+			Root:   repo.HTMLURL,
+			Tree:   pathAppend(repo.HTMLURL, "/src/{rev}/{path}"),
+			Blob:   pathAppend(repo.HTMLURL, "/src/{rev}/{path}"),
+			Commit: pathAppend(repo.HTMLURL, "/commit/{commit}"),
+		}
+	case "gerrit":
+		proj := r.Metadata.(*gerrit.Project)
+		if proj.Name == "" {
+			break
+		}
+
+		// Gerrit itself can't browse files, which is left to plugins such as gitiles. A
+		// commit ID query shows the change of the commit.
+		base := r.ExternalRepo.ServiceID
+		info.Links = &protocol.RepoLinks{
+			Root:   pathAppend(base, "/admin/repos/"+url.PathEscape(proj.Name)),
+			Commit: pathAppend(base, "/q/{commit}"),
+		}
+		for _, l := range proj.WebLinks {
+			if l.Name == "browse" || l.Name == "gitiles" {
+				info.Links.Tree = pathAppend(l.URL, "/+/{rev}/{path}")
+				info.Links.Blob = pathAppend(l.URL, "/+/{rev}/{path}")
+				break
+			}
+		}
 	case "bitbucketserver":
 		repo := r.Metadata.(*bitbucketserver.Repo)
 		if len(repo.Links.Self) == 0 {
@@ -22,6 +22,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
@@ -651,8 +653,9 @@ func TestServer_StatusMessages(t *testing.T) {
 			res: &protocol.StatusMessagesResponse{
 				Messages: []protocol.StatusMessage{
 					{
-						SyncError: &protocol.SyncError{
-							Message: "syncer.sync.streaming: syncer.storedExternalIDs: could not connect to database",
+						ExternalServiceSyncError: &protocol.ExternalServiceSyncError{
+							Message:           "syncer.sync-external-service.streaming: syncer.storedExternalIDs: could not connect to database",
+							ExternalServiceId: githubService.ID,
 						},
 					},
 				},
@@ -695,9 +698,9 @@ func TestServer_StatusMessages(t *testing.T) {
 			if tc.sourcerErr != nil || tc.listRepoErr != nil {
 				store.ListReposError = tc.listRepoErr
 				sourcer := repos.NewFakeSourcer(tc.sourcerErr, repos.NewFakeSource(githubService, nil))
-				// Run Sync so that possibly `LastSyncErrors` is set
+				// Sync the external service so that possibly its sync state has an error
 				syncer.Sourcer = sourcer
-				_ = syncer.Sync(ctx)
+				_ = syncer.SyncExternalService(ctx, githubService, time.Time{})
 			}
 
 			s := &Server{
@@ -844,6 +847,56 @@ func TestRepoLookup(t *testing.T) {
 		},
 	}
 
+	giteaRepository := &repos.Repo{
+		Name:        "gitea.mycorp.com/infra/deploy",
+		Description: "Deployment scripts",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "42",
+			ServiceType: gitea.ServiceType,
+			ServiceID:   "https://gitea.mycorp.com/",
+		},
+		Sources: map[string]*repos.SourceInfo{
+			"extsvc:789": {
+				ID:       "extsvc:789",
+				CloneURL: "https://gitea.mycorp.com/infra/deploy.git",
+			},
+		},
+		Metadata: &gitea.Repo{
+			ID:          42,
+			FullName:    "infra/deploy",
+			Description: "Deployment scripts",
+			HTMLURL:     "https://gitea.mycorp.com/infra/deploy",
+			CloneURL:    "https://gitea.mycorp.com/infra/deploy.git",
+		},
+	}
+
+	gerritRepository := &repos.Repo{
+		Name:        "gerrit.mycorp.com/platform/build",
+		Description: "Build system",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "platform%2Fbuild",
+			ServiceType: gerrit.ServiceType,
+			ServiceID:   "https://gerrit.mycorp.com/",
+		},
+		Sources: map[string]*repos.SourceInfo{
+			"extsvc:790": {
+				ID:       "extsvc:790",
+				CloneURL: "https://gerrit.mycorp.com/platform/build",
+				Refs:     []string{"refs/changes/45/12345/3"},
+			},
+		},
+		Metadata: &gerrit.Project{
+			ID:          "platform%2Fbuild",
+			Name:        "platform/build",
+			Description: "Build system",
+			WebLinks:    []*gerrit.WebLink{{Name: "browse", URL: "https://gerrit.mycorp.com/plugins/gitiles/platform/build"}},
+		},
+	}
+
 	testCases := []struct {
 		name               string
 		args               protocol.RepoLookupArgs
@@ -908,6 +961,52 @@ func TestRepoLookup(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "found - Gitea",
+			args: protocol.RepoLookupArgs{
+				Repo: api.RepoName("gitea.mycorp.com/infra/deploy"),
+			},
+			stored: []*repos.Repo{giteaRepository},
+			result: &protocol.RepoLookupResult{Repo: &protocol.RepoInfo{
+				ExternalRepo: api.ExternalRepoSpec{
+					ID:          "42",
+					ServiceType: gitea.ServiceType,
+					ServiceID:   "https://gitea.mycorp.com/",
+				},
+				Name:        "gitea.mycorp.com/infra/deploy",
+				Description: "Deployment scripts",
+				VCS:         protocol.VCSInfo{URL: "https://gitea.mycorp.com/infra/deploy.git"},
+				Links: &protocol.RepoLinks{
+					Root:   "https://gitea.mycorp.com/infra/deploy",
+					Tree:   "https://gitea.mycorp.com/infra/deploy/src/{rev}/{path}",
+					Blob:   "https://gitea.mycorp.com/infra/deploy/src/{rev}/{path}",
+					Commit: "https://gitea.mycorp.com/infra/deploy/commit/{commit}",
+				},
+			}},
+		},
+		{
+			name: "found - Gerrit",
+			args: protocol.RepoLookupArgs{
+				Repo: api.RepoName("gerrit.mycorp.com/platform/build"),
+			},
+			stored: []*repos.Repo{gerritRepository},
+			result: &protocol.RepoLookupResult{Repo: &protocol.RepoInfo{
+				ExternalRepo: api.ExternalRepoSpec{
+					ID:          "platform%2Fbuild",
+					ServiceType: gerrit.ServiceType,
+					ServiceID:   "https://gerrit.mycorp.com/",
+				},
+				Name:        "gerrit.mycorp.com/platform/build",
+				Description: "Build system",
+				VCS:         protocol.VCSInfo{URL: "https://gerrit.mycorp.com/platform/build"},
+				Links: &protocol.RepoLinks{
+					Root:   "https://gerrit.mycorp.com/admin/repos/platform%2Fbuild",
+					Tree:   "https://gerrit.mycorp.com/plugins/gitiles/platform/build/+/{rev}/{path}",
+					Blob:   "https://gerrit.mycorp.com/plugins/gitiles/platform/build/+/{rev}/{path}",
+					Commit: "https://gerrit.mycorp.com/q/{commit}",
+				},
+			}},
+		},
 		{
 			name: "found - GitHub.com on Sourcegraph.com",
 			args: protocol.RepoLookupArgs{
@@ -1136,7 +1235,7 @@ func (s *fakeRepoSource) GetRepo(context.Context, string) (*repos.Repo, error) {
 
 type fakeScheduler struct{}
 
-func (s *fakeScheduler) UpdateOnce(_ api.RepoID, _ api.RepoName, _ string) {}
+func (s *fakeScheduler) UpdateOnce(_ *repos.Repo, _ string) {}
 func (s *fakeScheduler) ScheduleInfo(id api.RepoID) *protocol.RepoUpdateSchedulerInfoResult {
 	return &protocol.RepoUpdateSchedulerInfoResult{}
 }
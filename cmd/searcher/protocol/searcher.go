@@ -67,6 +67,12 @@ type PatternInfo struct {
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// IsMultiline if true will let the regular expression in Pattern match
+	// across lines: "." also matches newlines. Matches are returned as
+	// MultilineMatches instead of LineMatches. It only applies when IsRegExp
+	// is true.
+	IsMultiline bool
+
 	// ExcludePattern is a pattern that may not match the returned files' paths.
 	// eg '**/node_modules'
 	ExcludePattern string
@@ -118,6 +124,9 @@ func (p *PatternInfo) String() string {
 	if p.IsCaseSensitive {
 		args = append(args, "case")
 	}
+	if p.IsMultiline {
+		args = append(args, "multiline")
+	}
 	if !p.PatternMatchesContent {
 		args = append(args, "nocontent")
 	}
@@ -163,7 +172,12 @@ type FileMatch struct {
 	Path        string
 	LineMatches []LineMatch
 
-	// LimitHit is true if LineMatches may not include all LineMatches.
+	// MultilineMatches is set instead of LineMatches if the request's
+	// IsMultiline is true.
+	MultilineMatches []MultilineMatch `json:",omitempty"`
+
+	// LimitHit is true if LineMatches (or MultilineMatches) may not include
+	// all matches.
 	LimitHit bool
 }
 
@@ -184,3 +198,28 @@ type LineMatch struct {
 	// LimitHit is true if OffsetAndLengths may not include all OffsetAndLengths.
 	LimitHit bool
 }
+
+// MultilineMatch is a match of a multiline regular expression. Unlike a
+// LineMatch, it is a single range that may span several lines.
+type MultilineMatch struct {
+	// Preview is the content of the lines that the match spans, without the
+	// trailing newline.
+	Preview string
+
+	// Start is the location of the first character of the match, and End is
+	// the location just after the last character of the match.
+	Start, End Location
+}
+
+// Location is a position in a file.
+type Location struct {
+	// Offset is the 0-based byte offset from the start of the file.
+	Offset int
+
+	// Line is the 0-based line number.
+	Line int
+
+	// Column is the 0-based offset from the start of the line, measured in
+	// characters (like the offsets of LineMatch), not bytes.
+	Column int
+}
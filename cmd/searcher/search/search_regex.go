@@ -46,6 +46,10 @@ type readerGrep struct {
 	// ignoreCase if true means we need to do case insensitive matching.
 	ignoreCase bool
 
+	// multiline if true means matches are reported as MultilineMatches
+	// rather than being split into LineMatches.
+	multiline bool
+
 	// transformBuf is reused between file searches to avoid
 	// re-allocating. It is only used if we need to transform the input
 	// before matching. For example we lower case the input in the case of
@@ -81,6 +85,11 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 			// We don't do the search line by line, therefore we want the
 			// regex engine to consider newlines for anchors (^$).
 			expr = "(?m:" + expr + ")"
+			if p.IsMultiline {
+				// Let "." match newlines, so that the pattern can span
+				// lines without spelling out every newline.
+				expr = "(?s:" + expr + ")"
+			}
 		}
 		if !p.IsCaseSensitive {
 			// We don't just use (?i) because regexp library doesn't seem
@@ -124,6 +133,7 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	return &readerGrep{
 		re:               re,
 		ignoreCase:       !p.IsCaseSensitive,
+		multiline:        p.IsRegExp && p.IsMultiline,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
 	}, nil
@@ -135,6 +145,7 @@ func (rg *readerGrep) Copy() *readerGrep {
 	return &readerGrep{
 		re:               rg.re,
 		ignoreCase:       rg.ignoreCase,
+		multiline:        rg.multiline,
 		matchPath:        rg.matchPath,
 		literalSubstring: rg.literalSubstring,
 	}
@@ -152,14 +163,15 @@ func (rg *readerGrep) matchString(s string) bool {
 	return rg.re.MatchString(s)
 }
 
-// Find returns a LineMatch for each line that matches rg in reader.
-// LimitHit is true if some matches may not have been included in the result.
+// findAllIndex returns the locations of the matches of rg in f, as well as
+// the content of f (fileBuf, for Preview) and the content that rg was run on
+// (fileMatchBuf). At most maxLineMatches+1 locations are returned.
 // NOTE: This is not safe to use concurrently.
-func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, limitHit bool, err error) {
+func (rg *readerGrep) findAllIndex(zf *store.ZipFile, f *store.SrcFile) (fileBuf, fileMatchBuf []byte, locs [][]int) {
 	// fileMatchBuf is what we run match on, fileBuf is the original
 	// data (for Preview).
-	fileBuf := zf.DataFor(f)
-	fileMatchBuf := fileBuf
+	fileBuf = zf.DataFor(f)
+	fileMatchBuf = fileBuf
 
 	// If we are ignoring case, we transform the input instead of
 	// relying on the regular expression engine which can be
@@ -182,10 +194,17 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 	// per-line. Additionally if we have a non-empty literalSubstring, we use
 	// that to prune out files since doing bytes.Index is very fast.
 	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
-		return nil, false, nil
+		return fileBuf, fileMatchBuf, nil
 	}
 
-	locs := rg.re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
+	return fileBuf, fileMatchBuf, rg.re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
+}
+
+// Find returns a LineMatch for each line that matches rg in reader.
+// LimitHit is true if some matches may not have been included in the result.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, limitHit bool, err error) {
+	fileBuf, fileMatchBuf, locs := rg.findAllIndex(zf, f)
 	lastStart := 0
 	lastLineNumber := 0
 	lastMatchIndex := 0
@@ -227,6 +246,67 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 	return matches, limitHit, nil
 }
 
+// FindMultiline returns a MultilineMatch for each match of rg in reader. Unlike
+// Find, matches that span several lines are returned as a single range.
+// LimitHit is true if some matches may not have been included in the result.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) FindMultiline(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.MultilineMatch, limitHit bool, err error) {
+	fileBuf, fileMatchBuf, locs := rg.findAllIndex(zf, f)
+	if len(locs) > maxLineMatches {
+		locs = locs[:maxLineMatches]
+		limitHit = true
+	}
+
+	// lineNumber is the line of lineStart, and lineStart is the index of
+	// the start of the line containing the start of the previous match. The
+	// matches are ordered, so we only need to count the newlines between
+	// consecutive matches.
+	lineNumber, lineStart, last := 0, 0, 0
+	for _, match := range locs {
+		start, end := match[0], match[1]
+
+		lineNumber += bytes.Count(fileMatchBuf[last:start], []byte{'\n'})
+		if idx := bytes.LastIndexByte(fileMatchBuf[last:start], '\n'); idx >= 0 {
+			lineStart = last + idx + 1
+		}
+		last = start
+
+		endLineNumber, endLineStart := lineNumber, lineStart
+		if idx := bytes.LastIndexByte(fileMatchBuf[start:end], '\n'); idx >= 0 {
+			endLineNumber += bytes.Count(fileMatchBuf[start:end], []byte{'\n'})
+			endLineStart = start + idx + 1
+		}
+
+		// The preview ends at the end of the last line of the match. If
+		// the match ends with a newline, that is the line it ends on.
+		lineEnd := end
+		if end > start && fileMatchBuf[end-1] == '\n' {
+			lineEnd = end - 1
+		} else if idx := bytes.IndexByte(fileMatchBuf[end:], '\n'); idx >= 0 {
+			lineEnd = end + idx
+		} else {
+			lineEnd = len(fileMatchBuf)
+		}
+
+		matches = append(matches, protocol.MultilineMatch{
+			// Copy, since we are not allowed to use the fileBuf data after
+			// the ZipFile has been Closed. See appendMatches.
+			Preview: string(fileBuf[lineStart:lineEnd]),
+			Start: protocol.Location{
+				Offset: start,
+				Line:   lineNumber,
+				Column: utf8.RuneCount(fileBuf[lineStart:start]),
+			},
+			End: protocol.Location{
+				Offset: end,
+				Line:   endLineNumber,
+				Column: utf8.RuneCount(fileBuf[endLineStart:end]),
+			},
+		})
+	}
+	return matches, limitHit, nil
+}
+
 func hydrateLineNumbers(fileBuf []byte, lastLineNumber, lastMatchIndex, lineStart int, match []int) (lineNumber, matchIndex int) {
 	lineNumber = lastLineNumber + bytes.Count(fileBuf[lastMatchIndex:match[0]], []byte{'\n'})
 	return lineNumber, lineStart
@@ -283,8 +363,16 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 	return matches
 }
 
-// FindZip is a convenience function to run Find on f.
+// FindZip is a convenience function to run Find (or FindMultiline) on f.
 func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.FileMatch, error) {
+	if rg.multiline {
+		mm, limitHit, err := rg.FindMultiline(zf, f)
+		return protocol.FileMatch{
+			Path:             f.Name,
+			MultilineMatches: mm,
+			LimitHit:         limitHit,
+		}, err
+	}
 	lm, limitHit, err := rg.Find(zf, f)
 	return protocol.FileMatch{
 		Path:        f.Name,
@@ -402,7 +490,7 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 					})
 					return
 				}
-				match := len(fm.LineMatches) > 0
+				match := len(fm.LineMatches) > 0 || len(fm.MultilineMatches) > 0
 				if !match && patternMatchesPaths {
 					// Try matching against the file path.
 					match = rg.matchString(f.Name)
@@ -522,6 +522,89 @@ func TestPathMatches(t *testing.T) {
 	}
 }
 
+func TestFindMultiline(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go": "package main\n\nfunc a() {\n}\n\nfunc B() {\n\treturn\n}\n",
+		"ü.go":    "// ü\nfunc ü() {}\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		pattern string
+		path    string
+		want    []protocol.MultilineMatch
+	}{{
+		pattern: `func\s+\w+\(\)\s*\{\s*\}`,
+		path:    "main.go",
+		want: []protocol.MultilineMatch{{
+			Preview: "func a() {\n}",
+			Start:   protocol.Location{Offset: 14, Line: 2, Column: 0},
+			End:     protocol.Location{Offset: 26, Line: 3, Column: 1},
+		}},
+	}, {
+		// "." matches newlines, and case is ignored.
+		pattern: `{.*?RETURN`,
+		path:    "main.go",
+		want: []protocol.MultilineMatch{{
+			Preview: "func a() {\n}\n\nfunc B() {\n\treturn",
+			Start:   protocol.Location{Offset: 23, Line: 2, Column: 9},
+			End:     protocol.Location{Offset: 46, Line: 6, Column: 7},
+		}},
+	}, {
+		// Matches ending with a newline end at the start of the next line.
+		pattern: `^}\n`,
+		path:    "main.go",
+		want: []protocol.MultilineMatch{{
+			Preview: "}",
+			Start:   protocol.Location{Offset: 25, Line: 3, Column: 0},
+			End:     protocol.Location{Offset: 27, Line: 4, Column: 0},
+		}, {
+			Preview: "}",
+			Start:   protocol.Location{Offset: 47, Line: 7, Column: 0},
+			End:     protocol.Location{Offset: 49, Line: 8, Column: 0},
+		}},
+	}, {
+		// Columns are measured in characters.
+		pattern: `ü\nfunc ü`,
+		path:    "ü.go",
+		want: []protocol.MultilineMatch{{
+			Preview: "// ü\nfunc ü() {}",
+			Start:   protocol.Location{Offset: 3, Line: 0, Column: 3},
+			End:     protocol.Location{Offset: 13, Line: 1, Column: 6},
+		}},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			rg, err := compile(&protocol.PatternInfo{Pattern: tc.pattern, IsRegExp: true, IsMultiline: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f *store.SrcFile
+			for i := range zf.Files {
+				if zf.Files[i].Name == tc.path {
+					f = &zf.Files[i]
+				}
+			}
+			fm, err := rg.FindZip(zf, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fm.LineMatches) != 0 {
+				t.Errorf("got LineMatches %v, want none", fm.LineMatches)
+			}
+			if !reflect.DeepEqual(fm.MultilineMatches, tc.want) {
+				t.Errorf("got %+v, want %+v", fm.MultilineMatches, tc.want)
+			}
+		})
+	}
+}
+
 // githubStore fetches from github and caches across test runs.
 var githubStore = &store.Store{
 	FetchTar: testutil.FetchTarFromGithub,
@@ -16,6 +16,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/hooks"
 	edb "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitea"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/github"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitlab"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/licensing"
@@ -72,6 +73,20 @@ func initAuthz(d dbutil.DB, clock func() time.Time) {
 			}
 		}
 
+		giteas, err := db.ExternalServices.ListGiteaConnections(ctx)
+		if err != nil {
+			return []*graphqlbackend.Alert{{
+				TypeValue:    graphqlbackend.AlertTypeError,
+				MessageValue: fmt.Sprintf("Unable to fetch Gitea external services: %s", err),
+			}}
+		}
+		for _, g := range giteas {
+			if g.Authorization != nil {
+				authzTypes = append(authzTypes, "Gitea")
+				break
+			}
+		}
+
 		if len(authzTypes) > 0 {
 			return []*graphqlbackend.Alert{{
 				TypeValue:    graphqlbackend.AlertTypeError,
@@ -137,6 +152,7 @@ type ExternalServicesStore interface {
 	ListGitLabConnections(context.Context) ([]*schema.GitLabConnection, error)
 	ListGitHubConnections(context.Context) ([]*schema.GitHubConnection, error)
 	ListBitbucketServerConnections(context.Context) ([]*schema.BitbucketServerConnection, error)
+	ListGiteaConnections(context.Context) ([]*schema.GiteaConnection, error)
 }
 
 // authzProvidersFromConfig returns the set of permission-related providers derived from the site config.
@@ -189,6 +205,15 @@ func authzProvidersFromConfig(
 		warnings = append(warnings, bbsWarnings...)
 	}
 
+	if giteaConns, err := s.ListGiteaConnections(ctx); err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load Gitea external service configs: %s", err))
+	} else {
+		giteaProviders, giteaProblems, giteaWarnings := gitea.NewAuthzProviders(giteaConns)
+		providers = append(providers, giteaProviders...)
+		seriousProblems = append(seriousProblems, giteaProblems...)
+		warnings = append(warnings, giteaWarnings...)
+	}
+
 	// 🚨 SECURITY: Warn the admin when both code host authz provider and the permissions user mapping are configured.
 	if cfg.SiteConfiguration.PermissionsUserMapping != nil &&
 		cfg.SiteConfiguration.PermissionsUserMapping.Enabled && len(providers) > 0 {
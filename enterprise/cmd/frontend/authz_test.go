@@ -551,6 +551,7 @@ type fakeStore struct {
 	gitlabs          []*schema.GitLabConnection
 	githubs          []*schema.GitHubConnection
 	bitbucketServers []*schema.BitbucketServerConnection
+	giteas           []*schema.GiteaConnection
 }
 
 func (s fakeStore) ListGitHubConnections(context.Context) ([]*schema.GitHubConnection, error) {
@@ -564,3 +565,7 @@ func (s fakeStore) ListGitLabConnections(context.Context) ([]*schema.GitLabConne
 func (s fakeStore) ListBitbucketServerConnections(context.Context) ([]*schema.BitbucketServerConnection, error) {
 	return s.bitbucketServers, nil
 }
+
+func (s fakeStore) ListGiteaConnections(context.Context) ([]*schema.GiteaConnection, error) {
+	return s.giteas, nil
+}
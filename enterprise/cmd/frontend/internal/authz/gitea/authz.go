@@ -0,0 +1,70 @@
+package gitea
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	iauthz "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// NewAuthzProviders returns the set of Gitea authz providers derived from the connections.
+// It also returns any validation problems with the config, separating these into "serious problems" and
+// "warnings". "Serious problems" are those that should make Sourcegraph set authz.allowAccessByDefault
+// to false. "Warnings" are all other validation problems.
+func NewAuthzProviders(
+	conns []*schema.GiteaConnection,
+) (ps []authz.Provider, problems []string, warnings []string) {
+	// Authorization (i.e., permissions) providers
+	for _, c := range conns {
+		p, err := newAuthzProvider(c.Authorization, c.Url, c.Token)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if p != nil {
+			ps = append(ps, p)
+		}
+	}
+
+	// 🚨 SECURITY: A provider that fails validation could list the repositories of the site
+	// admin for every user, so it is a serious problem and the provider is not used.
+	valid := ps[:0]
+	for _, p := range ps {
+		ok := true
+		for _, problem := range p.Validate() {
+			problems = append(problems, fmt.Sprintf("Gitea config for %s was invalid: %s", p.ServiceID(), problem))
+			ok = false
+		}
+		if ok {
+			valid = append(valid, p)
+		}
+	}
+
+	return valid, problems, warnings
+}
+
+func newAuthzProvider(a *schema.GiteaAuthorization, instanceURL, token string) (authz.Provider, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	baseURL, err := url.Parse(instanceURL)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse URL for Gitea instance %q: %s", instanceURL, err)
+	}
+
+	ttl, err := iauthz.ParseTTL(a.Ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProvider(extsvc.NormalizeBaseURL(baseURL), token, ttl, nil), nil
+}
+
+// ValidateAuthz validates the authorization fields of the given Gitea external
+// service config.
+func ValidateAuthz(cfg *schema.GiteaConnection) error {
+	_, err := newAuthzProvider(cfg.Authorization, cfg.Url, cfg.Token)
+	return err
+}
@@ -0,0 +1,54 @@
+package gitea
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// cache describes the shape of the repo permissions cache that Provider uses internally.
+type cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, b []byte)
+	Delete(key string)
+}
+
+// publicReposCacheKey is the key for caching the IDs of all public repositories. It must be
+// unique among all cache keys, including those for user repositories (see userReposCacheKey).
+const publicReposCacheKey = "public"
+
+// userReposCacheKey returns the key for caching the IDs of all repositories that the Gitea
+// user with the given account ID can access.
+func userReposCacheKey(accountID string) string {
+	return "u:" + accountID
+}
+
+// reposCacheVal is a set of Gitea repository IDs, as found in ExternalRepo.ID.
+type reposCacheVal struct {
+	IDs map[string]bool
+	TTL time.Duration
+}
+
+func cacheGetRepos(c cache, key string, ttl time.Duration) (v reposCacheVal, exists bool) {
+	b, exists := c.Get(key)
+	if !exists {
+		return reposCacheVal{}, false
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		c.Delete(key)
+		return reposCacheVal{}, false
+	}
+	if v.TTL != ttl {
+		c.Delete(key)
+		return reposCacheVal{}, false
+	}
+	return v, true
+}
+
+func cacheSetRepos(c cache, key string, v reposCacheVal) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.Set(key, b)
+	return nil
+}
@@ -0,0 +1,209 @@
+// Package gitea contains an authorization provider for Gitea.
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/internal/rcache"
+)
+
+// Provider implements authz.Provider for Gitea repository permissions. Sourcegraph users are
+// mapped to Gitea users with the same username. The repositories a Gitea user can access are
+// listed on behalf of the user with the site admin token of the connection (using the Sudo
+// header), and the whole list is cached for the cache TTL. Gogs ignores the Sudo header, so the
+// provider does not support Gogs.
+type Provider struct {
+	client   *gitea.Client
+	codeHost *extsvc.CodeHost
+	cacheTTL time.Duration
+	cache    cache
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Gitea authz provider for the Gitea instance at baseURL, which must
+// be normalized with extsvc.NormalizeBaseURL. The token must belong to a site admin of Gitea.
+func NewProvider(baseURL *url.URL, token string, cacheTTL time.Duration, mockCache cache) *Provider {
+	p := &Provider{
+		client:   gitea.NewClient(baseURL, token, nil),
+		codeHost: extsvc.NewCodeHost(baseURL, gitea.ServiceType),
+		cacheTTL: cacheTTL,
+		cache:    mockCache,
+	}
+	// Note: this will use the same underlying Redis instance and key namespace for every instance
+	// of Provider. This is by design, so that different instances, even in different processes,
+	// will share cache entries.
+	if p.cache == nil {
+		p.cache = rcache.NewWithTTL(fmt.Sprintf("giteaAuthz:%s", baseURL.String()), int(math.Ceil(cacheTTL.Seconds())))
+	}
+	return p
+}
+
+func (p *Provider) ServiceID() string {
+	return p.codeHost.ServiceID
+}
+
+func (p *Provider) ServiceType() string {
+	return p.codeHost.ServiceType
+}
+
+// Validate implements the authz.Provider interface. It checks that the token of the provider
+// belongs to a site admin, which is required to list repositories on behalf of other users. This
+// also rejects Gogs, which does not report whether a user is a site admin.
+func (p *Provider) Validate() (problems []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	u, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		if err == ctx.Err() {
+			return []string{fmt.Sprintf("Gitea API did not respond within 5s (%s)", err.Error())}
+		}
+		return []string{fmt.Sprintf("could not fetch the user of the access token: %s", err)}
+	}
+	if !u.IsAdmin {
+		problems = append(problems, "access token does not belong to a site admin of Gitea, which is required to enforce repository permissions (Gogs is not supported)")
+	}
+	return problems
+}
+
+// FetchAccount implements the authz.Provider interface. It returns the Gitea user with the same
+// username as the Sourcegraph user, if any.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	giteaUser, err := p.client.User(ctx, user.Username)
+	if err != nil {
+		if gitea.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	accountData, err := json.Marshal(giteaUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extsvc.ExternalAccount{
+		UserID: user.ID,
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: p.codeHost.ServiceType,
+			ServiceID:   p.codeHost.ServiceID,
+			AccountID:   strconv.FormatInt(giteaUser.ID, 10),
+		},
+		ExternalAccountData: extsvc.ExternalAccountData{
+			AccountData: (*json.RawMessage)(&accountData),
+		},
+	}, nil
+}
+
+// RepoPerms implements the authz.Provider interface. A user can read the repositories that the
+// Gitea user of the account can access, and unauthenticated users can read public repositories.
+func (p *Provider) RepoPerms(ctx context.Context, account *extsvc.ExternalAccount, repos []*types.Repo) ([]authz.RepoPerms, error) {
+	if len(repos) == 0 {
+		return nil, nil
+	}
+
+	var ids map[string]bool
+	var err error
+	if account != nil && account.ServiceID == p.codeHost.ServiceID && account.ServiceType == p.codeHost.ServiceType {
+		ids, err = p.userRepos(ctx, account)
+	} else {
+		ids, err = p.publicRepos(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	perms := make([]authz.RepoPerms, 0, len(repos))
+	for _, repo := range repos {
+		if ids[repo.ExternalRepo.ID] {
+			perms = append(perms, authz.RepoPerms{Repo: repo, Perms: authz.Read})
+		}
+	}
+	return perms, nil
+}
+
+// userRepos returns the IDs of all repositories that the Gitea user of the account can access.
+func (p *Provider) userRepos(ctx context.Context, account *extsvc.ExternalAccount) (map[string]bool, error) {
+	key := userReposCacheKey(account.AccountID)
+	if v, exists := cacheGetRepos(p.cache, key, p.cacheTTL); exists {
+		return v.IDs, nil
+	}
+
+	if account.AccountData == nil {
+		return nil, errors.Errorf("no account data for Gitea account %s", account.AccountID)
+	}
+	var u gitea.User
+	if err := json.Unmarshal(*account.AccountData, &u); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling Gitea account data")
+	}
+
+	// 🚨 SECURITY: Code hosts that ignore the Sudo header, like Gogs, list the repositories of
+	// the site admin instead, so check that requests are made on behalf of the user.
+	client := p.client.WithSudo(u.Login)
+	sudoUser, err := client.CurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching Gitea user %q with the Sudo header", u.Login)
+	}
+	if sudoUser.Login != u.Login {
+		return nil, errors.Errorf("Gitea made a request on behalf of user %q as %q; the code host does not support the Sudo header", u.Login, sudoUser.Login)
+	}
+
+	ids, err := p.fetchRepos(ctx, client, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing repositories of Gitea user %q", u.Login)
+	}
+	if err := cacheSetRepos(p.cache, key, reposCacheVal{IDs: ids, TTL: p.cacheTTL}); err != nil {
+		return nil, errors.Wrap(err, "could not set cached user repos")
+	}
+	return ids, nil
+}
+
+// publicRepos returns the IDs of all public repositories.
+func (p *Provider) publicRepos(ctx context.Context) (map[string]bool, error) {
+	if v, exists := cacheGetRepos(p.cache, publicReposCacheKey, p.cacheTTL); exists {
+		return v.IDs, nil
+	}
+
+	ids, err := p.fetchRepos(ctx, p.client, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing public Gitea repositories")
+	}
+	if err := cacheSetRepos(p.cache, publicReposCacheKey, reposCacheVal{IDs: ids, TTL: p.cacheTTL}); err != nil {
+		return nil, errors.Wrap(err, "could not set cached public repos")
+	}
+	return ids, nil
+}
+
+// fetchRepos returns the IDs of all repositories that the user of client can access.
+func (p *Provider) fetchRepos(ctx context.Context, client *gitea.Client, publicOnly bool) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	for page := 1; page != 0; {
+		repos, next, err := client.SearchRepos(ctx, publicOnly, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			if !publicOnly || !r.Private {
+				ids[strconv.FormatInt(r.ID, 10)] = true
+			}
+		}
+		page = next
+	}
+	return ids, nil
+}
@@ -0,0 +1,197 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+)
+
+// newFakeGitea returns a server that implements the parts of the Gitea API that the Provider
+// uses. Repo 1 is public, repo 2 is private to alice and repo 3 is private to nobody. Every
+// request is counted in requests. If gogs is true, the server behaves like Gogs, which ignores
+// the Sudo header and does not report site admins.
+func newFakeGitea(t *testing.T, requests *int, gogs bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("Authorization") != "token admin-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		sudo := r.Header.Get("Sudo")
+		if gogs {
+			sudo = ""
+		}
+		switch r.URL.Path {
+		case "/api/v1/user":
+			switch {
+			case sudo == "alice":
+				fmt.Fprint(w, `{"id":7,"login":"alice"}`)
+			case gogs:
+				fmt.Fprint(w, `{"id":1,"login":"admin","username":"admin"}`)
+			default:
+				fmt.Fprint(w, `{"id":1,"login":"admin","is_admin":true}`)
+			}
+		case "/api/v1/users/alice":
+			fmt.Fprint(w, `{"id":7,"login":"alice"}`)
+		case "/api/v1/repos/search":
+			var data []string
+			switch {
+			case r.URL.Query().Get("private") == "false":
+				data = []string{`{"id":1}`}
+			case sudo == "alice":
+				data = []string{`{"id":1}`, `{"id":2,"private":true}`}
+			case sudo == "":
+				data = []string{`{"id":1}`, `{"id":2,"private":true}`, `{"id":3,"private":true}`}
+			}
+			w.Header().Set("X-Total-Count", fmt.Sprint(len(data)))
+			fmt.Fprintf(w, `{"ok":true,"data":[%s]}`, strings.Join(data, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestProvider(t *testing.T) {
+	var requests int
+	srv := newFakeGitea(t, &requests, false)
+	defer srv.Close()
+
+	baseURL, _ := url.Parse(srv.URL + "/")
+	cache := mockCache{}
+	p := NewProvider(baseURL, "admin-token", time.Hour, cache)
+	ctx := context.Background()
+
+	if problems := p.Validate(); len(problems) != 0 {
+		t.Errorf("got problems %v, want none", problems)
+	}
+
+	if acct, err := p.FetchAccount(ctx, &types.User{ID: 2, Username: "bob"}, nil); err != nil || acct != nil {
+		t.Errorf("got account %+v and error %v for a user without a Gitea account, want none", acct, err)
+	}
+	acct, err := p.FetchAccount(ctx, &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSpec := extsvc.ExternalAccountSpec{ServiceType: "gitea", ServiceID: srv.URL + "/", AccountID: "7"}
+	if acct == nil || acct.UserID != 1 || acct.ExternalAccountSpec != wantSpec {
+		t.Fatalf("got account %+v, want %+v", acct, wantSpec)
+	}
+
+	var repos []*types.Repo
+	for _, id := range []string{"1", "2", "3"} {
+		repos = append(repos, &types.Repo{
+			Name:         api.RepoName("gitea.mycorp.com/org/" + id),
+			ExternalRepo: api.ExternalRepoSpec{ID: id, ServiceType: "gitea", ServiceID: srv.URL + "/"},
+		})
+	}
+	readable := func(perms []authz.RepoPerms) (ids []string) {
+		for _, p := range perms {
+			if p.Perms == authz.Read {
+				ids = append(ids, p.Repo.ExternalRepo.ID)
+			}
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		name    string
+		account *extsvc.ExternalAccount
+		want    []string
+	}{
+		{"alice", acct, []string{"1", "2"}},
+		{"unauthenticated", nil, []string{"1"}},
+		{"other code host", &extsvc.ExternalAccount{ExternalAccountSpec: extsvc.ExternalAccountSpec{ServiceType: "gitea", ServiceID: "https://other.mycorp.com/", AccountID: "7"}}, []string{"1"}},
+	} {
+		perms, err := p.RepoPerms(ctx, tc.account, repos)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if have := readable(perms); !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("%s: got readable repos %v, want %v", tc.name, have, tc.want)
+		}
+	}
+
+	// Permissions are cached.
+	before := requests
+	if _, err := p.RepoPerms(ctx, acct, repos); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RepoPerms(ctx, nil, repos); err != nil {
+		t.Fatal(err)
+	}
+	if requests != before {
+		t.Errorf("got %d requests, want permissions to be cached", requests-before)
+	}
+
+	// Cache entries with a different TTL are ignored.
+	p.cacheTTL = 2 * time.Hour
+	if _, err := p.RepoPerms(ctx, acct, repos); err != nil {
+		t.Fatal(err)
+	}
+	if requests == before {
+		t.Error("got no requests, want permissions to be fetched after the TTL changed")
+	}
+}
+
+func TestProvider_Validate(t *testing.T) {
+	var requests int
+	srv := newFakeGitea(t, &requests, false)
+	defer srv.Close()
+
+	baseURL, _ := url.Parse(srv.URL + "/")
+	p := NewProvider(baseURL, "user-token", time.Hour, mockCache{})
+	if problems := p.Validate(); len(problems) != 1 {
+		t.Errorf("got problems %v, want 1 for an invalid token", problems)
+	}
+}
+
+type mockCache map[string]string
+
+func (m mockCache) Get(key string) ([]byte, bool) {
+	v, ok := m[key]
+	return []byte(v), ok
+}
+
+func (m mockCache) Set(key string, b []byte) {
+	m[key] = string(b)
+}
+
+func (m mockCache) Delete(key string) {
+	delete(m, key)
+}
+
+func TestProvider_gogs(t *testing.T) {
+	var requests int
+	srv := newFakeGitea(t, &requests, true)
+	defer srv.Close()
+
+	baseURL, _ := url.Parse(srv.URL + "/")
+	p := NewProvider(baseURL, "admin-token", time.Hour, mockCache{})
+	if problems := p.Validate(); len(problems) != 1 {
+		t.Errorf("got problems %v, want 1 for Gogs", problems)
+	}
+
+	// Even if the provider is used, it must not grant the repositories of the site admin.
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := []*types.Repo{{
+		Name:         "gogs.mycorp.com/org/3",
+		ExternalRepo: api.ExternalRepoSpec{ID: "3", ServiceType: "gitea", ServiceID: srv.URL + "/"},
+	}}
+	if perms, err := p.RepoPerms(context.Background(), acct, repos); err == nil {
+		t.Errorf("got perms %+v, want an error because Gogs ignores the Sudo header", perms)
+	}
+}
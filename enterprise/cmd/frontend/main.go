@@ -69,13 +69,14 @@ func main() {
 	repositories := repos.NewDBStore(dbconn.Global, sql.TxOptions{})
 
 	githubWebhook := campaigns.NewGitHubWebhook(campaignsStore, repositories, clock)
+	gitlabWebhook := campaigns.NewGitLabWebhook(campaignsStore, repositories, clock)
 	bitbucketServerWebhook := campaigns.NewBitbucketServerWebhook(campaignsStore, repositories, clock)
 
 	go bitbucketServerWebhook.Upsert(30 * time.Second)
 
 	go campaigns.RunChangesetJobs(ctx, campaignsStore, clock, gitserver.DefaultClient, 5*time.Second)
 
-	shared.Main(githubWebhook, bitbucketServerWebhook)
+	shared.Main(githubWebhook, gitlabWebhook, bitbucketServerWebhook)
 }
 
 func initLicensing() {
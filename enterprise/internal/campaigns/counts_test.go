@@ -9,6 +9,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 func TestCalcCounts(t *testing.T) {
@@ -444,6 +445,45 @@ func TestCalcCounts(t *testing.T) {
 				{Time: daysAgo(0), Total: 1, Open: 1, OpenApproved: 1},
 			},
 		},
+		{
+			codehosts: "gitlab",
+			name:      "single changeset open, approved, closed, reopened",
+			changesets: []*campaigns.Changeset{
+				glChangeset(1, daysAgo(3)),
+			},
+			start: daysAgo(3),
+			events: []Event{
+				glNote(1, daysAgo(2), "user1", campaigns.ChangesetEventKindGitLabApproved),
+				glNote(1, daysAgo(1), "user1", campaigns.ChangesetEventKindGitLabClosed),
+				glNote(1, daysAgo(0), "user1", campaigns.ChangesetEventKindGitLabReopened),
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(3), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(2), Total: 1, Open: 1, OpenApproved: 1},
+				{Time: daysAgo(1), Total: 1, Closed: 1},
+				{Time: daysAgo(0), Total: 1, Open: 1, OpenApproved: 1},
+			},
+		},
+		{
+			codehosts: "gitlab",
+			name:      "single changeset open, approved, unapproved, merged",
+			changesets: []*campaigns.Changeset{
+				glChangeset(1, daysAgo(4)),
+			},
+			start: daysAgo(4),
+			events: []Event{
+				glNote(1, daysAgo(3), "user1", campaigns.ChangesetEventKindGitLabApproved),
+				glNote(1, daysAgo(2), "user1", campaigns.ChangesetEventKindGitLabUnapproved),
+				glNote(1, daysAgo(1), "user1", campaigns.ChangesetEventKindGitLabMerged),
+			},
+			want: []*ChangesetCounts{
+				{Time: daysAgo(4), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(3), Total: 1, Open: 1, OpenApproved: 1},
+				{Time: daysAgo(2), Total: 1, Open: 1, OpenPending: 1},
+				{Time: daysAgo(1), Total: 1, Merged: 1},
+				{Time: daysAgo(0), Total: 1, Merged: 1},
+			},
+		},
 		{
 			codehosts: "github",
 			name:      "single changeset open, approved, closed, merged",
@@ -1219,6 +1259,10 @@ func bbsChangeset(id int64, t time.Time) *campaigns.Changeset {
 	}
 }
 
+func glChangeset(id int64, t time.Time) *campaigns.Changeset {
+	return &campaigns.Changeset{ID: id, Metadata: &gitlab.MergeRequest{CreatedAt: t}}
+}
+
 func setExternalDeletedAt(c *campaigns.Changeset, t time.Time) *campaigns.Changeset {
 	c.SetDeleted()
 	c.ExternalDeletedAt = t
@@ -1271,3 +1315,15 @@ func bbsActivity(id int64, t time.Time, username string, kind campaigns.Changese
 		},
 	}
 }
+
+func glNote(id int64, t time.Time, username string, kind campaigns.ChangesetEventKind) *campaigns.ChangesetEvent {
+	return &campaigns.ChangesetEvent{
+		ChangesetID: id,
+		Kind:        kind,
+		Metadata: &gitlab.Note{
+			System:    true,
+			CreatedAt: t,
+			Author:    gitlab.Author{Username: username},
+		},
+	}
+}
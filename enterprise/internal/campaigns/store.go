@@ -17,6 +17,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 // Store exposes methods to read and write campaigns domain models
@@ -482,6 +483,7 @@ func countChangesetsQuery(opts *CountChangesetsOpts) *sqlf.Query {
 // GetChangesetOpts captures the query options needed for getting a Changeset
 type GetChangesetOpts struct {
 	ID                  int64
+	RepoID              api.RepoID
 	ExternalID          string
 	ExternalServiceType string
 }
@@ -533,6 +535,10 @@ func getChangesetQuery(opts *GetChangesetOpts) *sqlf.Query {
 		preds = append(preds, sqlf.Sprintf("id = %s", opts.ID))
 	}
 
+	if opts.RepoID != 0 {
+		preds = append(preds, sqlf.Sprintf("repo_id = %s", opts.RepoID))
+	}
+
 	if opts.ExternalID != "" && opts.ExternalServiceType != "" {
 		preds = append(preds,
 			sqlf.Sprintf("external_id = %s", opts.ExternalID),
@@ -2658,6 +2664,8 @@ func scanChangeset(t *campaigns.Changeset, s scanner) error {
 		t.Metadata = new(github.PullRequest)
 	case bitbucketserver.ServiceType:
 		t.Metadata = new(bitbucketserver.PullRequest)
+	case gitlab.ServiceType:
+		t.Metadata = new(gitlab.MergeRequest)
 	default:
 		return errors.New("unknown external service type")
 	}
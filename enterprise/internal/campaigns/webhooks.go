@@ -2,20 +2,28 @@ package campaigns
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	gh "github.com/google/go-github/v28/github"
 	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	bbs "github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
 	"github.com/sourcegraph/sourcegraph/schema"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -32,6 +40,18 @@ func (h Webhook) upsertChangesetEvent(
 	ctx context.Context,
 	pr int64,
 	ev interface{ Key() string },
+) error {
+	return h.upsertRepoChangesetEvent(ctx, 0, pr, ev)
+}
+
+// upsertRepoChangesetEvent upserts the event of the changeset with the given
+// external ID in the repo with the given ID, which is needed on code hosts where
+// external IDs are only unique per repo. A zero repo ID matches any repo.
+func (h Webhook) upsertRepoChangesetEvent(
+	ctx context.Context,
+	repo api.RepoID,
+	pr int64,
+	ev interface{ Key() string },
 ) (err error) {
 	var tx *Store
 	if tx, err = h.Store.Transact(ctx); err != nil {
@@ -40,6 +60,7 @@ func (h Webhook) upsertChangesetEvent(
 	defer tx.Done(&err)
 
 	cs, err := tx.GetChangeset(ctx, GetChangesetOpts{
+		RepoID:              repo,
 		ExternalID:          strconv.FormatInt(pr, 10),
 		ExternalServiceType: h.Service,
 	})
@@ -83,6 +104,69 @@ func (h Webhook) upsertChangesetEvent(
 	return tx.UpsertChangesetEvents(ctx, event)
 }
 
+// enqueueRepoUpdate enqueues a high priority update of the repo with the
+// given external ID on the code host of the external service e, so that
+// pushes are mirrored right away instead of when the update scheduler gets
+// to the repo.
+func (h Webhook) enqueueRepoUpdate(ctx context.Context, e *repos.ExternalService, externalID string) error {
+	rs, err := h.listRepos(ctx, e, externalID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rs {
+		log15.Debug("Enqueueing update of pushed repo", "repo", r.Name)
+		if _, err := repoupdater.DefaultClient.EnqueueRepoUpdate(ctx, gitserver.Repo{Name: api.RepoName(r.Name)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listRepos lists the repos with the given external ID on the code host of the
+// external service e.
+func (h Webhook) listRepos(ctx context.Context, e *repos.ExternalService, externalID string) ([]*repos.Repo, error) {
+	serviceID, err := externalServiceID(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Repos.ListRepos(ctx, repos.StoreListReposArgs{
+		ExternalRepos: []api.ExternalRepoSpec{{
+			ID:          externalID,
+			ServiceType: h.Service,
+			ServiceID:   serviceID,
+		}},
+	})
+}
+
+// externalServiceID returns the ServiceID of the external repos of the
+// external service e, which is the normalized URL of its code host.
+func externalServiceID(e *repos.ExternalService) (string, error) {
+	c, err := e.Configuration()
+	if err != nil {
+		return "", err
+	}
+
+	var rawURL string
+	switch c := c.(type) {
+	case *schema.GitHubConnection:
+		rawURL = c.Url
+	case *schema.GitLabConnection:
+		rawURL = c.Url
+	case *schema.BitbucketServerConnection:
+		rawURL = c.Url
+	default:
+		return "", errors.Errorf("external service kind %s does not support webhooks", e.Kind)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return extsvc.NormalizeBaseURL(u).String(), nil
+}
+
 // GitHubWebhook receives GitHub organization webhook events that are
 // relevant to campaigns, normalizes those events into ChangesetEvents
 // and upserts them to the database.
@@ -94,6 +178,13 @@ type BitbucketServerWebhook struct {
 	*Webhook
 }
 
+// GitLabWebhook receives GitLab webhook events. Push events enqueue updates
+// of the pushed repos, while merge request, note and pipeline events update
+// the changesets of campaigns.
+type GitLabWebhook struct {
+	*Webhook
+}
+
 func NewGitHubWebhook(store *Store, repos repos.Store, now func() time.Time) *GitHubWebhook {
 	return &GitHubWebhook{&Webhook{store, repos, now, github.ServiceType}}
 }
@@ -102,14 +193,25 @@ func NewBitbucketServerWebhook(store *Store, repos repos.Store, now func() time.
 	return &BitbucketServerWebhook{&Webhook{store, repos, now, bbs.ServiceType}}
 }
 
+func NewGitLabWebhook(store *Store, repos repos.Store, now func() time.Time) *GitLabWebhook {
+	return &GitLabWebhook{&Webhook{store, repos, now, gitlab.ServiceType}}
+}
+
 // ServeHTTP implements the http.Handler interface.
 func (h *GitHubWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	e, err := h.parseEvent(r)
+	e, svc, err := h.parseEvent(r)
 	if err != nil {
 		respond(w, err.code, err)
 		return
 	}
 
+	if push, ok := e.(*gh.PushEvent); ok {
+		if err := h.enqueueRepoUpdate(r.Context(), svc, push.GetRepo().GetNodeID()); err != nil {
+			respond(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	prs, ev := h.convertEvent(r.Context(), e)
 	if len(prs) == 0 || ev == nil {
 		respond(w, http.StatusOK, nil) // Nothing to do
@@ -128,16 +230,18 @@ func (h *GitHubWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *GitHubWebhook) parseEvent(r *http.Request) (interface{}, *httpError) {
+// parseEvent authenticates and parses the event of r. It returns the external
+// service whose webhook secret authenticated r.
+func (h *GitHubWebhook) parseEvent(r *http.Request) (interface{}, *repos.ExternalService, *httpError) {
 	args := repos.StoreListExternalServicesArgs{Kinds: []string{"GITHUB"}}
 	es, err := h.Repos.ListExternalServices(r.Context(), args)
 	if err != nil {
-		return nil, &httpError{http.StatusInternalServerError, err}
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
 	}
 
 	payload, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return nil, &httpError{http.StatusInternalServerError, err}
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
 	}
 
 	// 🚨 SECURITY: Try to authenticate the request with any of the stored secrets
@@ -146,31 +250,37 @@ func (h *GitHubWebhook) parseEvent(r *http.Request) (interface{}, *httpError) {
 	// If there are no secrets or no secret managed to authenticate the request,
 	// we return a 401 to the client.
 
-	var secrets [][]byte
+	var (
+		secrets [][]byte
+		owners  []*repos.ExternalService
+	)
 	for _, e := range es {
 		c, _ := e.Configuration()
 		for _, hook := range c.(*schema.GitHubConnection).Webhooks {
 			secrets = append(secrets, []byte(hook.Secret))
+			owners = append(owners, e)
 		}
 	}
 
+	var svc *repos.ExternalService
 	sig := r.Header.Get("X-Hub-Signature")
-	for _, secret := range secrets {
+	for i, secret := range secrets {
 		if err = gh.ValidateSignature(sig, payload, secret); err == nil {
+			svc = owners[i]
 			break
 		}
 	}
 
 	if len(secrets) == 0 || err != nil {
-		return nil, &httpError{http.StatusUnauthorized, err}
+		return nil, nil, &httpError{http.StatusUnauthorized, err}
 	}
 
 	e, err := gh.ParseWebHook(gh.WebHookType(r), payload)
 	if err != nil {
-		return nil, &httpError{http.StatusBadRequest, err}
+		return nil, nil, &httpError{http.StatusBadRequest, err}
 	}
 
-	return e, nil
+	return e, svc, nil
 }
 
 func (h *GitHubWebhook) convertEvent(ctx context.Context, theirs interface{}) (prs []int64, ours interface{ Key() string }) {
@@ -559,7 +669,7 @@ func (h *BitbucketServerWebhook) Upsert(every time.Duration) {
 			wh := bbs.Webhook{
 				Name:     "sourcegraph-campaigns",
 				Scope:    "global",
-				Events:   []string{"pr"},
+				Events:   []string{"pr", "repo"},
 				Endpoint: endpoint,
 				Secret:   secret,
 			}
@@ -575,12 +685,19 @@ func (h *BitbucketServerWebhook) Upsert(every time.Duration) {
 }
 
 func (h *BitbucketServerWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	e, err := h.parseEvent(r)
+	e, svc, err := h.parseEvent(r)
 	if err != nil {
 		respond(w, err.code, err)
 		return
 	}
 
+	if push, ok := e.(*bbs.PushEvent); ok {
+		if err := h.enqueueRepoUpdate(r.Context(), svc, strconv.Itoa(push.Repository.ID)); err != nil {
+			respond(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	pr, ev := h.convertEvent(e)
 	if pr == 0 || ev == nil {
 		respond(w, http.StatusOK, nil) // Nothing to do
@@ -592,19 +709,24 @@ func (h *BitbucketServerWebhook) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-func (h *BitbucketServerWebhook) parseEvent(r *http.Request) (interface{}, *httpError) {
+// parseEvent authenticates and parses the event of r. It returns the external
+// service whose webhook secret authenticated r.
+func (h *BitbucketServerWebhook) parseEvent(r *http.Request) (interface{}, *repos.ExternalService, *httpError) {
 	args := repos.StoreListExternalServicesArgs{Kinds: []string{"BITBUCKETSERVER"}}
 	es, err := h.Repos.ListExternalServices(r.Context(), args)
 	if err != nil {
-		return nil, &httpError{http.StatusInternalServerError, err}
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
 	}
 
 	payload, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return nil, &httpError{http.StatusInternalServerError, err}
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
 	}
 
-	var secrets [][]byte
+	var (
+		secrets [][]byte
+		owners  []*repos.ExternalService
+	)
 	for _, e := range es {
 		c, _ := e.Configuration()
 		con, ok := c.(*schema.BitbucketServerConnection)
@@ -614,25 +736,28 @@ func (h *BitbucketServerWebhook) parseEvent(r *http.Request) (interface{}, *http
 
 		if secret := con.WebhookSecret(); secret != "" {
 			secrets = append(secrets, []byte(secret))
+			owners = append(owners, e)
 		}
 	}
 
+	var svc *repos.ExternalService
 	sig := r.Header.Get("X-Hub-Signature")
-	for _, secret := range secrets {
+	for i, secret := range secrets {
 		if err = gh.ValidateSignature(sig, payload, secret); err == nil {
+			svc = owners[i]
 			break
 		}
 	}
 
 	if len(secrets) == 0 || err != nil {
-		return nil, &httpError{http.StatusUnauthorized, err}
+		return nil, nil, &httpError{http.StatusUnauthorized, err}
 	}
 
 	e, err := bbs.ParseWebHook(bbs.WebHookType(r), payload)
 	if err != nil {
-		return nil, &httpError{http.StatusBadRequest, err}
+		return nil, nil, &httpError{http.StatusBadRequest, err}
 	}
-	return e, nil
+	return e, svc, nil
 }
 
 func (h *BitbucketServerWebhook) convertEvent(theirs interface{}) (pr int64, ours interface{ Key() string }) {
@@ -644,6 +769,161 @@ func (h *BitbucketServerWebhook) convertEvent(theirs interface{}) (pr int64, our
 	return
 }
 
+// ServeHTTP implements the http.Handler interface.
+func (h *GitLabWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e, svc, err := h.parseEvent(r)
+	if err != nil {
+		respond(w, err.code, err)
+		return
+	}
+
+	var handleErr error
+	switch e := e.(type) {
+	case *gitlab.PushEvent:
+		handleErr = h.enqueueRepoUpdate(r.Context(), svc, strconv.Itoa(e.ProjectID))
+
+	case *gitlab.MergeRequestEvent:
+		handleErr = h.enqueueChangesetSync(r.Context(), svc, e.Project.ID, e.ObjectAttributes)
+
+	case *gitlab.NoteEvent:
+		if e.MergeRequest == nil {
+			break // Not a comment on a merge request
+		}
+		handleErr = h.upsertMergeRequestEvent(r.Context(), svc, e.ProjectID, *e.MergeRequest, e.Note())
+
+	case *gitlab.PipelineEvent:
+		if e.MergeRequest == nil {
+			break // Not a merge request pipeline
+		}
+		handleErr = h.upsertMergeRequestEvent(r.Context(), svc, e.Project.ID, *e.MergeRequest, e.Pipeline(h.Now()))
+
+	default:
+		respond(w, http.StatusOK, nil) // Nothing to do
+		return
+	}
+
+	if handleErr != nil {
+		respond(w, http.StatusInternalServerError, handleErr)
+	}
+}
+
+// enqueueChangesetSync enqueues a sync of the changeset of the given merge
+// request. The payloads of merge request events don't include the system
+// notes GitLab creates for changes to the merge request, so instead of
+// upserting an event that would duplicate the synced note, we sync the
+// changeset right away.
+func (h *GitLabWebhook) enqueueChangesetSync(ctx context.Context, e *repos.ExternalService, projectID int, mr gitlab.MergeRequestAttributes) error {
+	rs, err := h.listRepos(ctx, e, strconv.Itoa(mergeRequestProjectID(projectID, mr)))
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for _, r := range rs {
+		cs, err := h.Store.GetChangeset(ctx, GetChangesetOpts{
+			RepoID:              r.ID,
+			ExternalID:          strconv.Itoa(mr.IID),
+			ExternalServiceType: h.Service,
+		})
+		if err != nil {
+			if err == ErrNoResults {
+				continue // Not a changeset of any campaign
+			}
+			return err
+		}
+		ids = append(ids, cs.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	log15.Debug("Enqueueing sync of changesets of updated merge request", "ids", ids)
+	return repoupdater.DefaultClient.EnqueueChangesetSync(ctx, ids)
+}
+
+// upsertMergeRequestEvent upserts the given event of the changeset of the given
+// merge request. Since merge request IIDs are only unique per project, the
+// changeset is looked up in the repos of the merge request's project.
+func (h *GitLabWebhook) upsertMergeRequestEvent(
+	ctx context.Context,
+	e *repos.ExternalService,
+	projectID int,
+	mr gitlab.MergeRequestAttributes,
+	ev interface{ Key() string },
+) error {
+	rs, err := h.listRepos(ctx, e, strconv.Itoa(mergeRequestProjectID(projectID, mr)))
+	if err != nil {
+		return err
+	}
+
+	m := new(multierror.Error)
+	for _, r := range rs {
+		if err := h.upsertRepoChangesetEvent(ctx, r.ID, int64(mr.IID), ev); err != nil {
+			m = multierror.Append(m, err)
+		}
+	}
+	return m.ErrorOrNil()
+}
+
+// mergeRequestProjectID returns the ID of the project the given merge request
+// targets, falling back to the ID of the project of the event, which is the
+// source project for merge requests from forks.
+func mergeRequestProjectID(projectID int, mr gitlab.MergeRequestAttributes) int {
+	if mr.TargetProjectID != 0 {
+		return mr.TargetProjectID
+	}
+	return projectID
+}
+
+// parseEvent authenticates and parses the event of r. It returns the external
+// service whose webhook secret authenticated r.
+func (h *GitLabWebhook) parseEvent(r *http.Request) (interface{}, *repos.ExternalService, *httpError) {
+	args := repos.StoreListExternalServicesArgs{Kinds: []string{"GITLAB"}}
+	es, err := h.Repos.ListExternalServices(r.Context(), args)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
+	}
+
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusInternalServerError, err}
+	}
+
+	// 🚨 SECURITY: GitLab doesn't sign payloads, but sends the secret token
+	// of the webhook in a header, which must match one of the secrets in the
+	// GitLab external services config.
+	var svc *repos.ExternalService
+	token := []byte(gitlab.WebHookToken(r))
+	for _, e := range es {
+		c, _ := e.Configuration()
+		con, ok := c.(*schema.GitLabConnection)
+		if !ok {
+			continue
+		}
+
+		for _, hook := range con.Webhooks {
+			if hook.Secret != "" && subtle.ConstantTimeCompare(token, []byte(hook.Secret)) == 1 {
+				svc = e
+				break
+			}
+		}
+		if svc != nil {
+			break
+		}
+	}
+
+	if svc == nil {
+		return nil, nil, &httpError{http.StatusUnauthorized, nil}
+	}
+
+	e, err := gitlab.ParseWebHook(gitlab.WebHookType(r), payload)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusBadRequest, err}
+	}
+	return e, svc, nil
+}
+
 type httpError struct {
 	code int
 	err  error
@@ -23,11 +23,17 @@ import (
 	"github.com/google/go-cmp/cmp"
 	gh "github.com/google/go-github/github"
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	bbs "github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/httptestutil"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -269,6 +275,206 @@ func testGitHubWebhook(db *sql.DB) func(*testing.T) {
 	}
 }
 
+func TestWebhooks_push(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	secret := "secret"
+	repoStore := new(repos.FakeStore)
+	err := repoStore.UpsertExternalServices(ctx,
+		&repos.ExternalService{
+			Kind:        "GITHUB",
+			DisplayName: "GitHub",
+			Config: marshalJSON(t, &schema.GitHubConnection{
+				Url:      "https://github.com",
+				Token:    "abc",
+				Webhooks: []*schema.GitHubWebhook{{Org: "sourcegraph", Secret: secret}},
+			}),
+		},
+		&repos.ExternalService{
+			Kind:        "GITLAB",
+			DisplayName: "GitLab",
+			Config: marshalJSON(t, &schema.GitLabConnection{
+				Url:          "https://gitlab.com",
+				Token:        "abc",
+				ProjectQuery: []string{"none"},
+				Webhooks:     []*schema.GitLabWebhook{{Secret: secret}},
+			}),
+		},
+		&repos.ExternalService{
+			Kind:        "BITBUCKETSERVER",
+			DisplayName: "Bitbucket Server",
+			Config: marshalJSON(t, &schema.BitbucketServerConnection{
+				Url:   "https://bitbucket.sgdev.org",
+				Token: "abc",
+				Plugin: &schema.BitbucketServerPlugin{
+					Webhooks: &schema.BitbucketServerPluginWebhooks{Secret: secret},
+				},
+			}),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repoStore.UpsertRepos(ctx,
+		&repos.Repo{
+			Name: "github.com/sourcegraph/sourcegraph",
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          "MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA==",
+				ServiceType: github.ServiceType,
+				ServiceID:   "https://github.com/",
+			},
+		},
+		// The same external ID on another code host must not be updated.
+		&repos.Repo{
+			Name: "ghe.sgdev.org/sourcegraph/sourcegraph",
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          "MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA==",
+				ServiceType: github.ServiceType,
+				ServiceID:   "https://ghe.sgdev.org/",
+			},
+		},
+		&repos.Repo{
+			Name: "gitlab.com/gitlab-org/gitlab",
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          "278964",
+				ServiceType: gitlab.ServiceType,
+				ServiceID:   "https://gitlab.com/",
+			},
+		},
+		&repos.Repo{
+			Name: "bitbucket.sgdev.org/SOUR/vegeta",
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          "10067",
+				ServiceType: bbs.ServiceType,
+				ServiceID:   "https://bitbucket.sgdev.org/",
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var enqueued []api.RepoName
+	repoupdater.MockEnqueueRepoUpdate = func(ctx context.Context, repo gitserver.Repo) (*protocol.RepoUpdateResponse, error) {
+		enqueued = append(enqueued, repo.Name)
+		return &protocol.RepoUpdateResponse{Name: string(repo.Name)}, nil
+	}
+	defer func() { repoupdater.MockEnqueueRepoUpdate = nil }()
+
+	githubHook := NewGitHubWebhook(nil, repoStore, clock)
+	gitlabHook := NewGitLabWebhook(nil, repoStore, clock)
+	bbsHook := NewBitbucketServerWebhook(nil, repoStore, clock)
+
+	githubPush := func(nodeID string) string {
+		return `{"ref": "refs/heads/master", "repository": {"node_id": "` + nodeID + `"}}`
+	}
+	githubHeaders := func(body, secret string) map[string]string {
+		return map[string]string{
+			"X-Github-Event":  "push",
+			"X-Hub-Signature": sign(t, []byte(body), []byte(secret)),
+		}
+	}
+	gitlabPush := `{"object_kind": "push", "ref": "refs/heads/master", "project_id": 278964}`
+	bbsPush := `{"repository": {"id": 10067, "slug": "vegeta"}, "changes": [{"refId": "refs/heads/master", "type": "UPDATE"}]}`
+
+	for _, tc := range []struct {
+		name    string
+		hook    http.Handler
+		headers map[string]string
+		body    string
+		code    int
+		want    []api.RepoName
+	}{
+		{
+			name:    "github",
+			hook:    githubHook,
+			headers: githubHeaders(githubPush("MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA=="), secret),
+			body:    githubPush("MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA=="),
+			code:    http.StatusOK,
+			want:    []api.RepoName{"github.com/sourcegraph/sourcegraph"},
+		},
+		{
+			name:    "github-unauthorized",
+			hook:    githubHook,
+			headers: githubHeaders(githubPush("MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA=="), "wrong-secret"),
+			body:    githubPush("MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA=="),
+			code:    http.StatusUnauthorized,
+		},
+		{
+			name:    "github-unknown-repo",
+			hook:    githubHook,
+			headers: githubHeaders(githubPush("unknown"), secret),
+			body:    githubPush("unknown"),
+			code:    http.StatusOK,
+		},
+		{
+			name:    "gitlab",
+			hook:    gitlabHook,
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": secret},
+			body:    gitlabPush,
+			code:    http.StatusOK,
+			want:    []api.RepoName{"gitlab.com/gitlab-org/gitlab"},
+		},
+		{
+			name:    "gitlab-unauthorized",
+			hook:    gitlabHook,
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "wrong-secret"},
+			body:    gitlabPush,
+			code:    http.StatusUnauthorized,
+		},
+		{
+			name:    "gitlab-unsupported-event",
+			hook:    gitlabHook,
+			headers: map[string]string{"X-Gitlab-Event": "Issue Hook", "X-Gitlab-Token": secret},
+			body:    `{"object_kind": "issue"}`,
+			code:    http.StatusOK,
+		},
+		{
+			name:    "gitlab-merge-request-unknown-repo",
+			hook:    gitlabHook,
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": secret},
+			body:    `{"object_kind": "merge_request", "project": {"id": 1}, "object_attributes": {"iid": 1, "target_project_id": 1}}`,
+			code:    http.StatusOK,
+		},
+		{
+			name: "bitbucket-server",
+			hook: bbsHook,
+			headers: map[string]string{
+				"X-Event-Key":     "repo:refs_changed",
+				"X-Hub-Signature": sign(t, []byte(bbsPush), []byte(secret)),
+			},
+			body: bbsPush,
+			code: http.StatusOK,
+			want: []api.RepoName{"bitbucket.sgdev.org/SOUR/vegeta"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			enqueued = nil
+
+			req, err := http.NewRequest("POST", "", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			tc.hook.ServeHTTP(rec, req)
+			if have := rec.Result().StatusCode; have != tc.code {
+				t.Errorf("have status code %d, want %d (body: %q)", have, tc.code, rec.Body.String())
+			}
+
+			if diff := cmp.Diff(enqueued, tc.want); diff != "" {
+				t.Errorf("enqueued repo updates: %s", diff)
+			}
+		})
+	}
+}
+
 type event struct {
 	name  string
 	event interface{}
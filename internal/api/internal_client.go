@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -150,6 +151,32 @@ type SavedQueryInfo struct {
 
 	// ExecDuration is the amount of time it took for the query to execute.
 	ExecDuration time.Duration
+
+	// ResultMatches are the matches found by the last execution of the query,
+	// which are compared with the matches of the next execution to find the
+	// matches that appeared or disappeared. It is nil for commit and diff
+	// searches, which only search for new results (see LatestResult).
+	ResultMatches []*SavedQueryMatch
+}
+
+// SavedQueryMatch is a file or line match found by a saved query.
+type SavedQueryMatch struct {
+	Repository string
+	Commit     string `json:",omitempty"` // only set for commit and diff matches
+	Path       string `json:",omitempty"`
+
+	// LineNumber is the 0-based line number of the match. Preview is the line
+	// that matched, or the commit message of commit and diff matches. Both are
+	// empty for matches of a file path.
+	LineNumber int32
+	Preview    string `json:",omitempty"`
+}
+
+// Fingerprint identifies the match across executions of a saved query. It
+// does not include the line number, so that a line that moved (because lines
+// were added above it) is not considered new.
+func (m *SavedQueryMatch) Fingerprint() string {
+	return strings.Join([]string{m.Repository, m.Commit, m.Path, strings.TrimSpace(m.Preview)}, "\x00")
 }
 
 // SavedQueriesGetInfo gets the info from the DB for the given saved query. nil
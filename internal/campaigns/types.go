@@ -10,6 +10,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
@@ -20,6 +21,7 @@ import (
 var SupportedExternalServices = map[string]struct{}{
 	github.ServiceType:          {},
 	bitbucketserver.ServiceType: {},
+	gitlab.ServiceType:          {},
 }
 
 // IsRepoSupported returns whether the given ExternalRepoSpec is supported by
@@ -301,6 +303,8 @@ func (c *Changeset) Title() (string, error) {
 		return m.Title, nil
 	case *bitbucketserver.PullRequest:
 		return m.Title, nil
+	case *gitlab.MergeRequest:
+		return m.Title, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -315,6 +319,8 @@ func (c *Changeset) ExternalCreatedAt() time.Time {
 		return m.CreatedAt
 	case *bitbucketserver.PullRequest:
 		return unixMilliToTime(int64(m.CreatedDate))
+	case *gitlab.MergeRequest:
+		return m.CreatedAt
 	default:
 		return time.Time{}
 	}
@@ -327,6 +333,8 @@ func (c *Changeset) Body() (string, error) {
 		return m.Body, nil
 	case *bitbucketserver.PullRequest:
 		return m.Description, nil
+	case *gitlab.MergeRequest:
+		return m.Description, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -359,6 +367,17 @@ func (c *Changeset) State() (s ChangesetState, err error) {
 		} else {
 			s = ChangesetState(m.State)
 		}
+	case *gitlab.MergeRequest:
+		switch m.State {
+		case gitlab.MergeRequestStateOpened:
+			s = ChangesetStateOpen
+		case gitlab.MergeRequestStateMerged:
+			s = ChangesetStateMerged
+		case gitlab.MergeRequestStateClosed, gitlab.MergeRequestStateLocked:
+			s = ChangesetStateClosed
+		default:
+			s = ChangesetState(m.State)
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -381,6 +400,8 @@ func (c *Changeset) URL() (s string, err error) {
 		}
 		selfLink := m.Links.Self[0]
 		return selfLink.Href, nil
+	case *gitlab.MergeRequest:
+		return m.WebURL, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -407,6 +428,13 @@ func (c *Changeset) ReviewState() (s ChangesetReviewState, err error) {
 				states[ChangesetReviewStateApproved] = true
 			}
 		}
+
+	case *gitlab.MergeRequest:
+		// GitLab has no reviews requesting changes, so merge requests are
+		// either approved or pending.
+		if len(m.ApprovedBy) > 0 {
+			states[ChangesetReviewStateApproved] = true
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -449,6 +477,17 @@ func (c *Changeset) Events() (events []*ChangesetEvent) {
 				Metadata:    a,
 			})
 		}
+
+	case *gitlab.MergeRequest:
+		events = make([]*ChangesetEvent, 0, len(m.Notes))
+		for _, n := range m.Notes {
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         n.Key(),
+				Kind:        ChangesetEventKindFor(n),
+				Metadata:    n,
+			})
+		}
 	}
 	return events
 }
@@ -462,6 +501,8 @@ func (c *Changeset) HeadRefOid() (string, error) {
 		return m.HeadRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *gitlab.MergeRequest:
+		return m.DiffRefs.HeadSHA, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -475,6 +516,8 @@ func (c *Changeset) HeadRef() (string, error) {
 		return "refs/heads/" + m.HeadRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.FromRef.ID, nil
+	case *gitlab.MergeRequest:
+		return "refs/heads/" + m.SourceBranch, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -489,6 +532,8 @@ func (c *Changeset) BaseRefOid() (string, error) {
 		return m.BaseRefOid, nil
 	case *bitbucketserver.PullRequest:
 		return "", nil
+	case *gitlab.MergeRequest:
+		return m.DiffRefs.BaseSHA, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -502,6 +547,8 @@ func (c *Changeset) BaseRef() (string, error) {
 		return "refs/heads/" + m.BaseRefName, nil
 	case *bitbucketserver.PullRequest:
 		return m.ToRef.ID, nil
+	case *gitlab.MergeRequest:
+		return "refs/heads/" + m.TargetBranch, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -616,6 +663,9 @@ func ComputeCheckState(c *Changeset, events []*ChangesetEvent) ChangesetCheckSta
 
 	case *bitbucketserver.PullRequest:
 		return computeBitbucketBuildStatus(m)
+
+	case *gitlab.MergeRequest:
+		return computeGitLabPipelineStatus(m, events)
 	}
 
 	return ChangesetCheckStateUnknown
@@ -642,6 +692,56 @@ func parseBitbucketBuildState(s string) ChangesetCheckState {
 	}
 }
 
+func computeGitLabPipelineStatus(mr *gitlab.MergeRequest, events []*ChangesetEvent) ChangesetCheckState {
+	// Pipeline events received after the last sync can be more up to date
+	// than the synced pipelines, so we keep the most recently updated version
+	// of each pipeline.
+	pipelines := make(map[int]*gitlab.Pipeline, len(mr.Pipelines))
+	for _, p := range mr.Pipelines {
+		pipelines[p.ID] = p
+	}
+	for _, e := range events {
+		p, ok := e.Metadata.(*gitlab.Pipeline)
+		if !ok {
+			continue
+		}
+		if synced, ok := pipelines[p.ID]; !ok || p.UpdatedAt.After(synced.UpdatedAt) {
+			pipelines[p.ID] = p
+		}
+	}
+
+	// Only the latest pipeline ran for the latest commit of the merge request.
+	var latest *gitlab.Pipeline
+	for _, p := range pipelines {
+		if latest == nil || p.ID > latest.ID {
+			latest = p
+		}
+	}
+	if latest == nil {
+		return ChangesetCheckStateUnknown
+	}
+	return parseGitLabPipelineStatus(latest.Status)
+}
+
+func parseGitLabPipelineStatus(s gitlab.PipelineStatus) ChangesetCheckState {
+	switch s {
+	case gitlab.PipelineStatusFailed, gitlab.PipelineStatusCanceled:
+		return ChangesetCheckStateFailed
+	case gitlab.PipelineStatusCreated,
+		gitlab.PipelineStatusWaitingForResource,
+		gitlab.PipelineStatusPreparing,
+		gitlab.PipelineStatusPending,
+		gitlab.PipelineStatusRunning,
+		gitlab.PipelineStatusManual,
+		gitlab.PipelineStatusScheduled:
+		return ChangesetCheckStatePending
+	case gitlab.PipelineStatusSuccess:
+		return ChangesetCheckStatePassed
+	default:
+		return ChangesetCheckStateUnknown
+	}
+}
+
 func computeGitHubCheckState(lastSynced time.Time, pr *github.PullRequest, events []*ChangesetEvent) ChangesetCheckState {
 	// We should only consider the latest commit. This could be from a sync or a webhook that
 	// has occurred later
@@ -861,6 +961,8 @@ func (e *ChangesetEvent) Actor() string {
 		a = e.Actor.Login
 	case *github.LabelEvent:
 		a = e.Actor.Login
+	case *gitlab.Note:
+		a = e.Author.Username
 	}
 
 	return a
@@ -889,6 +991,13 @@ func (e *ChangesetEvent) ReviewAuthor() (string, error) {
 			return "", errors.New("activity user is blank")
 		}
 		return username, nil
+
+	case *gitlab.Note:
+		username := meta.Author.Username
+		if username == "" {
+			return "", errors.New("note author is blank")
+		}
+		return username, nil
 	default:
 		return "", nil
 	}
@@ -903,6 +1012,9 @@ func (e *ChangesetEvent) ReviewState() (ChangesetReviewState, error) {
 	case ChangesetEventKindBitbucketServerReviewed:
 		return ChangesetReviewStateChangesRequested, nil
 
+	case ChangesetEventKindGitLabApproved:
+		return ChangesetReviewStateApproved, nil
+
 	case ChangesetEventKindGitHubReviewed:
 		review, ok := e.Metadata.(*github.PullRequestReview)
 		if !ok {
@@ -975,6 +1087,10 @@ func (e *ChangesetEvent) Timestamp() time.Time {
 		return e.ReceivedAt
 	case *bitbucketserver.Activity:
 		t = unixMilliToTime(int64(e.CreatedDate))
+	case *gitlab.Note:
+		t = e.CreatedAt
+	case *gitlab.Pipeline:
+		t = e.UpdatedAt
 	}
 
 	return t
@@ -1242,6 +1358,51 @@ func (e *ChangesetEvent) Update(o *ChangesetEvent) {
 		if e.CreatedAt.IsZero() {
 			e.CreatedAt = o.CreatedAt
 		}
+
+	case *gitlab.Note:
+		o := o.Metadata.(*gitlab.Note)
+
+		if e.Author == (gitlab.Author{}) {
+			e.Author = o.Author
+		}
+
+		if o.Body != "" && e.Body != o.Body {
+			e.Body = o.Body
+		}
+
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = o.CreatedAt
+		}
+
+		if e.UpdatedAt.Before(o.UpdatedAt) {
+			e.UpdatedAt = o.UpdatedAt
+		}
+
+	case *gitlab.Pipeline:
+		o := o.Metadata.(*gitlab.Pipeline)
+
+		if o.SHA != "" && e.SHA != o.SHA {
+			e.SHA = o.SHA
+		}
+
+		if o.Ref != "" && e.Ref != o.Ref {
+			e.Ref = o.Ref
+		}
+
+		if o.WebURL != "" && e.WebURL != o.WebURL {
+			e.WebURL = o.WebURL
+		}
+
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = o.CreatedAt
+		}
+
+		// Webhook events don't arrive in order, so the status is only updated
+		// by events about more recent updates of the pipeline.
+		if e.UpdatedAt.Before(o.UpdatedAt) {
+			e.Status = o.Status
+			e.UpdatedAt = o.UpdatedAt
+		}
 	default:
 		panic(errors.Errorf("unknown changeset event metadata %T", e))
 	}
@@ -1362,17 +1523,50 @@ func ChangesetEventKindFor(e interface{}) ChangesetEventKind {
 		return ChangesetEventKindCheckRun
 	case *bitbucketserver.Activity:
 		return ChangesetEventKind("bitbucketserver:" + strings.ToLower(string(e.Action)))
+	case *gitlab.Note:
+		return changesetEventKindForGitLabNote(e)
+	case *gitlab.Pipeline:
+		return ChangesetEventKindGitLabPipeline
 	default:
 		panic(errors.Errorf("unknown changeset event kind for %T", e))
 	}
 }
 
+// changesetEventKindForGitLabNote returns the ChangesetEventKind of the given
+// note. GitLab creates system notes for changes to a merge request, whose
+// bodies describe the change.
+func changesetEventKindForGitLabNote(n *gitlab.Note) ChangesetEventKind {
+	if !n.System {
+		return ChangesetEventKindGitLabCommented
+	}
+
+	switch strings.TrimSpace(n.Body) {
+	case "approved this merge request":
+		return ChangesetEventKindGitLabApproved
+	case "unapproved this merge request":
+		return ChangesetEventKindGitLabUnapproved
+	case "closed":
+		return ChangesetEventKindGitLabClosed
+	case "reopened":
+		return ChangesetEventKindGitLabReopened
+	case "merged":
+		return ChangesetEventKindGitLabMerged
+	default:
+		return ChangesetEventKindGitLabSystemNote
+	}
+}
+
 // NewChangesetEventMetadata returns a new metadata object for the given
 // ChangesetEventKind.
 func NewChangesetEventMetadata(k ChangesetEventKind) (interface{}, error) {
 	switch {
 	case strings.HasPrefix(string(k), "bitbucketserver"):
 		return new(bitbucketserver.Activity), nil
+	case strings.HasPrefix(string(k), "gitlab"):
+		if k == ChangesetEventKindGitLabPipeline {
+			return new(gitlab.Pipeline), nil
+		}
+		return new(gitlab.Note), nil
 	case strings.HasPrefix(string(k), "github"):
 		switch k {
 		case ChangesetEventKindGitHubAssigned:
@@ -1452,6 +1646,15 @@ const (
 	ChangesetEventKindBitbucketServerUpdated    ChangesetEventKind = "bitbucketserver:updated"
 	ChangesetEventKindBitbucketServerCommented  ChangesetEventKind = "bitbucketserver:commented"
 	ChangesetEventKindBitbucketServerMerged     ChangesetEventKind = "bitbucketserver:merged"
+
+	ChangesetEventKindGitLabApproved   ChangesetEventKind = "gitlab:approved"
+	ChangesetEventKindGitLabUnapproved ChangesetEventKind = "gitlab:unapproved"
+	ChangesetEventKindGitLabClosed     ChangesetEventKind = "gitlab:closed"
+	ChangesetEventKindGitLabReopened   ChangesetEventKind = "gitlab:reopened"
+	ChangesetEventKindGitLabMerged     ChangesetEventKind = "gitlab:merged"
+	ChangesetEventKindGitLabCommented  ChangesetEventKind = "gitlab:commented"
+	ChangesetEventKindGitLabSystemNote ChangesetEventKind = "gitlab:system_note"
+	ChangesetEventKindGitLabPipeline   ChangesetEventKind = "gitlab:pipeline"
 )
 
 // ChangesetSyncHeuristics represents data about the sync status of a changeset
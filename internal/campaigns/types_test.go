@@ -10,6 +10,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
 )
 
 func TestChangesetMetadata(t *testing.T) {
@@ -81,6 +82,26 @@ func TestChangesetMetadata(t *testing.T) {
 	}
 }
 
+func TestChangesetState_GitLab(t *testing.T) {
+	for state, want := range map[gitlab.MergeRequestState]ChangesetState{
+		gitlab.MergeRequestStateOpened: ChangesetStateOpen,
+		gitlab.MergeRequestStateMerged: ChangesetStateMerged,
+		gitlab.MergeRequestStateClosed: ChangesetStateClosed,
+		gitlab.MergeRequestStateLocked: ChangesetStateClosed,
+	} {
+		c := &Changeset{Metadata: &gitlab.MergeRequest{State: state}}
+
+		have, err := c.State()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have != want {
+			t.Errorf("merge request state %q: want=%q, have=%q", state, want, have)
+		}
+	}
+}
+
 func TestChangesetEvents(t *testing.T) {
 	type testCase struct {
 		name      string
@@ -251,6 +272,67 @@ func TestChangesetEvents(t *testing.T) {
 		})
 	}
 
+	{ // GitLab
+
+		user := gitlab.Author{Username: "john-doe"}
+		reviewer := gitlab.Author{Username: "jane-doe"}
+
+		notes := []*gitlab.Note{
+			{ID: 1, Author: reviewer, Body: "Looks good"},
+			{ID: 2, Author: reviewer, Body: "approved this merge request", System: true},
+			{ID: 3, Author: reviewer, Body: "unapproved this merge request", System: true},
+			{ID: 4, Author: user, Body: "closed", System: true},
+			{ID: 5, Author: user, Body: "reopened", System: true},
+			{ID: 6, Author: user, Body: "added 1 commit", System: true},
+			{ID: 7, Author: user, Body: "merged", System: true},
+		}
+
+		cases = append(cases, testCase{"gitlab",
+			Changeset{
+				ID: 25,
+				Metadata: &gitlab.MergeRequest{
+					Notes: notes,
+				},
+			},
+			[]*ChangesetEvent{{
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabCommented,
+				Key:         "1",
+				Metadata:    notes[0],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabApproved,
+				Key:         "2",
+				Metadata:    notes[1],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabUnapproved,
+				Key:         "3",
+				Metadata:    notes[2],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabClosed,
+				Key:         "4",
+				Metadata:    notes[3],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabReopened,
+				Key:         "5",
+				Metadata:    notes[4],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabSystemNote,
+				Key:         "6",
+				Metadata:    notes[5],
+			}, {
+				ChangesetID: 25,
+				Kind:        ChangesetEventKindGitLabMerged,
+				Key:         "7",
+				Metadata:    notes[6],
+			}},
+		})
+	}
+
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -525,6 +607,79 @@ func TestComputeGithubCheckState(t *testing.T) {
 	}
 }
 
+func TestComputeGitLabPipelineStatus(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	pipeline := func(id int, status gitlab.PipelineStatus, updatedAt time.Time) *gitlab.Pipeline {
+		return &gitlab.Pipeline{ID: id, Status: status, UpdatedAt: updatedAt}
+	}
+	event := func(p *gitlab.Pipeline) *ChangesetEvent {
+		return &ChangesetEvent{Kind: ChangesetEventKindGitLabPipeline, Metadata: p}
+	}
+
+	tests := []struct {
+		name      string
+		pipelines []*gitlab.Pipeline
+		events    []*ChangesetEvent
+		want      ChangesetCheckState
+	}{
+		{
+			name: "no pipelines",
+			want: ChangesetCheckStateUnknown,
+		},
+		{
+			name:      "single success",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusSuccess, now)},
+			want:      ChangesetCheckStatePassed,
+		},
+		{
+			name:      "single running",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusRunning, now)},
+			want:      ChangesetCheckStatePending,
+		},
+		{
+			name:      "single canceled",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusCanceled, now)},
+			want:      ChangesetCheckStateFailed,
+		},
+		{
+			name: "latest pipeline has precedence",
+			pipelines: []*gitlab.Pipeline{
+				pipeline(2, gitlab.PipelineStatusFailed, now),
+				pipeline(1, gitlab.PipelineStatusSuccess, now),
+			},
+			want: ChangesetCheckStateFailed,
+		},
+		{
+			name:      "event for new pipeline",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusSuccess, now)},
+			events:    []*ChangesetEvent{event(pipeline(2, gitlab.PipelineStatusPending, now))},
+			want:      ChangesetCheckStatePending,
+		},
+		{
+			name:      "more recent event for synced pipeline",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusRunning, now)},
+			events:    []*ChangesetEvent{event(pipeline(1, gitlab.PipelineStatusSuccess, now.Add(time.Minute)))},
+			want:      ChangesetCheckStatePassed,
+		},
+		{
+			name:      "older event for synced pipeline",
+			pipelines: []*gitlab.Pipeline{pipeline(1, gitlab.PipelineStatusFailed, now)},
+			events:    []*ChangesetEvent{event(pipeline(1, gitlab.PipelineStatusRunning, now.Add(-time.Minute)))},
+			want:      ChangesetCheckStateFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := &gitlab.MergeRequest{Pipelines: tc.pipelines}
+			got := computeGitLabPipelineStatus(mr, tc.events)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
 func TestChangesetEventsLabels(t *testing.T) {
 	now := time.Now()
 	labelEvent := func(name string, kind ChangesetEventKind, when time.Time) *ChangesetEvent {
@@ -292,6 +292,12 @@ func StructuralSearchEnabled() bool {
 	return val == "enabled"
 }
 
+// AndOrQueryEnabled reports whether search queries may contain the boolean
+// operators AND, OR and NOT.
+func AndOrQueryEnabled() bool {
+	return ExperimentalFeatures().AndOrQuery == "enabled"
+}
+
 func SearchMultipleRevisionsPerRepository() bool {
 	x := ExperimentalFeatures()
 	return x.SearchMultipleRevisionsPerRepository != nil && *x.SearchMultipleRevisionsPerRepository
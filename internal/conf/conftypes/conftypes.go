@@ -11,6 +11,10 @@ type ServiceConnections struct {
 	// to.
 	GitServers []string `json:"gitServers"`
 
+	// GitServerReplicas is the number of gitservers that store each repo. 0
+	// and 1 both mean that each repo is stored on a single gitserver.
+	GitServerReplicas int `json:"gitServerReplicas,omitempty"`
+
 	// PostgresDSN is the PostgreSQL DB data source name.
 	// eg: "postgres://sg@pgsql/sourcegraph?sslmode=false"
 	PostgresDSN string `json:"postgresDSN"`
@@ -94,3 +94,27 @@ func (m *hashMap) get(key string, exclude map[string]bool) string {
 	}
 	return ""
 }
+
+// Gets the n closest distinct items in the hash to the provided key that are
+// not in exclude, closest first. Fewer than n items are returned if there are
+// not enough items.
+func (m *hashMap) getN(key string, n int, exclude map[string]bool) []string {
+	if m.isEmpty() || n <= 0 {
+		return nil
+	}
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= hash })
+
+	items := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for offset := 0; offset < len(m.keys) && len(items) < n; offset++ {
+		item := m.hashMap[m.keys[(idx+offset)%len(m.keys)]]
+		if seen[item] || exclude[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
@@ -130,6 +130,19 @@ func (m *Map) Get(key string, exclude map[string]bool) (string, error) {
 	return urls.get(key, exclude), nil
 }
 
+// GetN returns the n closest distinct URLs in the hash to the provided key
+// that are not in exclude, closest first. The first URL is the one Get
+// returns. Fewer than n URLs are returned if there are not enough endpoints.
+// It is used to choose the replicas that store a key.
+func (m *Map) GetN(key string, n int, exclude map[string]bool) ([]string, error) {
+	urls, err := m.getUrls()
+	if err != nil {
+		return nil, err
+	}
+
+	return urls.getN(key, n, exclude), nil
+}
+
 // GetMany is the same as calling Get on each item of keys. It will only
 // acquire the underlying endpoint map once, so is preferred to calling Get
 // for each key which will acquire the endpoint map for each call. The benefit
@@ -37,6 +37,47 @@ func TestExclude(t *testing.T) {
 	}
 }
 
+func TestGetN(t *testing.T) {
+	endpoints := []string{"http://test-1", "http://test-2", "http://test-3", "http://test-4"}
+	m := Static(endpoints...)
+
+	for i := 0; i < 40; i++ {
+		key := fmt.Sprintf("test-%d", i)
+		first, err := m.Get(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.GetN(key, 3, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3 || got[0] != first {
+			t.Fatalf("GetN(%q, 3) = %v, want 3 URLs starting with %q", key, got, first)
+		}
+		seen := map[string]bool{}
+		for _, u := range got {
+			if seen[u] {
+				t.Fatalf("GetN(%q, 3) = %v, want distinct URLs", key, got)
+			}
+			seen[u] = true
+		}
+
+		// Excluding the first URL shifts the others up.
+		excluded, err := m.GetN(key, 2, map[string]bool{first: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(excluded, got[1:]) {
+			t.Fatalf("GetN(%q, 2, %q excluded) = %v, want %v", key, first, excluded, got[1:])
+		}
+
+		if all, _ := m.GetN(key, 10, nil); len(all) != len(endpoints) {
+			t.Fatalf("GetN(%q, 10) = %v, want all %d endpoints", key, all, len(endpoints))
+		}
+	}
+}
+
 func expectEndpoints(t *testing.T, m *Map, exclude map[string]bool, endpoints ...string) {
 	t.Helper()
 
@@ -17,7 +17,12 @@ func WebHookType(r *http.Request) string {
 }
 
 func ParseWebHook(event string, payload []byte) (e interface{}, err error) {
-	e = &PullRequestEvent{}
+	switch event {
+	case "repo:refs_changed":
+		e = &PushEvent{}
+	default:
+		e = &PullRequestEvent{}
+	}
 	return e, json.Unmarshal(payload, e)
 }
 
@@ -28,6 +33,22 @@ type PullRequestEvent struct {
 	Activity    *Activity   `json:"activity"`
 }
 
+// PushEvent is sent when refs of a repository are pushed to.
+type PushEvent struct {
+	Date       time.Time   `json:"date"`
+	Actor      User        `json:"actor"`
+	Repository Repo        `json:"repository"`
+	Changes    []RefChange `json:"changes"`
+}
+
+// RefChange is a change of a ref by a push.
+type RefChange struct {
+	RefID    string `json:"refId"`
+	FromHash string `json:"fromHash"`
+	ToHash   string `json:"toHash"`
+	Type     string `json:"type"`
+}
+
 // Webhook defines the JSON schema from the BBS Sourcegraph plugin.
 // This is not the native BBS webhook.
 type Webhook struct {
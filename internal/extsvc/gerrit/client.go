@@ -0,0 +1,278 @@
+package gerrit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"golang.org/x/time/rate"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var requestCounter = metrics.NewRequestMeter("gerrit_requests_count", "Total number of requests sent to the Gerrit API.")
+
+// These fields define the self-imposed Gerrit rate limit (since Gerrit does not have a
+// concept of rate limiting in HTTP response headers).
+//
+// See https://godoc.org/golang.org/x/time/rate#Limiter for an explanation of these fields.
+const (
+	rateLimitRequestsPerSecond = 5 // 300/min or 18000/hr
+	RateLimitMaxBurstRequests  = 50
+)
+
+// PerPage is the number of results requested per page of a query.
+const PerPage = 100
+
+// xssiPrefix is the prefix of every JSON response of the Gerrit REST API, which
+// prevents the responses from being included with script tags by other sites.
+var xssiPrefix = []byte(")]}'")
+
+// Client accesses a Gerrit instance via the REST API.
+type Client struct {
+	// HTTP Client used to communicate with the API
+	httpClient httpcli.Doer
+
+	// URL is the base URL of the Gerrit instance.
+	URL *url.URL
+
+	// Username and Password are the HTTP credentials of the Gerrit account used to
+	// authenticate requests. If Username is empty, requests are anonymous.
+	//
+	// 🚨 SECURITY: Password contains secret information that must not be shown to non-site-admins.
+	Username, Password string
+
+	// RateLimit is the self-imposed rate limiter (since Gerrit does not have a concept
+	// of rate limiting in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// NewClient creates a new Gerrit API client for the instance at baseURL that authenticates
+// with the given HTTP credentials, or anonymously if username is empty. The path of baseURL
+// must end with a slash (see extsvc.NormalizeBaseURL). If a nil httpClient is provided,
+// http.DefaultClient will be used.
+func NewClient(baseURL *url.URL, username, password string, httpClient httpcli.Doer) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpClient = requestCounter.Doer(httpClient, func(u *url.URL) string {
+		// The first component of the Path after the base URL and the /a/ prefix of
+		// authenticated requests mostly maps to the type of API request we are making.
+		path := strings.TrimPrefix(strings.TrimPrefix(u.Path, baseURL.Path), "a/")
+		if i := strings.Index(path, "/"); i >= 0 {
+			path = path[:i]
+		}
+		return path
+	})
+
+	return &Client{
+		httpClient: httpClient,
+		URL:        baseURL,
+		Username:   username,
+		Password:   password,
+		RateLimit:  rate.NewLimiter(rateLimitRequestsPerSecond, RateLimitMaxBurstRequests),
+	}
+}
+
+// Project returns the project with the given name.
+func (c *Client) Project(ctx context.Context, name string) (*Project, error) {
+	var p Project
+	err := c.get(ctx, "projects/"+url.PathEscape(name), nil, &p)
+	return &p, err
+}
+
+// QueryProjects returns the projects matching the given Gerrit project query, starting at
+// the given offset, and whether there are more projects after them.
+//
+// See https://gerrit-review.googlesource.com/Documentation/rest-api-projects.html#query-projects.
+func (c *Client) QueryProjects(ctx context.Context, query string, start int) ([]*Project, bool, error) {
+	qry := url.Values{
+		"query": {query},
+		"n":     {strconv.Itoa(PerPage)},
+		"S":     {strconv.Itoa(start)},
+	}
+	var projects []*Project
+	if err := c.get(ctx, "projects/", qry, &projects); err != nil {
+		return nil, false, err
+	}
+	more := len(projects) > 0 && projects[len(projects)-1].MoreProjects
+	return projects, more, nil
+}
+
+// QueryChanges returns the changes matching the given Gerrit change query with their
+// current revision, starting at the given offset, and whether there are more changes
+// after them.
+//
+// See https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#list-changes.
+func (c *Client) QueryChanges(ctx context.Context, query string, start int) ([]*Change, bool, error) {
+	qry := url.Values{
+		"q": {query},
+		"o": {"CURRENT_REVISION"},
+		"n": {strconv.Itoa(PerPage)},
+		"S": {strconv.Itoa(start)},
+	}
+	var changes []*Change
+	if err := c.get(ctx, "changes/", qry, &changes); err != nil {
+		return nil, false, err
+	}
+	more := len(changes) > 0 && changes[len(changes)-1].MoreChanges
+	return changes, more, nil
+}
+
+// get requests the given path, which must be escaped, relative to the base URL.
+func (c *Client) get(ctx context.Context, path string, qry url.Values, result interface{}) error {
+	// Authenticated requests are made to the /a/ endpoints.
+	if c.Username != "" {
+		path = "a/" + path
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = qry.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, req, result)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Accept", "application/json")
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(),
+		req.WithContext(ctx),
+		nethttp.OperationName("Gerrit"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	startWait := time.Now()
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return err
+	}
+
+	if d := time.Since(startWait); d > 200*time.Millisecond {
+		log15.Warn("Gerrit self-enforced API rate limit: request delayed longer than expected due to rate limit", "delay", d)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.WithStack(&httpError{
+			URL:        req.URL,
+			StatusCode: resp.StatusCode,
+			Body:       bs,
+		})
+	}
+
+	if result != nil {
+		return json.Unmarshal(bytes.TrimPrefix(bs, xssiPrefix), result)
+	}
+
+	return nil
+}
+
+// Project is a project of a Gerrit instance.
+//
+// See https://gerrit-review.googlesource.com/Documentation/rest-api-projects.html#project-info.
+type Project struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Parent      string     `json:"parent,omitempty"`
+	Description string     `json:"description,omitempty"`
+	State       string     `json:"state,omitempty"` // ACTIVE, READ_ONLY or HIDDEN
+	WebLinks    []*WebLink `json:"web_links,omitempty"`
+
+	// MoreProjects is set on the last project of a page of results if there are more.
+	MoreProjects bool `json:"_more_projects,omitempty"`
+}
+
+// Change is a change of a Gerrit instance.
+//
+// See https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#change-info.
+type Change struct {
+	ID              string               `json:"id"`
+	Project         string               `json:"project"`
+	Branch          string               `json:"branch"`
+	ChangeID        string               `json:"change_id"`
+	Number          int64                `json:"_number"`
+	Subject         string               `json:"subject"`
+	Status          string               `json:"status"` // NEW, MERGED or ABANDONED
+	CurrentRevision string               `json:"current_revision"`
+	Revisions       map[string]*Revision `json:"revisions"`
+
+	// MoreChanges is set on the last change of a page of results if there are more.
+	MoreChanges bool `json:"_more_changes,omitempty"`
+}
+
+// CurrentRef returns the ref of the current patch set of the change, such as
+// refs/changes/45/12345/3, or "" if the current revision was not requested.
+func (c *Change) CurrentRef() string {
+	if r := c.Revisions[c.CurrentRevision]; r != nil {
+		return r.Ref
+	}
+	return ""
+}
+
+// Revision is a patch set of a change.
+type Revision struct {
+	Number int64  `json:"_number"`
+	Ref    string `json:"ref"`
+}
+
+// WebLink is a link to an external site, such as a repository browser.
+type WebLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+	Body       []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("Gerrit API HTTP error: code=%d url=%q body=%q", e.StatusCode, e.URL, e.Body)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is a Gerrit API error of type NOT_FOUND.
+func IsNotFound(err error) bool {
+	e, ok := errors.Cause(err).(*httpError)
+	return ok && e.NotFound()
+}
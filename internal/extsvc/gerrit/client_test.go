@@ -0,0 +1,86 @@
+package gerrit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("S"))
+		switch r.URL.EscapedPath() {
+		case "/gerrit/a/projects/platform%2Fbuild":
+			fmt.Fprint(w, ")]}'\n"+`{"id":"platform%2Fbuild","name":"platform/build","state":"ACTIVE"}`)
+		case "/gerrit/a/projects/":
+			if q := r.URL.Query().Get("query"); q != "state:active" {
+				t.Errorf("got project query %q, want state:active", q)
+			}
+			if start == 0 {
+				fmt.Fprint(w, ")]}'\n"+`[{"id":"a","name":"a"},{"id":"b","name":"b","_more_projects":true}]`)
+			} else {
+				fmt.Fprint(w, ")]}'\n"+`[{"id":"c","name":"c"}]`)
+			}
+		case "/gerrit/a/changes/":
+			fmt.Fprint(w, ")]}'\n"+`[{"project":"a","_number":12345,"current_revision":"abc","revisions":{"abc":{"_number":3,"ref":"refs/changes/45/12345/3"}}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/gerrit/")
+	c := NewClient(u, "admin", "secret", nil)
+	ctx := context.Background()
+
+	p, err := c.Project(ctx, "platform/build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "platform/build" || p.State != "ACTIVE" {
+		t.Errorf("got project %+v", p)
+	}
+
+	var names []string
+	for start, more := 0, true; more; start += PerPage {
+		var projects []*Project
+		projects, more, err = c.QueryProjects(ctx, "state:active", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		if start > PerPage {
+			t.Fatal("too many pages")
+		}
+	}
+	if fmt.Sprint(names) != "[a b c]" {
+		t.Errorf("got projects %v, want [a b c]", names)
+	}
+
+	changes, more, err := c.QueryChanges(ctx, "status:open", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || more || changes[0].CurrentRef() != "refs/changes/45/12345/3" {
+		t.Errorf("got changes %+v and more %t", changes, more)
+	}
+
+	if _, err := c.Project(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, want not found", err)
+	}
+
+	anon := NewClient(u, "", "", nil)
+	if _, err := anon.Project(ctx, "platform/build"); err == nil || IsNotFound(err) {
+		t.Errorf("got error %v for an anonymous request, want unauthorized", err)
+	}
+}
@@ -0,0 +1,288 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"golang.org/x/time/rate"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var requestCounter = metrics.NewRequestMeter("gitea_requests_count", "Total number of requests sent to the Gitea API.")
+
+// These fields define the self-imposed Gitea rate limit (since neither Gitea nor Gogs
+// have a concept of rate limiting in HTTP response headers). Gitea and Gogs are usually
+// self-hosted on small machines, so we are conservative.
+//
+// See https://godoc.org/golang.org/x/time/rate#Limiter for an explanation of these fields.
+const (
+	rateLimitRequestsPerSecond = 5 // 300/min or 18000/hr
+	RateLimitMaxBurstRequests  = 50
+)
+
+// PerPage is the number of results requested per page. It is the maximum number of
+// results per page of Gitea by default.
+const PerPage = 50
+
+// Client accesses a Gitea or Gogs instance via the REST API v1.
+type Client struct {
+	// HTTP Client used to communicate with the API
+	httpClient httpcli.Doer
+
+	// URL is the base URL of the Gitea or Gogs instance.
+	URL *url.URL
+
+	// Token is the access token used to authenticate requests.
+	//
+	// 🚨 SECURITY: This value contains secret information that must not be shown to non-site-admins.
+	Token string
+
+	// Sudo is the username of the user that requests are made on behalf of. It is only
+	// supported by Gitea, and requires Token to belong to a site admin.
+	Sudo string
+
+	// RateLimit is the self-imposed rate limiter (since Gitea does not have a concept
+	// of rate limiting in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// NewClient creates a new Gitea API client for the instance at baseURL that authenticates
+// with token. The path of baseURL must end with a slash (see extsvc.NormalizeBaseURL). If a
+// nil httpClient is provided, http.DefaultClient will be used.
+func NewClient(baseURL *url.URL, token string, httpClient httpcli.Doer) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpClient = requestCounter.Doer(httpClient, func(u *url.URL) string {
+		// The third component of the Path (/api/v1/{category}/...) mostly maps to the
+		// type of API request we are making.
+		var category string
+		if parts := strings.SplitN(u.Path, "/", 5); len(parts) > 3 {
+			category = parts[3]
+		}
+		return category
+	})
+
+	return &Client{
+		httpClient: httpClient,
+		URL:        baseURL,
+		Token:      token,
+		RateLimit:  rate.NewLimiter(rateLimitRequestsPerSecond, RateLimitMaxBurstRequests),
+	}
+}
+
+// WithSudo returns a copy of the client that makes requests on behalf of the user with
+// the given username. The copy shares the rate limiter of c.
+func (c *Client) WithSudo(username string) *Client {
+	c2 := *c
+	c2.Sudo = username
+	return &c2
+}
+
+// CurrentUser returns the user that the client authenticates as.
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var u User
+	_, err := c.get(ctx, "user", nil, &u)
+	return &u, err
+}
+
+// User returns the user with the given username.
+func (c *Client) User(ctx context.Context, username string) (*User, error) {
+	var u User
+	_, err := c.get(ctx, "users/"+url.PathEscape(username), nil, &u)
+	return &u, err
+}
+
+// Repo returns the repository with the given owner and name.
+func (c *Client) Repo(ctx context.Context, owner, name string) (*Repo, error) {
+	var r Repo
+	_, err := c.get(ctx, "repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), nil, &r)
+	return &r, err
+}
+
+// OrgRepos returns the given page (starting at 1) of the repositories of an organization
+// that are accessible to the client, and the next page, or 0 if it is the last page.
+func (c *Client) OrgRepos(ctx context.Context, org string, page int) ([]*Repo, int, error) {
+	var repos []*Repo
+	next, err := c.page(ctx, "orgs/"+url.PathEscape(org)+"/repos", nil, page, &repos)
+	return repos, next, err
+}
+
+// UserRepos returns the given page (starting at 1) of the repositories of a user that are
+// accessible to the client, and the next page, or 0 if it is the last page.
+func (c *Client) UserRepos(ctx context.Context, username string, page int) ([]*Repo, int, error) {
+	var repos []*Repo
+	next, err := c.page(ctx, "users/"+url.PathEscape(username)+"/repos", nil, page, &repos)
+	return repos, next, err
+}
+
+// SearchRepos returns the given page (starting at 1) of all repositories that are
+// accessible to the user that the client authenticates as, or to the Sudo user, and
+// the next page, or 0 if it is the last page. If publicOnly is true, only public
+// repositories are returned.
+func (c *Client) SearchRepos(ctx context.Context, publicOnly bool, page int) ([]*Repo, int, error) {
+	qry := url.Values{}
+	if publicOnly {
+		qry.Set("private", "false")
+	}
+	var result struct {
+		OK   bool    `json:"ok"`
+		Data []*Repo `json:"data"`
+	}
+	next, err := c.page(ctx, "repos/search", qry, page, &result)
+	return result.Data, next, err
+}
+
+// page requests the given page of a paginated list and returns the next page, or 0 if
+// there are no more pages. Gitea sets the Link or X-Total-Count response headers on
+// paginated lists. Gogs does not paginate, and returns all results on the first page.
+func (c *Client) page(ctx context.Context, path string, qry url.Values, page int, results interface{}) (int, error) {
+	if qry == nil {
+		qry = make(url.Values)
+	}
+	if page < 1 {
+		page = 1
+	}
+	qry.Set("page", strconv.Itoa(page))
+	qry.Set("limit", strconv.Itoa(PerPage))
+
+	header, err := c.get(ctx, path, qry, results)
+	if err != nil {
+		return 0, err
+	}
+
+	if link := header.Get("Link"); link != "" {
+		if strings.Contains(link, `rel="next"`) {
+			return page + 1, nil
+		}
+		return 0, nil
+	}
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil && page*PerPage < total {
+		return page + 1, nil
+	}
+	return 0, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, qry url.Values, result interface{}) (http.Header, error) {
+	u := url.URL{Path: "api/v1/" + path, RawQuery: qry.Encode()}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, req, result)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) (http.Header, error) {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+c.Token)
+	if c.Sudo != "" {
+		req.Header.Set("Sudo", c.Sudo)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(),
+		req.WithContext(ctx),
+		nethttp.OperationName("Gitea"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	startWait := time.Now()
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	if d := time.Since(startWait); d > 200*time.Millisecond {
+		log15.Warn("Gitea self-enforced API rate limit: request delayed longer than expected due to rate limit", "delay", d)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, errors.WithStack(&httpError{
+			URL:        req.URL,
+			StatusCode: resp.StatusCode,
+			Body:       bs,
+		})
+	}
+
+	if result != nil {
+		return resp.Header, json.Unmarshal(bs, result)
+	}
+
+	return resp.Header, nil
+}
+
+// User is a user of a Gitea or Gogs instance.
+type User struct {
+	ID       int64  `json:"id"`
+	Login    string `json:"login"` // Gogs also sets "username"
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	IsAdmin  bool   `json:"is_admin"` // only set by Gitea
+}
+
+// Repo is a repository of a Gitea or Gogs instance.
+type Repo struct {
+	ID            int64  `json:"id"`
+	Owner         User   `json:"owner"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	Empty         bool   `json:"empty"`
+	Private       bool   `json:"private"`
+	Fork          bool   `json:"fork"`
+	Mirror        bool   `json:"mirror"`
+	Archived      bool   `json:"archived"` // only set by Gitea
+	Parent        *Repo  `json:"parent"`
+	HTMLURL       string `json:"html_url"`
+	SSHURL        string `json:"ssh_url"`
+	CloneURL      string `json:"clone_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+	Body       []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("Gitea API HTTP error: code=%d url=%q body=%q", e.StatusCode, e.URL, e.Body)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is a Gitea API error of type NOT_FOUND.
+func IsNotFound(err error) bool {
+	e, ok := errors.Cause(err).(*httpError)
+	return ok && e.NotFound()
+}
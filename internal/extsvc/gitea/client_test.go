@@ -0,0 +1,86 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestClient_page(t *testing.T) {
+	var gotAuth, gotSudo string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotSudo = r.Header.Get("Authorization"), r.Header.Get("Sudo")
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		switch r.URL.Path {
+		case "/gitea/api/v1/orgs/link/repos":
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s?page=1>; rel="first"`, r.URL.Path))
+			}
+		case "/gitea/api/v1/orgs/total/repos":
+			w.Header().Set("X-Total-Count", strconv.Itoa(2*PerPage+1))
+		case "/gitea/api/v1/repos/search":
+			fmt.Fprintf(w, `{"ok":true,"data":[{"id":%d,"full_name":"o/r"}]}`, page)
+			return
+		case "/gitea/api/v1/orgs/gogs/repos":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `[{"id":%d,"full_name":"o/r"}]`, page)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/gitea/")
+	c := NewClient(u, "t0ken", nil)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		org  string
+		page int
+		next int
+	}{
+		{"link", 1, 2},
+		{"link", 3, 0},
+		{"total", 2, 3},
+		{"total", 3, 0},
+		{"gogs", 1, 0},
+	} {
+		repos, next, err := c.OrgRepos(ctx, tc.org, tc.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != tc.next {
+			t.Errorf("%s page %d: got next page %d, want %d", tc.org, tc.page, next, tc.next)
+		}
+		if len(repos) != 1 || repos[0].ID != int64(tc.page) {
+			t.Errorf("%s page %d: got repos %+v", tc.org, tc.page, repos)
+		}
+	}
+	if gotAuth != "token t0ken" || gotSudo != "" {
+		t.Errorf("got Authorization %q and Sudo %q, want the token and no Sudo", gotAuth, gotSudo)
+	}
+
+	repos, _, err := c.WithSudo("alice").SearchRepos(ctx, false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].ID != 2 {
+		t.Errorf("got repos %+v", repos)
+	}
+	if gotSudo != "alice" {
+		t.Errorf("got Sudo %q, want alice", gotSudo)
+	}
+	if c.Sudo != "" {
+		t.Error("WithSudo modified the client")
+	}
+
+	if _, err := c.User(ctx, "nobody"); !IsNotFound(err) {
+		t.Errorf("got error %v, want not found", err)
+	}
+}
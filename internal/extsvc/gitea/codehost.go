@@ -0,0 +1,5 @@
+package gitea
+
+// ServiceType is the (api.ExternalRepoSpec).ServiceType value for Gitea and Gogs repositories. The
+// ServiceID value is the base URL to the Gitea or Gogs instance.
+const ServiceType = "gitea"
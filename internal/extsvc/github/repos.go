@@ -35,6 +35,7 @@ type Repository struct {
 	IsFork           bool   // whether the repository is a fork of another repository
 	IsArchived       bool   // whether the repository is archived on the code host
 	ViewerPermission string // ADMIN, WRITE, READ, or empty if unknown. Only the graphql api populates this. https://developer.github.com/v4/enum/repositorypermission/
+	StargazerCount   int    // number of stars of the repository. The graphql api only populates this on github.com.
 }
 
 // repositoryFieldsGraphQLFragment returns a GraphQL fragment that contains the fields needed to populate the
@@ -52,12 +53,14 @@ fragment RepositoryFields on Repository {
 	isFork
 	isArchived
 	viewerPermission
+	stargazerCount
 }
 	`
 	}
 	// Some fields are not yet available on GitHub Enterprise yet
 	// or are available but too new to expect our customers to have updated:
 	// - viewerPermission
+	// - stargazerCount
 	return `
 fragment RepositoryFields on Repository {
 	id
@@ -251,6 +254,7 @@ type restRepository struct {
 	Private     bool
 	Fork        bool
 	Archived    bool
+	Stargazers  int                       `json:"stargazers_count"`
 	Permissions restRepositoryPermissions `json:"permissions"`
 }
 
@@ -283,6 +287,7 @@ func convertRestRepo(restRepo restRepository) *Repository {
 		IsFork:           restRepo.Fork,
 		IsArchived:       restRepo.Archived,
 		ViewerPermission: convertRestRepoPermissions(restRepo.Permissions),
+		StargazerCount:   restRepo.Stargazers,
 	}
 }
 
@@ -0,0 +1,167 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	eventTypeHeader = "X-Gitlab-Event"
+	tokenHeader     = "X-Gitlab-Token"
+)
+
+// WebHookType returns the type of the GitLab webhook event of r, such as
+// "Push Hook".
+func WebHookType(r *http.Request) string {
+	return r.Header.Get(eventTypeHeader)
+}
+
+// WebHookToken returns the secret token of the GitLab webhook that sent r.
+func WebHookToken(r *http.Request) string {
+	return r.Header.Get(tokenHeader)
+}
+
+// ParseWebHook parses the payload of a GitLab webhook event of the given
+// type. It returns nil for the events that are not supported, which are all
+// but push, merge request, note and pipeline events.
+func ParseWebHook(event string, payload []byte) (interface{}, error) {
+	switch event {
+	case "Push Hook", "Tag Push Hook":
+		e := &PushEvent{}
+		return e, json.Unmarshal(payload, e)
+	case "Merge Request Hook":
+		e := &MergeRequestEvent{}
+		return e, json.Unmarshal(payload, e)
+	case "Note Hook":
+		e := &NoteEvent{}
+		return e, json.Unmarshal(payload, e)
+	case "Pipeline Hook":
+		e := &PipelineEvent{}
+		return e, json.Unmarshal(payload, e)
+	default:
+		return nil, nil
+	}
+}
+
+// PushEvent is sent when branches or tags of a project are pushed to.
+type PushEvent struct {
+	ObjectKind string        `json:"object_kind"` // "push" or "tag_push"
+	Before     string        `json:"before"`      // the commit the ref pointed to before the push
+	After      string        `json:"after"`       // the commit the ref points to after the push
+	Ref        string        `json:"ref"`         // the ref that was pushed to ("refs/heads/master")
+	ProjectID  int           `json:"project_id"`
+	Project    ProjectCommon `json:"project"`
+}
+
+// MergeRequestEvent is sent when a merge request is opened, updated, approved,
+// unapproved, closed, reopened or merged.
+type MergeRequestEvent struct {
+	ObjectKind       string                 `json:"object_kind"` // "merge_request"
+	User             Author                 `json:"user"`
+	Project          ProjectCommon          `json:"project"`
+	ObjectAttributes MergeRequestAttributes `json:"object_attributes"`
+}
+
+// MergeRequestAttributes identify the merge request of a webhook event.
+type MergeRequestAttributes struct {
+	ID              int    `json:"id"`
+	IID             int    `json:"iid"`
+	TargetProjectID int    `json:"target_project_id"`
+	Action          string `json:"action,omitempty"` // only set in merge request events ("open", "merge", ...)
+}
+
+// NoteEvent is sent when a comment is added to a merge request, an issue, a
+// commit or a snippet. No events are sent for system notes.
+type NoteEvent struct {
+	ObjectKind       string                  `json:"object_kind"` // "note"
+	User             Author                  `json:"user"`
+	ProjectID        int                     `json:"project_id"`
+	ObjectAttributes NoteAttributes          `json:"object_attributes"`
+	MergeRequest     *MergeRequestAttributes `json:"merge_request"` // only set for comments on merge requests
+}
+
+// NoteAttributes are the attributes of the note of a NoteEvent.
+type NoteAttributes struct {
+	ID           int         `json:"id"`
+	Note         string      `json:"note"`
+	NoteableType string      `json:"noteable_type"` // "MergeRequest", "Issue", "Commit" or "Snippet"
+	System       bool        `json:"system"`
+	CreatedAt    WebhookTime `json:"created_at"`
+	UpdatedAt    WebhookTime `json:"updated_at"`
+}
+
+// Note returns the note of the event as returned by the API.
+func (e *NoteEvent) Note() *Note {
+	return &Note{
+		ID:        e.ObjectAttributes.ID,
+		Body:      e.ObjectAttributes.Note,
+		Author:    e.User,
+		System:    e.ObjectAttributes.System,
+		CreatedAt: e.ObjectAttributes.CreatedAt.Time,
+		UpdatedAt: e.ObjectAttributes.UpdatedAt.Time,
+	}
+}
+
+// PipelineEvent is sent when the status of a pipeline changes.
+type PipelineEvent struct {
+	ObjectKind       string                  `json:"object_kind"` // "pipeline"
+	Project          ProjectCommon           `json:"project"`
+	ObjectAttributes PipelineAttributes      `json:"object_attributes"`
+	MergeRequest     *MergeRequestAttributes `json:"merge_request"` // only set for merge request pipelines
+}
+
+// PipelineAttributes are the attributes of the pipeline of a PipelineEvent.
+type PipelineAttributes struct {
+	ID        int            `json:"id"`
+	Ref       string         `json:"ref"`
+	SHA       string         `json:"sha"`
+	Status    PipelineStatus `json:"status"`
+	CreatedAt WebhookTime    `json:"created_at"`
+}
+
+// Pipeline returns the pipeline of the event as returned by the API. Since the
+// event doesn't include when the pipeline was last updated, UpdatedAt is set
+// to the given time the event was received at.
+func (e *PipelineEvent) Pipeline(receivedAt time.Time) *Pipeline {
+	return &Pipeline{
+		ID:        e.ObjectAttributes.ID,
+		SHA:       e.ObjectAttributes.SHA,
+		Ref:       e.ObjectAttributes.Ref,
+		Status:    e.ObjectAttributes.Status,
+		WebURL:    e.Project.WebURL + "/pipelines/" + strconv.Itoa(e.ObjectAttributes.ID),
+		CreatedAt: e.ObjectAttributes.CreatedAt.Time,
+		UpdatedAt: receivedAt,
+	}
+}
+
+// WebhookTime is a timestamp in the payload of a webhook event. Unlike the
+// API, webhooks don't always format timestamps as RFC3339, but as
+// "2006-01-02 15:04:05 UTC".
+type WebhookTime struct {
+	time.Time
+}
+
+var webhookTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05 MST"}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *WebhookTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		return nil
+	}
+
+	for _, layout := range webhookTimeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return errors.Errorf("invalid webhook timestamp %q", s)
+}
@@ -0,0 +1,78 @@
+package gitlab
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWebHook(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		event   string
+		payload string
+		want    interface{}
+	}{
+		{
+			name:  "note on merge request",
+			event: "Note Hook",
+			payload: `{
+				"object_kind": "note",
+				"user": {"name": "Jane Doe", "username": "jane"},
+				"project_id": 5,
+				"object_attributes": {
+					"id": 1244,
+					"note": "Looks good",
+					"noteable_type": "MergeRequest",
+					"system": false,
+					"created_at": "2015-05-17 18:21:36 UTC",
+					"updated_at": "2015-05-17T18:22:36Z"
+				},
+				"merge_request": {"id": 7, "iid": 1, "target_project_id": 5}
+			}`,
+			want: &NoteEvent{
+				ObjectKind: "note",
+				User:       Author{Name: "Jane Doe", Username: "jane"},
+				ProjectID:  5,
+				ObjectAttributes: NoteAttributes{
+					ID:           1244,
+					Note:         "Looks good",
+					NoteableType: "MergeRequest",
+					CreatedAt:    WebhookTime{time.Date(2015, 5, 17, 18, 21, 36, 0, time.UTC)},
+					UpdatedAt:    WebhookTime{time.Date(2015, 5, 17, 18, 22, 36, 0, time.UTC)},
+				},
+				MergeRequest: &MergeRequestAttributes{ID: 7, IID: 1, TargetProjectID: 5},
+			},
+		},
+		{
+			name:  "merge request",
+			event: "Merge Request Hook",
+			payload: `{
+				"object_kind": "merge_request",
+				"project": {"id": 5},
+				"object_attributes": {"id": 7, "iid": 1, "target_project_id": 5, "action": "approved"}
+			}`,
+			want: &MergeRequestEvent{
+				ObjectKind:       "merge_request",
+				Project:          ProjectCommon{ID: 5},
+				ObjectAttributes: MergeRequestAttributes{ID: 7, IID: 1, TargetProjectID: 5, Action: "approved"},
+			},
+		},
+		{
+			name:    "unsupported",
+			event:   "Issue Hook",
+			payload: `{"object_kind": "issue"}`,
+			want:    nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := ParseWebHook(tc.event, []byte(tc.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, tc.want) {
+				t.Errorf("got event %+v, want %+v", have, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/peterhellberg/link"
+	"github.com/pkg/errors"
+)
+
+type MergeRequestState string
+
+const (
+	MergeRequestStateOpened MergeRequestState = "opened"
+	MergeRequestStateClosed MergeRequestState = "closed"
+	MergeRequestStateMerged MergeRequestState = "merged"
+	MergeRequestStateLocked MergeRequestState = "locked"
+)
+
+// MergeRequest is a GitLab merge request (equivalent to a GitHub pull request).
+type MergeRequest struct {
+	ID           int               `json:"id"`
+	IID          int               `json:"iid"`        // ID of the merge request in its project
+	ProjectID    int               `json:"project_id"` // ID of the project the merge request targets
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	State        MergeRequestState `json:"state"`
+	WebURL       string            `json:"web_url"`
+	SourceBranch string            `json:"source_branch"`
+	TargetBranch string            `json:"target_branch"`
+	SHA          string            `json:"sha"` // the commit the source branch points to
+	DiffRefs     DiffRefs          `json:"diff_refs"`
+	Labels       []string          `json:"labels"`
+	Author       Author            `json:"author"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+
+	// The following fields aren't part of the merge request returned by the API,
+	// they are loaded with separate requests by LoadMergeRequest.
+	Notes      []*Note     `json:"notes,omitempty"`
+	Pipelines  []*Pipeline `json:"pipelines,omitempty"`
+	ApprovedBy []Author    `json:"approved_by,omitempty"`
+}
+
+// DiffRefs are the commits the diff of a merge request is computed from.
+type DiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+// Author is the user who authored a merge request or a note, as embedded in
+// them by the API.
+type Author struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	WebURL    string `json:"web_url"`
+}
+
+// Note is a comment on a merge request. System notes are created by GitLab
+// for changes to the merge request, such as when it's closed or approved.
+type Note struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	Author    Author    `json:"author"`
+	System    bool      `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Key is a unique key identifying this note in the context of its merge
+// request.
+func (n *Note) Key() string {
+	return strconv.Itoa(n.ID)
+}
+
+type PipelineStatus string
+
+const (
+	PipelineStatusCreated            PipelineStatus = "created"
+	PipelineStatusWaitingForResource PipelineStatus = "waiting_for_resource"
+	PipelineStatusPreparing          PipelineStatus = "preparing"
+	PipelineStatusPending            PipelineStatus = "pending"
+	PipelineStatusRunning            PipelineStatus = "running"
+	PipelineStatusSuccess            PipelineStatus = "success"
+	PipelineStatusFailed             PipelineStatus = "failed"
+	PipelineStatusCanceled           PipelineStatus = "canceled"
+	PipelineStatusSkipped            PipelineStatus = "skipped"
+	PipelineStatusManual             PipelineStatus = "manual"
+	PipelineStatusScheduled          PipelineStatus = "scheduled"
+)
+
+// Pipeline is a CI pipeline run for the commits of a merge request.
+type Pipeline struct {
+	ID        int            `json:"id"`
+	SHA       string         `json:"sha"`
+	Ref       string         `json:"ref"`
+	Status    PipelineStatus `json:"status"`
+	WebURL    string         `json:"web_url"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Key is a unique key identifying this pipeline in the context of its merge
+// request.
+func (p *Pipeline) Key() string {
+	return strconv.Itoa(p.ID)
+}
+
+// ErrMergeRequestAlreadyExists is returned by CreateMergeRequest when an open
+// merge request for the same source branch already exists.
+var ErrMergeRequestAlreadyExists = errors.New("merge request already exists")
+
+// CreateMergeRequestInput is the input of CreateMergeRequest.
+type CreateMergeRequestInput struct {
+	ProjectID    int    `json:"-"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+}
+
+// CreateMergeRequest creates a merge request in the given project.
+func (c *Client) CreateMergeRequest(ctx context.Context, in *CreateMergeRequestInput) (*MergeRequest, error) {
+	if MockCreateMergeRequest != nil {
+		return MockCreateMergeRequest(c, ctx, in)
+	}
+
+	req, err := newJSONRequest("POST", fmt.Sprintf("projects/%d/merge_requests", in.ProjectID), in)
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if _, err := c.do(ctx, req, &mr); err != nil {
+		if HTTPErrorCode(err) == http.StatusConflict {
+			return nil, ErrMergeRequestAlreadyExists
+		}
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// GetOpenMergeRequestByRefs returns the open merge request of the given project
+// from the source to the target branch.
+func (c *Client) GetOpenMergeRequestByRefs(ctx context.Context, projectID int, source, target string) (*MergeRequest, error) {
+	if MockGetOpenMergeRequestByRefs != nil {
+		return MockGetOpenMergeRequestByRefs(c, ctx, projectID, source, target)
+	}
+
+	q := make(url.Values)
+	q.Set("state", string(MergeRequestStateOpened))
+	q.Set("source_branch", source)
+	q.Set("target_branch", target)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("projects/%d/merge_requests?%s", projectID, q.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mrs []*MergeRequest
+	if _, err := c.do(ctx, req, &mrs); err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, errors.Errorf("no open merge request from %q to %q found", source, target)
+	}
+	return mrs[0], nil
+}
+
+// GetMergeRequest gets the merge request with the given IID from the given
+// project.
+func (c *Client) GetMergeRequest(ctx context.Context, projectID, iid int) (*MergeRequest, error) {
+	if MockGetMergeRequest != nil {
+		return MockGetMergeRequest(c, ctx, projectID, iid)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("projects/%d/merge_requests/%d", projectID, iid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if _, err := c.do(ctx, req, &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// LoadMergeRequest loads the merge request with the given IID from the given
+// project, together with its notes, pipelines and approvals.
+func (c *Client) LoadMergeRequest(ctx context.Context, projectID, iid int) (*MergeRequest, error) {
+	mr, err := c.GetMergeRequest(ctx, projectID, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	if mr.Notes, err = c.ListMergeRequestNotes(ctx, projectID, iid); err != nil {
+		return nil, errors.Wrap(err, "loading merge request notes")
+	}
+
+	if mr.Pipelines, err = c.ListMergeRequestPipelines(ctx, projectID, iid); err != nil {
+		return nil, errors.Wrap(err, "loading merge request pipelines")
+	}
+
+	if mr.ApprovedBy, err = c.ListMergeRequestApprovers(ctx, projectID, iid); err != nil {
+		return nil, errors.Wrap(err, "loading merge request approvals")
+	}
+
+	return mr, nil
+}
+
+// UpdateMergeRequestInput is the input of UpdateMergeRequest. Empty fields are
+// left unchanged.
+type UpdateMergeRequestInput struct {
+	ProjectID    int    `json:"-"`
+	IID          int    `json:"-"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	// StateEvent is either "close" or "reopen".
+	StateEvent string `json:"state_event,omitempty"`
+}
+
+// UpdateMergeRequest updates the given merge request and returns it.
+func (c *Client) UpdateMergeRequest(ctx context.Context, in *UpdateMergeRequestInput) (*MergeRequest, error) {
+	if MockUpdateMergeRequest != nil {
+		return MockUpdateMergeRequest(c, ctx, in)
+	}
+
+	req, err := newJSONRequest("PUT", fmt.Sprintf("projects/%d/merge_requests/%d", in.ProjectID, in.IID), in)
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if _, err := c.do(ctx, req, &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// ListMergeRequestNotes lists all notes of the given merge request, including
+// system notes.
+func (c *Client) ListMergeRequestNotes(ctx context.Context, projectID, iid int) (notes []*Note, err error) {
+	if MockListMergeRequestNotes != nil {
+		return MockListMergeRequestNotes(c, ctx, projectID, iid)
+	}
+
+	urlStr := fmt.Sprintf("projects/%d/merge_requests/%d/notes?sort=asc&order_by=created_at&per_page=100", projectID, iid)
+	for urlStr != "" {
+		var page []*Note
+		if urlStr, err = c.getPage(ctx, urlStr, &page); err != nil {
+			return nil, err
+		}
+		notes = append(notes, page...)
+	}
+	return notes, nil
+}
+
+// ListMergeRequestPipelines lists all pipelines of the given merge request,
+// the most recent first.
+func (c *Client) ListMergeRequestPipelines(ctx context.Context, projectID, iid int) (pipelines []*Pipeline, err error) {
+	if MockListMergeRequestPipelines != nil {
+		return MockListMergeRequestPipelines(c, ctx, projectID, iid)
+	}
+
+	urlStr := fmt.Sprintf("projects/%d/merge_requests/%d/pipelines?per_page=100", projectID, iid)
+	for urlStr != "" {
+		var page []*Pipeline
+		if urlStr, err = c.getPage(ctx, urlStr, &page); err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, page...)
+	}
+	return pipelines, nil
+}
+
+// ListMergeRequestApprovers lists the users who approved the given merge
+// request. Since approvals aren't available in all GitLab editions and
+// versions, no approvers are returned if the API doesn't exist.
+func (c *Client) ListMergeRequestApprovers(ctx context.Context, projectID, iid int) ([]Author, error) {
+	if MockListMergeRequestApprovers != nil {
+		return MockListMergeRequestApprovers(c, ctx, projectID, iid)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("projects/%d/merge_requests/%d/approvals", projectID, iid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals struct {
+		ApprovedBy []struct {
+			User Author `json:"user"`
+		} `json:"approved_by"`
+	}
+	if _, err := c.do(ctx, req, &approvals); err != nil {
+		if IsNotFound(err) || HTTPErrorCode(err) == http.StatusForbidden {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	approvers := make([]Author, 0, len(approvals.ApprovedBy))
+	for _, a := range approvals.ApprovedBy {
+		approvers = append(approvers, a.User)
+	}
+	return approvers, nil
+}
+
+// getPage gets the page of results at urlStr into result and returns the URL
+// of the next page, which is empty on the last page.
+func (c *Client) getPage(ctx context.Context, urlStr string, result interface{}) (nextPageURL string, err error) {
+	req, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return "", err
+	}
+	respHeader, err := c.do(ctx, req, result)
+	if err != nil {
+		return "", err
+	}
+
+	// Get URL to next page. See https://docs.gitlab.com/ee/api/README.html#pagination-link-header.
+	if l := link.Parse(respHeader.Get("Link"))["next"]; l != nil {
+		nextPageURL = l.URI
+	}
+	return nextPageURL, nil
+}
+
+func newJSONRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequest(method, urlStr, bytes.NewReader(payload))
+}
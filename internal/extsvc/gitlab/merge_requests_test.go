@@ -0,0 +1,99 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_CreateMergeRequest(t *testing.T) {
+	in := &CreateMergeRequestInput{
+		ProjectID:    1,
+		SourceBranch: "campaigns/fix",
+		TargetBranch: "master",
+		Title:        "Fix",
+	}
+
+	t.Run("created", func(t *testing.T) {
+		c := newTestClient(t)
+		c.httpClient = &mockHTTPResponseBody{
+			responseBody: `
+{
+	"id": 11,
+	"iid": 2,
+	"project_id": 1,
+	"title": "Fix",
+	"state": "opened",
+	"web_url": "https://gitlab.example.com/n1/n2/r/-/merge_requests/2",
+	"source_branch": "campaigns/fix",
+	"target_branch": "master",
+	"created_at": "2020-03-04T10:11:12.000Z"
+}
+`,
+		}
+
+		mr, err := c.CreateMergeRequest(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := &MergeRequest{
+			ID:           11,
+			IID:          2,
+			ProjectID:    1,
+			Title:        "Fix",
+			State:        MergeRequestStateOpened,
+			WebURL:       "https://gitlab.example.com/n1/n2/r/-/merge_requests/2",
+			SourceBranch: "campaigns/fix",
+			TargetBranch: "master",
+			CreatedAt:    time.Date(2020, 3, 4, 10, 11, 12, 0, time.UTC),
+		}
+		if !reflect.DeepEqual(mr, want) {
+			t.Errorf("got merge request %+v, want %+v", mr, want)
+		}
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		c := newTestClient(t)
+		c.httpClient = mockHTTPEmptyResponse{statusCode: http.StatusConflict}
+
+		_, err := c.CreateMergeRequest(context.Background(), in)
+		if err != ErrMergeRequestAlreadyExists {
+			t.Errorf("got error %v, want %v", err, ErrMergeRequestAlreadyExists)
+		}
+	})
+}
+
+func TestClient_ListMergeRequestApprovers(t *testing.T) {
+	t.Run("approved", func(t *testing.T) {
+		c := newTestClient(t)
+		c.httpClient = &mockHTTPResponseBody{
+			responseBody: `{"approved_by": [{"user": {"id": 1, "name": "Jane Doe", "username": "jane"}}]}`,
+		}
+
+		approvers, err := c.ListMergeRequestApprovers(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Author{{ID: 1, Name: "Jane Doe", Username: "jane"}}
+		if !reflect.DeepEqual(approvers, want) {
+			t.Errorf("got approvers %+v, want %+v", approvers, want)
+		}
+	})
+
+	t.Run("approvals not available", func(t *testing.T) {
+		c := newTestClient(t)
+		c.httpClient = mockHTTPEmptyResponse{statusCode: http.StatusNotFound}
+
+		approvers, err := c.ListMergeRequestApprovers(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(approvers) != 0 {
+			t.Errorf("got approvers %+v, want none", approvers)
+		}
+	})
+}
@@ -16,3 +16,24 @@ var MockGetProject func(c *Client, ctx context.Context, op GetProjectOp) (*Proje
 
 // MockListTree, if non-nil, will be called instead of Client.ListTree
 var MockListTree func(c *Client, ctx context.Context, op ListTreeOp) ([]*Tree, error)
+
+// MockCreateMergeRequest, if non-nil, will be called instead of Client.CreateMergeRequest
+var MockCreateMergeRequest func(c *Client, ctx context.Context, in *CreateMergeRequestInput) (*MergeRequest, error)
+
+// MockGetOpenMergeRequestByRefs, if non-nil, will be called instead of Client.GetOpenMergeRequestByRefs
+var MockGetOpenMergeRequestByRefs func(c *Client, ctx context.Context, projectID int, source, target string) (*MergeRequest, error)
+
+// MockGetMergeRequest, if non-nil, will be called instead of Client.GetMergeRequest
+var MockGetMergeRequest func(c *Client, ctx context.Context, projectID, iid int) (*MergeRequest, error)
+
+// MockUpdateMergeRequest, if non-nil, will be called instead of Client.UpdateMergeRequest
+var MockUpdateMergeRequest func(c *Client, ctx context.Context, in *UpdateMergeRequestInput) (*MergeRequest, error)
+
+// MockListMergeRequestNotes, if non-nil, will be called instead of Client.ListMergeRequestNotes
+var MockListMergeRequestNotes func(c *Client, ctx context.Context, projectID, iid int) ([]*Note, error)
+
+// MockListMergeRequestPipelines, if non-nil, will be called instead of Client.ListMergeRequestPipelines
+var MockListMergeRequestPipelines func(c *Client, ctx context.Context, projectID, iid int) ([]*Pipeline, error)
+
+// MockListMergeRequestApprovers, if non-nil, will be called instead of Client.ListMergeRequestApprovers
+var MockListMergeRequestApprovers func(c *Client, ctx context.Context, projectID, iid int) ([]Author, error)
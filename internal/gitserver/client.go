@@ -3,8 +3,6 @@ package gitserver
 import (
 	"bytes"
 	"context"
-	"crypto/md5"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +26,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
@@ -60,6 +59,9 @@ func NewClient(cli httpcli.Doer) *Client {
 		Addrs: func(ctx context.Context) []string {
 			return conf.Get().ServiceConnections.GitServers
 		},
+		Replicas: func(ctx context.Context) int {
+			return conf.Get().ServiceConnections.GitServerReplicas
+		},
 		HTTPClient:  cli,
 		HTTPLimiter: parallel.NewRun(500),
 		// Use the binary name for UserAgent. This should effectively identify
@@ -82,6 +84,11 @@ type Client struct {
 	// concurrent use. It may return different results at different times.
 	Addrs func(ctx context.Context) []string
 
+	// Replicas is a function which should return the number of gitservers
+	// that store each repo (see AddrsForRepo). If it is nil or returns a
+	// number less than 2, each repo is stored on a single gitserver.
+	Replicas func(ctx context.Context) int
+
 	// UserAgent is a string identifing who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
@@ -103,10 +110,89 @@ func (c *Client) addrForKey(ctx context.Context, key string) string {
 	return addrForKey(addrs, key)
 }
 
+// AddrForRepoIn returns the address of the gitserver in addrs that the given
+// repo is stored on, or "" if addrs is empty. Unlike Client.AddrForRepo it
+// does not use the current gitserver addresses, so gitservers use it to find
+// where a repo was stored before the addresses changed.
+func AddrForRepoIn(addrs []string, repo api.RepoName) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrForKey(addrs, string(protocol.NormalizeRepo(repo)))
+}
+
+// addrForKey returns the address in addrs closest to key on a consistent hash
+// ring of addrs, so that adding or removing a gitserver only moves the keys
+// of the gitservers next to it on the ring.
 func addrForKey(addrs []string, key string) string {
-	sum := md5.Sum([]byte(key))
-	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
-	return addrs[serverIndex]
+	// Static maps never return an error.
+	addr, _ := addrRing(addrs).Get(key, nil)
+	return addr
+}
+
+// AddrsForRepo returns the addresses of the gitservers that store the given
+// repo name. The first address is the one AddrForRepo returns, and the others
+// are its replicas, which are used when the first gitserver is unreachable.
+func (c *Client) AddrsForRepo(ctx context.Context, repo api.RepoName) []string {
+	addrs := c.Addrs(ctx)
+	if len(addrs) == 0 {
+		panic("unexpected state: no gitserver addresses")
+	}
+	replicas := 1
+	if c.Replicas != nil {
+		replicas = c.Replicas(ctx)
+	}
+	return AddrsForRepoIn(addrs, replicas, repo)
+}
+
+// AddrsForRepoIn returns the addresses of the gitservers in addrs that store
+// the given repo when each repo is stored on replicas gitservers, or nil if
+// addrs is empty.
+//
+// The first address is the one AddrForRepoIn returns, so that enabling
+// replication does not move any repos. The replicas are the next closest
+// gitservers on the same consistent hash ring, so that the repos of an
+// unreachable gitserver are spread over the other gitservers.
+func AddrsForRepoIn(addrs []string, replicas int, repo api.RepoName) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	if replicas < 1 {
+		replicas = 1
+	}
+	// Static maps never return an error.
+	owners, _ := addrRing(addrs).GetN(string(protocol.NormalizeRepo(repo)), replicas, nil)
+	return owners
+}
+
+// addrRingCache caches the hash ring of the last addresses passed to
+// addrRing, since building it hashes every address many times.
+var addrRingCache struct {
+	sync.Mutex
+	addrs []string
+	ring  *endpoint.Map
+}
+
+func addrRing(addrs []string) *endpoint.Map {
+	addrRingCache.Lock()
+	defer addrRingCache.Unlock()
+	if !stringsEqual(addrRingCache.addrs, addrs) {
+		addrRingCache.addrs = append([]string(nil), addrs...)
+		addrRingCache.ring = endpoint.Static(addrs...)
+	}
+	return addrRingCache.ring
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // ArchiveOptions contains options for the Archive func.
@@ -144,6 +230,15 @@ func (a *archiveReader) Close() error {
 // ArchiveURL returns a URL from which an archive of the given Git repository can
 // be downloaded from.
 func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions) *url.URL {
+	return &url.URL{
+		Scheme:   "http",
+		Host:     c.AddrForRepo(ctx, repo.Name),
+		Path:     "/archive",
+		RawQuery: archiveQuery(repo, opt).Encode(),
+	}
+}
+
+func archiveQuery(repo Repo, opt ArchiveOptions) url.Values {
 	q := url.Values{
 		"repo":    {string(repo.Name)},
 		"treeish": {opt.Treeish},
@@ -153,13 +248,7 @@ func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions)
 	for _, path := range opt.Paths {
 		q.Add("path", path)
 	}
-
-	return &url.URL{
-		Scheme:   "http",
-		Host:     c.AddrForRepo(ctx, repo.Name),
-		Path:     "/archive",
-		RawQuery: q.Encode(),
-	}
+	return q
 }
 
 // Archive produces an archive from a Git repository.
@@ -181,8 +270,7 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 		return nil, err
 	}
 
-	u := c.ArchiveURL(ctx, repo, opt)
-	resp, err := c.do(ctx, repo.Name, "GET", u.String(), nil)
+	resp, err := c.doWithFailover(ctx, repo.Name, "GET", "archive?"+archiveQuery(repo, opt).Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +305,87 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 	}
 }
 
+// LFSObject returns the content of the Git LFS object oid of the repository.
+// It returns an error satisfying os.IsNotExist if the gitserver doesn't store
+// the object, which is the case if LFS is not enabled for the repository or
+// the object is too large.
+func (c *Client) LFSObject(ctx context.Context, repo api.RepoName, oid string) (io.ReadCloser, error) {
+	q := url.Values{
+		"repo": {string(repo)},
+		"oid":  {oid},
+	}
+	resp, err := c.do(ctx, repo, "GET", "lfs-object?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &os.PathError{Op: "LFSObject", Path: oid, Err: os.ErrNotExist}
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// ErrEmptyBundle is returned by Bundle if there are no objects that are not
+// reachable from the commits a bundle is requested since.
+var ErrEmptyBundle = errors.New("no changes since the given commits")
+
+// Bundle returns a reader of a git bundle of all refs of repo. If since is
+// not empty, the bundle only contains the objects that are not reachable from
+// the commits in since, which are usually the commits that the refs of a
+// previous bundle point to. The reader returns an error at the end of the
+// bundle if gitserver failed to write all of it.
+func (c *Client) Bundle(ctx context.Context, repo api.RepoName, since []api.CommitID) (io.ReadCloser, error) {
+	q := url.Values{"repo": {string(repo)}}
+	for _, commit := range since {
+		q.Add("since", string(commit))
+	}
+	resp, err := c.doWithFailover(ctx, repo, "GET", "bundle?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &bundleReader{resp: resp}, nil
+	case http.StatusNoContent:
+		resp.Body.Close()
+		return nil, ErrEmptyBundle
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &vcs.RepoNotExistError{Repo: repo}
+	default:
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+}
+
+// bundleReader returns the error gitserver reports in the X-Bundle-Error
+// trailer at the end of the bundle.
+type bundleReader struct {
+	resp *http.Response
+}
+
+func (r *bundleReader) Read(p []byte) (int, error) {
+	n, err := r.resp.Body.Read(p)
+	if err == io.EOF {
+		if msg := r.resp.Trailer.Get("X-Bundle-Error"); msg != "" {
+			err = errors.New(msg)
+		}
+	}
+	return n, err
+}
+
+func (r *bundleReader) Close() error {
+	return r.resp.Body.Close()
+}
+
 type badRequestError struct{ error }
 
 func (e badRequestError) BadRequest() bool { return true }
@@ -249,7 +418,7 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 		EnsureRevision: c.EnsureRevision,
 		Args:           c.Args[1:],
 	}
-	resp, err := c.client.httpPost(ctx, repoName, "exec", req)
+	resp, err := c.client.doWithFailover(ctx, repoName, "POST", "exec", req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -280,8 +449,16 @@ var deadlineExceededCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Help:      "Times that Client.sendExec() returned context.DeadlineExceeded",
 })
 
+var failoverCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "client_failover",
+	Help:      "Times that a request was sent to a replica because a gitserver was unreachable",
+})
+
 func init() {
 	prometheus.MustRegister(deadlineExceededCounter)
+	prometheus.MustRegister(failoverCounter)
 }
 
 // Cmd represents a command to be executed remotely.
@@ -555,19 +732,50 @@ func (c *Client) doListOne(ctx context.Context, urlSuffix, addr string) ([]strin
 	return list, err
 }
 
+// RepoUpdateOptions are per-repository options for RequestRepoUpdate. The
+// gitserver remembers them for later clones and fetches of the repository.
+type RepoUpdateOptions struct {
+	// LFS is whether to fetch Git LFS objects of the repository.
+	LFS bool
+
+	// CloneFilter is the partial clone filter to clone the repository with,
+	// such as blob:none. Missing objects are fetched when they are needed.
+	CloneFilter string
+
+	// CloneDepth is the number of recent commits to clone shallowly, or 0
+	// to clone the full history.
+	CloneDepth int
+
+	// Refs are refs to fetch in addition to branches and tags, such as the
+	// refs/changes/* refs of open Gerrit changes. Refs that were requested
+	// before but are no longer listed are deleted.
+	Refs []string
+}
+
 // RequestRepoUpdate is the new protocol endpoint for synchronous requests
 // with more detailed responses. Do not use this if you are not repo-updater.
 //
 // Repo updates are not guaranteed to occur. If a repo has been updated
 // recently (within the Since duration specified in the request), the
 // update won't happen.
-func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
+func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration, opts RepoUpdateOptions) (*protocol.RepoUpdateResponse, error) {
+	return c.RequestRepoUpdateOn(ctx, c.AddrForRepo(ctx, repo.Name), repo, since, opts)
+}
+
+// RequestRepoUpdateOn is like RequestRepoUpdate, except that it sends the
+// request to the gitserver at addr, which should be one of the addresses
+// AddrsForRepo returns for repo. It is used to update all replicas of repo.
+func (c *Client) RequestRepoUpdateOn(ctx context.Context, addr string, repo Repo, since time.Duration, opts RepoUpdateOptions) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
-		Repo:  repo.Name,
-		URL:   repo.URL,
-		Since: since,
+		Repo:        repo.Name,
+		URL:         repo.URL,
+		Since:       since,
+		LFS:         opts.LFS,
+		CloneFilter: opts.CloneFilter,
+		CloneDepth:  opts.CloneDepth,
+		Refs:        opts.Refs,
 	}
-	resp, err := c.httpPost(ctx, repo.Name, "repo-update", req)
+	resp, err := c.doOn(ctx, addr, repo.Name, "POST", "repo-update", req)
 	if err != nil {
 		return nil, err
 	}
@@ -667,70 +875,89 @@ func (c *Client) IsRepoCloned(ctx context.Context, repo api.RepoName) (bool, err
 // If multiple errors occurred, an incomplete result is returned along with a
 // *multierror.Error.
 func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol.RepoInfoResponse, error) {
-	numPossibleShards := len(c.Addrs(ctx))
-	shards := make(map[string]*protocol.RepoInfoRequest, (len(repos)/numPossibleShards)*2) // 2x because it may not be a perfect division
-
+	replicas := make(map[api.RepoName][]string, len(repos))
 	for _, r := range repos {
-		addr := c.AddrForRepo(ctx, r)
-		shard := shards[addr]
-
-		if shard == nil {
-			shard = new(protocol.RepoInfoRequest)
-			shards[addr] = shard
-		}
-
-		shard.Repos = append(shard.Repos, r)
+		replicas[r] = c.AddrsForRepo(ctx, r)
 	}
 
-	type op struct {
-		req *protocol.RepoInfoRequest
-		res *protocol.RepoInfoResponse
-		err error
+	err := new(multierror.Error)
+	res := protocol.RepoInfoResponse{
+		Results: make(map[api.RepoName]*protocol.RepoInfo),
 	}
 
-	ch := make(chan op, len(shards))
-	for _, req := range shards {
-		go func(o op) {
-			var resp *http.Response
-			resp, o.err = c.httpPost(ctx, o.req.Repos[0], "repos", o.req)
-			if o.err != nil {
-				ch <- o
-				return
-			}
+	// The repos on unreachable gitservers are requested from their next
+	// replica in the following round.
+	for replica := 0; len(repos) > 0; replica++ {
+		numPossibleShards := len(c.Addrs(ctx))
+		shards := make(map[string]*protocol.RepoInfoRequest, (len(repos)/numPossibleShards)*2) // 2x because it may not be a perfect division
 
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				o.err = &url.Error{
-					URL: resp.Request.URL.String(),
-					Op:  "RepoInfo",
-					Err: errors.Errorf("RepoInfo: http status %d", resp.StatusCode),
-				}
-				ch <- o
-				return // we never get an error status code AND result
+		for _, r := range repos {
+			addr := replicas[r][replica]
+			shard := shards[addr]
+
+			if shard == nil {
+				shard = new(protocol.RepoInfoRequest)
+				shards[addr] = shard
 			}
 
-			o.res = new(protocol.RepoInfoResponse)
-			o.err = json.NewDecoder(resp.Body).Decode(o.res)
-			ch <- o
-		}(op{req: req})
-	}
+			shard.Repos = append(shard.Repos, r)
+		}
 
-	err := new(multierror.Error)
-	res := protocol.RepoInfoResponse{
-		Results: make(map[api.RepoName]*protocol.RepoInfo),
-	}
+		type op struct {
+			addr string
+			req  *protocol.RepoInfoRequest
+			res  *protocol.RepoInfoResponse
+			err  error
+		}
 
-	for i := 0; i < cap(ch); i++ {
-		o := <-ch
+		ch := make(chan op, len(shards))
+		for addr, req := range shards {
+			go func(o op) {
+				var resp *http.Response
+				resp, o.err = c.doOn(ctx, o.addr, o.req.Repos[0], "POST", "repos", o.req)
+				if o.err != nil {
+					ch <- o
+					return
+				}
 
-		if o.err != nil {
-			err = multierror.Append(err, o.err)
-			continue
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					o.err = &url.Error{
+						URL: resp.Request.URL.String(),
+						Op:  "RepoInfo",
+						Err: errors.Errorf("RepoInfo: http status %d", resp.StatusCode),
+					}
+					ch <- o
+					return // we never get an error status code AND result
+				}
+
+				o.res = new(protocol.RepoInfoResponse)
+				o.err = json.NewDecoder(resp.Body).Decode(o.res)
+				ch <- o
+			}(op{addr: addr, req: req})
 		}
 
-		for repo, info := range o.res.Results {
-			res.Results[repo] = info
+		var retry []api.RepoName
+		for i := 0; i < cap(ch); i++ {
+			o := <-ch
+
+			if o.err != nil {
+				// All repos have the same number of replicas.
+				if isUnreachable(ctx, o.err) && replica+1 < len(replicas[o.req.Repos[0]]) {
+					log15.Warn("gitserver unreachable, trying next replicas", "addr", o.addr, "error", o.err)
+					failoverCounter.Inc()
+					retry = append(retry, o.req.Repos...)
+				} else {
+					err = multierror.Append(err, o.err)
+				}
+				continue
+			}
+
+			for repo, info := range o.res.Results {
+				res.Results[repo] = info
+			}
 		}
+		repos = retry
 	}
 
 	return &res, err.ErrorOrNil()
@@ -761,9 +988,41 @@ func (c *Client) httpPost(ctx context.Context, repo api.RepoName, op string, pay
 // do performs a request to a gitserver, sharding based on the given
 // repo name (the repo name is otherwise not used).
 func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
+	addr := ""
+	if !strings.HasPrefix(op, "http") {
+		addr = c.AddrForRepo(ctx, repo)
+	}
+	return c.doOn(ctx, addr, repo, method, op, payload)
+}
+
+// doWithFailover is like do, except that it sends the request to the
+// replicas of repo in turn while the gitservers it sent it to are
+// unreachable. It must only be used for requests that read from repo.
+func (c *Client) doWithFailover(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
+	addrs := c.AddrsForRepo(ctx, repo)
+	for i, addr := range addrs {
+		resp, err = c.doOn(ctx, addr, repo, method, op, payload)
+		if !isUnreachable(ctx, err) || i == len(addrs)-1 {
+			break
+		}
+		log15.Warn("gitserver unreachable, trying next replica", "repo", repo, "addr", addr, "next", addrs[i+1], "error", err)
+		failoverCounter.Inc()
+	}
+	return resp, err
+}
+
+// isUnreachable reports whether err is the error of a request to a gitserver
+// that failed before the gitserver responded, for a reason other than ctx.
+func isUnreachable(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil
+}
+
+// doOn performs a request to the gitserver at addr. If addr is "", op must
+// be a URL.
+func (c *Client) doOn(ctx context.Context, addr string, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.do")
 	defer func() {
-		span.LogKV("repo", string(repo), "method", method, "op", op)
+		span.LogKV("repo", string(repo), "addr", addr, "method", method, "op", op)
 		if err != nil {
 			ext.Error.Set(span, true)
 			span.SetTag("err", err.Error())
@@ -777,8 +1036,8 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	}
 
 	uri := op
-	if !strings.HasPrefix(op, "http") {
-		uri = "http://" + c.AddrForRepo(ctx, repo) + "/" + op
+	if addr != "" {
+		uri = "http://" + addr + "/" + op
 	}
 
 	req, err := http.NewRequest(method, uri, bytes.NewReader(reqBody))
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -22,7 +23,9 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
 )
 
 func TestClient_ListCloned(t *testing.T) {
@@ -33,11 +36,11 @@ func TestClient_ListCloned(t *testing.T) {
 			switch r.URL.String() {
 			case "http://gitserver-0/list?cloned":
 				return &http.Response{
-					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo0-a", "repo0-b"]`)),
+					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo-a", "repo-c"]`)),
 				}, nil
 			case "http://gitserver-1/list?cloned":
 				return &http.Response{
-					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo1-a", "repo1-b"]`)),
+					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo-d", "repo-g"]`)),
 				}, nil
 			default:
 				return nil, fmt.Errorf("unexpected url: %s", r.URL.String())
@@ -45,7 +48,7 @@ func TestClient_ListCloned(t *testing.T) {
 		}),
 	}
 
-	want := []string{"repo0-a", "repo1-a", "repo1-b"}
+	want := []string{"repo-a", "repo-d", "repo-g"}
 	got, err := cli.ListCloned(context.Background())
 	if err != nil {
 		t.Fatal(err)
@@ -57,6 +60,186 @@ func TestClient_ListCloned(t *testing.T) {
 	}
 }
 
+func TestAddrsForRepoIn(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2", "gitserver-3"}
+	for i := 0; i < 20; i++ {
+		repo := api.RepoName(fmt.Sprintf("example.com/repo%d", i))
+		if got := gitserver.AddrsForRepoIn(addrs, 0, repo); !cmp.Equal(got, []string{gitserver.AddrForRepoIn(addrs, repo)}) {
+			t.Errorf("%s: got %v without replication, want only %s", repo, got, gitserver.AddrForRepoIn(addrs, repo))
+		}
+
+		got := gitserver.AddrsForRepoIn(addrs, 3, repo)
+		if len(got) != 3 || got[0] != gitserver.AddrForRepoIn(addrs, repo) {
+			t.Errorf("%s: got %v, want 3 addresses starting with %s", repo, got, gitserver.AddrForRepoIn(addrs, repo))
+		}
+		if got[0] == got[1] || got[0] == got[2] || got[1] == got[2] {
+			t.Errorf("%s: got %v, want distinct addresses", repo, got)
+		}
+
+		if got := gitserver.AddrsForRepoIn(addrs, 10, repo); len(got) != len(addrs) {
+			t.Errorf("%s: got %v, want every address", repo, got)
+		}
+	}
+}
+
+func TestAddrForRepoIn_addedAddr(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	added := append(addrs[:len(addrs):len(addrs)], "gitserver-3")
+
+	// Only repos which move to the added gitserver change their owner.
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		repo := api.RepoName(fmt.Sprintf("example.com/repo%d", i))
+		before, after := gitserver.AddrForRepoIn(addrs, repo), gitserver.AddrForRepoIn(added, repo)
+		if before == after {
+			continue
+		}
+		if after != "gitserver-3" {
+			t.Errorf("%s: moved from %s to %s, want only moves to gitserver-3", repo, before, after)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 500 {
+		t.Errorf("got %d of 1000 repos moved, want about a quarter", moved)
+	}
+}
+
+func TestClient_failover(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	var requested []string
+	cli := &gitserver.Client{
+		Addrs:    func(ctx context.Context) []string { return addrs },
+		Replicas: func(ctx context.Context) int { return 2 },
+		HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			requested = append(requested, r.URL.Host+r.URL.Path)
+			if r.URL.Host == "gitserver-0" {
+				return nil, errors.New("connection refused")
+			}
+			switch r.URL.Path {
+			case "/repos":
+				var req protocol.RepoInfoRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					return nil, err
+				}
+				res := protocol.RepoInfoResponse{Results: map[api.RepoName]*protocol.RepoInfo{}}
+				for _, repo := range req.Repos {
+					res.Results[repo] = &protocol.RepoInfo{Cloned: true}
+				}
+				b, _ := json.Marshal(res)
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+			default:
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(bytes.NewBufferString(r.URL.Host)),
+					Trailer:    http.Header{"X-Exec-Exit-Status": {"0"}},
+				}, nil
+			}
+		}),
+	}
+
+	// Find a repo stored on the unreachable gitserver.
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		if r := api.RepoName(fmt.Sprintf("example.com/repo%d", i)); cli.AddrForRepo(context.Background(), r) == "gitserver-0" {
+			repo = r
+		}
+	}
+	replica := cli.AddrsForRepo(context.Background(), repo)[1]
+	ctx := context.Background()
+
+	t.Run("Archive", func(t *testing.T) {
+		requested = nil
+		rc, err := cli.Archive(ctx, gitserver.Repo{Name: repo}, gitserver.ArchiveOptions{Treeish: "HEAD", Format: "zip"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		if want := []string{"gitserver-0/archive", replica + "/archive"}; !cmp.Equal(requested, want) {
+			t.Errorf("got requests %v, want %v", requested, want)
+		}
+	})
+
+	t.Run("Command", func(t *testing.T) {
+		requested = nil
+		cmd := cli.Command("git", "rev-parse", "HEAD")
+		cmd.Repo = gitserver.Repo{Name: repo}
+		out, err := cmd.Output(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != replica {
+			t.Errorf("got output %q, want %q", out, replica)
+		}
+	})
+
+	t.Run("RepoInfo", func(t *testing.T) {
+		requested = nil
+		res, err := cli.RepoInfo(ctx, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info := res.Results[repo]; info == nil || !info.Cloned {
+			t.Errorf("got %+v, want cloned repo", res.Results)
+		}
+	})
+
+	t.Run("writes do not fail over", func(t *testing.T) {
+		requested = nil
+		if err := cli.Remove(ctx, repo); err == nil {
+			t.Error("got no error, want connection error")
+		}
+		if want := []string{"gitserver-0/delete"}; !cmp.Equal(requested, want) {
+			t.Errorf("got requests %v, want %v", requested, want)
+		}
+	})
+}
+
+func TestClient_Bundle(t *testing.T) {
+	responses := map[string]*http.Response{
+		"example.com/ok":     {StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewBufferString("bundle"))},
+		"example.com/failed": {StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewBufferString("bund")), Trailer: http.Header{"X-Bundle-Error": {"exit status 128"}}},
+		"example.com/empty":  {StatusCode: http.StatusNoContent, Body: ioutil.NopCloser(&bytes.Buffer{})},
+		"example.com/absent": {StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(&bytes.Buffer{})},
+	}
+	var gotSince []string
+	cli := &gitserver.Client{
+		Addrs: func(ctx context.Context) []string { return []string{"gitserver-0"} },
+		HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			gotSince = r.URL.Query()["since"]
+			return responses[r.URL.Query().Get("repo")], nil
+		}),
+	}
+	ctx := context.Background()
+
+	rc, err := cli.Bundle(ctx, "example.com/ok", []api.CommitID{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(rc); err != nil || string(b) != "bundle" {
+		t.Errorf("got bundle %q and error %v, want %q", b, err, "bundle")
+	}
+	rc.Close()
+	if want := []string{"a", "b"}; !cmp.Equal(gotSince, want) {
+		t.Errorf("got since %v, want %v", gotSince, want)
+	}
+
+	rc, err = cli.Bundle(ctx, "example.com/failed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(rc); err == nil || err.Error() != "exit status 128" {
+		t.Errorf("got error %v at the end of the bundle, want the trailer error", err)
+	}
+	rc.Close()
+
+	if _, err := cli.Bundle(ctx, "example.com/empty", []api.CommitID{"a"}); err != gitserver.ErrEmptyBundle {
+		t.Errorf("got error %v, want ErrEmptyBundle", err)
+	}
+	if _, err := cli.Bundle(ctx, "example.com/absent", nil); !vcs.IsRepoNotExist(err) {
+		t.Errorf("got error %v, want repo not exist", err)
+	}
+}
+
 func TestClient_Archive(t *testing.T) {
 	root, err := ioutil.TempDir("", t.Name())
 	if err != nil {
@@ -101,7 +284,7 @@ func TestClient_Archive(t *testing.T) {
 	for name, test := range tests {
 		t.Run(string(name), func(t *testing.T) {
 			if test.remote != "" {
-				if _, err := cli.RequestRepoUpdate(ctx, gitserver.Repo{Name: name, URL: test.remote}, 0); err != nil {
+				if _, err := cli.RequestRepoUpdate(ctx, gitserver.Repo{Name: name, URL: test.remote}, 0, gitserver.RepoUpdateOptions{}); err != nil {
 					t.Fatal(err)
 				}
 			}
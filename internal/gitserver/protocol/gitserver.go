@@ -45,9 +45,13 @@ type HTTPSConfig struct {
 
 // RepoUpdateRequest is a request to update the contents of a given repo, or clone it if it doesn't exist.
 type RepoUpdateRequest struct {
-	Repo  api.RepoName  `json:"repo"`  // identifying URL for repo
-	URL   string        `json:"url"`   // repo's remote URL
-	Since time.Duration `json:"since"` // debounce interval for queries, used only with request-repo-update
+	Repo        api.RepoName  `json:"repo"`        // identifying URL for repo
+	URL         string        `json:"url"`         // repo's remote URL
+	Since       time.Duration `json:"since"`       // debounce interval for queries, used only with request-repo-update
+	LFS         bool          `json:"lfs"`         // whether to fetch Git LFS objects
+	CloneFilter string        `json:"cloneFilter"` // partial clone filter, such as blob:none
+	CloneDepth  int           `json:"cloneDepth"`  // number of recent commits to clone shallowly, or 0 for the full history
+	Refs        []string      `json:"refs"`        // refs to fetch in addition to branches and tags, such as the refs of open Gerrit changes
 }
 
 // RepoUpdateResponse returns meta information of the repo enqueued for
@@ -122,6 +126,34 @@ type RepoInfo struct {
 	// recloned automatically, so this time is likely to move forward
 	// periodically.
 	CloneTime *time.Time
+
+	// Maintenance is the status of the most recent git maintenance run by
+	// the gitserver janitor. It is nil if the repository was not maintained
+	// since it was cloned.
+	Maintenance *MaintenanceStatus
+
+	// UpdateHistory are the most recent attempts to clone or fetch the
+	// repository, most recent first. It is kept when cloning fails.
+	UpdateHistory []RepoUpdateAttempt
+}
+
+// RepoUpdateAttempt describes an attempt to clone or fetch a repository.
+type RepoUpdateAttempt struct {
+	Kind       string    // "clone" or "fetch"
+	Start      time.Time // when the attempt started
+	End        time.Time // when the attempt finished
+	Bytes      int64     // how much the git objects of the repository grew
+	ExitStatus int       // the exit status of git, or -1 if the attempt failed otherwise
+	Stderr     string    // the end of the output of git, with credentials redacted
+	Error      string    // why the attempt failed with credentials redacted, or empty if it succeeded
+}
+
+// MaintenanceStatus describes a git maintenance run on a repository, which
+// writes commit-graphs, packs objects and writes bitmaps.
+type MaintenanceStatus struct {
+	LastRun  time.Time     // when the run started
+	Duration time.Duration // how long the run took
+	Error    string        // why the run failed, or empty if it succeeded
 }
 
 // RepoInfoResponse is the response to a repository information request
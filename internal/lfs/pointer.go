@@ -0,0 +1,83 @@
+// Package lfs implements the parts of Git LFS that Sourcegraph needs to show
+// the content of files stored with Git LFS: parsing pointer files and the
+// layout of the local object store.
+package lfs
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// MaxPointerSize is the largest size in bytes of a pointer file. Larger blobs
+// are never pointers. It matches git-lfs, which never reads more than this
+// many bytes to detect pointers.
+const MaxPointerSize = 1024
+
+// Pointer is a Git LFS pointer file, which is stored in git in place of the
+// content of a file.
+//
+// See https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md.
+type Pointer struct {
+	OID  string // the SHA-256 of the content, as 64 lowercase hex characters
+	Size int64  // the size in bytes of the content
+}
+
+var (
+	versionLine = []byte("version https://git-lfs.github.com/spec/v1")
+	oidRegexp   = regexp.MustCompile(`^sha256:([0-9a-f]{64})$`)
+)
+
+// ParsePointer parses b as a pointer file. It returns false if b is not a
+// pointer file.
+func ParsePointer(b []byte) (Pointer, bool) {
+	if len(b) > MaxPointerSize || !bytes.HasSuffix(b, []byte("\n")) {
+		return Pointer{}, false
+	}
+	lines := bytes.Split(b[:len(b)-1], []byte("\n"))
+	if len(lines) < 3 || !bytes.Equal(lines[0], versionLine) {
+		return Pointer{}, false
+	}
+
+	var p Pointer
+	var haveSize bool
+	for _, line := range lines[1:] {
+		i := bytes.IndexByte(line, ' ')
+		if i < 0 {
+			return Pointer{}, false
+		}
+		key, value := string(line[:i]), string(line[i+1:])
+		switch key {
+		case "oid":
+			m := oidRegexp.FindStringSubmatch(value)
+			if m == nil {
+				return Pointer{}, false
+			}
+			p.OID = m[1]
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return Pointer{}, false
+			}
+			p.Size, haveSize = size, true
+		}
+		// Other keys are extensions, which we ignore.
+	}
+	if p.OID == "" || !haveSize {
+		return Pointer{}, false
+	}
+	return p, true
+}
+
+// IsValidOID reports whether oid is a valid object ID. Object IDs are used as
+// file names, so they must be validated if they come from untrusted input.
+func IsValidOID(oid string) bool {
+	return oidRegexp.MatchString("sha256:" + oid)
+}
+
+// ObjectPath returns the path of the object with the given ID in the object
+// store at dir. It is the same layout git-lfs uses in $GIT_DIR/lfs/objects.
+func ObjectPath(dir, oid string) string {
+	return filepath.Join(dir, oid[0:2], oid[2:4], oid)
+}
@@ -0,0 +1,56 @@
+package lfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	oid := strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		name string
+		in   string
+		want Pointer
+		ok   bool
+	}{
+		{
+			name: "pointer",
+			in:   "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345\n",
+			want: Pointer{OID: oid, Size: 12345},
+			ok:   true,
+		},
+		{
+			name: "extensions",
+			in:   "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + oid + "\noid sha256:" + oid + "\nsize 1\n",
+			want: Pointer{OID: oid, Size: 1},
+			ok:   true,
+		},
+		{
+			name: "missing trailing newline",
+			in:   "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345",
+		},
+		{
+			name: "missing size",
+			in:   "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n",
+		},
+		{
+			name: "bad oid",
+			in:   "version https://git-lfs.github.com/spec/v1\noid sha256:../../etc/passwd\nsize 1\n",
+		},
+		{
+			name: "not a pointer",
+			in:   "package lfs\n",
+		},
+		{
+			name: "too large",
+			in:   "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\nx " + strings.Repeat("x", MaxPointerSize) + "\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParsePointer([]byte(tc.in))
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
@@ -243,6 +243,30 @@ func (c *Client) SyncExternalService(ctx context.Context, svc api.ExternalServic
 	return &result, nil
 }
 
+// MockExternalServiceSyncState mocks (*Client).ExternalServiceSyncState for tests.
+var MockExternalServiceSyncState func(protocol.ExternalServiceSyncStateArgs) (*protocol.ExternalServiceSyncStateResult, error)
+
+// ExternalServiceSyncState returns the state of the last sync of the repositories of
+// an external service.
+func (c *Client) ExternalServiceSyncState(ctx context.Context, args protocol.ExternalServiceSyncStateArgs) (result *protocol.ExternalServiceSyncStateResult, err error) {
+	if MockExternalServiceSyncState != nil {
+		return MockExternalServiceSyncState(args)
+	}
+
+	resp, err := c.httpPost(ctx, "external-service-sync-state", args)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		stack := fmt.Sprintf("ExternalServiceSyncState: %+v", args)
+		return nil, errors.Wrap(fmt.Errorf("http status %d", resp.StatusCode), stack)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
 // RepoExternalServices requests the external services associated with a
 // repository with the given id.
 func (c *Client) RepoExternalServices(ctx context.Context, id api.RepoID) ([]api.ExternalService, error) {
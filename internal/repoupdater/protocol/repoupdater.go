@@ -185,6 +185,39 @@ type ExternalServiceSyncResult struct {
 	Error           string
 }
 
+// ExternalServiceSyncStateArgs is a request for the state of the last sync of the
+// repositories of an external service.
+type ExternalServiceSyncStateArgs struct {
+	// ID of the external service being queried.
+	ExternalServiceID int64
+}
+
+// ExternalServiceSyncStateResult is returned in response to an
+// ExternalServiceSyncStateArgs request.
+type ExternalServiceSyncStateResult struct {
+	// State is nil if the external service's repositories weren't synced since
+	// repo-updater was started. Sync states are not persisted, so they are lost
+	// when repo-updater restarts.
+	State *ExternalServiceSyncState `json:",omitempty"`
+}
+
+// ExternalServiceSyncState is the state of the last sync of the repositories of
+// an external service.
+type ExternalServiceSyncState struct {
+	ExternalServiceID int64
+	StartedAt         time.Time
+	FinishedAt        time.Time
+	// NextSyncAt is zero if no next sync is scheduled.
+	NextSyncAt time.Time
+	// Incremental is true if only the repositories which changed since the
+	// previous sync were synced.
+	Incremental bool
+	// Error is empty if the sync succeeded.
+	Error string `json:",omitempty"`
+	// The numbers of repositories in each state of the diff of the sync.
+	Added, Modified, Deleted, Unmodified int
+}
+
 type CloningProgress struct {
 	Message string
 }
@@ -0,0 +1,289 @@
+package query
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
+	"github.com/sourcegraph/sourcegraph/internal/search/query/types"
+)
+
+// A Node is a node in a typechecked query that contains the boolean operators
+// AND, OR or NOT. It is either a *Leaf or an *Operator.
+type Node interface {
+	String() string
+	node()
+}
+
+// A Leaf is a query without boolean operators, typechecked like any other
+// query.
+type Leaf struct {
+	Query     *Query           // the typechecked query
+	ParseTree syntax.ParseTree // the parse tree the query was checked from
+}
+
+// An Operator is a boolean operator applied to typechecked operands. A Not
+// operator only ever appears as an operand of an And operator that has at
+// least one operand that is not negated.
+type Operator struct {
+	Kind     syntax.OperatorKind
+	Operands []Node
+}
+
+func (*Leaf) node()     {}
+func (*Operator) node() {}
+
+// String returns the simplified query as a string.
+func (l *Leaf) String() string { return l.ParseTree.String() }
+
+// String returns the simplified query as a string.
+func (o *Operator) String() string { return toSyntax(o).String() }
+
+// toSyntax returns the parse tree of node.
+func toSyntax(node Node) syntax.Node {
+	switch n := node.(type) {
+	case *Leaf:
+		return n.ParseTree
+	case *Operator:
+		operands := make([]syntax.Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			operands[i] = toSyntax(operand)
+		}
+		return &syntax.Operator{Kind: n.Kind, Operands: operands}
+	default:
+		panic("unreachable")
+	}
+}
+
+// ProcessAndOr parses, simplifies, typechecks and validates a query that may
+// contain the boolean operators AND, OR and NOT. If the input contains no
+// operators, it returns a nil node and the caller should use Process instead.
+//
+// Simplification moves field-only expressions in a conjunction into each of
+// the other operands, and turns NOT applied to a single field expression into
+// the negated field. For example, `lang:go NOT file:_test (a OR b)` becomes
+// `(lang:go -file:_test a) OR (lang:go -file:_test b)`. The result is a single
+// *Leaf if no operators remain after simplification.
+func ProcessAndOr(queryString string, searchType SearchType) (Node, error) {
+	parsed, err := syntax.ParseBoolean(queryString)
+	if err != nil && searchType == SearchTypeLiteral {
+		// Parentheses in literal patterns need not be balanced, so they are
+		// searched for literally if they don't form groups.
+		parsed, err = syntax.ParseBooleanWithoutGroups(queryString)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parsed.(syntax.ParseTree); ok {
+		return nil, nil
+	}
+
+	simplified := simplify(lowercaseFields(parsed))
+	if err := validateOperators(simplified, false); err != nil {
+		return nil, err
+	}
+	return checkNode(simplified, searchType)
+}
+
+// lowercaseFields returns a copy of node where all field names are lowercase,
+// to make query fields case insensitive.
+func lowercaseFields(node syntax.Node) syntax.Node {
+	switch n := node.(type) {
+	case syntax.ParseTree:
+		return syntax.Map(n, func(e syntax.Expr) *syntax.Expr {
+			e.Field = strings.ToLower(e.Field)
+			return &e
+		})
+	case *syntax.Operator:
+		operands := make([]syntax.Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			operands[i] = lowercaseFields(operand)
+		}
+		return &syntax.Operator{Pos: n.Pos, Kind: n.Kind, Operands: operands}
+	}
+	panic("unreachable")
+}
+
+// isPatternExpr reports whether the expression is a search pattern (as opposed
+// to a field that scopes the search).
+func isPatternExpr(e *syntax.Expr) bool {
+	return e.Field == FieldDefault || e.Field == FieldContent
+}
+
+// isScope reports whether node is a parse tree that contains only fields.
+func isScope(node syntax.Node) bool {
+	p, ok := node.(syntax.ParseTree)
+	if !ok {
+		return false
+	}
+	for _, e := range p {
+		if isPatternExpr(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// simplify applies the simplifications described in ProcessAndOr.
+func simplify(node syntax.Node) syntax.Node {
+	o, ok := node.(*syntax.Operator)
+	if !ok {
+		return node
+	}
+
+	operands := make([]syntax.Node, 0, len(o.Operands))
+	for _, operand := range o.Operands {
+		operands = append(operands, simplify(operand))
+	}
+
+	switch o.Kind {
+	case syntax.Not:
+		switch operand := operands[0].(type) {
+		case syntax.ParseTree:
+			if len(operand) == 1 && !isPatternExpr(operand[0]) {
+				e := *operand[0]
+				e.Not = !e.Not
+				return syntax.ParseTree{&e}
+			}
+		case *syntax.Operator:
+			if operand.Kind == syntax.Not {
+				return operand.Operands[0]
+			}
+		}
+		return &syntax.Operator{Pos: o.Pos, Kind: o.Kind, Operands: operands}
+
+	case syntax.And:
+		var scope syntax.ParseTree
+		var rest []syntax.Node
+		for _, operand := range flatten(o.Kind, operands) {
+			if isScope(operand) {
+				scope = append(scope, operand.(syntax.ParseTree)...)
+			} else {
+				rest = append(rest, operand)
+			}
+		}
+		if len(rest) == 0 {
+			return scope
+		}
+		for i, operand := range rest {
+			rest[i] = withScope(operand, scope)
+		}
+		return newOperator(o.Kind, o.Pos, rest)
+
+	default:
+		return newOperator(o.Kind, o.Pos, operands)
+	}
+}
+
+// withScope returns a copy of node where the scope fields are added to every
+// parse tree in it.
+func withScope(node syntax.Node, scope syntax.ParseTree) syntax.Node {
+	if len(scope) == 0 {
+		return node
+	}
+	switch n := node.(type) {
+	case syntax.ParseTree:
+		p := make(syntax.ParseTree, 0, len(scope)+len(n))
+		p = append(p, scope...)
+		return append(p, n...)
+	case *syntax.Operator:
+		operands := make([]syntax.Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			operands[i] = withScope(operand, scope)
+		}
+		return &syntax.Operator{Pos: n.Pos, Kind: n.Kind, Operands: operands}
+	}
+	panic("unreachable")
+}
+
+// flatten merges operands that are operators of the given kind into the
+// operand list.
+func flatten(kind syntax.OperatorKind, operands []syntax.Node) []syntax.Node {
+	var flat []syntax.Node
+	for _, operand := range operands {
+		if o, ok := operand.(*syntax.Operator); ok && o.Kind == kind {
+			flat = append(flat, o.Operands...)
+		} else {
+			flat = append(flat, operand)
+		}
+	}
+	return flat
+}
+
+func newOperator(kind syntax.OperatorKind, pos int, operands []syntax.Node) syntax.Node {
+	operands = flatten(kind, operands)
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &syntax.Operator{Pos: pos, Kind: kind, Operands: operands}
+}
+
+// validateOperators checks that every NOT operator is an operand of an AND
+// operator that also has an operand that is not negated. We evaluate NOT as a
+// set difference, so there must be something to subtract from.
+func validateOperators(node syntax.Node, inAnd bool) error {
+	o, ok := node.(*syntax.Operator)
+	if !ok {
+		return nil
+	}
+	switch o.Kind {
+	case syntax.Not:
+		if !inAnd {
+			return &types.TypeError{Pos: o.Pos, Err: errors.New("NOT must be combined with a search that is not negated, as in `a AND NOT b`")}
+		}
+		return validateOperators(o.Operands[0], false)
+	case syntax.And:
+		positive := false
+		for _, operand := range o.Operands {
+			if operand, ok := operand.(*syntax.Operator); !ok || operand.Kind != syntax.Not {
+				positive = true
+			}
+			if err := validateOperators(operand, true); err != nil {
+				return err
+			}
+		}
+		if !positive {
+			return &types.TypeError{Pos: o.Pos, Err: errors.New("at least one operand of AND must not be negated")}
+		}
+	default:
+		for _, operand := range o.Operands {
+			if err := validateOperators(operand, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkNode typechecks and validates every parse tree in node.
+func checkNode(node syntax.Node, searchType SearchType) (Node, error) {
+	switch n := node.(type) {
+	case syntax.ParseTree:
+		if searchType == SearchTypeLiteral {
+			var err error
+			n, err = syntax.Parse(ConvertToLiteral(n.String()))
+			if err != nil {
+				return nil, err
+			}
+		}
+		q, err := Check(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := Validate(q, searchType); err != nil {
+			return nil, err
+		}
+		return &Leaf{Query: q, ParseTree: n}, nil
+	case *syntax.Operator:
+		operands := make([]Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			checked, err := checkNode(operand, searchType)
+			if err != nil {
+				return nil, err
+			}
+			operands[i] = checked
+		}
+		return &Operator{Kind: n.Kind, Operands: operands}, nil
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+// nodeString renders a typechecked node, wrapping every operator in
+// parentheses.
+func nodeString(node Node) string {
+	switch n := node.(type) {
+	case *Leaf:
+		return "[" + n.ParseTree.String() + "]"
+	case *Operator:
+		s := make([]string, len(n.Operands))
+		for i, operand := range n.Operands {
+			s[i] = nodeString(operand)
+		}
+		return "(" + n.Kind.String() + " " + strings.Join(s, " ") + ")"
+	}
+	return "<nil>"
+}
+
+func TestProcessAndOr(t *testing.T) {
+	tests := []struct {
+		input      string
+		searchType SearchType
+		want       string
+		wantErr    string
+	}{
+		{input: "a b", want: "<nil>"},
+		{input: "(a b)", want: "<nil>"},
+		{input: "a AND b", want: "(AND [a] [b])"},
+		{input: "(a AND b) OR c", want: "(OR (AND [a] [b]) [c])"},
+		{input: "lang:go NOT file:_test", want: "[lang:go -file:_test]"},
+		{input: "lang:go NOT -file:_test", want: "[lang:go file:_test]"},
+		{input: "Lang:go AND a", want: "[lang:go a]"},
+		{input: "repo:x (a OR b)", want: "(OR [repo:x a] [repo:x b])"},
+		{input: "repo:x (a OR b) NOT c", want: "(AND (OR [repo:x a] [repo:x b]) (NOT [repo:x c]))"},
+		{input: "a NOT (b OR c)", want: "(AND [a] (NOT (OR [b] [c])))"},
+		{input: "a NOT NOT b", want: "(AND [a] [b])"},
+		{input: "a AND b", searchType: SearchTypeLiteral, want: `(AND ["a"] ["b"])`},
+		{input: "lang:go foo( AND bar", searchType: SearchTypeLiteral, want: `(AND [lang:go "foo("] ["bar"])`},
+		{input: "foo)", searchType: SearchTypeLiteral, want: "<nil>"},
+		{input: "(foo", searchType: SearchTypeLiteral, want: "<nil>"},
+		{input: "foo) OR bar", searchType: SearchTypeLiteral, want: `(OR ["foo)"] ["bar"])`},
+		{input: "a AND (b", searchType: SearchTypeLiteral, want: `(AND ["a"] ["(b"])`},
+		{input: "(a OR b) AND c", searchType: SearchTypeLiteral, want: `(AND (OR ["a"] ["b"]) ["c"])`},
+		{input: "NOT a", wantErr: "type error at character 0: NOT must be combined with a search that is not negated, as in `a AND NOT b`"},
+		{input: "a OR NOT b", wantErr: "type error at character 5: NOT must be combined with a search that is not negated, as in `a AND NOT b`"},
+		{input: "NOT a NOT b", wantErr: "type error at character 0: at least one operand of AND must not be negated"},
+		{input: "a AND NOT case:yes", wantErr: `type error at character 10: field "case" does not support negation`},
+		{input: "a AND zz:b", wantErr: `type error at character 6: unrecognized field "zz"`},
+		{input: "(a OR b", wantErr: "parse error at character 0: unclosed parenthesis"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			node, err := ProcessAndOr(test.input, test.searchType)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("got err == %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodeString(node); got != test.want {
+				t.Errorf("got  %s\nwant %s", got, test.want)
+			}
+		})
+	}
+}
@@ -25,6 +25,10 @@ const (
 	FieldRepoHasCommitAfter = "repohascommitafter"
 	FieldPatternType        = "patterntype"
 	FieldContent            = "content"
+	FieldSelect             = "select"
+	FieldAsOf               = "asof"
+	FieldMultiline          = "multiline"
+	FieldSubmodules         = "submodules"
 
 	// For diff and commit search only:
 	FieldBefore    = "before"
@@ -59,6 +63,10 @@ var (
 			FieldType:        stringFieldType,
 			FieldPatternType: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 			FieldContent:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldSelect:      {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldAsOf:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldMultiline:   {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldSubmodules:  {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
 
 			FieldRepoHasFile:        regexpNegatableFieldType,
 			FieldRepoHasCommitAfter: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
@@ -164,6 +172,14 @@ func Validate(q *Query, searchType SearchType) error {
 			return errors.New(`the parameter "type:" is not valid for structural search, search is always performed on file content`)
 		}
 	}
+	if q.Fields[FieldMultiline] != nil && searchType != SearchTypeRegex {
+		return errors.New(`the parameter "multiline:" is only valid for regular expression search`)
+	}
+	if value, _ := q.StringValue(FieldSelect); value != "" {
+		if _, err := ParseSelector(value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -225,6 +241,12 @@ func (q *Query) IsCaseSensitive() bool {
 	return q.BoolValue(FieldCase)
 }
 
+// IsMultiline reports whether the query's regular expression patterns may
+// match across lines.
+func (q *Query) IsMultiline() bool {
+	return q.BoolValue(FieldMultiline)
+}
+
 // Values returns the values for the given field.
 func (q *Query) Values(field string) []*types.Value {
 	if _, ok := q.conf.FieldTypes[field]; !ok {
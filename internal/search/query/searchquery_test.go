@@ -163,6 +163,33 @@ func TestQuery_Validate(t *testing.T) {
 			SearchType: SearchTypeStructural,
 			Want:       "",
 		},
+		{
+			Name:  `Valid "select:"`,
+			Query: `select:symbol.Function foo`,
+			Want:  "",
+		},
+		{
+			Name:  `Invalid "select:"`,
+			Query: `select:branch foo`,
+			Want:  `invalid select:branch (valid values are: repo, file, symbol, symbol.<kind>, commit, commit.author)`,
+		},
+		{
+			Name:  `Invalid symbol kind in "select:"`,
+			Query: `select:symbol.func foo`,
+			Want:  `invalid select:symbol.func (unknown symbol kind "func")`,
+		},
+		{
+			Name:       `Regexp search with "multiline:"`,
+			Query:      `multiline:yes func.*\{`,
+			SearchType: SearchTypeRegex,
+			Want:       "",
+		},
+		{
+			Name:       `Literal search incompatible with "multiline:"`,
+			Query:      `multiline:yes foo`,
+			SearchType: SearchTypeLiteral,
+			Want:       `the parameter "multiline:" is only valid for regular expression search`,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -203,3 +230,26 @@ func checkPanic(t *testing.T, msg string, f func()) {
 	}()
 	f()
 }
+
+func TestQuery_Selector(t *testing.T) {
+	cases := []struct {
+		Query string
+		Want  *Selector
+	}{
+		{Query: `foo`, Want: nil},
+		{Query: `select:repo foo`, Want: &Selector{Kind: SelectRepo}},
+		{Query: `select:Symbol.Function foo`, Want: &Selector{Kind: SelectSymbol, Field: "function"}},
+		{Query: `select:commit.author foo`, Want: &Selector{Kind: SelectCommit, Field: "author"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.Query, func(t *testing.T) {
+			q, err := ParseAndCheck(tt.Query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.Want, q.Selector()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result kinds that the select: field can project search results to.
+const (
+	SelectRepo   = "repo"
+	SelectFile   = "file"
+	SelectSymbol = "symbol"
+	SelectCommit = "commit"
+)
+
+// symbolKinds are the symbol kinds accepted by select:symbol.<kind>. They are
+// the lowercase names of the LSP symbol kinds that symbol results report.
+var symbolKinds = map[string]struct{}{
+	"file": {}, "module": {}, "namespace": {}, "package": {}, "class": {},
+	"method": {}, "property": {}, "field": {}, "constructor": {}, "enum": {},
+	"interface": {}, "function": {}, "variable": {}, "constant": {}, "string": {},
+	"number": {}, "boolean": {}, "array": {}, "object": {}, "key": {}, "null": {},
+	"enummember": {}, "struct": {}, "event": {}, "operator": {}, "typeparameter": {},
+}
+
+// A Selector is the parsed value of the select: field, which projects search
+// results to a single kind of result and removes duplicates. For example,
+// select:repo returns each repository that contains a match once.
+type Selector struct {
+	// Kind is one of SelectRepo, SelectFile, SelectSymbol or SelectCommit.
+	Kind string
+
+	// Field optionally restricts the projection: for SelectSymbol it is a
+	// lowercase symbol kind (select:symbol.function), and for SelectCommit it
+	// is "author" (select:commit.author, one commit per distinct author).
+	Field string
+}
+
+func (s *Selector) String() string {
+	if s.Field == "" {
+		return s.Kind
+	}
+	return s.Kind + "." + s.Field
+}
+
+// ParseSelector parses the value of the select: field.
+func ParseSelector(value string) (*Selector, error) {
+	kind, field := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		kind, field = value[:i], strings.ToLower(value[i+1:])
+	}
+	s := &Selector{Kind: strings.ToLower(kind), Field: field}
+
+	switch s.Kind {
+	case SelectRepo, SelectFile:
+		if field == "" {
+			return s, nil
+		}
+	case SelectSymbol:
+		if _, ok := symbolKinds[field]; ok || field == "" {
+			return s, nil
+		}
+		return nil, fmt.Errorf("invalid select:%s (unknown symbol kind %q)", value, field)
+	case SelectCommit:
+		if field == "" || field == "author" {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid select:%s (valid values are: repo, file, symbol, symbol.<kind>, commit, commit.author)", value)
+}
+
+// Selector returns the parsed select: field of the query, or nil if it is not
+// set. The query must have been validated.
+func (q *Query) Selector() *Selector {
+	value, _ := q.StringValue(FieldSelect)
+	if value == "" {
+		return nil
+	}
+	s, _ := ParseSelector(value)
+	return s
+}
@@ -56,6 +56,69 @@ func (p ParseTree) String() string {
 	return strings.Join(s, " ")
 }
 
+// A Node is a node in the parse tree of a query that may contain boolean
+// operators. It is either a ParseTree or an *Operator.
+type Node interface {
+	String() string
+	node()
+}
+
+func (ParseTree) node() {}
+func (*Operator) node() {}
+
+// OperatorKind is the kind of a boolean operator.
+type OperatorKind int
+
+// All OperatorKind values.
+const (
+	And OperatorKind = iota
+	Or
+	Not
+)
+
+func (k OperatorKind) String() string {
+	switch k {
+	case And:
+		return "AND"
+	case Or:
+		return "OR"
+	case Not:
+		return "NOT"
+	default:
+		return fmt.Sprintf("OperatorKind(%d)", int(k))
+	}
+}
+
+// An Operator is a boolean operator applied to its operands. A Not operator
+// always has exactly one operand.
+type Operator struct {
+	Pos      int          // the starting character position of the operator expression
+	Kind     OperatorKind // the kind of operator
+	Operands []Node       // the operands
+}
+
+// String returns a string that parses to the operator with ParseBoolean.
+func (o *Operator) String() string {
+	s := make([]string, len(o.Operands))
+	for i, operand := range o.Operands {
+		s[i] = operand.String()
+		switch operand := operand.(type) {
+		case *Operator:
+			if operand.Kind != Not {
+				s[i] = "(" + s[i] + ")"
+			}
+		case ParseTree:
+			if o.Kind == Not && len(operand) > 1 {
+				s[i] = "(" + s[i] + ")"
+			}
+		}
+	}
+	if o.Kind == Not {
+		return "NOT " + s[0]
+	}
+	return strings.Join(s, " "+o.Kind.String()+" ")
+}
+
 // An Expr describes an expression in the parse tree.
 type Expr struct {
 	Pos       int       // the starting character position of the expression
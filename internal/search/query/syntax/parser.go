@@ -18,6 +18,7 @@ type parser struct {
 	tokens      []Token
 	pos         int
 	allowErrors bool
+	grouping    bool // whether the tokens were scanned with ScanGrouping
 }
 
 // context holds settings active within a given scope during parsing.
@@ -145,14 +146,15 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 			valueTok := p.next()
 			switch valueTok.Type {
 			case TokenLiteral, TokenQuoted:
-				if tok3 := p.next(); tok3.Type != TokenSep && tok3.Type != TokenEOF {
+				if tok3 := p.next(); !p.endOfExpr(tok3) {
 					if p.allowErrors {
 						return p.errorExpr(tok, tok2, tok3), nil
 					}
 					return nil, &ParseError{Pos: tok3.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok3.Type)}
 				}
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: valueTok.Value, ValueType: valueTok.Type}, nil
-			case TokenSep, TokenEOF:
+			case TokenSep, TokenEOF, TokenRParen:
+				p.endOfExpr(valueTok)
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: "", ValueType: TokenLiteral}, nil
 			default:
 				if p.allowErrors {
@@ -160,22 +162,21 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 				}
 				return nil, &ParseError{Pos: valueTok.Pos, Msg: fmt.Sprintf("got %s, want value", valueTok.Type)}
 			}
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			p.endOfExpr(tok2)
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			panic("unreachable")
 		}
 	case TokenQuoted, TokenPattern:
 		tok2 := p.next()
-		switch tok2.Type {
-		case TokenSep, TokenEOF:
+		if p.endOfExpr(tok2) {
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
-		default:
-			if p.allowErrors {
-				return p.errorExpr(tok, tok2), nil
-			}
-			return nil, &ParseError{Pos: tok2.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok2.Type)}
 		}
+		if p.allowErrors {
+			return p.errorExpr(tok, tok2), nil
+		}
+		return nil, &ParseError{Pos: tok2.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok2.Type)}
 	}
 
 	if p.allowErrors {
@@ -193,10 +194,249 @@ func (p *parser) errorExpr(toks ...Token) *Expr {
 	}
 	for {
 		t := p.next()
-		switch t.Type {
-		case TokenSep, TokenEOF:
+		if p.endOfExpr(t) {
 			return e
 		}
 		e.Value = e.Value + t.Value
 	}
 }
+
+// endOfExpr reports whether tok terminates an expression. A closing
+// parenthesis also terminates an expression, but it is put back so that the
+// enclosing group can consume it.
+func (p *parser) endOfExpr(tok Token) bool {
+	switch tok.Type {
+	case TokenSep, TokenEOF:
+		return true
+	case TokenRParen:
+		p.backup()
+		return true
+	}
+	return false
+}
+
+// ParseBoolean parses an input string that may contain the boolean operators
+// AND, OR and NOT and parenthesized groups. Operators must be written in
+// uppercase and separated from their operands. Consecutive expressions that
+// are not separated by an operator form a single ParseTree, so they keep the
+// meaning they have in a query without operators. If the input contains no
+// operators or groups, the returned node is a ParseTree.
+//
+// BNF-ish query syntax, where exprList is as in Parse:
+//
+//   orExpr  := andExpr ("OR" andExpr)*
+//   andExpr := term ({"AND"} term)*
+//   term    := not | group | exprList
+//   not     := "NOT" (not | group | exprSign)
+//   group   := "(" orExpr ")"
+func ParseBoolean(input string) (Node, error) {
+	return parseBoolean(parser{tokens: ScanGrouping(input), grouping: true})
+}
+
+// ParseBooleanWithoutGroups is like ParseBoolean except that parentheses are
+// part of the expressions instead of grouping them. It is used for literal
+// searches whose patterns contain unbalanced parentheses, as in `foo(`.
+func ParseBooleanWithoutGroups(input string) (Node, error) {
+	return parseBoolean(parser{tokens: Scan(input)})
+}
+
+func parseBoolean(p parser) (Node, error) {
+	ctx := context{field: ""}
+	p.skipSep()
+	if p.peek().Type == TokenEOF {
+		return ParseTree{}, nil
+	}
+	node, err := p.parseOr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSep()
+	if tok := p.next(); tok.Type != TokenEOF {
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want EOF", tok.Type)}
+	}
+	return node, nil
+}
+
+// keywords maps the operator keywords to their kinds.
+var keywords = map[string]OperatorKind{
+	"AND": And,
+	"OR":  Or,
+	"NOT": Not,
+}
+
+// skipSep advances the cursor past any separators.
+func (p *parser) skipSep() {
+	for p.peek().Type == TokenSep {
+		p.next()
+	}
+}
+
+// peekKeyword reports whether the next token is an operator keyword, without
+// consuming it. A keyword must be followed by a separator, a parenthesis or
+// EOF, so that e.g. "AND:x" or "ORDER" are not treated as keywords.
+func (p *parser) peekKeyword() (OperatorKind, bool) {
+	tok := p.peek()
+	if tok.Type != TokenLiteral {
+		return 0, false
+	}
+	kind, ok := keywords[tok.Value]
+	if !ok {
+		return 0, false
+	}
+	if p.pos+1 < len(p.tokens) {
+		switch p.tokens[p.pos+1].Type {
+		case TokenSep, TokenEOF, TokenLParen, TokenRParen:
+		default:
+			return 0, false
+		}
+	}
+	return kind, true
+}
+
+// orExpr := andExpr ("OR" andExpr)*
+func (p *parser) parseOr(ctx context) (Node, error) {
+	pos := p.peek().Pos
+	node, err := p.parseAnd(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operands := []Node{node}
+	for {
+		p.skipSep()
+		if kind, ok := p.peekKeyword(); !ok || kind != Or {
+			break
+		}
+		p.next()
+		node, err := p.parseAnd(ctx)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+	}
+	return newOperator(Or, pos, operands), nil
+}
+
+// andExpr := term ({"AND"} term)*
+func (p *parser) parseAnd(ctx context) (Node, error) {
+	pos := p.peek().Pos
+	var operands []Node
+	for {
+		p.skipSep()
+		if tok := p.peek(); tok.Type == TokenEOF || tok.Type == TokenRParen {
+			break
+		}
+		kind, ok := p.peekKeyword()
+		if ok && kind == Or {
+			break
+		}
+		if ok && kind == And {
+			if len(operands) == 0 {
+				return nil, &ParseError{Pos: p.peek().Pos, Msg: "got AND, want expr"}
+			}
+			p.next()
+			p.skipSep()
+		}
+		node, err := p.parseTerm(ctx)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+	}
+	if len(operands) == 0 {
+		tok := p.peek()
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want expr", tok.Type)}
+	}
+	return newOperator(And, pos, operands), nil
+}
+
+// term := not | group | exprList
+// not  := "NOT" (not | group | exprSign)
+func (p *parser) parseTerm(ctx context) (Node, error) {
+	tok := p.peek()
+	if kind, ok := p.peekKeyword(); ok {
+		if kind != Not {
+			return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want expr", tok.Value)}
+		}
+		p.next()
+		p.skipSep()
+		var operand Node
+		if kind, ok := p.peekKeyword(); ok && kind == Not {
+			not, err := p.parseTerm(ctx)
+			if err != nil {
+				return nil, err
+			}
+			operand = not
+		} else if p.peek().Type == TokenLParen {
+			group, err := p.parseGroup(ctx)
+			if err != nil {
+				return nil, err
+			}
+			operand = group
+		} else {
+			if next := p.peek(); next.Type == TokenEOF || next.Type == TokenRParen {
+				return nil, &ParseError{Pos: next.Pos, Msg: fmt.Sprintf("got %s, want expr after NOT", next.Type)}
+			}
+			if _, ok := p.peekKeyword(); ok {
+				return nil, &ParseError{Pos: p.peek().Pos, Msg: fmt.Sprintf("got %s, want expr after NOT", p.peek().Value)}
+			}
+			expr, err := p.parseExprSign(ctx)
+			if err != nil {
+				return nil, err
+			}
+			operand = ParseTree{expr}
+		}
+		return &Operator{Pos: tok.Pos, Kind: Not, Operands: []Node{operand}}, nil
+	}
+	if tok.Type == TokenLParen {
+		return p.parseGroup(ctx)
+	}
+
+	var exprList ParseTree
+	for {
+		p.skipSep()
+		if tok := p.peek(); tok.Type == TokenEOF || tok.Type == TokenLParen || tok.Type == TokenRParen {
+			break
+		}
+		if _, ok := p.peekKeyword(); ok {
+			break
+		}
+		expr, err := p.parseExprSign(ctx)
+		if err != nil {
+			return nil, err
+		}
+		exprList = append(exprList, expr)
+	}
+	return exprList, nil
+}
+
+// group := "(" orExpr ")"
+func (p *parser) parseGroup(ctx context) (Node, error) {
+	open := p.next()
+	node, err := p.parseOr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSep()
+	if tok := p.next(); tok.Type != TokenRParen {
+		return nil, &ParseError{Pos: open.Pos, Msg: "unclosed parenthesis"}
+	}
+	return node, nil
+}
+
+// newOperator returns an operator of the given kind. Operands that are
+// themselves operators of the same kind are flattened into it, and an operator
+// with a single operand is replaced by that operand.
+func newOperator(kind OperatorKind, pos int, operands []Node) Node {
+	var flat []Node
+	for _, operand := range operands {
+		if o, ok := operand.(*Operator); ok && o.Kind == kind {
+			flat = append(flat, o.Operands...)
+		} else {
+			flat = append(flat, operand)
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return &Operator{Pos: pos, Kind: kind, Operands: flat}
+}
@@ -155,3 +155,98 @@ func TestParseAllowingErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBoolean(t *testing.T) {
+	tests := map[string]struct {
+		wantString string
+		wantErr    *ParseError
+	}{
+		"":                          {wantString: ""},
+		"a b":                       {wantString: "a b"},
+		"(a b)":                     {wantString: "a b"},
+		"a()":                       {wantString: "a()"},
+		"a AND b":                   {wantString: "a AND b"},
+		"a b OR c":                  {wantString: "a b OR c"},
+		"a OR b AND c":              {wantString: "a OR (b AND c)"},
+		"(a OR b) AND c":            {wantString: "(a OR b) AND c"},
+		"(a OR b) c":                {wantString: "(a OR b) AND c"},
+		"a OR (b OR c)":             {wantString: "a OR b OR c"},
+		"(a AND b) OR c":            {wantString: "(a AND b) OR c"},
+		"lang:go NOT file:_test":    {wantString: "lang:go AND NOT file:_test"},
+		"a NOT b c":                 {wantString: "a AND NOT b AND c"},
+		"a NOT (b c)":               {wantString: "a AND NOT (b c)"},
+		"a NOT (b OR c)":            {wantString: "a AND NOT (b OR c)"},
+		"(f:a.go (x OR y))":         {wantString: "f:a.go AND (x OR y)"},
+		"ORDER or and":              {wantString: "ORDER or and"},
+		"AND:x":                     {wantString: "AND:x"},
+		`"a AND b"`:                 {wantString: `"a AND b"`},
+		"AND a":                     {wantErr: &ParseError{Pos: 0, Msg: "got AND, want expr"}},
+		"a OR":                      {wantErr: &ParseError{Pos: 4, Msg: "got TokenEOF, want expr"}},
+		"a NOT":                     {wantErr: &ParseError{Pos: 5, Msg: "got TokenEOF, want expr after NOT"}},
+		"a NOT NOT b":               {wantString: "a AND NOT NOT b"},
+		"NOT OR a":                  {wantErr: &ParseError{Pos: 4, Msg: "got OR, want expr after NOT"}},
+		"(a":                        {wantErr: &ParseError{Pos: 0, Msg: "unclosed parenthesis"}},
+		"a)":                        {wantErr: &ParseError{Pos: 1, Msg: "got TokenRParen, want EOF"}},
+		"()":                        {wantErr: &ParseError{Pos: 1, Msg: "got TokenRParen, want expr"}},
+		`a:"b"(`:                    {wantErr: &ParseError{Pos: 5, Msg: "got TokenLParen, want separator or EOF"}},
+		"(repo:a b) OR (repo:c d)":  {wantString: "repo:a b OR repo:c d"},
+		"x AND (y OR NOT z) AND -w": {wantString: "x AND (y OR NOT z) AND -w"},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			node, err := ParseBoolean(input)
+			if err != nil && test.wantErr == nil {
+				t.Fatal(err)
+			} else if err == nil && test.wantErr != nil {
+				t.Fatalf("got err == nil, want %q", test.wantErr)
+			} else if test.wantErr != nil && !reflect.DeepEqual(err, test.wantErr) {
+				t.Fatalf("got err == %q, want %q", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := node.String(); got != test.wantString {
+				t.Errorf("node string: %s\ngot  %s\nwant %s", input, got, test.wantString)
+			}
+			// The string form must parse to an equivalent node.
+			node2, err := ParseBoolean(node.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := node2.String(); got != test.wantString {
+				t.Errorf("reparsed node string: %s\ngot  %s\nwant %s", input, got, test.wantString)
+			}
+		})
+	}
+}
+
+func TestParseBooleanWithoutGroups(t *testing.T) {
+	// The operands of the top-level operator, or the parse tree if there is
+	// no operator.
+	tests := map[string][]string{
+		"(a":         {"(a"},
+		"a)":         {"a)"},
+		"a) OR b":    {"a)", "b"},
+		"(a AND b":   {"(a", "b"},
+		"(a OR b) c": {"(a", "b) c"},
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			node, err := ParseBooleanWithoutGroups(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if o, ok := node.(*Operator); ok {
+				for _, operand := range o.Operands {
+					got = append(got, operand.String())
+				}
+			} else {
+				got = []string{node.String()}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got  %q\nwant %q", got, want)
+			}
+		})
+	}
+}
@@ -21,7 +21,9 @@ const (
 	TokenPattern
 	TokenColon
 	TokenMinus
-	TokenSep // separator (like a semicolon)
+	TokenSep    // separator (like a semicolon)
+	TokenLParen // opening parenthesis of a group (only when scanning with grouping)
+	TokenRParen // closing parenthesis of a group (only when scanning with grouping)
 )
 
 var singleCharTokens = map[rune]TokenType{
@@ -38,8 +40,19 @@ type Token struct {
 
 // Scan scans the query and returns a list of tokens.
 func Scan(input string) []Token {
-	s := &scanner{input: input}
+	return scan(&scanner{input: input})
+}
+
+// ScanGrouping is like Scan except that parentheses are treated as grouping
+// tokens. An opening parenthesis starts a group only at the beginning of a
+// token, and a closing parenthesis ends a group only if it is not balanced by
+// an opening parenthesis inside the same token. For example, `(foo(x) bar)`
+// is scanned as TokenLParen, `foo(x)`, TokenSep, `bar`, TokenRParen.
+func ScanGrouping(input string) []Token {
+	return scan(&scanner{input: input, grouping: true})
+}
 
+func scan(s *scanner) []Token {
 	for state := scanDefault; state != nil; {
 		state = state(s)
 	}
@@ -49,11 +62,12 @@ func Scan(input string) []Token {
 type stateFn func(*scanner) stateFn
 
 type scanner struct {
-	input   string
-	tokens  []Token
-	pos     int
-	prevPos int
-	start   int
+	input    string
+	tokens   []Token
+	pos      int
+	prevPos  int
+	start    int
+	grouping bool // whether parentheses are scanned as TokenLParen and TokenRParen
 }
 
 func (s *scanner) next() rune {
@@ -111,6 +125,15 @@ func scanDefault(s *scanner) stateFn {
 			s.emit(typ)
 			return scanDefault
 		}
+		if s.grouping && (r == '(' || r == ')') {
+			s.next()
+			if r == '(' {
+				s.emit(TokenLParen)
+			} else {
+				s.emit(TokenRParen)
+			}
+			return scanDefault
+		}
 
 		if r == '"' || r == '\'' {
 			return scanQuoted
@@ -129,6 +152,7 @@ func scanText(s *scanner) stateFn {
 	preColonChars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 	escaped := false
+	depth := 0
 	for {
 		if s.eof() {
 			break
@@ -144,6 +168,10 @@ func scanText(s *scanner) stateFn {
 				s.backup()
 				break
 			}
+			if s.endsGroup(r, &depth) {
+				s.backup()
+				break
+			}
 		}
 		escaped = false
 		if r == ':' {
@@ -155,7 +183,7 @@ func scanText(s *scanner) stateFn {
 			return scanValue
 		}
 		if !strings.ContainsRune(preColonChars, r) {
-			return scanLiteral
+			return scanLiteralDepth(depth)
 		}
 	}
 
@@ -174,32 +202,67 @@ func scanValue(s *scanner) stateFn {
 	if r == '"' || r == '\'' {
 		return scanQuoted
 	}
+	if r == ')' && s.grouping {
+		return scanDefault
+	}
 	return scanLiteral
 }
 
 func scanLiteral(s *scanner) stateFn {
-	escaped := false
-	for {
-		if s.eof() {
-			break
-		}
-		r := s.next()
-		if !escaped {
-			if r == '\\' {
-				escaped = true
-				continue
-			}
+	return scanLiteralDepth(0)(s)
+}
 
-			if unicode.IsSpace(r) {
-				s.backup()
+// scanLiteralDepth returns a state function that scans the remainder of a
+// literal, where depth is the number of unclosed parentheses already consumed
+// in the literal.
+func scanLiteralDepth(depth int) stateFn {
+	return func(s *scanner) stateFn {
+		escaped := false
+		for {
+			if s.eof() {
 				break
 			}
+			r := s.next()
+			if !escaped {
+				if r == '\\' {
+					escaped = true
+					continue
+				}
+
+				if unicode.IsSpace(r) {
+					s.backup()
+					break
+				}
+				if s.endsGroup(r, &depth) {
+					s.backup()
+					break
+				}
+			}
+			escaped = false
 		}
-		escaped = false
+
+		s.emit(TokenLiteral)
+		return scanDefault
 	}
+}
 
-	s.emit(TokenLiteral)
-	return scanDefault
+// endsGroup reports whether r is a closing parenthesis that ends the
+// enclosing group rather than being part of the current literal. It keeps
+// track of the parentheses opened inside the literal in depth.
+func (s *scanner) endsGroup(r rune, depth *int) bool {
+	if !s.grouping {
+		return false
+	}
+	switch r {
+	case '(':
+		*depth++
+	case ')':
+		if *depth == 0 {
+			return true
+		}
+		*depth--
+	}
+	return false
 }
 
 func scanQuoted(s *scanner) stateFn {
@@ -106,3 +106,40 @@ func tokenValues(tokens []Token) []string {
 	}
 	return values
 }
+
+func TestScanGrouping(t *testing.T) {
+	tests := map[string]struct {
+		wantTypes  []TokenType /* + implicit TokenEOF */
+		wantValues []string
+	}{
+		"(a)":        {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ")"}},
+		"( a )":      {wantTypes: []TokenType{TokenLParen, TokenSep, TokenLiteral, TokenSep, TokenRParen}},
+		"a()":        {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"a()"}},
+		"(a(b))":     {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a(b)", ")"}},
+		"(a:b)":      {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ":", "b", ")"}},
+		"(a:)":       {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenRParen}},
+		"(a.b)":      {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a.b", ")"}},
+		`(a\))`:      {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", `a\)`, ")"}},
+		`("a)")`:     {wantTypes: []TokenType{TokenLParen, TokenQuoted, TokenRParen}, wantValues: []string{"(", `"a)"`, ")"}},
+		"(/a)/)":     {wantTypes: []TokenType{TokenLParen, TokenPattern, TokenRParen}, wantValues: []string{"(", "a)", ")"}},
+		"((a) b)":    {wantTypes: []TokenType{TokenLParen, TokenLParen, TokenLiteral, TokenRParen, TokenSep, TokenLiteral, TokenRParen}},
+		"-(a)":       {wantTypes: []TokenType{TokenMinus, TokenLParen, TokenLiteral, TokenRParen}},
+		"a OR (b c)": {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenLiteral, TokenSep, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			tokens := ScanGrouping(input)
+			if len(tokens) > 0 && tokens[len(tokens)-1].Type == TokenEOF {
+				tokens = tokens[:len(tokens)-1]
+			}
+			if tokenTypes := tokenTypes(tokens); !reflect.DeepEqual(tokenTypes, test.wantTypes) {
+				t.Errorf("token types: %s\ngot  %v\nwant %v", input, tokenTypes, test.wantTypes)
+			}
+			if test.wantValues != nil {
+				if tokenValues := tokenValues(tokens); !reflect.DeepEqual(tokenValues, test.wantValues) {
+					t.Errorf("token values: %s\ngot  %q\nwant %q", input, tokenValues, test.wantValues)
+				}
+			}
+		})
+	}
+}
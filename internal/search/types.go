@@ -111,8 +111,10 @@ type TextPatternInfo struct {
 	IsCaseSensitive bool
 	FileMatchLimit  int32
 
-	// We do not support IsMultiline
-	// IsMultiline     bool
+	// IsMultiline lets the regular expression Pattern match across lines
+	// (multiline:yes).
+	IsMultiline bool
+
 	IncludePatterns []string
 	ExcludePattern  string
 
@@ -1,9 +1,14 @@
 package git
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,8 +26,21 @@ type BlameOptions struct {
 
 	StartLine int `json:",omitempty" url:",omitempty"` // 1-indexed start byte (or 0 for beginning of file)
 	EndLine   int `json:",omitempty" url:",omitempty"` // 1-indexed end byte (or 0 for end of file)
+
+	// IgnoreRevs are commits whose changes are ignored, as with git blame
+	// --ignore-rev. The lines they changed are blamed on the commits that
+	// changed them before. The commits listed in the .git-blame-ignore-revs
+	// file at NewestCommit are always ignored if NewestCommit is set.
+	IgnoreRevs []string `json:",omitempty" url:",omitempty"`
+
+	DetectMoves  bool `json:",omitempty" url:",omitempty"` // detect lines moved within the file (git blame -M)
+	DetectCopies bool `json:",omitempty" url:",omitempty"` // detect lines moved or copied from other files (git blame -C)
 }
 
+// ignoreRevsFile is the name of the file that lists commits that blames
+// ignore, which GitHub and GitLab also use.
+const ignoreRevsFile = ".git-blame-ignore-revs"
+
 // A Hunk is a contiguous portion of a file associated with a commit.
 type Hunk struct {
 	StartLine int // 1-indexed start line number
@@ -41,120 +59,301 @@ func BlameFile(ctx context.Context, repo gitserver.Repo, path string, opt *Blame
 	span.SetTag("path", path)
 	span.SetTag("opt", opt)
 	defer span.Finish()
-	return blameFileCmd(ctx, gitserverCmdFunc(repo), path, opt)
+
+	var hunks []*Hunk
+	err := StreamBlameFile(ctx, repo, path, opt, func(hunk *Hunk) error {
+		hunks = append(hunks, hunk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(hunks, func(i, j int) bool { return hunks[i].StartLine < hunks[j].StartLine })
+	return hunks, nil
 }
 
-func blameFileCmd(ctx context.Context, command cmdFunc, path string, opt *BlameOptions) ([]*Hunk, error) {
+// StreamBlameFile is like BlameFile, except that it calls onHunk with each
+// hunk as soon as git blame outputs it, so that blames of large files can be
+// shown before git blame finishes. The hunks are not ordered by line: git
+// blame outputs the lines of the newest commits first. If onHunk returns an
+// error, the blame is stopped and the error is returned.
+func StreamBlameFile(ctx context.Context, repo gitserver.Repo, path string, opt *BlameOptions, onHunk func(*Hunk) error) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: StreamBlameFile")
+	span.SetTag("repo", repo.Name)
+	span.SetTag("path", path)
+	defer span.Finish()
+
 	if opt == nil {
 		opt = &BlameOptions{}
 	}
 	if opt.OldestCommit != "" {
-		return nil, fmt.Errorf("OldestCommit not implemented")
+		return fmt.Errorf("OldestCommit not implemented")
 	}
 	if err := checkSpecArgSafety(string(opt.NewestCommit)); err != nil {
-		return nil, err
+		return err
 	}
 	if err := checkSpecArgSafety(string(opt.OldestCommit)); err != nil {
-		return nil, err
+		return err
+	}
+
+	commitID := opt.NewestCommit
+	if commitID == "" {
+		var err error
+		if commitID, err = ResolveRevision(ctx, repo, nil, "HEAD", nil); err != nil {
+			return err
+		}
 	}
 
-	args := []string{"blame", "-w", "--porcelain"}
+	ignoreRevs, err := blameIgnoreRevs(ctx, repo, commitID, opt)
+	if err != nil {
+		return err
+	}
+
+	// git blame --incremental outputs every hunk as soon as it is blamed,
+	// but not the content of the lines, which we need for byte offsets.
+	lineOffsets, err := blameLineOffsets(ctx, repo, commitID, path)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"blame", "-w", "--incremental"}
+	if opt.DetectMoves {
+		args = append(args, "-M")
+	}
+	if opt.DetectCopies {
+		args = append(args, "-C")
+	}
+	for _, rev := range ignoreRevs {
+		args = append(args, "--ignore-rev", rev)
+	}
 	if opt.StartLine != 0 || opt.EndLine != 0 {
 		args = append(args, fmt.Sprintf("-L%d,%d", opt.StartLine, opt.EndLine))
 	}
-	args = append(args, string(opt.NewestCommit), "--", filepath.ToSlash(path))
+	args = append(args, string(commitID), "--", filepath.ToSlash(path))
 
-	out, err := command(args).Output(ctx)
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	rc, err := gitserver.StdoutReader(ctx, cmd)
 	if err != nil {
-		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+		return errors.WithMessage(err, fmt.Sprintf("git command %v failed", args))
+	}
+	defer rc.Close()
+
+	// Byte offsets are relative to the first line that is blamed.
+	firstLine := 1
+	if opt.StartLine > 1 {
+		firstLine = opt.StartLine
+	}
+	p := newBlameParser(rc, lineOffsets, lineOffsets.offset(firstLine))
+	for {
+		hunk, err := p.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("git command %v failed", args))
+		}
+		if err := onHunk(hunk); err != nil {
+			return err
+		}
+	}
+}
+
+// blameIgnoreRevs returns the commits that a blame with opt ignores: the
+// commits in opt.IgnoreRevs and in the .git-blame-ignore-revs file at
+// commitID which exist in repo. git blame fails if a commit to ignore does
+// not exist, which is common for commits in .git-blame-ignore-revs of forks
+// and shallow clones.
+func blameIgnoreRevs(ctx context.Context, repo gitserver.Repo, commitID api.CommitID, opt *BlameOptions) ([]string, error) {
+	revs := make([]string, 0, len(opt.IgnoreRevs))
+	for _, rev := range opt.IgnoreRevs {
+		if err := checkSpecArgSafety(rev); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
 	}
-	if len(out) == 0 {
+
+	const maxIgnoreRevsFileBytes = 1024 * 1024
+	b, err := ReadFile(ctx, repo, commitID, ignoreRevsFile, maxIgnoreRevsFileBytes)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "reading %s", ignoreRevsFile)
+	}
+	revs = append(revs, parseIgnoreRevs(string(b))...)
+	if len(revs) == 0 {
 		return nil, nil
 	}
 
-	commits := make(map[string]Commit)
-	hunks := make([]*Hunk, 0)
-	remainingLines := strings.Split(string(out[:len(out)-1]), "\n")
-	byteOffset := 0
-	for len(remainingLines) > 0 {
-		// Consume hunk
-		hunkHeader := strings.Split(remainingLines[0], " ")
-		if len(hunkHeader) != 4 {
-			return nil, fmt.Errorf("Expected at least 4 parts to hunkHeader, but got: '%s'", hunkHeader)
+	cmd := gitserver.DefaultClient.Command("git", append([]string{"rev-list", "--no-walk", "--ignore-missing"}, append(revs, "--")...)...)
+	cmd.Repo = repo
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// parseIgnoreRevs returns the commits listed in a .git-blame-ignore-revs
+// file, which has one commit per line and comments starting with "#".
+func parseIgnoreRevs(file string) []string {
+	var revs []string
+	for _, line := range strings.Split(file, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
 		}
-		commitID := hunkHeader[0]
-		lineNoCur, _ := strconv.Atoi(hunkHeader[2])
-		nLines, _ := strconv.Atoi(hunkHeader[3])
-		hunk := &Hunk{
-			CommitID:  api.CommitID(commitID),
-			StartLine: int(lineNoCur),
-			EndLine:   int(lineNoCur + nLines),
-			StartByte: byteOffset,
+		line = strings.TrimSpace(line)
+		// Anything that is not a commit ID could be interpreted as an
+		// argument.
+		if len(line) != 40 || checkSpecArgSafety(line) != nil {
+			continue
 		}
+		revs = append(revs, line)
+	}
+	return revs
+}
 
-		if _, in := commits[commitID]; in {
-			// Already seen commit
-			byteOffset += len(remainingLines[1])
-			remainingLines = remainingLines[2:]
-		} else {
-			// New commit
-			author := strings.Join(strings.Split(remainingLines[1], " ")[1:], " ")
-			email := strings.Join(strings.Split(remainingLines[2], " ")[1:], " ")
-			if len(email) >= 2 && email[0] == '<' && email[len(email)-1] == '>' {
-				email = email[1 : len(email)-1]
-			}
-			authorTime, err := strconv.ParseInt(strings.Join(strings.Split(remainingLines[3], " ")[1:], " "), 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to parse author-time %q", remainingLines[3])
-			}
-			summary := strings.Join(strings.Split(remainingLines[9], " ")[1:], " ")
-			commit := Commit{
-				ID:      api.CommitID(commitID),
-				Message: summary,
-				Author: Signature{
-					Name:  author,
-					Email: email,
-					Date:  time.Unix(authorTime, 0).UTC(),
-				},
-			}
+// lineOffsets are the byte offsets of the lines of a file.
+type lineOffsets struct {
+	starts []int // the offset of each line, in order
+	size   int   // the size of the file
+}
 
-			if len(remainingLines) >= 13 && strings.HasPrefix(remainingLines[10], "previous ") {
-				byteOffset += len(remainingLines[12])
-				remainingLines = remainingLines[13:]
-			} else if len(remainingLines) >= 13 && remainingLines[10] == "boundary" {
-				byteOffset += len(remainingLines[12])
-				remainingLines = remainingLines[13:]
-			} else if len(remainingLines) >= 12 {
-				byteOffset += len(remainingLines[11])
-				remainingLines = remainingLines[12:]
-			} else if len(remainingLines) == 11 {
-				// Empty file
-				remainingLines = remainingLines[11:]
-			} else {
-				return nil, fmt.Errorf("Unexpected number of remaining lines (%d):\n%s", len(remainingLines), "  "+strings.Join(remainingLines, "\n  "))
-			}
+// blameLineOffsets returns the line offsets of the file at path in commitID,
+// as blamed by git blame. Git LFS pointer files are not resolved, since git
+// blame blames the pointers.
+func blameLineOffsets(ctx context.Context, repo gitserver.Repo, commitID api.CommitID, path string) (lineOffsets, error) {
+	br, err := newBlobReader(ctx, repo, commitID, path)
+	if err != nil {
+		return lineOffsets{}, err
+	}
+	defer br.Close()
+	content, err := ioutil.ReadAll(br)
+	if err != nil {
+		return lineOffsets{}, err
+	}
 
-			commits[commitID] = commit
+	o := lineOffsets{starts: []int{0}, size: len(content)}
+	for i, c := range content {
+		if c == '\n' && i+1 < len(content) {
+			o.starts = append(o.starts, i+1)
 		}
+	}
+	return o, nil
+}
 
-		if commit, present := commits[commitID]; present {
-			// Should always be present, but check just to avoid
-			// panicking in case of a (somewhat likely) bug in our
-			// git-blame parser above.
-			hunk.CommitID = commit.ID
-			hunk.Author = commit.Author
-			hunk.Message = commit.Message
-		}
+// offset returns the offset of the 1-indexed line, or the size of the file
+// if the file has fewer lines.
+func (o lineOffsets) offset(line int) int {
+	if line-1 < len(o.starts) {
+		return o.starts[line-1]
+	}
+	return o.size
+}
+
+// blameParser parses the output of git blame --incremental one hunk at a
+// time.
+type blameParser struct {
+	r *bufio.Reader
+
+	// commits are the commits seen so far. git blame only outputs the
+	// details of a commit the first time it blames a hunk on it.
+	commits map[string]*Commit
+
+	lineOffsets lineOffsets
+
+	// baseOffset is subtracted from the byte offsets of hunks.
+	baseOffset int
+}
+
+func newBlameParser(r io.Reader, lineOffsets lineOffsets, baseOffset int) *blameParser {
+	return &blameParser{
+		r:           bufio.NewReader(r),
+		commits:     make(map[string]*Commit),
+		lineOffsets: lineOffsets,
+		baseOffset:  baseOffset,
+	}
+}
+
+// next returns the next hunk, or io.EOF after the last hunk. It only reads
+// the output of the hunk, so that hunks are returned as soon as git blame
+// outputs them.
+//
+// Every hunk starts with the line "<commit> <original line> <line> <number
+// of lines>", which is followed by the details of its commit if this is the
+// first hunk blamed on the commit, and ends with a "filename" line.
+func (p *blameParser) next() (*Hunk, error) {
+	header, err := p.readOutputLine()
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(header, " ")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("expected 4 fields in hunk header, got %q", header)
+	}
+	line, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid hunk header %q", header)
+	}
+	lines, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid hunk header %q", header)
+	}
+
+	commitID := fields[0]
+	commit, ok := p.commits[commitID]
+	if !ok {
+		commit = &Commit{ID: api.CommitID(commitID)}
+		p.commits[commitID] = commit
+	}
 
-		// Consume remaining lines in hunk
-		for i := 1; i < nLines; i++ {
-			byteOffset += len(remainingLines[1])
-			remainingLines = remainingLines[2:]
+	for {
+		l, err := p.readOutputLine()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
 		}
 
-		hunk.EndByte = byteOffset
-		hunks = append(hunks, hunk)
+		key, value := l, ""
+		if i := strings.Index(l, " "); i >= 0 {
+			key, value = l[:i], l[i+1:]
+		}
+		switch key {
+		case "author":
+			commit.Author.Name = value
+		case "author-mail":
+			if len(value) >= 2 && value[0] == '<' && value[len(value)-1] == '>' {
+				value = value[1 : len(value)-1]
+			}
+			commit.Author.Email = value
+		case "author-time":
+			authorTime, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse author-time %q", value)
+			}
+			commit.Author.Date = time.Unix(authorTime, 0).UTC()
+		case "summary":
+			commit.Message = value
+		case "filename":
+			return &Hunk{
+				CommitID:  commit.ID,
+				StartLine: line,
+				EndLine:   line + lines,
+				StartByte: p.lineOffsets.offset(line) - p.baseOffset,
+				EndByte:   p.lineOffsets.offset(line+lines) - p.baseOffset,
+				Author:    commit.Author,
+				Message:   commit.Message,
+			}, nil
+		}
 	}
+}
 
-	return hunks, nil
+// readOutputLine returns the next line of output without its newline, or
+// io.EOF at the end of the output.
+func (p *blameParser) readOutputLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
 }
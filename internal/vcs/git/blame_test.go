@@ -1,10 +1,12 @@
 package git
 
 import (
+	"io"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 )
 
@@ -65,3 +67,147 @@ func TestRepository_BlameFile(t *testing.T) {
 		}
 	}
 }
+
+func TestRepository_BlameFile_options(t *testing.T) {
+	t.Parallel()
+
+	commit := func(msg string) string {
+		return "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m " + msg + " --author='a <a@a.com>' --date 2006-01-02T15:04:05Z"
+	}
+	repo := MakeGitRepository(t,
+		"printf 'first line of the file\\nsecond line of the file\\nthird line of the file\\nthe last line, which is moved to the top\\n' > f",
+		"git add f",
+		commit("add"),
+		"printf 'FIRST line of the file\\nsecond line of the file\\nthird line of the file\\nthe last line, which is moved to the top\\n' > f",
+		"git add f",
+		commit("reformat"),
+		"printf 'the last line, which is moved to the top\\nFIRST line of the file\\nsecond line of the file\\nthird line of the file\\n' > f",
+		"git add f",
+		commit("move"),
+		"git branch before-ignore-revs-file",
+		"(echo '# Reformatting'; git rev-parse HEAD~1; echo; echo 0000000000000000000000000000000000000000) > .git-blame-ignore-revs",
+		"git add .git-blame-ignore-revs",
+		commit("ignore"),
+	)
+	resolve := func(rev string) api.CommitID {
+		t.Helper()
+		commitID, err := ResolveRevision(ctx, repo, nil, rev, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commitID
+	}
+	add, reformat, move := resolve("HEAD~3"), resolve("HEAD~2"), resolve("HEAD~1")
+
+	tests := map[string]struct {
+		opt  *BlameOptions
+		want []api.CommitID // the commit of each line
+	}{
+		"default": {
+			opt:  &BlameOptions{NewestCommit: resolve("before-ignore-revs-file")},
+			want: []api.CommitID{move, reformat, add, add},
+		},
+		"detect moves": {
+			opt:  &BlameOptions{NewestCommit: resolve("before-ignore-revs-file"), DetectMoves: true},
+			want: []api.CommitID{add, reformat, add, add},
+		},
+		"ignore revs": {
+			opt:  &BlameOptions{NewestCommit: resolve("before-ignore-revs-file"), DetectMoves: true, IgnoreRevs: []string{string(reformat)}},
+			want: []api.CommitID{add, add, add, add},
+		},
+		"ignore revs file": {
+			opt:  &BlameOptions{NewestCommit: resolve("HEAD"), DetectMoves: true},
+			want: []api.CommitID{add, add, add, add},
+		},
+	}
+	for label, test := range tests {
+		hunks, err := BlameFile(ctx, repo, "f", test.opt)
+		if err != nil {
+			t.Errorf("%s: BlameFile: %s", label, err)
+			continue
+		}
+		var got []api.CommitID
+		for _, hunk := range hunks {
+			for line := hunk.StartLine; line < hunk.EndLine; line++ {
+				got = append(got, hunk.CommitID)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got commits %v, want %v", label, got, test.want)
+		}
+	}
+
+	if _, err := BlameFile(ctx, repo, "f", &BlameOptions{IgnoreRevs: []string{"--output=x"}}); err == nil {
+		t.Error("want error for unsafe ignored rev")
+	}
+}
+
+func TestParseIgnoreRevs(t *testing.T) {
+	got := parseIgnoreRevs("# comment\n e6093374dcf5725d8517db0dccbbf69df65dbde0 # reformat\n\nHEAD\n-fad406f4fe02c358a09df0d03ec7a36c2c8a20f\n")
+	if want := []string{"e6093374dcf5725d8517db0dccbbf69df65dbde0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBlameParser_streaming(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	offsets := lineOffsets{starts: []int{0, 6, 12}, size: 18}
+	p := newBlameParser(r, offsets, 0)
+
+	go func() {
+		// Write the first hunk, but do not close w, like a git blame
+		// that is still blaming the rest of the file.
+		_, _ = io.WriteString(w, `e6093374dcf5725d8517db0dccbbf69df65dbde0 2 2 2
+author a
+author-mail <a@example.com>
+author-time 1563812288
+author-tz +0000
+committer a
+committer-mail <a@example.com>
+committer-time 1563812288
+committer-tz +0000
+summary second
+previous fad406f4fe02c358a09df0d03ec7a36c2c8a20f1 f
+filename f
+`)
+	}()
+
+	hunk, err := p.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Hunk{
+		StartLine: 2,
+		EndLine:   4,
+		StartByte: 6,
+		EndByte:   18,
+		CommitID:  "e6093374dcf5725d8517db0dccbbf69df65dbde0",
+		Author: Signature{
+			Name:  "a",
+			Email: "a@example.com",
+			Date:  time.Unix(1563812288, 0).UTC(),
+		},
+		Message: "second",
+	}
+	if !reflect.DeepEqual(hunk, want) {
+		t.Errorf("got hunk %+v, want %+v", hunk, want)
+	}
+
+	// The details of a commit are only output for its first hunk.
+	go func() {
+		_, _ = io.WriteString(w, "e6093374dcf5725d8517db0dccbbf69df65dbde0 1 1 1\nfilename f\n")
+		w.Close()
+	}()
+	hunk, err = p.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.StartLine, want.EndLine, want.StartByte, want.EndByte = 1, 2, 0, 6
+	if !reflect.DeepEqual(hunk, want) {
+		t.Errorf("got hunk %+v, want %+v", hunk, want)
+	}
+	if _, err := p.next(); err != io.EOF {
+		t.Errorf("got error %v, want io.EOF", err)
+	}
+}
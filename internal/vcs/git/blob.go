@@ -1,6 +1,7 @@
 package git
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/lfs"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/util"
 )
 
@@ -54,7 +56,7 @@ func NewFileReader(ctx context.Context, repo gitserver.Repo, commit api.CommitID
 	if err != nil {
 		return nil, errors.Wrapf(err, "getting blobReader for %q", name)
 	}
-	return br, nil
+	return resolveLFSPointer(ctx, repo, br)
 }
 
 func readFileBytes(ctx context.Context, repo gitserver.Repo, commit api.CommitID, name string, maxBytes int64) ([]byte, error) {
@@ -62,9 +64,13 @@ func readFileBytes(ctx context.Context, repo gitserver.Repo, commit api.CommitID
 	if err != nil {
 		return nil, err
 	}
-	defer br.Close()
+	rc, err := resolveLFSPointer(ctx, repo, br)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
 
-	r := io.Reader(br)
+	r := io.Reader(rc)
 	if maxBytes > 0 {
 		r = io.LimitReader(r, maxBytes)
 	}
@@ -75,6 +81,35 @@ func readFileBytes(ctx context.Context, repo gitserver.Repo, commit api.CommitID
 	return data, nil
 }
 
+// resolveLFSPointer returns a reader of the Git LFS object if rc reads a
+// Git LFS pointer file and gitserver stores the object. Otherwise it returns
+// a reader of the content of rc. rc is closed when the returned reader is
+// closed, or before an error is returned, so callers must not close it.
+func resolveLFSPointer(ctx context.Context, repo gitserver.Repo, rc io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(rc, lfs.MaxPointerSize+1)
+	// Errors are returned by Read after the peeked content.
+	b, _ := br.Peek(lfs.MaxPointerSize + 1)
+	pointer, ok := lfs.ParsePointer(b)
+	if !ok {
+		return &readCloser{Reader: br, Closer: rc}, nil
+	}
+
+	object, err := gitserver.DefaultClient.LFSObject(ctx, repo.Name, pointer.OID)
+	if os.IsNotExist(err) {
+		return &readCloser{Reader: br, Closer: rc}, nil
+	}
+	rc.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting LFS object %s", pointer.OID)
+	}
+	return object, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // blobReader, which should be created using newBlobReader, is a struct that allows
 // us to get a ReadCloser to a specific named file at a specific commit
 type blobReader struct {
@@ -2,9 +2,19 @@ package git
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/lfs"
 )
 
 func TestRead(t *testing.T) {
@@ -76,3 +86,111 @@ func TestRead(t *testing.T) {
 		}
 	})
 }
+
+func TestRead_lfs(t *testing.T) {
+	t.Parallel()
+
+	const content = "stored with LFS\n"
+	h := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(h[:])
+	pointer := fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, len(content))
+	notStoredPointer := fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize 1\n", strings.Repeat("0", 64))
+	repo := MakeGitRepository(t,
+		fmt.Sprintf("printf %q > stored", pointer),
+		fmt.Sprintf("printf %q > notstored", notStoredPointer),
+		"git add stored notstored",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com git commit -m commit1 --author='a <a@a.com>'",
+	)
+
+	// gitserver stores the objects it fetched from the code host.
+	path := lfs.ObjectPath(filepath.Join(root, "repos", string(repo.Name), ".git", "lfs", "objects"), oid)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	commitID, err := ResolveRevision(ctx, repo, nil, "HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"stored":    content,
+		"notstored": notStoredPointer,
+	} {
+		data, err := ReadFile(ctx, repo, commitID, name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("ReadFile %s: got %q, want %q", name, data, want)
+		}
+
+		rc, err := NewFileReader(ctx, repo, commitID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("NewFileReader %s: got %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestRead_partialClone(t *testing.T) {
+	t.Parallel()
+
+	src := InitGitRepository(t,
+		"git config uploadpack.allowFilter true",
+		"echo -n partial > file",
+		"git add file",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com git commit -m commit1 --author='a <a@a.com>'",
+	)
+	repo := gitserver.Repo{Name: api.RepoName(filepath.Base(src)), URL: "file://" + src}
+	if _, err := gitserver.DefaultClient.RequestRepoUpdate(context.Background(), repo, 0, gitserver.RepoUpdateOptions{CloneFilter: "blob:none"}); err != nil {
+		t.Fatal(err)
+	}
+
+	missingObjects := func() string {
+		t.Helper()
+		cmd := exec.Command("git", "rev-list", "--objects", "--missing=print", "HEAD")
+		cmd.Dir = filepath.Join(root, "repos", string(repo.Name), ".git")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		var missing []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(line, "?") {
+				missing = append(missing, line)
+			}
+		}
+		return strings.Join(missing, "\n")
+	}
+	if missingObjects() == "" {
+		t.Fatal("want file contents to be missing from partial clone")
+	}
+
+	// The file contents are fetched when they are read.
+	ctx := context.Background()
+	commitID, err := ResolveRevision(ctx, repo, nil, "HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ReadFile(ctx, repo, commitID, "file", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "partial" {
+		t.Errorf("got %q, want %q", data, "partial")
+	}
+	if missing := missingObjects(); missing != "" {
+		t.Errorf("got missing objects %s, want none", missing)
+	}
+}
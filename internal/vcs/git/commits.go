@@ -129,6 +129,35 @@ func HasCommitAfter(ctx context.Context, repo gitserver.Repo, date string, revsp
 	return n > 0, err
 }
 
+// LastCommitBefore returns the ID of the last commit reachable from revspec
+// that was committed before date (e.g. "2019-06-01" or "6 months ago"). It
+// returns a *gitserver.RevisionNotFoundError if there is no such commit.
+func LastCommitBefore(ctx context.Context, repo gitserver.Repo, date string, revspec string) (api.CommitID, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: LastCommitBefore")
+	span.SetTag("Date", date)
+	span.SetTag("RevSpec", revspec)
+	defer span.Finish()
+
+	if revspec == "" {
+		revspec = "HEAD"
+	}
+	if err := checkSpecArgSafety(revspec); err != nil {
+		return "", err
+	}
+
+	cmd := gitserver.DefaultClient.Command("git", "rev-list", "--max-count=1", "--before="+date, revspec, "--")
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		return "", errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+	commitID := api.CommitID(bytes.TrimSpace(out))
+	if commitID == "" {
+		return "", &gitserver.RevisionNotFoundError{Repo: repo.Name, Spec: fmt.Sprintf("%s before %s", revspec, date)}
+	}
+	return commitID, nil
+}
+
 func isBadObjectErr(output, obj string) bool {
 	return string(output) == "fatal: bad object "+obj
 }
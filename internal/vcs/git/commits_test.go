@@ -131,6 +131,48 @@ func TestRepository_HasCommitAfter(t *testing.T) {
 	}
 }
 
+func TestRepository_LastCommitBefore(t *testing.T) {
+	t.Parallel()
+
+	gitCommands := []string{
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --allow-empty -m foo --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2007-01-02T15:04:05Z git commit --allow-empty -m bar --author='a <a@a.com>' --date 2007-01-02T15:04:05Z",
+	}
+	repo := MakeGitRepository(t, gitCommands...)
+
+	first, err := ResolveRevision(ctx, repo, nil, "HEAD~1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ResolveRevision(ctx, repo, nil, "HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		date    string
+		revspec string
+		want    api.CommitID
+	}{
+		{date: "2006-06-01", revspec: "", want: first},
+		{date: "2008-01-01", revspec: "master", want: second},
+		{date: "2008-01-01", revspec: "HEAD~1", want: first},
+	}
+	for _, tc := range testCases {
+		got, err := LastCommitBefore(ctx, repo, tc.date, tc.revspec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("LastCommitBefore(%q, %q): got %s, want %s", tc.date, tc.revspec, got, tc.want)
+		}
+	}
+
+	if _, err := LastCommitBefore(ctx, repo, "2005-01-01", ""); !gitserver.IsRevisionNotFound(err) {
+		t.Errorf("got err %v, want a revision not found error", err)
+	}
+}
+
 func TestRepository_Commits(t *testing.T) {
 	t.Parallel()
 
@@ -175,23 +175,6 @@ func isWhitelistedGitCmd(args []string) bool {
 	return true
 }
 
-func gitserverCmdFunc(repo gitserver.Repo) cmdFunc {
-	return func(args []string) cmd {
-		cmd := gitserver.DefaultClient.Command("git", args...)
-		cmd.Repo = gitserver.Repo(repo)
-		return cmd
-	}
-}
-
-// cmdFunc is a func that creates a new executable Git command.
-type cmdFunc func(args []string) cmd
-
-// cmd is an executable Git command.
-type cmd interface {
-	Output(context.Context) ([]byte, error)
-	String() string
-}
-
 // commandRetryer executes a gitserver command first without a remote URL and
 // ensured revision, then secondarily retries with a remote URL and ensured
 // revision.
@@ -0,0 +1,69 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// isShallowCache caches whether repositories are shallow clones. It is
+// checked for every repository in every commit search, but it only changes
+// when a repository is recloned with another clone strategy, so it is fine
+// for it to be out of date for isShallowCacheTTL.
+var (
+	isShallowCacheMu sync.Mutex
+	isShallowCache   = lru.New(10000)
+)
+
+const isShallowCacheTTL = 10 * time.Minute
+
+type isShallowCacheEntry struct {
+	shallow bool
+	expires time.Time
+}
+
+// IsShallow returns true if the repository is a shallow clone, which means
+// that its history is truncated.
+func IsShallow(ctx context.Context, repo gitserver.Repo) (bool, error) {
+	if Mocks.IsShallow != nil {
+		return Mocks.IsShallow(repo)
+	}
+
+	isShallowCacheMu.Lock()
+	v, ok := isShallowCache.Get(repo.Name)
+	isShallowCacheMu.Unlock()
+	if ok {
+		if e := v.(isShallowCacheEntry); time.Now().Before(e.expires) {
+			return e.shallow, nil
+		}
+	}
+
+	shallow, err := isShallowUncached(ctx, repo)
+	if err != nil {
+		return false, err
+	}
+	isShallowCacheMu.Lock()
+	isShallowCache.Add(repo.Name, isShallowCacheEntry{shallow: shallow, expires: time.Now().Add(isShallowCacheTTL)})
+	isShallowCacheMu.Unlock()
+	return shallow, nil
+}
+
+func isShallowUncached(ctx context.Context, repo gitserver.Repo) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: IsShallow")
+	defer span.Finish()
+
+	cmd := gitserver.DefaultClient.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		return false, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+	return string(bytes.TrimSpace(out)) == "true", nil
+}
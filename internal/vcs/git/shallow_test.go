@@ -0,0 +1,48 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+func TestIsShallow(t *testing.T) {
+	t.Parallel()
+
+	src := InitGitRepository(t,
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com git commit --allow-empty -m commit1 --author='a <a@a.com>'",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com git commit --allow-empty -m commit2 --author='a <a@a.com>'",
+	)
+	for depth, want := range map[int]bool{0: false, 1: true} {
+		// Shallow clones from local paths are not supported, so clone using
+		// a file URL.
+		repo := gitserver.Repo{Name: api.RepoName(fmt.Sprintf("%s-%d", filepath.Base(src), depth)), URL: "file://" + src}
+		if _, err := gitserver.DefaultClient.RequestRepoUpdate(context.Background(), repo, 0, gitserver.RepoUpdateOptions{CloneDepth: depth}); err != nil {
+			t.Fatal(err)
+		}
+
+		shallow, err := IsShallow(context.Background(), repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shallow != want {
+			t.Errorf("depth %d: got shallow %v, want %v", depth, shallow, want)
+		}
+
+		// The result is cached, so gitserver is not asked again.
+		if err := gitserver.DefaultClient.Remove(context.Background(), repo.Name); err != nil {
+			t.Fatal(err)
+		}
+		shallow, err = IsShallow(context.Background(), repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shallow != want {
+			t.Errorf("depth %d, cached: got shallow %v, want %v", depth, shallow, want)
+		}
+	}
+}
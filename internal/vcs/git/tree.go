@@ -121,6 +121,116 @@ func ReadDir(ctx context.Context, repo gitserver.Repo, commit api.CommitID, path
 	return lsTree(ctx, repo, commit, path, recurse)
 }
 
+// ListFiles returns the paths of all files in the tree of commit. Unlike a
+// recursive ReadDir, it returns neither directories nor submodules, and it
+// does not compute the size of every file, so it is much cheaper for large
+// repositories.
+func ListFiles(ctx context.Context, repo gitserver.Repo, commit api.CommitID) ([]string, error) {
+	if Mocks.ListFiles != nil {
+		return Mocks.ListFiles(commit)
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: ListFiles")
+	span.SetTag("Commit", commit)
+	defer span.Finish()
+
+	if err := ensureAbsoluteCommit(commit); err != nil {
+		return nil, err
+	}
+
+	// With -z, paths are not quoted, so they may contain newlines.
+	cmd := gitserver.DefaultClient.Command("git", "ls-tree", "-r", "-z", "--full-name", string(commit))
+	cmd.Repo = repo
+	out, stderr, err := cmd.DividedOutput(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (stderr: %q)", cmd.Args, stderr))
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+		// Each line is "<mode> <type> <object>\t<path>".
+		tabPos := strings.IndexByte(line, '\t')
+		if tabPos == -1 {
+			return nil, fmt.Errorf("invalid `git ls-tree` output: %q", line)
+		}
+		if info := strings.SplitN(line[:tabPos], " ", 3); len(info) == 3 && info[1] == "blob" {
+			paths = append(paths, line[tabPos+1:])
+		}
+	}
+	return paths, nil
+}
+
+// ListSubmodules returns the submodules in the tree of commit, with the URLs
+// from the .gitmodules file at commit. If there is no .gitmodules file, it
+// returns no submodules. Submodules that are not listed in .gitmodules have an
+// empty URL.
+func ListSubmodules(ctx context.Context, repo gitserver.Repo, commit api.CommitID) ([]Submodule, error) {
+	if Mocks.ListSubmodules != nil {
+		return Mocks.ListSubmodules(commit)
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: ListSubmodules")
+	span.SetTag("Commit", commit)
+	defer span.Finish()
+
+	if err := ensureAbsoluteCommit(commit); err != nil {
+		return nil, err
+	}
+
+	// Submodules are added with a .gitmodules file, so most repositories
+	// without one have no submodules. Check for it first to avoid listing the
+	// whole tree of every repository.
+	b, err := ReadFile(ctx, repo, commit, ".gitmodules", 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cfg config.Config
+	if err := config.NewDecoder(bytes.NewReader(b)).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error parsing .gitmodules: %s", err)
+	}
+
+	cmd := gitserver.DefaultClient.Command("git", "ls-tree", "-r", "-z", "--full-name", string(commit))
+	cmd.Repo = repo
+	out, stderr, err := cmd.DividedOutput(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (stderr: %q)", cmd.Args, stderr))
+	}
+
+	var submodules []Submodule
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+		// Each line is "<mode> <type> <object>\t<path>".
+		tabPos := strings.IndexByte(line, '\t')
+		if tabPos == -1 {
+			return nil, fmt.Errorf("invalid `git ls-tree` output: %q", line)
+		}
+		if info := strings.SplitN(line[:tabPos], " ", 3); len(info) == 3 && info[1] == "commit" {
+			submodules = append(submodules, Submodule{Path: line[tabPos+1:], CommitID: api.CommitID(info[2])})
+		}
+	}
+	if len(submodules) == 0 {
+		return nil, nil
+	}
+
+	// The name of a submodule is usually, but not always, its path.
+	urls := map[string]string{}
+	for _, s := range cfg.Section("submodule").Subsections {
+		urls[s.Option("path")] = s.Option("url")
+	}
+	for i := range submodules {
+		submodules[i].URL = urls[submodules[i].Path]
+	}
+	return submodules, nil
+}
+
 // lsTreeRootCache caches the result of running `git ls-tree ...` on a repository's root path
 // (because non-root paths are likely to have a lower cache hit rate). It is intended to improve the
 // perceived performance of large monorepos, where the tree for a given repo+commit (usually the
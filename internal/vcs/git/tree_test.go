@@ -442,3 +442,88 @@ func TestRepository_FileSystem_gitSubmodules(t *testing.T) {
 		}
 	}
 }
+
+func TestRepository_ListFiles(t *testing.T) {
+	t.Parallel()
+
+	gitCommands := []string{
+		"mkdir -p dir1/dir2",
+		"echo -n a > dir1/dir2/a",
+		"echo -n b > 'file 2'",
+		"ln -s 'file 2' link",
+		`touch "$(printf 'new\nline')"`,
+		"git add -A",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m commit1 --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	}
+	repo := MakeGitRepository(t, gitCommands...)
+	commitID := api.CommitID(ComputeCommitHash(repo.URL, true))
+
+	paths, err := ListFiles(ctx, repo, commitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dir1/dir2/a", "file 2", "link", "new\nline"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %q, want %q", paths, want)
+	}
+
+	if _, err := ListFiles(ctx, repo, "HEAD"); err == nil {
+		t.Error("got no error for a non-absolute commit ID")
+	}
+}
+
+func TestListSubmodules(t *testing.T) {
+	t.Parallel()
+
+	submodDir := InitGitRepository(t,
+		"touch f",
+		"git add f",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m commit1 --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	const submodCommit = "94aa9078934ce2776ccbb589569eca5ef575f12e"
+
+	repo := MakeGitRepository(t,
+		"git -c protocol.file.allow=always submodule add --name other "+filepath.ToSlash(submodDir)+" a/submod",
+		"git update-index --add --cacheinfo 160000,"+submodCommit+",unlisted",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m 'add submodule' --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	commitID, err := ResolveRevision(ctx, repo, nil, "master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submodules, err := ListSubmodules(ctx, repo, commitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Submodule{
+		{Path: "a/submod", URL: filepath.ToSlash(submodDir), CommitID: submodCommit},
+		{Path: "unlisted", CommitID: submodCommit},
+	}
+	if !reflect.DeepEqual(submodules, want) {
+		t.Errorf("got submodules %+v, want %+v", submodules, want)
+	}
+}
+
+func TestListSubmodules_noGitmodules(t *testing.T) {
+	t.Parallel()
+
+	// Without a .gitmodules file, the tree is not listed, so the gitlink is
+	// not found.
+	repo := MakeGitRepository(t,
+		"git update-index --add --cacheinfo 160000,94aa9078934ce2776ccbb589569eca5ef575f12e,unlisted",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m 'add gitlink' --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	commitID, err := ResolveRevision(ctx, repo, nil, "master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submodules, err := ListSubmodules(ctx, repo, commitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submodules != nil {
+		t.Errorf("got submodules %+v, want none", submodules)
+	}
+}
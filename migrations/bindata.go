@@ -78,6 +78,8 @@
 // 1528395654_add_external_updated_at_to_changesets.up.sql (224B)
 // 1528395655_repo_drop_enabled.down.sql (84B)
 // 1528395655_repo_drop_enabled.up.sql (65B)
+// 1528395656_query_runner_state_result_matches.down.sql (86B)
+// 1528395656_query_runner_state_result_matches.up.sql (95B)
 
 package migrations
 
@@ -1706,6 +1708,46 @@ func _1528395655_repo_drop_enabledUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395656_query_runner_state_result_matchesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x2c\x4d\x2d\xaa\x8c\x2f\x2a\xcd\xcb\x4b\x2d\x8a\x2f\x2e\x49\x2c\x49\x55\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4a\x2d\x2e\xcd\x29\x89\xcf\x4d\x2c\x49\xce\x48\x2d\x06\x9a\xe2\xec\xef\xeb\xeb\x19\x62\xcd\x05\x00\x02\xe1\x28\xe7\x56\x00\x00\x00")
+
+func _1528395656_query_runner_state_result_matchesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395656_query_runner_state_result_matchesDownSql,
+		"1528395656_query_runner_state_result_matches.down.sql",
+	)
+}
+
+func _1528395656_query_runner_state_result_matchesDownSql() (*asset, error) {
+	bytes, err := _1528395656_query_runner_state_result_matchesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395656_query_runner_state_result_matches.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x87, 0x8b, 0xa5, 0xb1, 0xef, 0xeb, 0xe1, 0x98, 0x90, 0x0e, 0x80, 0xcc, 0x11, 0x06, 0x07, 0x9f, 0xdb, 0x49, 0xf3, 0xfd, 0xcc, 0x51, 0x82, 0xdd, 0xb9, 0xdb, 0xa6, 0x15, 0x0b, 0xc6, 0xf8, 0x9f}}
+	return a, nil
+}
+
+var __1528395656_query_runner_state_result_matchesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x2c\x4d\x2d\xaa\x8c\x2f\x2a\xcd\xcb\x4b\x2d\x8a\x2f\x2e\x49\x2c\x49\x55\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\xf0\xf3\x0f\x51\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4a\x2d\x2e\xcd\x29\x89\xcf\x4d\x2c\x49\xce\x48\x2d\x56\xc8\x2a\xce\xcf\x4b\x02\x1a\xe7\xec\xef\xeb\xeb\x19\x62\xcd\x05\x00\x73\xec\x16\x21\x5f\x00\x00\x00")
+
+func _1528395656_query_runner_state_result_matchesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395656_query_runner_state_result_matchesUpSql,
+		"1528395656_query_runner_state_result_matches.up.sql",
+	)
+}
+
+func _1528395656_query_runner_state_result_matchesUpSql() (*asset, error) {
+	bytes, err := _1528395656_query_runner_state_result_matchesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395656_query_runner_state_result_matches.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xce, 0x1c, 0x2e, 0x24, 0x4f, 0x66, 0xc2, 0x25, 0x6a, 0x77, 0x81, 0xf8, 0xd7, 0x0d, 0x10, 0x42, 0xf9, 0x39, 0x2a, 0x64, 0xc0, 0xee, 0xf2, 0x5f, 0xd3, 0xb1, 0x72, 0x56, 0x32, 0xd9, 0x3b, 0x7c}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -1875,6 +1917,8 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395654_add_external_updated_at_to_changesets.up.sql":          _1528395654_add_external_updated_at_to_changesetsUpSql,
 	"1528395655_repo_drop_enabled.down.sql":                            _1528395655_repo_drop_enabledDownSql,
 	"1528395655_repo_drop_enabled.up.sql":                              _1528395655_repo_drop_enabledUpSql,
+	"1528395656_query_runner_state_result_matches.down.sql":            _1528395656_query_runner_state_result_matchesDownSql,
+	"1528395656_query_runner_state_result_matches.up.sql":              _1528395656_query_runner_state_result_matchesUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -1996,6 +2040,8 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395654_add_external_updated_at_to_changesets.up.sql":          {_1528395654_add_external_updated_at_to_changesetsUpSql, map[string]*bintree{}},
 	"1528395655_repo_drop_enabled.down.sql":                            {_1528395655_repo_drop_enabledDownSql, map[string]*bintree{}},
 	"1528395655_repo_drop_enabled.up.sql":                              {_1528395655_repo_drop_enabledUpSql, map[string]*bintree{}},
+	"1528395656_query_runner_state_result_matches.down.sql":            {_1528395656_query_runner_state_result_matchesDownSql, map[string]*bintree{}},
+	"1528395656_query_runner_state_result_matches.up.sql":              {_1528395656_query_runner_state_result_matchesUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
@@ -58,6 +58,39 @@ const BitbucketServerSchemaJSON = `{
       "default": "http",
       "examples": ["ssh"]
     },
+    "gitLFS": {
+      "description": "Whether to fetch Git LFS objects of repositories on this Bitbucket Server instance, so that files stored with Git LFS show their content instead of LFS pointers. Only objects on the default branch are fetched, up to the size limit of searched files (see the search.largeFiles site configuration property). Requires \"gitURLType\" to be \"http\".",
+      "type": "boolean",
+      "default": false
+    },
+    "cloneStrategies": {
+      "description": "How to clone very large repositories on this Bitbucket Server instance, which would take too long to clone in full. The first strategy whose pattern matches a repository applies to it. Missing file contents of partial clones are fetched from Bitbucket Server when they are needed. Commit and diff searches only search the history of shallow clones, and report when it is truncated.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "title": "BitbucketServerCloneStrategy",
+        "additionalProperties": false,
+        "required": ["pattern"],
+        "properties": {
+          "pattern": {
+            "description": "Regular expression which matches the repository on Bitbucket Server (e.g., \"projectKey/repositorySlug\").",
+            "type": "string",
+            "format": "regex"
+          },
+          "filter": {
+            "description": "Partial clone filter passed to git clone --filter. \"blob:none\" clones all commits and trees, but no file contents.",
+            "type": "string",
+            "pattern": "^(blob:none|blob:limit=[0-9]+[kmg]?|tree:0)$"
+          },
+          "depth": {
+            "description": "Only clone this many of the most recent commits of each branch and tag.",
+            "type": "integer",
+            "minimum": 1
+          }
+        }
+      },
+      "examples": [[{ "pattern": "^my-org/monorepo$", "filter": "blob:none" }], [{ "pattern": "^my-org/", "depth": 1000 }]]
+    },
     "certificate": {
       "description": "TLS certificate of the Bitbucket Server instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run ` + "`" + `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM` + "`" + `. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.",
       "type": "string",
@@ -30,6 +30,39 @@ const GitHubSchemaJSON = `{
       "enum": ["http", "ssh"],
       "default": "http"
     },
+    "gitLFS": {
+      "description": "Whether to fetch Git LFS objects of repositories on this GitHub instance, so that files stored with Git LFS show their content instead of LFS pointers. Only objects on the default branch are fetched, up to the size limit of searched files (see the search.largeFiles site configuration property). Requires \"gitURLType\" to be \"http\".",
+      "type": "boolean",
+      "default": false
+    },
+    "cloneStrategies": {
+      "description": "How to clone very large repositories on this GitHub instance, which would take too long to clone in full. The first strategy whose pattern matches a repository applies to it. Missing file contents of partial clones are fetched from GitHub when they are needed. Commit and diff searches only search the history of shallow clones, and report when it is truncated.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "title": "GitHubCloneStrategy",
+        "additionalProperties": false,
+        "required": ["pattern"],
+        "properties": {
+          "pattern": {
+            "description": "Regular expression which matches the repository name on GitHub (e.g., \"owner/name\").",
+            "type": "string",
+            "format": "regex"
+          },
+          "filter": {
+            "description": "Partial clone filter passed to git clone --filter. \"blob:none\" clones all commits and trees, but no file contents.",
+            "type": "string",
+            "pattern": "^(blob:none|blob:limit=[0-9]+[kmg]?|tree:0)$"
+          },
+          "depth": {
+            "description": "Only clone this many of the most recent commits of each branch and tag.",
+            "type": "integer",
+            "minimum": 1
+          }
+        }
+      },
+      "examples": [[{ "pattern": "^my-org/monorepo$", "filter": "blob:none" }], [{ "pattern": "^my-org/", "depth": 1000 }]]
+    },
     "token": {
       "description": "A GitHub personal access token. Create one for GitHub.com at https://github.com/settings/tokens/new?scopes=repo&description=Sourcegraph (for GitHub Enterprise, replace github.com with your instance's hostname). The \"repo\" scope is required to mirror private repositories. If using only public repositories, you can create the token with no scopes.",
       "type": "string",
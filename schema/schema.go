@@ -158,6 +158,14 @@ type BitbucketServerAuthorization struct {
 	// If set to zero, Sourcegraph will sync a user's entire accessible repository list on every request (NOT recommended).
 	Ttl string `json:"ttl,omitempty"`
 }
+type BitbucketServerCloneStrategy struct {
+	// Depth description: Only clone this many of the most recent commits of each branch and tag.
+	Depth int `json:"depth,omitempty"`
+	// Filter description: Partial clone filter passed to git clone --filter. "blob:none" clones all commits and trees, but no file contents.
+	Filter string `json:"filter,omitempty"`
+	// Pattern description: Regular expression which matches the repository on Bitbucket Server (e.g., "projectKey/repositorySlug").
+	Pattern string `json:"pattern"`
+}
 
 // BitbucketServerConnection description: Configuration for a connection to Bitbucket Server.
 type BitbucketServerConnection struct {
@@ -165,12 +173,16 @@ type BitbucketServerConnection struct {
 	Authorization *BitbucketServerAuthorization `json:"authorization,omitempty"`
 	// Certificate description: TLS certificate of the Bitbucket Server instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
 	Certificate string `json:"certificate,omitempty"`
+	// CloneStrategies description: How to clone very large repositories on this Bitbucket Server instance, which would take too long to clone in full. The first strategy whose pattern matches a repository applies to it. Missing file contents of partial clones are fetched from Bitbucket Server when they are needed. Commit and diff searches only search the history of shallow clones, and report when it is truncated.
+	CloneStrategies []*BitbucketServerCloneStrategy `json:"cloneStrategies,omitempty"`
 	// Exclude description: A list of repositories to never mirror from this Bitbucket Server instance. Takes precedence over "repos" and "repositoryQuery".
 	//
 	// Supports excluding by name ({"name": "projectKey/repositorySlug"}) or by ID ({"id": 42}).
 	Exclude []*ExcludedBitbucketServerRepo `json:"exclude,omitempty"`
 	// ExcludePersonalRepositories description: Whether or not personal repositories should be excluded or not. When true, Sourcegraph will ignore personal repositories it may have access to. See https://docs.sourcegraph.com/integration/bitbucket_server#excluding-personal-repositories for more information.
 	ExcludePersonalRepositories bool `json:"excludePersonalRepositories,omitempty"`
+	// GitLFS description: Whether to fetch Git LFS objects of repositories on this Bitbucket Server instance, so that files stored with Git LFS show their content instead of LFS pointers. Only objects on the default branch are fetched, up to the size limit of searched files (see the search.largeFiles site configuration property). Requires "gitURLType" to be "http".
+	GitLFS bool `json:"gitLFS,omitempty"`
 	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on this Bitbucket Server instance.
 	//
 	// If "http", Sourcegraph will access Bitbucket Server repositories using Git URLs of the form http(s)://bitbucket.example.com/scm/myproject/myrepo.git (using https: if the Bitbucket Server instance uses HTTPS).
@@ -340,6 +352,12 @@ type ExcludedBitbucketServerRepo struct {
 	// Pattern description: Regular expression which matches against the name of a Bitbucket Server repo.
 	Pattern string `json:"pattern,omitempty"`
 }
+type ExcludedGerritProject struct {
+	// Name description: The name of a project ("platform/build") to exclude from mirroring.
+	Name string `json:"name,omitempty"`
+	// Pattern description: Regular expression which matches against the name of a project.
+	Pattern string `json:"pattern,omitempty"`
+}
 type ExcludedGitHubRepo struct {
 	// Id description: The node ID of a GitHub repository (as returned by the GitHub instance's API) to exclude from mirroring. Use this to exclude the repository, even if renamed. Note: This is the GraphQL ID, not the GitHub database ID. eg: "curl https://api.github.com/repos/vuejs/vue | jq .node_id"
 	Id string `json:"id,omitempty"`
@@ -354,6 +372,14 @@ type ExcludedGitLabProject struct {
 	// Name description: The name of a GitLab project ("group/name") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
 }
+type ExcludedGiteaRepo struct {
+	// Id description: The ID of a repository (as returned by the Gitea or Gogs API) to exclude from mirroring.
+	Id int `json:"id,omitempty"`
+	// Name description: The name of a repository ("myorg/myrepo") to exclude from mirroring.
+	Name string `json:"name,omitempty"`
+	// Pattern description: Regular expression which matches against the name of a repository.
+	Pattern string `json:"pattern,omitempty"`
+}
 type ExcludedGitoliteRepo struct {
 	// Name description: The name of a Gitolite repo ("my-repo") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
@@ -361,6 +387,8 @@ type ExcludedGitoliteRepo struct {
 
 // ExperimentalFeatures description: Experimental features to enable or disable. Features that are now enabled by default are marked as deprecated.
 type ExperimentalFeatures struct {
+	// AndOrQuery description: Enables the boolean operators AND, OR and NOT and parenthesized grouping of search patterns in search queries.
+	AndOrQuery string `json:"andOrQuery,omitempty"`
 	// Automation description: Enables the experimental code change management campaigns feature. NOTE: The automation feature was renamed to campaigns, but this experimental feature flag name was not changed (because the feature flag will go away soon anyway).
 	Automation string `json:"automation,omitempty"`
 	// BitbucketServerFastPerm description: DEPRECATED: Configure in Bitbucket Server config.
@@ -402,6 +430,40 @@ type ExternalIdentity struct {
 	Type           string `json:"type"`
 }
 
+// GerritConnection description: Configuration for a connection to Gerrit.
+type GerritConnection struct {
+	// Certificate description: TLS certificate of the Gerrit instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
+	Certificate string `json:"certificate,omitempty"`
+	// ChangeQuery description: A Gerrit change query selecting the changes whose current patch sets are fetched along with the branches and tags of the mirrored projects. This makes the changes searchable by revision, such as repo:^gerrit\.example\.com/platform/build$@refs/changes/45/12345/3. If unset, no changes are fetched.
+	//
+	// See the Gerrit documentation for the query operators: https://gerrit-review.googlesource.com/Documentation/user-search.html. Only the changes of mirrored projects are fetched.
+	ChangeQuery string `json:"changeQuery,omitempty"`
+	// Exclude description: A list of projects to never mirror from this Gerrit instance. Takes precedence over "projects" and "projectQuery" configuration.
+	//
+	// Supports excluding by name ({"name": "platform/build"}) or by a regular expression matching the name ({"pattern": "^archive/.*"}).
+	Exclude []*ExcludedGerritProject `json:"exclude,omitempty"`
+	// Password description: The HTTP password of the Gerrit account, which is generated on the "HTTP Credentials" page of the account settings in Gerrit. It is used for the REST API and for cloning over HTTP(S).
+	Password string `json:"password,omitempty"`
+	// ProjectQuery description: An array of Gerrit project queries. The projects that match any of the queries are mirrored on Sourcegraph.
+	//
+	// See the Gerrit documentation for the query operators: https://gerrit-review.googlesource.com/Documentation/user-search-projects.html. For example, "state:active" selects all active projects, and "inname:platform state:active" selects the active projects with platform in their name.
+	ProjectQuery []string `json:"projectQuery,omitempty"`
+	// Projects description: An array of names of projects to mirror on Sourcegraph.
+	Projects []string `json:"projects,omitempty"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for a Gerrit project.
+	//
+	//  - "{host}" is replaced with the Gerrit URL's host (such as gerrit.example.com), and "{name}" is replaced with the project's name (such as "platform/build").
+	//
+	// For example, if your Gerrit is https://gerrit.example.com and your Sourcegraph is https://src.example.com, then a repositoryPathPattern of "{host}/{name}" would mean that the project platform/build is available on Sourcegraph at https://src.example.com/gerrit.example.com/platform/build.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// Url description: URL of a Gerrit instance, such as https://gerrit.example.com.
+	Url string `json:"url"`
+	// Username description: The username of a Gerrit account. The projects that are visible to this account are mirrored on Sourcegraph. If unset, Sourcegraph accesses Gerrit anonymously and mirrors the projects that are visible to anonymous users.
+	Username string `json:"username,omitempty"`
+}
+
 // GitHubAuthProvider description: Configures the GitHub (or GitHub Enterprise) OAuth authentication provider for SSO. In addition to specifying this configuration object, you must also create a OAuth App on your GitHub instance: https://developer.github.com/apps/building-oauth-apps/creating-an-oauth-app/. When a user signs into Sourcegraph or links their GitHub account to their existing Sourcegraph account, GitHub will prompt the user for the repo scope.
 type GitHubAuthProvider struct {
 	// AllowOrgs description: Restricts new logins to members of these GitHub organizations. Existing sessions won't be invalidated. Leave empty or unset for no org restrictions.
@@ -429,6 +491,14 @@ type GitHubAuthorization struct {
 	// Public repositories are cached once for all users per cache TTL period.
 	Ttl string `json:"ttl,omitempty"`
 }
+type GitHubCloneStrategy struct {
+	// Depth description: Only clone this many of the most recent commits of each branch and tag.
+	Depth int `json:"depth,omitempty"`
+	// Filter description: Partial clone filter passed to git clone --filter. "blob:none" clones all commits and trees, but no file contents.
+	Filter string `json:"filter,omitempty"`
+	// Pattern description: Regular expression which matches the repository name on GitHub (e.g., "owner/name").
+	Pattern string `json:"pattern"`
+}
 
 // GitHubConnection description: Configuration for a connection to GitHub or GitHub Enterprise.
 type GitHubConnection struct {
@@ -436,12 +506,16 @@ type GitHubConnection struct {
 	Authorization *GitHubAuthorization `json:"authorization,omitempty"`
 	// Certificate description: TLS certificate of the GitHub Enterprise instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
 	Certificate string `json:"certificate,omitempty"`
+	// CloneStrategies description: How to clone very large repositories on this GitHub instance, which would take too long to clone in full. The first strategy whose pattern matches a repository applies to it. Missing file contents of partial clones are fetched from GitHub when they are needed. Commit and diff searches only search the history of shallow clones, and report when it is truncated.
+	CloneStrategies []*GitHubCloneStrategy `json:"cloneStrategies,omitempty"`
 	// Exclude description: A list of repositories to never mirror from this GitHub instance. Takes precedence over "orgs", "repos", and "repositoryQuery" configuration.
 	//
 	// Supports excluding by name ({"name": "owner/name"}) or by ID ({"id": "MDEwOlJlcG9zaXRvcnkxMTczMDM0Mg=="}).
 	//
 	// Note: ID is the GitHub GraphQL ID, not the GitHub database ID. eg: "curl https://api.github.com/repos/vuejs/vue | jq .node_id"
 	Exclude []*ExcludedGitHubRepo `json:"exclude,omitempty"`
+	// GitLFS description: Whether to fetch Git LFS objects of repositories on this GitHub instance, so that files stored with Git LFS show their content instead of LFS pointers. Only objects on the default branch are fetched, up to the size limit of searched files (see the search.largeFiles site configuration property). Requires "gitURLType" to be "http".
+	GitLFS bool `json:"gitLFS,omitempty"`
 	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on this GitHub instance.
 	//
 	// If "http", Sourcegraph will access GitHub repositories using Git URLs of the form http(s)://github.com/myteam/myproject.git (using https: if the GitHub instance uses HTTPS).
@@ -516,6 +590,14 @@ type GitLabAuthorization struct {
 	// Public and internal repositories are cached once for all users per cache TTL period.
 	Ttl string `json:"ttl,omitempty"`
 }
+type GitLabCloneStrategy struct {
+	// Depth description: Only clone this many of the most recent commits of each branch and tag.
+	Depth int `json:"depth,omitempty"`
+	// Filter description: Partial clone filter passed to git clone --filter. "blob:none" clones all commits and trees, but no file contents.
+	Filter string `json:"filter,omitempty"`
+	// Pattern description: Regular expression which matches the project path on GitLab (e.g., "group/name").
+	Pattern string `json:"pattern"`
+}
 
 // GitLabConnection description: Configuration for a connection to GitLab (GitLab.com or GitLab self-managed).
 type GitLabConnection struct {
@@ -523,8 +605,12 @@ type GitLabConnection struct {
 	Authorization *GitLabAuthorization `json:"authorization,omitempty"`
 	// Certificate description: TLS certificate of the GitLab instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
 	Certificate string `json:"certificate,omitempty"`
+	// CloneStrategies description: How to clone very large repositories on this GitLab instance, which would take too long to clone in full. The first strategy whose pattern matches a repository applies to it. Missing file contents of partial clones are fetched from GitLab when they are needed. Commit and diff searches only search the history of shallow clones, and report when it is truncated.
+	CloneStrategies []*GitLabCloneStrategy `json:"cloneStrategies,omitempty"`
 	// Exclude description: A list of projects to never mirror from this GitLab instance. Takes precedence over "projects" and "projectQuery" configuration. Supports excluding by name ({"name": "group/name"}) or by ID ({"id": 42}).
 	Exclude []*ExcludedGitLabProject `json:"exclude,omitempty"`
+	// GitLFS description: Whether to fetch Git LFS objects of repositories on this GitLab instance, so that files stored with Git LFS show their content instead of LFS pointers. Only objects on the default branch are fetched, up to the size limit of searched files (see the search.largeFiles site configuration property). Requires "gitURLType" to be "http".
+	GitLFS bool `json:"gitLFS,omitempty"`
 	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on this GitLab instance.
 	//
 	// If "http", Sourcegraph will access GitLab repositories using Git URLs of the form http(s)://gitlab.example.com/myteam/myproject.git (using https: if the GitLab instance uses HTTPS).
@@ -551,6 +637,8 @@ type GitLabConnection struct {
 	Token string `json:"token"`
 	// Url description: URL of a GitLab instance, such as https://gitlab.example.com or (for GitLab.com) https://gitlab.com.
 	Url string `json:"url"`
+	// Webhooks description: An array of configurations defining existing GitLab webhooks that send updates back to Sourcegraph.
+	Webhooks []*GitLabWebhook `json:"webhooks,omitempty"`
 }
 type GitLabNameTransformation struct {
 	// Regex description: The regex to match for the occurrences of its replacement.
@@ -564,6 +652,58 @@ type GitLabProject struct {
 	// Name description: The name of a GitLab project ("group/name") to mirror.
 	Name string `json:"name,omitempty"`
 }
+type GitLabWebhook struct {
+	// Secret description: The secret token used when creating the webhook
+	Secret string `json:"secret"`
+}
+
+// GiteaAuthorization description: If non-null, enforces Gitea repository permissions. Sourcegraph users are matched to users of the code host by username, so this requires that the usernames on Sourcegraph and the code host are the same (for example, by using the same LDAP or SAML identity provider for both). The token of this connection must belong to a site admin of the code host. Gogs is not supported, because it cannot list repositories on behalf of other users; if the token does not belong to a Gitea site admin, access to all repositories is blocked.
+type GiteaAuthorization struct {
+	// Ttl description: The TTL of how long to cache permissions data. This is 3 hours by default.
+	//
+	// Decreasing the TTL will increase the load on the code host API. If you have X repositories on your instance, it will take ~X/50 API requests to fetch the complete list for 1 user. If you have Y users, you will incur up to X*Y/50 API requests per cache refresh period (depending on user activity).
+	//
+	// If set to zero, Sourcegraph will sync a user's entire accessible repository list on every request (NOT recommended).
+	//
+	// Public repositories are cached once for all users per cache TTL period.
+	Ttl string `json:"ttl,omitempty"`
+}
+
+// GiteaConnection description: Configuration for a connection to Gitea or Gogs.
+type GiteaConnection struct {
+	// Authorization description: If non-null, enforces Gitea repository permissions. Sourcegraph users are matched to users of the code host by username, so this requires that the usernames on Sourcegraph and the code host are the same (for example, by using the same LDAP or SAML identity provider for both). The token of this connection must belong to a site admin of the code host. Gogs is not supported, because it cannot list repositories on behalf of other users; if the token does not belong to a Gitea site admin, access to all repositories is blocked.
+	Authorization *GiteaAuthorization `json:"authorization,omitempty"`
+	// Certificate description: TLS certificate of the Gitea or Gogs instance. This is only necessary if the certificate is self-signed or signed by an internal CA. To get the certificate run `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM`. To escape the value into a JSON string, you may want to use a tool like https://json-escape-text.now.sh.
+	Certificate string `json:"certificate,omitempty"`
+	// Exclude description: A list of repositories to never mirror from this Gitea or Gogs instance. Takes precedence over "orgs", "users" and "repos" configuration.
+	//
+	// Supports excluding by name ({"name": "myorg/myrepo"}), by ID ({"id": 42}) or by a regular expression matching the name ({"pattern": "^myorg/.*"}).
+	Exclude []*ExcludedGiteaRepo `json:"exclude,omitempty"`
+	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on this Gitea or Gogs instance.
+	//
+	// If "http", Sourcegraph will access repositories using Git URLs of the form https://gitea.example.com/myorg/myrepo.git, with the token inserted in the URL.
+	//
+	// If "ssh", Sourcegraph will access repositories using Git URLs of the form git@gitea.example.com:myorg/myrepo.git. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.
+	GitURLType string `json:"gitURLType,omitempty"`
+	// Orgs description: An array of organization names identifying organizations whose repositories should be mirrored on Sourcegraph.
+	Orgs []string `json:"orgs,omitempty"`
+	// Repos description: An array of repository "owner/name" strings specifying which repositories to mirror on Sourcegraph.
+	Repos []string `json:"repos,omitempty"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for a Gitea or Gogs repository.
+	//
+	//  - "{host}" is replaced with the Gitea URL's host (such as gitea.example.com), and "{nameWithOwner}" is replaced with the repository's "owner/name" (such as "myorg/myrepo").
+	//
+	// For example, if your Gitea is https://gitea.example.com and your Sourcegraph is https://src.example.com, then a repositoryPathPattern of "{host}/{nameWithOwner}" would mean that a repository at https://gitea.example.com/alice/my-repo is available on Sourcegraph at https://src.example.com/gitea.example.com/alice/my-repo.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// Token description: An access token of a user of the Gitea or Gogs instance. The repositories that are accessible to this user are mirrored on Sourcegraph. To enforce repository permissions with "authorization", the token must belong to a site admin.
+	Token string `json:"token"`
+	// Url description: URL of a Gitea or Gogs instance, such as https://gitea.example.com.
+	Url string `json:"url"`
+	// Users description: An array of user names identifying users whose repositories should be mirrored on Sourcegraph.
+	Users []string `json:"users,omitempty"`
+}
 
 // GitoliteConnection description: Configuration for a connection to Gitolite.
 type GitoliteConnection struct {
@@ -147,6 +147,10 @@ function serviceTypeDisplayNameAndIcon(
             return { displayName: 'GitHub', icon: GithubCircleIcon }
         case 'gitlab':
             return { displayName: 'GitLab' }
+        case 'gitea':
+            return { displayName: 'Gitea' }
+        case 'gerrit':
+            return { displayName: 'Gerrit' }
         case 'bitbucketServer':
             // TODO: Why is bitbucketServer (correctly) camelCase but
             // awscodecommit is (correctly) lowercase? Why is serviceType
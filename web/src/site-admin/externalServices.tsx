@@ -9,6 +9,8 @@ import React from 'react'
 import awsCodeCommitSchemaJSON from '../../../schema/aws_codecommit.schema.json'
 import bitbucketCloudSchemaJSON from '../../../schema/bitbucket_cloud.schema.json'
 import bitbucketServerSchemaJSON from '../../../schema/bitbucket_server.schema.json'
+import gerritSchemaJSON from '../../../schema/gerrit.schema.json'
+import giteaSchemaJSON from '../../../schema/gitea.schema.json'
 import githubSchemaJSON from '../../../schema/github.schema.json'
 import gitlabSchemaJSON from '../../../schema/gitlab.schema.json'
 import gitoliteSchemaJSON from '../../../schema/gitolite.schema.json'
@@ -885,6 +887,196 @@ const GITLAB_SELF_MANAGED: AddExternalServiceOptions = {
 }`,
 }
 
+const GITEA: AddExternalServiceOptions = {
+    kind: GQL.ExternalServiceKind.GITEA,
+    title: 'Gitea / Gogs',
+    icon: GitIcon,
+    jsonSchema: giteaSchemaJSON,
+    defaultDisplayName: 'Gitea',
+    defaultConfig: `{
+  "url": "https://gitea.example.com",
+  "token": "<access token>",
+  "orgs": []
+}`,
+    editorActions: [
+        {
+            id: 'setAccessToken',
+            label: 'Set access token',
+            run: config => {
+                const value = '<access token>'
+                const edits = setProperty(config, ['token'], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addOrgRepositories',
+            label: 'Add repositories of an organization',
+            run: config => {
+                const value = '<organization name>'
+                const edits = setProperty(config, ['orgs', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addUserRepositories',
+            label: 'Add repositories of a user',
+            run: config => {
+                const value = '<username>'
+                const edits = setProperty(config, ['users', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addRepo',
+            label: 'Add a single repository',
+            run: config => {
+                const value = '<owner>/<repository>'
+                const edits = setProperty(config, ['repos', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'excludeRepo',
+            label: 'Exclude a repository',
+            run: config => {
+                const value = { name: '<owner>/<repository>' }
+                const edits = setProperty(config, ['exclude', -1], value, defaultFormattingOptions)
+                return { edits, selectText: '<owner>/<repository>' }
+            },
+        },
+        {
+            id: 'enforcePermissions',
+            label: 'Enforce permissions',
+            run: config => {
+                const value = { ttl: '3h' }
+                const edits = setProperty(config, ['authorization'], value, defaultFormattingOptions)
+                return { edits, selectText: '"authorization"' }
+            },
+        },
+    ],
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    Set the <Field>url</Field> field to the URL of your Gitea or Gogs instance.
+                </li>
+                <li>
+                    Create an access token in the <b>Applications</b> tab of the user settings of your Gitea or Gogs
+                    instance, and set it to be the value of the <Field>token</Field> field in the configuration below.
+                    The token must belong to a site admin to enforce repository permissions.
+                </li>
+                <li>
+                    Set the <Field>orgs</Field>, <Field>users</Field> or <Field>repos</Field> fields to select the
+                    repositories Sourcegraph should index.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/gitea#configuration"
+                >
+                    the docs for more options
+                </a>
+                , or try one of the buttons below.
+            </p>
+        </div>
+    ),
+}
+const GERRIT: AddExternalServiceOptions = {
+    kind: GQL.ExternalServiceKind.GERRIT,
+    title: 'Gerrit',
+    icon: GitIcon,
+    jsonSchema: gerritSchemaJSON,
+    defaultDisplayName: 'Gerrit',
+    defaultConfig: `{
+  "url": "https://gerrit.example.com",
+  "username": "<username>",
+  "password": "<HTTP password>",
+  "projectQuery": [
+    "state:active"
+  ],
+  "changeQuery": "status:open"
+}`,
+    editorActions: [
+        {
+            id: 'setPassword',
+            label: 'Set HTTP password',
+            run: config => {
+                const value = '<HTTP password>'
+                const edits = setProperty(config, ['password'], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addProjectQuery',
+            label: 'Add projects matching a query',
+            run: config => {
+                const value = '<project query>'
+                const edits = setProperty(config, ['projectQuery', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addProject',
+            label: 'Add a single project',
+            run: config => {
+                const value = '<project name>'
+                const edits = setProperty(config, ['projects', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'excludeProject',
+            label: 'Exclude a project',
+            run: config => {
+                const value = { name: '<project name>' }
+                const edits = setProperty(config, ['exclude', -1], value, defaultFormattingOptions)
+                return { edits, selectText: '<project name>' }
+            },
+        },
+        {
+            id: 'fetchOpenChanges',
+            label: 'Fetch open changes',
+            run: config => {
+                const value = 'status:open'
+                const edits = setProperty(config, ['changeQuery'], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+    ],
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    Set the <Field>url</Field> field to the URL of your Gerrit instance.
+                </li>
+                <li>
+                    Generate an HTTP password on the <b>HTTP Credentials</b> page of the settings of your Gerrit
+                    account, and set the <Field>username</Field> and <Field>password</Field> fields in the
+                    configuration below. Remove both fields to access Gerrit anonymously.
+                </li>
+                <li>
+                    Set the <Field>projectQuery</Field> or <Field>projects</Field> fields to select the projects
+                    Sourcegraph should index, and the <Field>changeQuery</Field> field to select the changes whose
+                    current patch sets should be searchable.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/gerrit#configuration"
+                >
+                    the docs for more options
+                </a>
+                , or try one of the buttons below.
+            </p>
+        </div>
+    ),
+}
 const GITOLITE: AddExternalServiceOptions = {
     kind: GQL.ExternalServiceKind.GITOLITE,
     title: 'Gitolite',
@@ -1054,6 +1246,8 @@ export const codeHostExternalServices: Record<string, AddExternalServiceOptions>
     gitlab: GITLAB_SELF_MANAGED,
     bitbucket: BITBUCKET_CLOUD,
     bitbucketserver: BITBUCKET_SERVER,
+    gitea: GITEA,
+    gerrit: GERRIT,
     aws_codecommit: AWS_CODE_COMMIT,
     gitolite: GITOLITE,
     git: GENERIC_GIT,
@@ -1072,6 +1266,8 @@ export const defaultExternalServices: Record<GQL.ExternalServiceKind, AddExterna
     [GQL.ExternalServiceKind.GITHUB]: GITHUB_DOTCOM,
     [GQL.ExternalServiceKind.BITBUCKETCLOUD]: BITBUCKET_CLOUD,
     [GQL.ExternalServiceKind.BITBUCKETSERVER]: BITBUCKET_SERVER,
+    [GQL.ExternalServiceKind.GERRIT]: GERRIT,
+    [GQL.ExternalServiceKind.GITEA]: GITEA,
     [GQL.ExternalServiceKind.GITLAB]: GITLAB_DOTCOM,
     [GQL.ExternalServiceKind.GITOLITE]: GITOLITE,
     [GQL.ExternalServiceKind.PHABRICATOR]: PHABRICATOR_SERVICE,